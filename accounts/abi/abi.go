@@ -19,10 +19,13 @@ package abi
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // The ABI holds information about a contract's context and available
@@ -190,3 +193,61 @@ func (abi *ABI) EventByID(topic common.Hash) (*Event, error) {
 	}
 	return nil, fmt.Errorf("no event with id: %#x", topic.Hex())
 }
+
+var (
+	// revertSelector is the selector of the Error(string) payload of reverts.
+	revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+	// panicSelector is the selector of the Panic(uint256) payload of failed
+	// assertions and compiler inserted checks.
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+
+	// panicReasons are the descriptions of the panic codes defined by Solidity.
+	panicReasons = map[uint64]string{
+		0x00: "generic panic",
+		0x01: "assert(false)",
+		0x11: "arithmetic underflow or overflow",
+		0x12: "division or modulo by zero",
+		0x21: "enum overflow",
+		0x22: "invalid encoded storage byte array accessed",
+		0x31: "out-of-bounds array access; popping on an empty array",
+		0x32: "out-of-bounds access of an array or bytesN",
+		0x41: "out of memory",
+		0x51: "uninitialized function",
+	}
+
+	errInvalidRevert = errors.New("invalid data for unpacking")
+)
+
+// UnpackRevert resolves the ABI encoded reason of a reverted call. Solidity
+// encodes it as if it were a call to Error(string), or to Panic(uint256) for
+// failed assertions and compiler inserted checks.
+func UnpackRevert(data []byte) (string, error) {
+	if len(data) < 4 {
+		return "", errInvalidRevert
+	}
+	switch {
+	case bytes.Equal(data[:4], revertSelector):
+		typ, _ := NewType("string", nil)
+		unpacked, err := (Arguments{{Type: typ}}).UnpackValues(data[4:])
+		if err != nil {
+			return "", err
+		}
+		return unpacked[0].(string), nil
+
+	case bytes.Equal(data[:4], panicSelector):
+		typ, _ := NewType("uint256", nil)
+		unpacked, err := (Arguments{{Type: typ}}).UnpackValues(data[4:])
+		if err != nil {
+			return "", err
+		}
+		code := unpacked[0].(*big.Int)
+		if code.IsUint64() {
+			if reason, ok := panicReasons[code.Uint64()]; ok {
+				return reason, nil
+			}
+		}
+		return fmt.Sprintf("unknown panic code: %#x", code), nil
+	}
+	return "", errInvalidRevert
+}
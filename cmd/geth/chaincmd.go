@@ -17,8 +17,10 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -30,17 +32,24 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/console"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/chainspec"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/trie"
 	"gopkg.in/urfave/cli.v1"
 )
 
 var (
+	genesisFormatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Format of the genesis specification (geth, parity)",
+		Value: "geth",
+	}
 	initCommand = cli.Command{
 		Action:    utils.MigrateFlags(initGenesis),
 		Name:      "init",
@@ -48,6 +57,7 @@ var (
 		ArgsUsage: "<genesisPath>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			genesisFormatFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -55,7 +65,33 @@ The init command initializes a new genesis block and definition for the network.
 This is a destructive action and changes the network in which you will be
 participating.
 
-It expects the genesis file as argument.`,
+It expects the genesis file as argument. Besides the go-ethereum genesis format,
+Parity chain specifications are accepted with --format=parity.`,
+	}
+	dumpGenesisCommand = cli.Command{
+		Action:    utils.MigrateFlags(dumpGenesis),
+		Name:      "dumpgenesis",
+		Usage:     "Dump the genesis specification of the selected network",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			genesisFormatFlag,
+			utils.ChainFlag,
+			utils.TestnetFlag,
+			utils.ClassicFlag,
+			utils.MordorFlag,
+			utils.SocialFlag,
+			utils.MixFlag,
+			utils.EthersocialFlag,
+			utils.MusicoinFlag,
+			utils.RinkebyFlag,
+			utils.KottiFlag,
+			utils.GoerliFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The dumpgenesis command writes the genesis specification of the network selected
+by the command line flags to standard output, either in the go-ethereum genesis
+format or, with --format=parity, as a Parity chain specification.`,
 	}
 	importCommand = cli.Command{
 		Action:    utils.MigrateFlags(importChain),
@@ -67,8 +103,10 @@ It expects the genesis file as argument.`,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.SnapshotFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.CacheSnapshotFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -134,6 +172,7 @@ The export-preimages command export hash preimages to an RLP encoded stream`,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.FakePoWFlag,
+			utils.ChainFlag,
 			utils.TestnetFlag,
 			utils.ClassicFlag,
 			utils.MordorFlag,
@@ -187,6 +226,7 @@ Use "ethereum dump 0" to dump the genesis block.`,
 			utils.DataDirFlag,
 			utils.AncientFlag,
 			utils.CacheFlag,
+			utils.ChainFlag,
 			utils.TestnetFlag,
 			utils.RinkebyFlag,
 			utils.GoerliFlag,
@@ -204,15 +244,9 @@ func initGenesis(ctx *cli.Context) error {
 	if len(genesisPath) == 0 {
 		utils.Fatalf("Must supply path to genesis JSON file")
 	}
-	file, err := os.Open(genesisPath)
+	genesis, err := readGenesis(genesisPath, genesisFormat(ctx), false)
 	if err != nil {
-		utils.Fatalf("Failed to read genesis file: %v", err)
-	}
-	defer file.Close()
-
-	genesis := new(core.Genesis)
-	if err := json.NewDecoder(file).Decode(genesis); err != nil {
-		utils.Fatalf("invalid genesis file: %v", err)
+		utils.Fatalf("%v", err)
 	}
 	// Open an initialise both full and light databases
 	stack := makeFullNode(ctx)
@@ -233,6 +267,119 @@ func initGenesis(ctx *cli.Context) error {
 	return nil
 }
 
+// genesisFormat returns the genesis specification format selected on the command
+// line. The flag is command specific, so it's never migrated to the global set.
+func genesisFormat(ctx *cli.Context) string {
+	if format := ctx.String(genesisFormatFlag.Name); format != "" {
+		return format
+	}
+	return genesisFormatFlag.Value
+}
+
+// readGenesis loads a genesis specification of the given format from a file. In
+// strict mode, unknown fields of the chain config are rejected instead of being
+// silently ignored.
+func readGenesis(path string, format string, strict bool) (*core.Genesis, error) {
+	blob, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read genesis file: %v", err)
+	}
+	switch format {
+	case "geth":
+		genesis := new(core.Genesis)
+		if err := json.Unmarshal(blob, genesis); err != nil {
+			return nil, fmt.Errorf("invalid genesis file: %v", err)
+		}
+		if strict {
+			var fields struct {
+				Config json.RawMessage `json:"config"`
+			}
+			if err := json.Unmarshal(blob, &fields); err == nil && len(fields.Config) > 0 {
+				dec := json.NewDecoder(bytes.NewReader(fields.Config))
+				dec.DisallowUnknownFields()
+				if err := dec.Decode(new(params.ChainConfig)); err != nil {
+					return nil, fmt.Errorf("invalid chain config: %v", err)
+				}
+			}
+		}
+		return genesis, nil
+
+	case "parity":
+		spec := new(chainspec.ParityChainSpec)
+		if err := json.Unmarshal(blob, spec); err != nil {
+			return nil, fmt.Errorf("invalid chain spec file: %v", err)
+		}
+		genesis, err := spec.ToGenesis()
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert chain spec: %v", err)
+		}
+		return genesis, nil
+
+	default:
+		return nil, fmt.Errorf("unknown genesis format %q", format)
+	}
+}
+
+// dumpGenesis writes the genesis specification of the selected network to the
+// standard output in the requested format.
+func dumpGenesis(ctx *cli.Context) error {
+	genesis := utils.MakeGenesis(ctx)
+	if genesis == nil {
+		genesis = core.DefaultGenesisBlock()
+	}
+	var out interface{}
+	switch format := genesisFormat(ctx); format {
+	case "geth":
+		out = genesis
+	case "parity":
+		// Find the registered network the genesis belongs to for its name and bootnodes
+		network := utils.MakeNetwork(ctx)
+		if network == nil {
+			network = lookupGenesisNetwork(genesis)
+		}
+		name, networkID, bootnodes := "", uint64(0), []string(nil)
+		if network != nil {
+			name, networkID, bootnodes = network.Name, network.NetworkID, network.Bootnodes
+		}
+		if ctx.GlobalIsSet(utils.NetworkIdFlag.Name) {
+			networkID = ctx.GlobalUint64(utils.NetworkIdFlag.Name)
+		}
+		spec, err := chainspec.NewParityChainSpec(name, networkID, genesis, bootnodes)
+		if err != nil {
+			utils.Fatalf("Failed to convert genesis: %v", err)
+		}
+		out = spec
+	default:
+		utils.Fatalf("Unknown genesis format %q", format)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		utils.Fatalf("Failed to encode genesis: %v", err)
+	}
+	return nil
+}
+
+// lookupGenesisNetwork returns the registered network with the same genesis block
+// as the given one, or nil if there's none.
+func lookupGenesisNetwork(genesis *core.Genesis) *core.Network {
+	hash := genesis.ToBlock(nil).Hash()
+	for _, name := range core.NetworkNames() {
+		network, err := core.LookupNetwork(name)
+		if err != nil {
+			continue
+		}
+		// Only hash the genesis of networks with the same chain ID, it's expensive
+		if have, want := network.Genesis.Config.ChainID, genesis.Config.ChainID; have != nil && want != nil && have.Cmp(want) != 0 {
+			continue
+		}
+		if network.Genesis.ToBlock(nil).Hash() == hash {
+			return network
+		}
+	}
+	return nil
+}
+
 func importChain(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -415,7 +562,7 @@ func copyDb(ctx *cli.Context) error {
 	dl := downloader.New(0, chainDb, syncBloom, new(event.TypeMux), chain, nil, nil)
 
 	// Create a source peer to satisfy downloader requests from
-	db, err := rawdb.NewLevelDBDatabaseWithFreezer(ctx.Args().First(), ctx.GlobalInt(utils.CacheFlag.Name)/2, 256, ctx.Args().Get(1), "")
+	db, err := rawdb.NewDatabaseWithEngine("", ctx.Args().First(), ctx.GlobalInt(utils.CacheFlag.Name)/2, 256, ctx.Args().Get(1), rawdb.FreezerConfig{}, "")
 	if err != nil {
 		return err
 	}
@@ -527,7 +674,7 @@ func dump(ctx *cli.Context) error {
 			fmt.Println("{}")
 			utils.Fatalf("block not found")
 		} else {
-			state, err := state.New(block.Root(), state.NewDatabase(chainDb))
+			state, err := state.New(block.Root(), state.NewDatabase(chainDb), nil)
 			if err != nil {
 				utils.Fatalf("could not create new state: %v", err)
 			}
@@ -0,0 +1,152 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	// networkFlags select one of the built-in networks.
+	networkFlags = []cli.Flag{
+		utils.ChainFlag,
+		utils.TestnetFlag,
+		utils.ClassicFlag,
+		utils.MordorFlag,
+		utils.SocialFlag,
+		utils.MixFlag,
+		utils.EthersocialFlag,
+		utils.MusicoinFlag,
+		utils.RinkebyFlag,
+		utils.KottiFlag,
+		utils.GoerliFlag,
+	}
+	chainConfigFlags   = append([]cli.Flag{genesisFormatFlag}, networkFlags...)
+	chainConfigCommand = cli.Command{
+		Name:      "chainconfig",
+		Usage:     "Inspect and validate chain configurations",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+The chainconfig commands operate on the chain configuration of a genesis file,
+given as argument (in the format selected with --format), or otherwise of the
+network selected by the command line flags.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "check",
+				Usage:     "Validate the consistency of a chain configuration",
+				ArgsUsage: "[<genesisPath>]",
+				Action:    utils.MigrateFlags(checkChainConfig),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags:     chainConfigFlags,
+				Description: `
+The check command validates the hard fork ordering, the dependencies between the
+individual EIPs and ECIPs, and the completeness of multi-field settings, such as
+the ECIP-1010 parameters. Genesis files are also checked for unknown chain config
+fields, which are usually misspelled fork names. It fails if any error is found.`,
+			},
+			{
+				Name:      "show",
+				Usage:     "Show the fork schedule of a chain configuration",
+				ArgsUsage: "[<genesisPath>]",
+				Action:    utils.MigrateFlags(showChainConfig),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags:     chainConfigFlags,
+				Description: `
+The show command prints the activation block of every individual EIP and ECIP,
+followed by the fork ID (EIP-2124) announced by the network at each transition.`,
+			},
+		},
+	}
+)
+
+// loadChainConfigGenesis returns the genesis given as command argument, or the
+// one of the network selected by the command line flags.
+func loadChainConfigGenesis(ctx *cli.Context) *core.Genesis {
+	if path := ctx.Args().First(); path != "" {
+		genesis, err := readGenesis(path, genesisFormat(ctx), true)
+		if err != nil {
+			utils.Fatalf("%v", err)
+		}
+		if genesis.Config == nil {
+			utils.Fatalf("Genesis file has no chain config")
+		}
+		return genesis
+	}
+	if genesis := utils.MakeGenesis(ctx); genesis != nil {
+		return genesis
+	}
+	return core.DefaultGenesisBlock()
+}
+
+// checkChainConfig validates the selected chain config, failing if it's unsound.
+func checkChainConfig(ctx *cli.Context) error {
+	config := loadChainConfigGenesis(ctx).Config
+
+	warnings, errs := config.Check()
+	for _, warning := range warnings {
+		fmt.Println("WARNING:", warning)
+	}
+	for _, err := range errs {
+		fmt.Println("ERROR:", err)
+	}
+	if len(errs) > 0 {
+		utils.Fatalf("Chain config has %d error(s)", len(errs))
+	}
+	fmt.Printf("Chain config OK (%d warnings)\n", len(warnings))
+	return nil
+}
+
+// showChainConfig prints the activation table and fork IDs of the selected chain
+// config.
+func showChainConfig(ctx *cli.Context) error {
+	genesis := loadChainConfigGenesis(ctx)
+	config := genesis.Config
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Fork", "Change", "Block"})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	for _, activation := range config.Activations() {
+		block := "-"
+		if activation.Block != nil {
+			block = activation.Block.String()
+		}
+		table.Append([]string{activation.Fork, activation.Name, block})
+	}
+	table.Render()
+	fmt.Println()
+
+	hash := genesis.ToBlock(nil).Hash()
+	fmt.Printf("Genesis: %x\n", hash)
+
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Block", "Fork hash", "Next fork"})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	for _, head := range append([]uint64{0}, forkid.Forks(config)...) {
+		id := forkid.NewStaticID(config, hash, head)
+		table.Append([]string{fmt.Sprint(head), fmt.Sprintf("%#x", id.Hash), fmt.Sprint(id.Next)})
+	}
+	table.Render()
+	return nil
+}
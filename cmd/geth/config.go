@@ -18,8 +18,10 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"reflect"
 	"unicode"
@@ -46,6 +48,24 @@ var (
 		Description: `The dumpconfig command shows configuration values.`,
 	}
 
+	openrpcCommand = cli.Command{
+		Action:    utils.MigrateFlags(dumpOpenRPC),
+		Name:      "openrpc",
+		Usage:     "Export the OpenRPC document of the node's APIs",
+		ArgsUsage: "[<filename>]",
+		Flags:     append(append(nodeFlags, rpcFlags...), whisperFlags...),
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `
+The openrpc command starts a throwaway node with the given configuration and
+exports the OpenRPC document of all the APIs it offers, generated from their
+Go signatures. The node runs from a temporary data directory with networking
+disabled, so the command doesn't touch any existing chain.
+
+The document published in internal/openrpc/openrpc.json is regenerated with:
+
+    geth openrpc internal/openrpc/openrpc.json`,
+	}
+
 	configFileFlag = cli.StringFlag{
 		Name:  "config",
 		Usage: "TOML configuration file",
@@ -129,6 +149,7 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 		utils.Fatalf("Failed to create the protocol stack: %v", err)
 	}
 	utils.SetEthConfig(ctx, stack, &cfg.Eth)
+
 	if ctx.GlobalIsSet(utils.EthStatsURLFlag.Name) {
 		cfg.Ethstats.URL = ctx.GlobalString(utils.EthStatsURLFlag.Name)
 	}
@@ -150,6 +171,7 @@ func enableWhisper(ctx *cli.Context) bool {
 
 func makeFullNode(ctx *cli.Context) *node.Node {
 	stack, cfg := makeConfigNode(ctx)
+	resumeConvertDB(stack, &cfg)
 	utils.RegisterEthService(stack, &cfg.Eth)
 
 	if ctx.GlobalBool(utils.DashboardEnabledFlag.Name) {
@@ -172,7 +194,7 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	}
 	// Configure GraphQL if requested
 	if ctx.GlobalIsSet(utils.GraphQLEnabledFlag.Name) {
-		utils.RegisterGraphQLService(stack, cfg.Node.GraphQLEndpoint(), cfg.Node.GraphQLCors, cfg.Node.GraphQLVirtualHosts, cfg.Node.HTTPTimeouts)
+		utils.RegisterGraphQLService(stack, cfg.Node.GraphQLEndpoint(), cfg.Node.GraphQLCors, cfg.Node.GraphQLVirtualHosts, cfg.Node.HTTPTimeouts, cfg.Node.GraphQLTracing)
 	}
 	// Add the Ethereum Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
@@ -209,3 +231,47 @@ func dumpConfig(ctx *cli.Context) error {
 
 	return nil
 }
+
+// dumpOpenRPC starts a temporary node with networking disabled and exports the
+// OpenRPC document served by its in-process RPC endpoint.
+func dumpOpenRPC(ctx *cli.Context) error {
+	datadir, err := ioutil.TempDir("", "geth-openrpc-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(datadir)
+
+	ctx.GlobalSet(utils.DataDirFlag.Name, datadir)
+	ctx.GlobalSet(utils.IPCDisabledFlag.Name, "true")
+	ctx.GlobalSet(utils.NoDiscoverFlag.Name, "true")
+	ctx.GlobalSet(utils.MaxPeersFlag.Name, "0")
+	ctx.GlobalSet(utils.ListenPortFlag.Name, "0")
+
+	stack := makeFullNode(ctx)
+	defer stack.Close()
+
+	if err := stack.Start(); err != nil {
+		return fmt.Errorf("failed to start node: %v", err)
+	}
+	client, err := stack.Attach()
+	if err != nil {
+		return fmt.Errorf("failed to attach to node: %v", err)
+	}
+	defer client.Close()
+
+	var doc json.RawMessage
+	if err := client.Call(&doc, "rpc_discover"); err != nil {
+		return fmt.Errorf("failed to retrieve OpenRPC document: %v", err)
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+
+	if ctx.NArg() > 0 {
+		return ioutil.WriteFile(ctx.Args().Get(0), out, 0644)
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
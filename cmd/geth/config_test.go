@@ -0,0 +1,51 @@
+// Copyright 2016 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests that the published OpenRPC document matches the one generated from the
+// registered APIs. If this fails, regenerate the document from the repository
+// root with `geth openrpc internal/openrpc/openrpc.json`.
+func TestOpenRPCDocument(t *testing.T) {
+	want, err := ioutil.ReadFile(filepath.Join("..", "..", "internal", "openrpc", "openrpc.json"))
+	if err != nil {
+		t.Fatalf("failed to read published document: %v", err)
+	}
+	datadir := tmpdir(t)
+	defer os.RemoveAll(datadir)
+
+	out := filepath.Join(datadir, "openrpc.json")
+	geth := runGeth(t, "openrpc", out)
+	geth.WaitExit()
+	if status := geth.ExitStatus(); status != 0 {
+		t.Fatalf("openrpc command failed with status %d: %s", status, geth.StderrText())
+	}
+	have, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read generated document: %v", err)
+	}
+	if !bytes.Equal(have, want) {
+		t.Fatalf("published OpenRPC document is out of date, regenerate it with `geth openrpc internal/openrpc/openrpc.json`")
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dbCommand = cli.Command{
+		Name:      "db",
+		Usage:     "Low level database operations",
+		ArgsUsage: "",
+		Category:  "DATABASE COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:      "convert",
+				Usage:     "Convert the chain database to a different engine",
+				ArgsUsage: " ",
+				Action:    utils.MigrateFlags(convertDB),
+				Category:  "DATABASE COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.DBEngineFlag,
+					utils.CacheFlag,
+					utils.SyncModeFlag,
+					utils.TestnetFlag,
+					utils.ClassicFlag,
+					utils.MordorFlag,
+					utils.RinkebyFlag,
+					utils.KottiFlag,
+					utils.GoerliFlag,
+				},
+				Description: `
+geth --db.engine=<engine> db convert
+
+copies every entry of the chain database into a new database of the engine
+given by --db.engine, replacing the original one afterwards. The ancient chain
+segments are engine independent and are left untouched.
+
+The original database is moved aside to a backup directory next to the chain
+database, which can be removed once the converted database is verified. If the
+command is interrupted while swapping the two, the swap is finished the next
+time geth is started.`,
+			},
+		},
+	}
+)
+
+// convertDB copies the content of the chain database into a fresh key-value
+// store of the requested engine, swapping the two afterwards.
+func convertDB(ctx *cli.Context) error {
+	stack, cfg := makeConfigNode(ctx)
+	defer stack.Close()
+	resumeConvertDB(stack, &cfg)
+
+	target := cfg.Node.DBEngine
+	if target == "" {
+		utils.Fatalf("Target database engine missing, use --%s", utils.DBEngineFlag.Name)
+	}
+	path := chainDataPath(stack, &cfg)
+
+	source := rawdb.PreexistingDatabase(path)
+	switch source {
+	case "":
+		utils.Fatalf("No database found at %s", path)
+	case target:
+		utils.Fatalf("Database at %s already uses %s", path, target)
+	}
+	backup := path + "." + source + ".bak"
+	if _, err := os.Stat(backup); err == nil {
+		utils.Fatalf("Database backup %s already exists, remove it first", backup)
+	}
+	// Remove the leftovers of any previously interrupted conversion
+	temp := path + ".convert"
+	if err := os.RemoveAll(temp); err != nil {
+		return err
+	}
+	cache := ctx.GlobalInt(utils.CacheFlag.Name) / 2
+
+	srcdb, err := rawdb.NewKeyValueStore(source, path, cache, 256, "")
+	if err != nil {
+		utils.Fatalf("Failed to open %s database: %v", source, err)
+	}
+	dstdb, err := rawdb.NewKeyValueStore(target, temp, cache, 256, "")
+	if err != nil {
+		srcdb.Close()
+		utils.Fatalf("Failed to create %s database: %v", target, err)
+	}
+	log.Info("Converting database", "path", path, "from", source, "to", target)
+	err = copyKeyValueStore(srcdb, dstdb)
+
+	srcdb.Close()
+	if cerr := dstdb.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.RemoveAll(temp)
+		utils.Fatalf("Database conversion failed: %v", err)
+	}
+	// Conversion done, record it so an interrupted swap is finished on the next
+	// start instead of leaving the chain database half moved
+	if err := writeConvertMarker(path+".convert.done", backup); err != nil {
+		utils.Fatalf("Failed to record database conversion: %v", err)
+	}
+	if err := swapConvertedDB(path); err != nil {
+		utils.Fatalf("Failed to move %s database in place: %v", target, err)
+	}
+	log.Info("Database conversion finished", "engine", target, "backup", backup)
+	return nil
+}
+
+// chainDataPath returns the location of the chain database of the node.
+func chainDataPath(stack *node.Node, cfg *gethConfig) string {
+	if cfg.Eth.SyncMode == downloader.LightSync {
+		return stack.ResolvePath("lightchaindata")
+	}
+	return stack.ResolvePath("chaindata")
+}
+
+// resumeConvertDB finishes swapping in a converted chain database if a previous
+// conversion was interrupted. It must only be called by commands about to open
+// the chain database, as it moves directories around within the data dir.
+func resumeConvertDB(stack *node.Node, cfg *gethConfig) {
+	path := chainDataPath(stack, cfg)
+	if path == "" {
+		return // ephemeral node without a data dir
+	}
+	if err := swapConvertedDB(path); err != nil {
+		utils.Fatalf("Failed to finish database conversion: %v", err)
+	}
+}
+
+// writeConvertMarker durably creates the marker of a completed conversion,
+// holding the location the original database is to be moved to.
+func writeConvertMarker(marker string, backup string) error {
+	f, err := os.Create(marker)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(backup); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// swapConvertedDB moves the converted database at path.convert in place of the
+// original one, which is moved to the backup location recorded in the marker.
+// Each step is a single directory rename, so the swap can be resumed from any
+// point it was interrupted at. It's a noop if no conversion is pending.
+//
+// The ancient store in the chain database directory is engine independent, so
+// it's moved into the converted database before the two are swapped.
+func swapConvertedDB(path string) error {
+	var (
+		temp   = path + ".convert"
+		marker = path + ".convert.done"
+	)
+	blob, err := ioutil.ReadFile(marker)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	backup := string(blob)
+
+	if common.FileExist(temp) {
+		if common.FileExist(path) {
+			ancient := filepath.Join(path, "ancient")
+			if common.FileExist(ancient) {
+				if err := os.Rename(ancient, filepath.Join(temp, "ancient")); err != nil {
+					return err
+				}
+			}
+			if err := os.Rename(path, backup); err != nil {
+				return err
+			}
+		}
+		if err := os.Rename(temp, path); err != nil {
+			return err
+		}
+	}
+	return os.Remove(marker)
+}
+
+// copyKeyValueStore copies every entry of a key-value store into another.
+func copyKeyValueStore(src ethdb.KeyValueStore, dst ethdb.KeyValueStore) error {
+	it := src.NewIterator()
+	defer it.Release()
+
+	var (
+		count  int
+		size   common.StorageSize
+		start  = time.Now()
+		logged = time.Now()
+		batch  = dst.NewBatch()
+	)
+	for it.Next() {
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return err
+		}
+		count++
+		size += common.StorageSize(len(it.Key()) + len(it.Value()))
+
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Converting database", "entries", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Copied database entries", "entries", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
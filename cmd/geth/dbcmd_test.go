@@ -0,0 +1,83 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests that swapping in a converted database can be resumed from every point
+// it may have been interrupted at.
+func TestSwapConvertedDB(t *testing.T) {
+	for stage := 0; stage <= 3; stage++ {
+		dir, err := ioutil.TempDir("", "geth-convert-")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		var (
+			path   = filepath.Join(dir, "chaindata")
+			temp   = path + ".convert"
+			backup = path + ".leveldb.bak"
+		)
+		// Create the original and converted databases along with the marker
+		for _, file := range []string{
+			filepath.Join(path, "old.db"),
+			filepath.Join(path, "ancient", "bodies.cdat"),
+			filepath.Join(temp, "new.db"),
+		} {
+			os.MkdirAll(filepath.Dir(file), 0700)
+			if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := writeConvertMarker(path+".convert.done", backup); err != nil {
+			t.Fatalf("stage %d: failed to write marker: %v", stage, err)
+		}
+		// Simulate an interruption after the given number of swap steps
+		if stage > 0 {
+			os.Rename(filepath.Join(path, "ancient"), filepath.Join(temp, "ancient"))
+		}
+		if stage > 1 {
+			os.Rename(path, backup)
+		}
+		if stage > 2 {
+			os.Rename(temp, path)
+		}
+		if err := swapConvertedDB(path); err != nil {
+			t.Fatalf("stage %d: failed to swap databases: %v", stage, err)
+		}
+		for _, file := range []string{
+			filepath.Join(path, "new.db"),
+			filepath.Join(path, "ancient", "bodies.cdat"),
+			filepath.Join(backup, "old.db"),
+		} {
+			if _, err := os.Stat(file); err != nil {
+				t.Errorf("stage %d: missing %s after swap", stage, file)
+			}
+		}
+		for _, file := range []string{temp, path + ".convert.done", filepath.Join(backup, "ancient")} {
+			if _, err := os.Stat(file); err == nil {
+				t.Errorf("stage %d: %s left behind after swap", stage, file)
+			}
+		}
+	}
+}
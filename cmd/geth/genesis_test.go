@@ -21,6 +21,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/ethereum/go-ethereum/core"
 )
 
 var customGenesisTests = []struct {
@@ -108,3 +110,20 @@ func TestCustomGenesis(t *testing.T) {
 		geth.ExpectExit()
 	}
 }
+
+// Tests that the network of a genesis is found by its genesis block, even if its
+// chain config is a copy of the registered one.
+func TestLookupGenesisNetwork(t *testing.T) {
+	genesis := core.DefaultMordorGenesisBlock()
+	config := *genesis.Config
+	genesis.Config = &config
+
+	network := lookupGenesisNetwork(genesis)
+	if network == nil || network.Name != "mordor" {
+		t.Fatalf("network mismatch: have %v, want mordor", network)
+	}
+	genesis.ExtraData = []byte("custom")
+	if network := lookupGenesisNetwork(genesis); network != nil {
+		t.Fatalf("custom genesis matched network %s", network.Name)
+	}
+}
@@ -67,6 +67,10 @@ var (
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
 		utils.AncientFlag,
+		utils.AncientCompressionFlag,
+		utils.AncientRetentionFlag,
+		utils.AncientReadOnlyFlag,
+		utils.DBEngineFlag,
 		utils.KeyStoreDirFlag,
 		utils.ExternalSignerFlag,
 		utils.NoUSBFlag,
@@ -95,6 +99,8 @@ var (
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
+		utils.TraceIndexFlag,
+		utils.SnapshotFlag,
 		utils.LightServeFlag,
 		utils.LightLegacyServFlag,
 		utils.LightIngressFlag,
@@ -106,10 +112,12 @@ var (
 		utils.UltraLightFractionFlag,
 		utils.UltraLightOnlyAnnounceFlag,
 		utils.WhitelistFlag,
+		utils.ReorgPenaltyFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheTrieFlag,
 		utils.CacheGCFlag,
+		utils.CacheSnapshotFlag,
 		utils.CacheNoPrefetchFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
@@ -137,6 +145,7 @@ var (
 		utils.NodeKeyHexFlag,
 		utils.DeveloperFlag,
 		utils.DeveloperPeriodFlag,
+		utils.ChainFlag,
 		utils.TestnetFlag,
 		utils.ClassicFlag,
 		utils.MordorFlag,
@@ -170,6 +179,7 @@ var (
 		utils.GraphQLPortFlag,
 		utils.GraphQLCORSDomainFlag,
 		utils.GraphQLVirtualHostsFlag,
+		utils.GraphQLTracingFlag,
 		utils.RPCApiFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
@@ -209,6 +219,9 @@ func init() {
 	app.Commands = []cli.Command{
 		// See chaincmd.go:
 		initCommand,
+		dumpGenesisCommand,
+		// See chainconfigcmd.go:
+		chainConfigCommand,
 		importCommand,
 		exportCommand,
 		importPreimagesCommand,
@@ -217,6 +230,8 @@ func init() {
 		removedbCommand,
 		dumpCommand,
 		inspectCommand,
+		// See dbcmd.go
+		dbCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -231,6 +246,9 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		openrpcCommand,
+		// See snapshot.go
+		snapshotCommand,
 		// See retesteth.go
 		retestethCommand,
 	}
@@ -254,7 +272,7 @@ func init() {
 		// If we're a full node on mainnet without --cache specified, bump default cache allowance
 		if ctx.GlobalString(utils.SyncModeFlag.Name) != "light" && !ctx.GlobalIsSet(utils.CacheFlag.Name) && !ctx.GlobalIsSet(utils.NetworkIdFlag.Name) {
 			// Make sure we're not on any supported preconfigured testnet either
-			if !ctx.GlobalIsSet(utils.TestnetFlag.Name) && !ctx.GlobalIsSet(utils.RinkebyFlag.Name) && !ctx.GlobalIsSet(utils.GoerliFlag.Name) && !ctx.GlobalIsSet(utils.DeveloperFlag.Name) {
+			if !ctx.GlobalIsSet(utils.ChainFlag.Name) && !ctx.GlobalIsSet(utils.TestnetFlag.Name) && !ctx.GlobalIsSet(utils.RinkebyFlag.Name) && !ctx.GlobalIsSet(utils.GoerliFlag.Name) && !ctx.GlobalIsSet(utils.DeveloperFlag.Name) {
 				// Nope, we're really on mainnet. Bump that cache up!
 				log.Info("Bumping default cache on mainnet", "provided", ctx.GlobalInt(utils.CacheFlag.Name), "updated", 4096)
 				ctx.GlobalSet(utils.CacheFlag.Name, strconv.Itoa(4096))
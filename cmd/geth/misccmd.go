@@ -18,6 +18,7 @@ package main
 
 import (
 	"fmt"
+	"math/big"
 	"os"
 	"runtime"
 	"strconv"
@@ -34,12 +35,16 @@ var (
 	makecacheCommand = cli.Command{
 		Action:    utils.MigrateFlags(makecache),
 		Name:      "makecache",
+		Flags:     networkFlags,
 		Usage:     "Generate ethash verification cache (for testing)",
 		ArgsUsage: "<blockNum> <outputDir>",
 		Category:  "MISCELLANEOUS COMMANDS",
 		Description: `
 The makecache command generates an ethash cache in <outputDir>.
 
+The epoch length is taken from the chain config of the network selected by the
+command line flags, defaulting to mainnet.
+
 This command exists to support the system testing project.
 Regular users do not need to execute it.
 `,
@@ -47,12 +52,16 @@ Regular users do not need to execute it.
 	makedagCommand = cli.Command{
 		Action:    utils.MigrateFlags(makedag),
 		Name:      "makedag",
+		Flags:     networkFlags,
 		Usage:     "Generate ethash mining DAG (for testing)",
 		ArgsUsage: "<blockNum> <outputDir>",
 		Category:  "MISCELLANEOUS COMMANDS",
 		Description: `
 The makedag command generates an ethash DAG in <outputDir>.
 
+The epoch length is taken from the chain config of the network selected by the
+command line flags, defaulting to mainnet.
+
 This command exists to support the system testing project.
 Regular users do not need to execute it.
 `,
@@ -86,7 +95,7 @@ func makecache(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Invalid block number: %v", err)
 	}
-	ethash.MakeCache(block, args[1])
+	ethash.MakeCache(block, ecip1099Block(ctx), args[1])
 
 	return nil
 }
@@ -101,11 +110,20 @@ func makedag(ctx *cli.Context) error {
 	if err != nil {
 		utils.Fatalf("Invalid block number: %v", err)
 	}
-	ethash.MakeDataset(block, args[1])
+	ethash.MakeDataset(block, ecip1099Block(ctx), args[1])
 
 	return nil
 }
 
+// ecip1099Block returns the ethash epoch length doubling block of the network
+// selected by the command line flags.
+func ecip1099Block(ctx *cli.Context) *big.Int {
+	if genesis := utils.MakeGenesis(ctx); genesis != nil {
+		return genesis.Config.ECIP1099Block
+	}
+	return params.MainnetChainConfig.ECIP1099Block
+}
+
 func version(ctx *cli.Context) error {
 	versionClientIdentifier := clientIdentifier
 	if params.VersionName != "" {
@@ -385,6 +385,7 @@ func (api *RetestethAPI) SetChainParams(ctx context.Context, chainParams ChainPa
 			CachesOnDisk:   3,
 			DatasetsInMem:  1,
 			DatasetsOnDisk: 2,
+			ECIP1099Block:  chainConfig.ECIP1099Block,
 		}, nil, false)
 	default:
 		return false, fmt.Errorf("unrecognised seal engine: %s", chainParams.SealEngine)
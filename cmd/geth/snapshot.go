@@ -0,0 +1,111 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state/pruner"
+	"github.com/ethereum/go-ethereum/log"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	bloomFilterSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to bloom-filter for pruning",
+		Value: 2048,
+	}
+	snapshotCommand = cli.Command{
+		Name:        "snapshot",
+		Usage:       "A set of commands operating on the state",
+		Category:    "MISCELLANEOUS COMMANDS",
+		Description: `
+The snapshot commands operate on the state data of a stopped node.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "prune-state",
+				Usage:     "Prune stale ethereum state data",
+				ArgsUsage: "<root>",
+				Action:    utils.MigrateFlags(pruneState),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.CacheFlag,
+					utils.ChainFlag,
+					utils.TestnetFlag,
+					utils.ClassicFlag,
+					utils.MordorFlag,
+					utils.RinkebyFlag,
+					utils.KottiFlag,
+					utils.GoerliFlag,
+					bloomFilterSizeFlag,
+				},
+				Description: `
+geth snapshot prune-state <state-root>
+will prune historical state data with the help of a bloom filter. The state
+of the given root, or of the most recent canonical block with a complete state
+if none is given, is retained along with the genesis state. Every other trie
+node and contract code is deleted from the database, which is compacted
+afterwards.
+
+The node must be stopped while pruning. If the pruning is interrupted after
+the bloom filter was written to disk, it's resumed on the next startup, either
+by this command or by the node itself.
+
+Pruning to a root older than the chain head rewinds the head to the block of
+that root on the next startup.`,
+			},
+		},
+	}
+)
+
+func pruneState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack)
+	defer chaindb.Close()
+
+	if ctx.NArg() > 1 {
+		log.Error("Too many arguments given")
+		return errors.New("too many arguments")
+	}
+	var targetRoot common.Hash
+	if ctx.NArg() == 1 {
+		root, err := hexutil.Decode(ctx.Args()[0])
+		if err != nil || len(root) != common.HashLength {
+			log.Error("Failed to resolve state root", "root", ctx.Args()[0])
+			return errors.New("invalid state root")
+		}
+		targetRoot = common.BytesToHash(root)
+	}
+	p, err := pruner.NewPruner(chaindb, stack.ResolvePath(""), ctx.Uint64(bloomFilterSizeFlag.Name))
+	if err != nil {
+		log.Error("Failed to create state pruner", "error", err)
+		return err
+	}
+	if err := p.Prune(targetRoot); err != nil {
+		log.Error("Failed to prune state", "error", err)
+		return err
+	}
+	return nil
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/dashboard"
@@ -123,6 +124,22 @@ var (
 		Name:  "datadir.ancient",
 		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
 	}
+	AncientCompressionFlag = cli.StringFlag{
+		Name:  "datadir.ancient.compression",
+		Usage: "Comma separated table=snappy|none compression of newly created ancient tables, existing tables can't be changed (e.g. receipts=none)",
+	}
+	AncientRetentionFlag = cli.StringFlag{
+		Name:  "datadir.ancient.retention",
+		Usage: "Comma separated table=blocks number of recent blocks to retain in the ancient bodies and receipts tables (default = all)",
+	}
+	AncientReadOnlyFlag = cli.BoolFlag{
+		Name:  "datadir.ancient.readonly",
+		Usage: "Open an ancient store shared with a different node (e.g. on a network mount) in read-only mode, deleting local copies of all but the latest 10000 shared blocks",
+	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Backing database implementation to use ('leveldb' or 'badger', default = pre-existing or leveldb)",
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -141,6 +158,10 @@ var (
 		Usage: "Network identifier (integer, 1=Frontier, 2=Morden (disused), 3=Ropsten, 4=Rinkeby, 6=Kotti)",
 		Value: eth.DefaultConfig.NetworkId,
 	}
+	ChainFlag = cli.StringFlag{
+		Name:  "chain",
+		Usage: "Network to join: name of a built-in network (" + strings.Join(core.NetworkNames(), ", ") + ") or path to a network definition file",
+	}
 	TestnetFlag = cli.BoolFlag{
 		Name:  "testnet",
 		Usage: "Ropsten network: pre-configured proof-of-work test network",
@@ -229,6 +250,14 @@ var (
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Maintain a flat snapshot of the state for fast account and storage reads",
+	}
+	TraceIndexFlag = cli.BoolFlag{
+		Name:  "trace.index",
+		Usage: "Index the internal transactions of all blocks for trace queries (requires --gcmode=archive)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -237,6 +266,10 @@ var (
 		Name:  "whitelist",
 		Usage: "Comma separated block number-to-hash mappings to enforce (<number>=<hash>)",
 	}
+	ReorgPenaltyFlag = cli.BoolFlag{
+		Name:  "reorg.penalty",
+		Usage: "Require competing chains to be exponentially heavier the older the reorged blocks are (MESS, not consensus)",
+	}
 	// Light server and client settings
 	LightLegacyServFlag = cli.IntFlag{ // Deprecated in favor of light.serve, remove in 2021
 		Name:  "lightserv",
@@ -407,6 +440,11 @@ var (
 		Usage: "Percentage of cache memory allowance to use for trie pruning (default = 25% full mode, 0% archive mode)",
 		Value: 25,
 	}
+	CacheSnapshotFlag = cli.IntFlag{
+		Name:  "cache.snapshot",
+		Usage: "Percentage of cache memory allowance to use for snapshot caching (default = 10%)",
+		Value: 10,
+	}
 	CacheNoPrefetchFlag = cli.BoolFlag{
 		Name:  "cache.noprefetch",
 		Usage: "Disable heuristic state prefetch during block import (less CPU and disk IO, more time waiting for data)",
@@ -609,6 +647,10 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 	}
+	GraphQLTracingFlag = cli.BoolFlag{
+		Name:  "graphql.tracing",
+		Usage: "Enable transaction call traces over GraphQL (re-executes the transactions on request)",
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -772,6 +814,9 @@ var (
 // the a subdirectory of the specified datadir will be used.
 func MakeDataDir(ctx *cli.Context) string {
 	if path := ctx.GlobalString(DataDirFlag.Name); path != "" {
+		if network := MakeNetwork(ctx); network != nil {
+			return filepath.Join(path, network.DataDirName())
+		}
 		if ctx.GlobalBool(TestnetFlag.Name) {
 			return filepath.Join(path, "testnet")
 		}
@@ -808,6 +853,19 @@ func MakeDataDir(ctx *cli.Context) string {
 	return ""
 }
 
+// MakeNetwork resolves the network definition selected with the --chain flag,
+// terminating if it cannot be loaded. It returns nil if no network was selected.
+func MakeNetwork(ctx *cli.Context) *core.Network {
+	if !ctx.GlobalIsSet(ChainFlag.Name) {
+		return nil
+	}
+	network, err := core.ResolveNetwork(ctx.GlobalString(ChainFlag.Name))
+	if err != nil {
+		Fatalf("Option %q: %v", ChainFlag.Name, err)
+	}
+	return network
+}
+
 // setNodeKey creates a node key from set command line flags, either loading it
 // from a file or as a specified hex value. If neither flags were provided, this
 // method returns nil and an emphemeral key is to be generated.
@@ -852,6 +910,8 @@ func setBootstrapNodes(ctx *cli.Context, cfg *p2p.Config) {
 		} else {
 			urls = strings.Split(ctx.GlobalString(BootnodesFlag.Name), ",")
 		}
+	case ctx.GlobalIsSet(ChainFlag.Name):
+		urls = MakeNetwork(ctx).Bootnodes
 	case ctx.GlobalBool(TestnetFlag.Name):
 		urls = params.TestnetBootnodes
 	case ctx.GlobalBool(ClassicFlag.Name):
@@ -900,6 +960,8 @@ func setBootstrapNodesV5(ctx *cli.Context, cfg *p2p.Config) {
 		} else {
 			urls = strings.Split(ctx.GlobalString(BootnodesFlag.Name), ",")
 		}
+	case ctx.GlobalIsSet(ChainFlag.Name):
+		urls = MakeNetwork(ctx).BootnodesV5
 	case ctx.GlobalBool(RinkebyFlag.Name):
 		urls = params.RinkebyBootnodes
 	case ctx.GlobalBool(KottiFlag.Name):
@@ -991,6 +1053,9 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
 		cfg.GraphQLVirtualHosts = splitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(GraphQLTracingFlag.Name) {
+		cfg.GraphQLTracing = ctx.GlobalBool(GraphQLTracingFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -1223,6 +1288,14 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
 
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		engine := ctx.GlobalString(DBEngineFlag.Name)
+		if engine != rawdb.DBLevelDB && engine != rawdb.DBBadger {
+			Fatalf("Invalid choice for db.engine '%s', allowed '%s' or '%s'", engine, rawdb.DBLevelDB, rawdb.DBBadger)
+		}
+		cfg.DBEngine = engine
+	}
+
 	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
 		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
 	}
@@ -1267,6 +1340,8 @@ func setDataDir(ctx *cli.Context, cfg *node.Config) {
 		cfg.DataDir = ctx.GlobalString(DataDirFlag.Name)
 	case ctx.GlobalBool(DeveloperFlag.Name):
 		cfg.DataDir = "" // unless explicitly requested, use memory databases
+	case ctx.GlobalIsSet(ChainFlag.Name) && cfg.DataDir == node.DefaultDataDir():
+		cfg.DataDir = filepath.Join(node.DefaultDataDir(), MakeNetwork(ctx).DataDirName())
 	case ctx.GlobalBool(TestnetFlag.Name) && cfg.DataDir == node.DefaultDataDir():
 		cfg.DataDir = filepath.Join(node.DefaultDataDir(), "testnet")
 	case ctx.GlobalBool(ClassicFlag.Name) && cfg.DataDir == node.DefaultDataDir():
@@ -1396,6 +1471,14 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	}
 }
 
+// setReorgPenalty enables the subjective reorg penalty if requested.
+func setReorgPenalty(ctx *cli.Context, cfg *eth.Config) {
+	if ctx.GlobalBool(ReorgPenaltyFlag.Name) && cfg.ReorgPenalty == nil {
+		penalty := core.DefaultReorgPenaltyConfig
+		cfg.ReorgPenalty = &penalty
+	}
+}
+
 func setWhitelist(ctx *cli.Context, cfg *eth.Config) {
 	whitelist := ctx.GlobalString(WhitelistFlag.Name)
 	if whitelist == "" {
@@ -1477,6 +1560,7 @@ func SetShhConfig(ctx *cli.Context, stack *node.Node, cfg *whisper.Config) {
 func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	// Avoid conflicting network flags
 	CheckExclusive(ctx, DeveloperFlag, TestnetFlag, RinkebyFlag, GoerliFlag)
+	CheckExclusive(ctx, ChainFlag, DeveloperFlag, TestnetFlag, ClassicFlag, MordorFlag, SocialFlag, MixFlag, EthersocialFlag, MusicoinFlag, RinkebyFlag, KottiFlag, GoerliFlag)
 	CheckExclusive(ctx, LightLegacyServFlag, LightServeFlag, SyncModeFlag, "light")
 	CheckExclusive(ctx, DeveloperFlag, ExternalSignerFlag) // Can't use both ephemeral unlocked and external signer
 
@@ -1490,6 +1574,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	setEthash(ctx, cfg)
 	setMiner(ctx, &cfg.Miner)
 	setWhitelist(ctx, cfg)
+	setReorgPenalty(ctx, cfg)
 	setLes(ctx, cfg)
 
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
@@ -1505,6 +1590,16 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(AncientFlag.Name) {
 		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
 	}
+	freezer := makeFreezerConfig(ctx)
+	if freezer.NoSnappy != nil {
+		cfg.DatabaseFreezerConfig.NoSnappy = freezer.NoSnappy
+	}
+	if freezer.Retention != nil {
+		cfg.DatabaseFreezerConfig.Retention = freezer.Retention
+	}
+	if freezer.ReadOnly {
+		cfg.DatabaseFreezerConfig.ReadOnly = true
+	}
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1512,12 +1607,22 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
 	cfg.NoPrefetch = ctx.GlobalBool(CacheNoPrefetchFlag.Name)
 
+	if ctx.GlobalBool(TraceIndexFlag.Name) {
+		if !cfg.NoPruning {
+			Fatalf("--%s requires --%s=archive", TraceIndexFlag.Name, GCModeFlag.Name)
+		}
+		cfg.TraceIndex = true
+	}
+
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieDirtyCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalBool(SnapshotFlag.Name) {
+		cfg.SnapshotCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100
+	}
 	if ctx.GlobalIsSet(DocRootFlag.Name) {
 		cfg.DocRoot = ctx.GlobalString(DocRootFlag.Name)
 	}
@@ -1539,6 +1644,18 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 
 	// Override any default configs for hard coded networks.
 	switch {
+	case ctx.GlobalIsSet(ChainFlag.Name):
+		network := MakeNetwork(ctx)
+		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
+			cfg.NetworkId = network.NetworkID
+		}
+		cfg.Genesis = network.Genesis
+		if network.Checkpoint != nil {
+			cfg.Checkpoint = network.Checkpoint
+		}
+		if network.CheckpointOracle != nil {
+			cfg.CheckpointOracle = network.CheckpointOracle
+		}
 	case ctx.GlobalBool(TestnetFlag.Name):
 		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
 			cfg.NetworkId = params.NetworkIDTestnet
@@ -1567,7 +1684,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 
 	case ctx.GlobalBool(MusicoinFlag.Name):
 		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
-			cfg.NetworkId = 7762959
+			cfg.NetworkId = params.NetworkIDMusicoin
 		}
 		cfg.Genesis = core.DefaultMusicoinGenesisBlock()
 
@@ -1684,17 +1801,17 @@ func RegisterEthStatsService(stack *node.Node, url string) {
 }
 
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
-func RegisterGraphQLService(stack *node.Node, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts) {
+func RegisterGraphQLService(stack *node.Node, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts, tracing bool) {
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 		// Try to construct the GraphQL service backed by a full node
 		var ethServ *eth.Ethereum
 		if err := ctx.Service(&ethServ); err == nil {
-			return graphql.New(ethServ.APIBackend, endpoint, cors, vhosts, timeouts)
+			return graphql.New(ethServ.APIBackend, endpoint, cors, vhosts, timeouts, tracing)
 		}
 		// Try to construct the GraphQL service backed by a light node
 		var lesServ *les.LightEthereum
 		if err := ctx.Service(&lesServ); err == nil {
-			return graphql.New(lesServ.ApiBackend, endpoint, cors, vhosts, timeouts)
+			return graphql.New(lesServ.ApiBackend, endpoint, cors, vhosts, timeouts, tracing)
 		}
 		// Well, this should not have happened, bail out
 		return nil, errors.New("no Ethereum service")
@@ -1741,6 +1858,37 @@ func SplitTagsFlag(tagsFlag string) map[string]string {
 	return tagsMap
 }
 
+// makeFreezerConfig assembles the per-table settings of the ancient store from
+// the command line flags. Tables not mentioned are left at their defaults.
+func makeFreezerConfig(ctx *cli.Context) rawdb.FreezerConfig {
+	var config rawdb.FreezerConfig
+	if ctx.GlobalIsSet(AncientCompressionFlag.Name) {
+		config.NoSnappy = make(map[string]bool)
+		for table, mode := range SplitTagsFlag(ctx.GlobalString(AncientCompressionFlag.Name)) {
+			switch mode {
+			case "snappy":
+				config.NoSnappy[table] = false
+			case "none":
+				config.NoSnappy[table] = true
+			default:
+				Fatalf("Invalid --%s compression %q for table %s, must be 'snappy' or 'none'", AncientCompressionFlag.Name, mode, table)
+			}
+		}
+	}
+	if ctx.GlobalIsSet(AncientRetentionFlag.Name) {
+		config.Retention = make(map[string]uint64)
+		for table, blocks := range SplitTagsFlag(ctx.GlobalString(AncientRetentionFlag.Name)) {
+			retention, err := strconv.ParseUint(blocks, 10, 64)
+			if err != nil {
+				Fatalf("Invalid --%s retention %q for table %s: %v", AncientRetentionFlag.Name, blocks, table, err)
+			}
+			config.Retention[table] = retention
+		}
+	}
+	config.ReadOnly = ctx.GlobalBool(AncientReadOnlyFlag.Name)
+	return config
+}
+
 // MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
 func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 	var (
@@ -1751,7 +1899,7 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 	if ctx.GlobalString(SyncModeFlag.Name) == "light" {
 		name = "lightchaindata"
 	}
-	chainDb, err := stack.OpenDatabaseWithFreezer(name, cache, handles, ctx.GlobalString(AncientFlag.Name), "")
+	chainDb, err := stack.OpenDatabaseWithFreezer(name, cache, handles, ctx.GlobalString(AncientFlag.Name), makeFreezerConfig(ctx), "")
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
@@ -1761,6 +1909,8 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
 func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	var genesis *core.Genesis
 	switch {
+	case ctx.GlobalIsSet(ChainFlag.Name):
+		genesis = MakeNetwork(ctx).Genesis
 	case ctx.GlobalBool(TestnetFlag.Name):
 		genesis = core.DefaultTestnetGenesisBlock()
 	case ctx.GlobalBool(ClassicFlag.Name):
@@ -1808,6 +1958,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 				DatasetDir:     stack.ResolvePath(eth.DefaultConfig.Ethash.DatasetDir),
 				DatasetsInMem:  eth.DefaultConfig.Ethash.DatasetsInMem,
 				DatasetsOnDisk: eth.DefaultConfig.Ethash.DatasetsOnDisk,
+				ECIP1099Block:  config.ECIP1099Block,
 			}, nil, false)
 		}
 	}
@@ -1827,6 +1978,9 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieDirtyLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalBool(SnapshotFlag.Name) {
+		cache.SnapshotLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100
+	}
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
 	chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg, nil)
 	if err != nil {
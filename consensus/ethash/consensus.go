@@ -366,6 +366,17 @@ func CalcDifficulty(config *params.ChainConfig, time uint64, parent *types.Heade
 	if config.IsBombDisposal(next) {
 		return out
 
+	} else if delay := config.BombDelay(next); delay != nil {
+		// The delay schedule of the chain config takes precedence over the
+		// hard coded EIP and ECIP delays below.
+		// The fake block number is the block number less the total delay, as with
+		// EIP-1234 and EIP-649 (which are delays of 5M and 3M respectively).
+		fakeBlockNumber := new(big.Int)
+		if next.Cmp(delay) >= 0 {
+			fakeBlockNumber = fakeBlockNumber.Sub(next, delay)
+		}
+		exPeriodRef.Set(fakeBlockNumber)
+
 	} else if config.IsEIP1234F(next) {
 		// calcDifficultyEIP1234 is the difficulty adjustment algorithm for Constantinople.
 		// The calculation uses the Byzantium rules, but with bomb offset 5M.
@@ -477,7 +488,7 @@ func (ethash *Ethash) verifySeal(chain consensus.ChainReader, header *types.Head
 	if !fulldag {
 		cache := ethash.cache(number)
 
-		size := datasetSize(number)
+		size := datasetSize(cache.epoch)
 		if ethash.config.PowMode == ModeTest {
 			size = 32 * 1024
 		}
@@ -557,10 +568,53 @@ var (
 	big32 = big.NewInt(32)
 )
 
+// RewardKind is the reason of a balance credit made when finalizing a block.
+type RewardKind string
+
+const (
+	RewardBlock    RewardKind = "block"    // Reward of the block's miner
+	RewardUncle    RewardKind = "uncle"    // Reward of an included uncle's miner
+	RewardExternal RewardKind = "external" // Reward of any other beneficiary
+)
+
+// Reward is a single balance credit made when finalizing a block.
+type Reward struct {
+	Kind        RewardKind
+	Beneficiary common.Address
+	Amount      *big.Int
+}
+
+// rewardFn credits a block reward to the given beneficiary.
+type rewardFn func(kind RewardKind, beneficiary common.Address, amount *big.Int)
+
+// BlockRewards returns the balance credits made when finalizing the given block,
+// in the order they are applied to the state.
+func BlockRewards(config *params.ChainConfig, header *types.Header, uncles []*types.Header) []Reward {
+	var rewards []Reward
+	blockRewards(config, header, uncles, func(kind RewardKind, beneficiary common.Address, amount *big.Int) {
+		rewards = append(rewards, Reward{Kind: kind, Beneficiary: beneficiary, Amount: new(big.Int).Set(amount)})
+	})
+	return rewards
+}
+
 // AccumulateRewards credits the coinbase of the given block with the mining
 // reward. The total reward consists of the static block reward and rewards for
 // included uncles. The coinbase of each uncle block is also rewarded.
 func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
+	blockRewards(config, header, uncles, func(kind RewardKind, beneficiary common.Address, amount *big.Int) {
+		state.AddBalance(beneficiary, amount)
+	})
+}
+
+// blockRewards calculates the mining reward of the given block and its uncles,
+// handing each balance credit to the given callback.
+func blockRewards(config *params.ChainConfig, header *types.Header, uncles []*types.Header, credit rewardFn) {
+	// The block reward schedule of the chain config takes precedence over the
+	// hard coded rules below
+	if rule := config.BlockRewardAt(header.Number); rule != nil {
+		scheduledBlockReward(rule, header, uncles, credit)
+		return
+	}
 	// Select the correct block reward based on chain progression
 	blockReward := FrontierBlockReward
 	if config.IsEIP649F(header.Number) {
@@ -576,11 +630,11 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 		blockReward = params.EthersocialBlockReward
 	}
 	if config.IsMCIP0(header.Number) {
-		musicoinBlockReward(config, state, header, uncles)
+		musicoinBlockReward(config, header, uncles, credit)
 		return
 	}
 	if config.HasECIP1017() {
-		ecip1017BlockReward(config, state, header, uncles)
+		ecip1017BlockReward(config, header, uncles, credit)
 	} else {
 		// Accumulate the rewards for the miner and any included uncles
 		reward := new(big.Int).Set(blockReward)
@@ -590,12 +644,12 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 			r.Sub(r, header.Number)
 			r.Mul(r, blockReward)
 			r.Div(r, big8)
-			state.AddBalance(uncle.Coinbase, r)
+			credit(RewardUncle, uncle.Coinbase, r)
 
 			r.Div(blockReward, big32)
 			reward.Add(reward, r)
 		}
-		state.AddBalance(header.Coinbase, reward)
+		credit(RewardBlock, header.Coinbase, reward)
 	}
 }
 
@@ -18,12 +18,11 @@ package ethash
 import (
 	"math/big"
 
-	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
 )
 
-func ecip1017BlockReward(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
+func ecip1017BlockReward(config *params.ChainConfig, header *types.Header, uncles []*types.Header, credit rewardFn) {
 	blockReward := FrontierBlockReward
 
 	// Ensure value 'era' is configured.
@@ -32,12 +31,12 @@ func ecip1017BlockReward(config *params.ChainConfig, state *state.StateDB, heade
 	wr := GetBlockWinnerRewardByEra(era, blockReward)                    // wr "winner reward". 5, 4, 3.2, 2.56, ...
 	wurs := GetBlockWinnerRewardForUnclesByEra(era, uncles, blockReward) // wurs "winner uncle rewards"
 	wr.Add(wr, wurs)
-	state.AddBalance(header.Coinbase, wr) // $$
+	credit(RewardBlock, header.Coinbase, wr) // $$
 
 	// Reward uncle miners.
 	for _, uncle := range uncles {
 		ur := GetBlockUncleRewardByEra(era, header, uncle, blockReward)
-		state.AddBalance(uncle.Coinbase, ur) // $$
+		credit(RewardUncle, uncle.Coinbase, ur) // $$
 	}
 }
 
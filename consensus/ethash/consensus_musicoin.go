@@ -19,12 +19,11 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
 )
 
-func musicoinBlockReward(config *params.ChainConfig, state *state.StateDB, header *types.Header, uncles []*types.Header) {
+func musicoinBlockReward(config *params.ChainConfig, header *types.Header, uncles []*types.Header, credit rewardFn) {
 	// Select the correct block reward based on chain progression
 	blockReward := params.Mcip0BlockReward
 	mcip3Reward := params.Mcip3BlockReward
@@ -35,19 +34,19 @@ func musicoinBlockReward(config *params.ChainConfig, state *state.StateDB, heade
 	reward := new(big.Int).Set(blockReward)
 
 	if config.IsMCIP8(header.Number) {
-		state.AddBalance(header.Coinbase, mcip8Reward)
-		state.AddBalance(common.HexToAddress("0x00eFdd5883eC628983E9063c7d969fE268BBf310"), ubiReservoir)
-		state.AddBalance(common.HexToAddress("0x00756cF8159095948496617F5FB17ED95059f536"), devReservoir)
+		credit(RewardBlock, header.Coinbase, mcip8Reward)
+		credit(RewardExternal, common.HexToAddress("0x00eFdd5883eC628983E9063c7d969fE268BBf310"), ubiReservoir)
+		credit(RewardExternal, common.HexToAddress("0x00756cF8159095948496617F5FB17ED95059f536"), devReservoir)
 		blockReward := mcip8Reward
 		reward := new(big.Int).Set(blockReward)
 		_ = reward
 	} else if config.IsMCIP3(header.Number) {
-		state.AddBalance(header.Coinbase, mcip3Reward)
-		state.AddBalance(common.HexToAddress("0x00eFdd5883eC628983E9063c7d969fE268BBf310"), ubiReservoir)
-		state.AddBalance(common.HexToAddress("0x00756cF8159095948496617F5FB17ED95059f536"), devReservoir)
+		credit(RewardBlock, header.Coinbase, mcip3Reward)
+		credit(RewardExternal, common.HexToAddress("0x00eFdd5883eC628983E9063c7d969fE268BBf310"), ubiReservoir)
+		credit(RewardExternal, common.HexToAddress("0x00756cF8159095948496617F5FB17ED95059f536"), devReservoir)
 		// no change to uncle reward during UBI fork, a mistake but now a legacy
 	} else {
-		state.AddBalance(header.Coinbase, reward)
+		credit(RewardBlock, header.Coinbase, reward)
 	}
 
 	// Accumulate the rewards for the miner and any included uncles
@@ -57,7 +56,7 @@ func musicoinBlockReward(config *params.ChainConfig, state *state.StateDB, heade
 		r.Sub(r, header.Number)
 		r.Mul(r, blockReward)
 		r.Div(r, big8)
-		state.AddBalance(uncle.Coinbase, r)
+		credit(RewardUncle, uncle.Coinbase, r)
 
 		r.Div(blockReward, big32)
 		reward.Add(reward, r)
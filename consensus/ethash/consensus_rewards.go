@@ -0,0 +1,79 @@
+// Copyright 2019 The multi-geth Authors
+// This file is part of the multi-geth library.
+//
+// The multi-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The multi-geth library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the multi-geth library. If not, see <http://www.gnu.org/licenses/>.
+package ethash
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// scheduledBlockReward credits the miner of the given block, the miners of its
+// uncles and any additional beneficiaries as defined by an entry of the block
+// reward schedule of the chain config.
+func scheduledBlockReward(rule *params.BlockReward, header *types.Header, uncles []*types.Header, credit rewardFn) {
+	blockReward, uncleBase := rule.Reward, rule.UncleBase
+	if uncleBase == nil {
+		uncleBase = blockReward
+	}
+	// Reduce the rewards according to the era the block is in
+	era := new(big.Int)
+	if rule.EraLength != nil {
+		rate := rule.EraRate
+		if rate == nil {
+			rate = new(big.Rat).SetFrac(params.DisinflationRateQuotient, params.DisinflationRateDivisor)
+		}
+		era = GetBlockEra(header.Number, rule.EraLength)
+		blockReward = eraReward(blockReward, era, rate)
+		uncleBase = eraReward(uncleBase, era, rate)
+	}
+	// Accumulate the rewards for the miner and any included uncles
+	reward := new(big.Int).Set(blockReward)
+	for _, uncle := range uncles {
+		switch {
+		case rule.UncleRule == params.UncleRewardECIP1017 && era.Sign() > 0:
+			r := new(big.Int).Div(blockReward, big32)
+			credit(RewardUncle, uncle.Coinbase, r)
+			reward.Add(reward, r)
+
+		default:
+			r := new(big.Int).Add(uncle.Number, big8)
+			r.Sub(r, header.Number)
+			r.Mul(r, uncleBase)
+			r.Div(r, big8)
+			credit(RewardUncle, uncle.Coinbase, r)
+
+			if rule.UncleRule != params.UncleRewardNoInclusion {
+				reward.Add(reward, new(big.Int).Div(blockReward, big32))
+			}
+		}
+	}
+	credit(RewardBlock, header.Coinbase, reward)
+
+	for _, split := range rule.Splits {
+		credit(RewardExternal, split.Beneficiary, split.Amount)
+	}
+}
+
+// eraReward reduces a block reward by the given rate for every era passed.
+func eraReward(reward *big.Int, era *big.Int, rate *big.Rat) *big.Int {
+	if era.Sign() == 0 {
+		return new(big.Int).Set(reward)
+	}
+	r := new(big.Int).Mul(reward, new(big.Int).Exp(rate.Num(), era, nil))
+	return r.Div(r, new(big.Int).Exp(rate.Denom(), era, nil))
+}
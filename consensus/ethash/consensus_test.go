@@ -23,7 +23,10 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/params"
 )
@@ -84,3 +87,130 @@ func TestCalcDifficulty(t *testing.T) {
 		}
 	}
 }
+
+// Tests that a difficulty bomb delay schedule in the chain config reproduces the
+// hard coded EIP-649 and EIP-1234 delays, and that the bomb can be disabled.
+func TestCalcDifficultyBombDelays(t *testing.T) {
+	legacy := &params.ChainConfig{
+		HomesteadBlock: big.NewInt(0),
+		EIP100FBlock:   big.NewInt(4370000),
+		EIP649FBlock:   big.NewInt(4370000),
+		EIP1234FBlock:  big.NewInt(7280000),
+	}
+	scheduled := &params.ChainConfig{
+		HomesteadBlock: big.NewInt(0),
+		EIP100FBlock:   big.NewInt(4370000),
+		DifficultyBombDelays: params.BombDelaySchedule{
+			{Block: big.NewInt(4370000), Delay: big.NewInt(3000000)},
+			{Block: big.NewInt(7280000), Delay: big.NewInt(2000000)},
+		},
+	}
+	disabled := &params.ChainConfig{
+		HomesteadBlock:         big.NewInt(0),
+		EIP100FBlock:           big.NewInt(4370000),
+		DifficultyBombDisabled: true,
+	}
+	noBomb := &params.ChainConfig{
+		HomesteadBlock: big.NewInt(0),
+		EIP100FBlock:   big.NewInt(4370000),
+		DisposalBlock:  big.NewInt(0),
+	}
+	for _, number := range []int64{0, 200000, 3000000, 4369998, 4369999, 4370000, 6000000, 7279998, 7279999, 7280000, 9000000} {
+		parent := &types.Header{
+			Number:     big.NewInt(number),
+			Time:       1000,
+			Difficulty: big.NewInt(2000000000000),
+			UncleHash:  types.EmptyUncleHash,
+		}
+		if have, want := CalcDifficulty(scheduled, 1013, parent), CalcDifficulty(legacy, 1013, parent); have.Cmp(want) != 0 {
+			t.Errorf("block %d: scheduled difficulty mismatch: have %v, want %v", number+1, have, want)
+		}
+		if have, want := CalcDifficulty(disabled, 1013, parent), CalcDifficulty(noBomb, 1013, parent); have.Cmp(want) != 0 {
+			t.Errorf("block %d: disabled difficulty mismatch: have %v, want %v", number+1, have, want)
+		}
+	}
+	// Sanity check that the bomb actually goes off without a delay
+	parent := &types.Header{Number: big.NewInt(9000000), Time: 1000, Difficulty: big.NewInt(2000000000000), UncleHash: types.EmptyUncleHash}
+	if CalcDifficulty(disabled, 1013, parent).Cmp(CalcDifficulty(scheduled, 1013, parent)) >= 0 {
+		t.Errorf("delayed bomb does not add to the difficulty")
+	}
+}
+
+// Tests that block reward schedules in the chain config reproduce the hard coded
+// block reward rules of the various networks.
+func TestScheduledBlockRewards(t *testing.T) {
+	var (
+		ether    = big.NewInt(1e18)
+		musicoin = func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), ether) }
+		ubi      = common.HexToAddress("0x00eFdd5883eC628983E9063c7d969fE268BBf310")
+		dev      = common.HexToAddress("0x00756cF8159095948496617F5FB17ED95059f536")
+		splits   = []params.RewardSplit{{Beneficiary: ubi, Amount: musicoin(50)}, {Beneficiary: dev, Amount: musicoin(14)}}
+		coinbase = common.HexToAddress("0x01")
+	)
+	tests := []struct {
+		name      string
+		legacy    *params.ChainConfig
+		scheduled *params.ChainConfig
+	}{
+		{
+			name:   "ethereum",
+			legacy: &params.ChainConfig{EIP649FBlock: big.NewInt(10), EIP1234FBlock: big.NewInt(20)},
+			scheduled: &params.ChainConfig{BlockRewardSchedule: []params.BlockReward{
+				{Block: big.NewInt(0), Reward: FrontierBlockReward},
+				{Block: big.NewInt(10), Reward: EIP649FBlockReward},
+				{Block: big.NewInt(20), Reward: EIP1234FBlockReward, UncleRule: params.UncleRewardStandard},
+			}},
+		},
+		{
+			name:   "classic",
+			legacy: &params.ChainConfig{ECIP1017EraRounds: big.NewInt(10)},
+			scheduled: &params.ChainConfig{BlockRewardSchedule: []params.BlockReward{
+				{Block: big.NewInt(0), Reward: FrontierBlockReward, UncleRule: params.UncleRewardECIP1017, EraLength: big.NewInt(10)},
+			}},
+		},
+		{
+			name:   "social",
+			legacy: &params.ChainConfig{SocialBlock: big.NewInt(0)},
+			scheduled: &params.ChainConfig{BlockRewardSchedule: []params.BlockReward{
+				{Block: big.NewInt(0), Reward: params.SocialBlockReward},
+			}},
+		},
+		{
+			name:   "musicoin",
+			legacy: &params.ChainConfig{MCIP0Block: big.NewInt(0), MCIP3Block: big.NewInt(10), MCIP8Block: big.NewInt(20)},
+			scheduled: &params.ChainConfig{BlockRewardSchedule: []params.BlockReward{
+				{Block: big.NewInt(0), Reward: musicoin(314), UncleRule: params.UncleRewardNoInclusion},
+				{Block: big.NewInt(10), Reward: musicoin(250), UncleRule: params.UncleRewardNoInclusion, UncleBase: musicoin(314), Splits: splits},
+				{Block: big.NewInt(20), Reward: musicoin(50), UncleRule: params.UncleRewardNoInclusion, UncleBase: musicoin(314), Splits: splits},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		for _, number := range []int64{1, 9, 10, 11, 19, 20, 21, 35} {
+			for uncles := 0; uncles <= 2; uncles++ {
+				header := &types.Header{Number: big.NewInt(number), Coinbase: coinbase}
+				var us []*types.Header
+				for i := 0; i < uncles; i++ {
+					us = append(us, &types.Header{Number: big.NewInt(number - 1 - int64(i)), Coinbase: common.BigToAddress(big.NewInt(int64(2 + i)))})
+				}
+				legacy, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+				accumulateRewards(tt.legacy, legacy, header, us)
+
+				scheduled, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+				accumulateRewards(tt.scheduled, scheduled, header, us)
+
+				if have, want := scheduled.IntermediateRoot(false), legacy.IntermediateRoot(false); have != want {
+					t.Errorf("%s: block %d with %d uncles: reward mismatch: miner %v, want %v", tt.name, number, uncles, scheduled.GetBalance(coinbase), legacy.GetBalance(coinbase))
+				}
+				// The reported rewards must add up to the credited balances
+				reported, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+				for _, reward := range BlockRewards(tt.scheduled, header, us) {
+					reported.AddBalance(reward.Beneficiary, reward.Amount)
+				}
+				if have, want := reported.IntermediateRoot(false), legacy.IntermediateRoot(false); have != want {
+					t.Errorf("%s: block %d with %d uncles: reported reward mismatch: miner %v, want %v", tt.name, number, uncles, reported.GetBalance(coinbase), legacy.GetBalance(coinbase))
+				}
+			}
+		}
+	}
+}
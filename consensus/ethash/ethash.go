@@ -50,7 +50,7 @@ var (
 	two256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
 
 	// sharedEthash is a full instance that can be shared between multiple users.
-	sharedEthash = New(Config{"", 3, 0, "", 1, 0, ModeNormal}, nil, false)
+	sharedEthash = New(Config{"", 3, 0, "", 1, 0, ModeNormal, nil}, nil, false)
 
 	// algorithmRevision is the data structure version used for file naming.
 	algorithmRevision = 23
@@ -147,21 +147,27 @@ func memoryMapAndGenerate(path string, size uint64, generator func(buffer []uint
 	return memoryMap(path)
 }
 
+// epochID identifies an epoch, which is only unique together with its length.
+type epochID struct {
+	epoch  uint64
+	length uint64
+}
+
 // lru tracks caches or datasets by their last use time, keeping at most N of them.
 type lru struct {
 	what string
-	new  func(epoch uint64) interface{}
+	new  func(epoch uint64, epochLength uint64) interface{}
 	mu   sync.Mutex
 	// Items are kept in a LRU cache, but there is a special case:
 	// We always keep an item for (highest seen epoch) + 1 as the 'future item'.
 	cache      *simplelru.LRU
-	future     uint64
+	future     epochID
 	futureItem interface{}
 }
 
 // newlru create a new least-recently-used cache for either the verification caches
 // or the mining datasets.
-func newlru(what string, maxItems int, new func(epoch uint64) interface{}) *lru {
+func newlru(what string, maxItems int, new func(epoch uint64, epochLength uint64) interface{}) *lru {
 	if maxItems <= 0 {
 		maxItems = 1
 	}
@@ -174,26 +180,34 @@ func newlru(what string, maxItems int, new func(epoch uint64) interface{}) *lru
 // get retrieves or creates an item for the given epoch. The first return value is always
 // non-nil. The second return value is non-nil if lru thinks that an item will be useful in
 // the near future.
-func (lru *lru) get(epoch uint64) (item, future interface{}) {
+//
+// The epoch following the requested one is looked up with the length in effect at
+// its first block, so the future item is correct across the ECIP-1099 transition.
+func (lru *lru) get(epoch uint64, epochLength uint64, ecip1099Block *big.Int) (item, future interface{}) {
 	lru.mu.Lock()
 	defer lru.mu.Unlock()
 
 	// Get or create the item for the requested epoch.
-	item, ok := lru.cache.Get(epoch)
+	id := epochID{epoch, epochLength}
+	item, ok := lru.cache.Get(id)
 	if !ok {
-		if lru.future > 0 && lru.future == epoch {
+		if lru.future.length > 0 && lru.future == id {
 			item = lru.futureItem
 		} else {
-			log.Trace("Requiring new ethash "+lru.what, "epoch", epoch)
-			item = lru.new(epoch)
+			log.Trace("Requiring new ethash "+lru.what, "epoch", epoch, "length", epochLength)
+			item = lru.new(epoch, epochLength)
 		}
-		lru.cache.Add(epoch, item)
+		lru.cache.Add(id, item)
 	}
 	// Update the 'future item' if epoch is larger than previously seen.
-	if epoch < maxEpoch-1 && lru.future < epoch+1 {
-		log.Trace("Requiring new future ethash "+lru.what, "epoch", epoch+1)
-		future = lru.new(epoch + 1)
-		lru.future = epoch + 1
+	next := (epoch + 1) * epochLength
+	nextLength := calcEpochLength(next, ecip1099Block)
+	nextID := epochID{next / nextLength, nextLength}
+
+	if nextID.epoch < maxEpoch && lru.future.epoch*lru.future.length < next {
+		log.Trace("Requiring new future ethash "+lru.what, "epoch", nextID.epoch, "length", nextID.length)
+		future = lru.new(nextID.epoch, nextID.length)
+		lru.future = nextID
 		lru.futureItem = future
 	}
 	return item, future
@@ -201,24 +215,25 @@ func (lru *lru) get(epoch uint64) (item, future interface{}) {
 
 // cache wraps an ethash cache with some metadata to allow easier concurrent use.
 type cache struct {
-	epoch uint64    // Epoch for which this cache is relevant
-	dump  *os.File  // File descriptor of the memory mapped cache
-	mmap  mmap.MMap // Memory map itself to unmap before releasing
-	cache []uint32  // The actual cache data content (may be memory mapped)
-	once  sync.Once // Ensures the cache is generated only once
+	epoch       uint64    // Epoch for which this cache is relevant
+	epochLength uint64    // Length of the epoch, in blocks
+	dump        *os.File  // File descriptor of the memory mapped cache
+	mmap        mmap.MMap // Memory map itself to unmap before releasing
+	cache       []uint32  // The actual cache data content (may be memory mapped)
+	once        sync.Once // Ensures the cache is generated only once
 }
 
 // newCache creates a new ethash verification cache and returns it as a plain Go
 // interface to be usable in an LRU cache.
-func newCache(epoch uint64) interface{} {
-	return &cache{epoch: epoch}
+func newCache(epoch uint64, epochLength uint64) interface{} {
+	return &cache{epoch: epoch, epochLength: epochLength}
 }
 
 // generate ensures that the cache content is generated before use.
 func (c *cache) generate(dir string, limit int, test bool) {
 	c.once.Do(func() {
-		size := cacheSize(c.epoch*epochLength + 1)
-		seed := seedHash(c.epoch*epochLength + 1)
+		size := cacheSize(c.epoch)
+		seed := seedHash(c.epoch*c.epochLength + 1)
 		if test {
 			size = 1024
 		}
@@ -229,12 +244,8 @@ func (c *cache) generate(dir string, limit int, test bool) {
 			return
 		}
 		// Disk storage is needed, this will get fancy
-		var endian string
-		if !isLittleEndian() {
-			endian = ".be"
-		}
-		path := filepath.Join(dir, fmt.Sprintf("cache-R%d-%x%s", algorithmRevision, seed[:8], endian))
-		logger := log.New("epoch", c.epoch)
+		path := dumpPath(dir, "cache", seed, c.epochLength)
+		logger := log.New("epoch", c.epoch, "length", c.epochLength)
 
 		// We're about to mmap the file, ensure that the mapping is cleaned up when the
 		// cache becomes unused.
@@ -259,9 +270,8 @@ func (c *cache) generate(dir string, limit int, test bool) {
 		}
 		// Iterate over all previous instances and delete old ones
 		for ep := int(c.epoch) - limit; ep >= 0; ep-- {
-			seed := seedHash(uint64(ep)*epochLength + 1)
-			path := filepath.Join(dir, fmt.Sprintf("cache-R%d-%x%s", algorithmRevision, seed[:8], endian))
-			os.Remove(path)
+			seed := seedHash(uint64(ep)*c.epochLength + 1)
+			os.Remove(dumpPath(dir, "cache", seed, c.epochLength))
 		}
 	})
 }
@@ -277,18 +287,19 @@ func (c *cache) finalizer() {
 
 // dataset wraps an ethash dataset with some metadata to allow easier concurrent use.
 type dataset struct {
-	epoch   uint64    // Epoch for which this cache is relevant
-	dump    *os.File  // File descriptor of the memory mapped cache
-	mmap    mmap.MMap // Memory map itself to unmap before releasing
-	dataset []uint32  // The actual cache data content
-	once    sync.Once // Ensures the cache is generated only once
-	done    uint32    // Atomic flag to determine generation status
+	epoch       uint64    // Epoch for which this cache is relevant
+	epochLength uint64    // Length of the epoch, in blocks
+	dump        *os.File  // File descriptor of the memory mapped cache
+	mmap        mmap.MMap // Memory map itself to unmap before releasing
+	dataset     []uint32  // The actual cache data content
+	once        sync.Once // Ensures the cache is generated only once
+	done        uint32    // Atomic flag to determine generation status
 }
 
 // newDataset creates a new ethash mining dataset and returns it as a plain Go
 // interface to be usable in an LRU cache.
-func newDataset(epoch uint64) interface{} {
-	return &dataset{epoch: epoch}
+func newDataset(epoch uint64, epochLength uint64) interface{} {
+	return &dataset{epoch: epoch, epochLength: epochLength}
 }
 
 // generate ensures that the dataset content is generated before use.
@@ -297,9 +308,9 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 		// Mark the dataset generated after we're done. This is needed for remote
 		defer atomic.StoreUint32(&d.done, 1)
 
-		csize := cacheSize(d.epoch*epochLength + 1)
-		dsize := datasetSize(d.epoch*epochLength + 1)
-		seed := seedHash(d.epoch*epochLength + 1)
+		csize := cacheSize(d.epoch)
+		dsize := datasetSize(d.epoch)
+		seed := seedHash(d.epoch*d.epochLength + 1)
 		if test {
 			csize = 1024
 			dsize = 32 * 1024
@@ -315,12 +326,8 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 			return
 		}
 		// Disk storage is needed, this will get fancy
-		var endian string
-		if !isLittleEndian() {
-			endian = ".be"
-		}
-		path := filepath.Join(dir, fmt.Sprintf("full-R%d-%x%s", algorithmRevision, seed[:8], endian))
-		logger := log.New("epoch", d.epoch)
+		path := dumpPath(dir, "full", seed, d.epochLength)
+		logger := log.New("epoch", d.epoch, "length", d.epochLength)
 
 		// We're about to mmap the file, ensure that the mapping is cleaned up when the
 		// cache becomes unused.
@@ -348,9 +355,8 @@ func (d *dataset) generate(dir string, limit int, test bool) {
 		}
 		// Iterate over all previous instances and delete old ones
 		for ep := int(d.epoch) - limit; ep >= 0; ep-- {
-			seed := seedHash(uint64(ep)*epochLength + 1)
-			path := filepath.Join(dir, fmt.Sprintf("full-R%d-%x%s", algorithmRevision, seed[:8], endian))
-			os.Remove(path)
+			seed := seedHash(uint64(ep)*d.epochLength + 1)
+			os.Remove(dumpPath(dir, "full", seed, d.epochLength))
 		}
 	})
 }
@@ -371,15 +377,33 @@ func (d *dataset) finalizer() {
 	}
 }
 
-// MakeCache generates a new ethash cache and optionally stores it to disk.
-func MakeCache(block uint64, dir string) {
-	c := cache{epoch: block / epochLength}
+// dumpPath returns the path of a cache or dataset dump file. Since the seeds of
+// the doubled ECIP-1099 epochs coincide with those of default length epochs, the
+// dumps of the former are named apart to avoid mixing up differently sized data.
+func dumpPath(dir string, kind string, seed []byte, length uint64) string {
+	var endian string
+	if !isLittleEndian() {
+		endian = ".be"
+	}
+	if length != epochLength {
+		kind = fmt.Sprintf("%s-E%d", kind, length)
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-R%d-%x%s", kind, algorithmRevision, seed[:8], endian))
+}
+
+// MakeCache generates a new ethash cache for a block and optionally stores it to
+// disk. The ECIP-1099 transition block may be nil if not scheduled.
+func MakeCache(block uint64, ecip1099Block *big.Int, dir string) {
+	length := calcEpochLength(block, ecip1099Block)
+	c := cache{epoch: block / length, epochLength: length}
 	c.generate(dir, math.MaxInt32, false)
 }
 
-// MakeDataset generates a new ethash dataset and optionally stores it to disk.
-func MakeDataset(block uint64, dir string) {
-	d := dataset{epoch: block / epochLength}
+// MakeDataset generates a new ethash dataset for a block and optionally stores it
+// to disk. The ECIP-1099 transition block may be nil if not scheduled.
+func MakeDataset(block uint64, ecip1099Block *big.Int, dir string) {
+	length := calcEpochLength(block, ecip1099Block)
+	d := dataset{epoch: block / length, epochLength: length}
 	d.generate(dir, math.MaxInt32, false)
 }
 
@@ -403,6 +427,10 @@ type Config struct {
 	DatasetsInMem  int
 	DatasetsOnDisk int
 	PowMode        Mode
+
+	// ECIP1099Block is the block from which on the epoch length is doubled, as
+	// per ECIP-1099. It is derived from the chain config and never configured.
+	ECIP1099Block *big.Int `toml:"-"`
 }
 
 // sealTask wraps a seal block with relative result channel for remote sealer thread.
@@ -588,8 +616,8 @@ func (ethash *Ethash) Close() error {
 // by first checking against a list of in-memory caches, then against caches
 // stored on disk, and finally generating one if none can be found.
 func (ethash *Ethash) cache(block uint64) *cache {
-	epoch := block / epochLength
-	currentI, futureI := ethash.caches.get(epoch)
+	length := calcEpochLength(block, ethash.config.ECIP1099Block)
+	currentI, futureI := ethash.caches.get(block/length, length, ethash.config.ECIP1099Block)
 	current := currentI.(*cache)
 
 	// Wait for generation finish.
@@ -611,8 +639,8 @@ func (ethash *Ethash) cache(block uint64) *cache {
 // generates on a background thread.
 func (ethash *Ethash) dataset(block uint64, async bool) *dataset {
 	// Retrieve the requested ethash dataset
-	epoch := block / epochLength
-	currentI, futureI := ethash.datasets.get(epoch)
+	length := calcEpochLength(block, ethash.config.ECIP1099Block)
+	currentI, futureI := ethash.datasets.get(block/length, length, ethash.config.ECIP1099Block)
 	current := currentI.(*dataset)
 
 	// If async is specified, generate everything in a background thread
@@ -711,7 +739,8 @@ func (ethash *Ethash) APIs(chain consensus.ChainReader) []rpc.API {
 }
 
 // SeedHash is the seed to use for generating a verification cache and the mining
-// dataset.
-func SeedHash(block uint64) []byte {
-	return seedHash(block)
+// dataset of a block. The ECIP-1099 transition block may be nil if not scheduled.
+func SeedHash(block uint64, ecip1099Block *big.Int) []byte {
+	length := calcEpochLength(block, ecip1099Block)
+	return seedHash(block/length*length + 1)
 }
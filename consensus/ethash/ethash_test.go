@@ -17,10 +17,12 @@
 package ethash
 
 import (
+	"bytes"
 	"io/ioutil"
 	"math/big"
 	"math/rand"
 	"os"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -75,6 +77,70 @@ func TestCacheFileEvict(t *testing.T) {
 	wg.Wait()
 }
 
+// Tests that the cache lru logic doesn't crash across the ECIP-1099 transition.
+func TestCacheFileEvictECIP1099(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "ethash-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	e := New(Config{CachesInMem: 3, CachesOnDisk: 10, CacheDir: tmpdir, PowMode: ModeTest, ECIP1099Block: big.NewInt(50 * epochLength)}, nil, false)
+	defer e.Close()
+
+	workers := 8
+	epochs := 100
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go verifyTest(&wg, e, i, epochs)
+	}
+	wg.Wait()
+}
+
+// Tests that the epoch length doubles at the ECIP-1099 transition, and that the
+// seed hashes and future cache epochs follow.
+func TestECIP1099Epochs(t *testing.T) {
+	transition := big.NewInt(4 * epochLength)
+
+	tests := []struct {
+		block  uint64
+		length uint64
+		seed   uint64 // Block whose default length seed hash must match
+	}{
+		{0, epochLength, 0},
+		{4*epochLength - 1, epochLength, 3 * epochLength},
+		{4 * epochLength, epochLengthECIP1099, 4 * epochLength},
+		{5 * epochLength, epochLengthECIP1099, 4 * epochLength},
+		{6*epochLength + 1, epochLengthECIP1099, 6 * epochLength},
+	}
+	for i, tt := range tests {
+		if length := calcEpochLength(tt.block, transition); length != tt.length {
+			t.Errorf("test %d: epoch length mismatch: have %d, want %d", i, length, tt.length)
+		}
+		if have, want := SeedHash(tt.block, transition), SeedHash(tt.seed, nil); !bytes.Equal(have, want) {
+			t.Errorf("test %d: seed hash mismatch: have %x, want %x", i, have, want)
+		}
+	}
+	// The future item of the last default length epoch must be the first doubled one
+	var made []epochID
+	cache := newlru("cache", 2, func(epoch uint64, epochLength uint64) interface{} {
+		made = append(made, epochID{epoch, epochLength})
+		return nil
+	})
+	cache.get(3, epochLength, transition)
+
+	want := []epochID{{3, epochLength}, {2, epochLengthECIP1099}}
+	if !reflect.DeepEqual(made, want) {
+		t.Errorf("generated epochs mismatch: have %v, want %v", made, want)
+	}
+	// Requesting the doubled epoch must reuse the future item
+	made = nil
+	cache.get(2, epochLengthECIP1099, transition)
+	if want := []epochID{{3, epochLengthECIP1099}}; !reflect.DeepEqual(made, want) {
+		t.Errorf("generated epochs mismatch: have %v, want %v", made, want)
+	}
+}
+
 func verifyTest(wg *sync.WaitGroup, e *Ethash, workerIndex, epochs int) {
 	defer wg.Done()
 
@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -60,6 +61,10 @@ var (
 	storageUpdateTimer = metrics.NewRegisteredTimer("chain/storage/updates", nil)
 	storageCommitTimer = metrics.NewRegisteredTimer("chain/storage/commits", nil)
 
+	snapshotAccountReadTimer = metrics.NewRegisteredTimer("chain/snapshot/account/reads", nil)
+	snapshotStorageReadTimer = metrics.NewRegisteredTimer("chain/snapshot/storage/reads", nil)
+	snapshotCommitTimer      = metrics.NewRegisteredTimer("chain/snapshot/commits", nil)
+
 	blockInsertTimer     = metrics.NewRegisteredTimer("chain/inserts", nil)
 	blockValidationTimer = metrics.NewRegisteredTimer("chain/validation", nil)
 	blockExecutionTimer  = metrics.NewRegisteredTimer("chain/execution", nil)
@@ -78,6 +83,7 @@ const (
 	maxFutureBlocks     = 256
 	maxTimeFutureBlocks = 30
 	badBlockLimit       = 10
+	blockArrivalLimit   = 4096
 	TriesInMemory       = 128
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
@@ -111,6 +117,7 @@ type CacheConfig struct {
 	TrieDirtyLimit      int           // Memory limit (MB) at which to start flushing dirty trie nodes to disk
 	TrieDirtyDisabled   bool          // Whether to disable trie write caching and GC altogether (archive node)
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
+	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory, 0 disables snapshots
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -151,11 +158,13 @@ type BlockChain struct {
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache    state.Database // State database to reuse between imports (contains state cache)
+	snaps         *snapshot.Tree // Snapshot tree for fast trie leaf access
 	bodyCache     *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache  *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	receiptsCache *lru.Cache     // Cache for the most recent receipts per block
 	blockCache    *lru.Cache     // Cache for the most recent entire blocks
 	futureBlocks  *lru.Cache     // future blocks are blocks added for later processing
+	blockArrivals *lru.Cache     // Local first-seen time of the most recent blocks
 
 	quit    chan struct{} // blockchain quit channel
 	running int32         // running must be called atomically
@@ -170,6 +179,7 @@ type BlockChain struct {
 	vmConfig   vm.Config
 
 	badBlocks       *lru.Cache                     // Bad block cache
+	reorgPenalty    *ReorgPenaltyConfig            // Subjective reorg resistance policy, nil if disabled
 	shouldPreserve  func(*types.Block) bool        // Function used to determine whether should preserve the given block.
 	terminateInsert func(common.Hash, uint64) bool // Testing hook used to terminate ancient receipt chain insertion.
 }
@@ -191,6 +201,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	blockCache, _ := lru.New(blockCacheLimit)
 	futureBlocks, _ := lru.New(maxFutureBlocks)
 	badBlocks, _ := lru.New(badBlockLimit)
+	blockArrivals, _ := lru.New(blockArrivalLimit)
 
 	bc := &BlockChain{
 		chainConfig:    chainConfig,
@@ -205,6 +216,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		receiptsCache:  receiptsCache,
 		blockCache:     blockCache,
 		futureBlocks:   futureBlocks,
+		blockArrivals:  blockArrivals,
 		engine:         engine,
 		vmConfig:       vmConfig,
 		badBlocks:      badBlocks,
@@ -226,6 +238,13 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if bc.empty() {
 		rawdb.InitDatabaseFromFreezer(bc.db)
 	}
+	// Refuse to start if the local chain diverged from a shared ancient store, as
+	// its data would shadow the local blocks
+	if frozen, err := bc.db.Ancients(); err == nil && frozen > 0 && rawdb.ReadOnlyAncients(bc.db) {
+		if err := bc.checkSharedAncients(frozen); err != nil {
+			return nil, err
+		}
+	}
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
@@ -234,7 +253,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	// it in advance.
 	bc.engine.VerifyHeader(bc, bc.CurrentHeader(), true)
 
-	if frozen, err := bc.db.Ancients(); err == nil && frozen > 0 {
+	// A read-only ancient store is shared and maintained by a different node, it
+	// may legitimately run ahead of the local chain and is never rewound locally.
+	if frozen, err := bc.db.Ancients(); err == nil && frozen > 0 && !rawdb.ReadOnlyAncients(bc.db) {
 		var (
 			needRewind bool
 			low        uint64
@@ -280,6 +301,10 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			}
 		}
 	}
+	// Load any existing snapshot, regenerating it if loading failed
+	if bc.cacheConfig.SnapshotLimit > 0 {
+		bc.snaps = snapshot.New(bc.db, bc.stateCache.TrieDB(), bc.cacheConfig.SnapshotLimit, bc.CurrentBlock().Root())
+	}
 	// Take ownership of this particular state
 	go bc.update()
 	return bc, nil
@@ -308,6 +333,39 @@ func (bc *BlockChain) empty() bool {
 	return true
 }
 
+// checkSharedAncients ensures that the local chain heads agree with the given
+// number of blocks frozen into a shared, read-only ancient store.
+func (bc *BlockChain) checkSharedAncients(frozen uint64) error {
+	heads := []struct {
+		kind string
+		hash common.Hash
+	}{
+		{"header", rawdb.ReadHeadHeaderHash(bc.db)},
+		{"fast block", rawdb.ReadHeadFastBlockHash(bc.db)},
+		{"full block", rawdb.ReadHeadBlockHash(bc.db)},
+	}
+	for _, head := range heads {
+		number := rawdb.ReadHeaderNumber(bc.db, head.hash)
+		if number == nil {
+			continue
+		}
+		// Heads within the shared store must be part of it, heads beyond must
+		// descend from its last block
+		at, hash := *number, head.hash
+		if at >= frozen {
+			header := rawdb.ReadHeader(bc.db, rawdb.ReadCanonicalHash(bc.db, frozen), frozen)
+			if header == nil {
+				continue
+			}
+			at, hash = frozen-1, header.ParentHash
+		}
+		if want := rawdb.ReadCanonicalHash(bc.db, at); hash != want {
+			return fmt.Errorf("local %s head #%d diverged from shared ancient store at #%d: have %x, want %x", head.kind, *number, at, hash, want)
+		}
+	}
+	return nil
+}
+
 // loadLastState loads the last known chain state from the database. This method
 // assumes that the chain manager mutex is held.
 func (bc *BlockChain) loadLastState() error {
@@ -326,7 +384,7 @@ func (bc *BlockChain) loadLastState() error {
 		return bc.Reset()
 	}
 	// Make sure the state associated with the block is available
-	if _, err := state.New(currentBlock.Root(), bc.stateCache); err != nil {
+	if _, err := state.New(currentBlock.Root(), bc.stateCache, nil); err != nil {
 		// Dangling block without a state associated, init from scratch
 		log.Warn("Head state missing, repairing chain", "number", currentBlock.Number(), "hash", currentBlock.Hash())
 		if err := bc.repair(&currentBlock); err != nil {
@@ -388,7 +446,7 @@ func (bc *BlockChain) SetHead(head uint64) error {
 			if newHeadBlock == nil {
 				newHeadBlock = bc.genesisBlock
 			} else {
-				if _, err := state.New(newHeadBlock.Root(), bc.stateCache); err != nil {
+				if _, err := state.New(newHeadBlock.Root(), bc.stateCache, nil); err != nil {
 					// Rewound state missing, rolled back to before pivot, reset to genesis
 					newHeadBlock = bc.genesisBlock
 				}
@@ -415,7 +473,7 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	delFn := func(db ethdb.KeyValueWriter, hash common.Hash, num uint64) {
 		// Ignore the error here since light client won't hit this path
 		frozen, _ := bc.db.Ancients()
-		if num+1 <= frozen {
+		if num+1 <= frozen && !rawdb.ReadOnlyAncients(bc.db) {
 			// Truncate all relative data(header, total difficulty, body, receipt
 			// and canonical hash) from ancient store.
 			if err := bc.db.TruncateAncients(num + 1); err != nil {
@@ -442,7 +500,16 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	bc.blockCache.Purge()
 	bc.futureBlocks.Purge()
 
-	return bc.loadLastState()
+	if err := bc.loadLastState(); err != nil {
+		return err
+	}
+	// Regenerate the state snapshot if the new head is not tracked by it
+	if bc.snaps != nil {
+		if root := bc.CurrentBlock().Root(); bc.snaps.Snapshot(root) == nil {
+			bc.snaps.Rebuild(root)
+		}
+	}
+	return nil
 }
 
 // FastSyncCommitHead sets the current head block to the one defined by the hash
@@ -462,6 +529,10 @@ func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	headBlockGauge.Update(int64(block.NumberU64()))
 	bc.chainmu.Unlock()
 
+	// Destroy any existing state snapshot and regenerate it in the background
+	if bc.snaps != nil {
+		bc.snaps.Rebuild(block.Root())
+	}
 	log.Info("Committed new head block", "number", block.Number(), "hash", hash)
 	return nil
 }
@@ -500,7 +571,7 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	return state.New(root, bc.stateCache, bc.snaps)
 }
 
 // StateCache returns the caching database underpinning the blockchain instance.
@@ -551,7 +622,7 @@ func (bc *BlockChain) ResetWithGenesisBlock(genesis *types.Block) error {
 func (bc *BlockChain) repair(head **types.Block) error {
 	for {
 		// Abort if we've rewound to a head block that does have associated state
-		if _, err := state.New((*head).Root(), bc.stateCache); err == nil {
+		if _, err := state.New((*head).Root(), bc.stateCache, nil); err == nil {
 			log.Info("Rewound blockchain to past state", "number", (*head).Number(), "hash", (*head).Hash())
 			return nil
 		}
@@ -806,6 +877,14 @@ func (bc *BlockChain) Stop() {
 
 	bc.wg.Wait()
 
+	// Ensure that the entirety of the state snapshot is journalled to disk.
+	var snapBase common.Hash
+	if bc.snaps != nil {
+		var err error
+		if snapBase, err = bc.snaps.Journal(bc.CurrentBlock().Root()); err != nil {
+			log.Error("Failed to journal state snapshot", "err", err)
+		}
+	}
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
@@ -824,6 +903,13 @@ func (bc *BlockChain) Stop() {
 				}
 			}
 		}
+		// The snapshot generator resumes from the trie of the persistent layer
+		if snapBase != (common.Hash{}) {
+			log.Info("Writing snapshot state to disk", "root", snapBase)
+			if err := triedb.Commit(snapBase, true); err != nil {
+				log.Error("Failed to commit snapshot base trie", "err", err)
+			}
+		}
 		for !bc.triegc.Empty() {
 			triedb.Dereference(bc.triegc.PopItem().(common.Hash))
 		}
@@ -899,6 +985,10 @@ func (bc *BlockChain) Rollback(chain []common.Hash) {
 // truncateAncient rewinds the blockchain to the specified header and deletes all
 // data in the ancient store that exceeds the specified header.
 func (bc *BlockChain) truncateAncient(head uint64) error {
+	// Shared read-only ancient stores can't be truncated locally
+	if rawdb.ReadOnlyAncients(bc.db) {
+		return nil
+	}
 	frozen, err := bc.db.Ancients()
 	if err != nil {
 		return err
@@ -945,6 +1035,11 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		ancientBlocks, liveBlocks     types.Blocks
 		ancientReceipts, liveReceipts []types.Receipts
 	)
+	// Shared read-only ancient stores are filled by a different node, keep all
+	// the data in the active store until it gets frozen there
+	if rawdb.ReadOnlyAncients(bc.db) {
+		ancientLimit = 0
+	}
 	// Do a sanity check that the provided chain is actually ordered and linked
 	for i := 0; i < len(blockChain); i++ {
 		if i != 0 {
@@ -1219,6 +1314,7 @@ func (bc *BlockChain) writeBlockWithoutState(block *types.Block, td *big.Int) (e
 		return err
 	}
 	rawdb.WriteBlock(bc.db, block)
+	bc.markArrival(block)
 
 	return nil
 }
@@ -1231,6 +1327,9 @@ func (bc *BlockChain) writeKnownBlock(block *types.Block) error {
 
 	current := bc.CurrentBlock()
 	if block.ParentHash() != current.Hash() {
+		if err := bc.checkReorgPenalty(current, block, bc.GetTd(block.Hash(), block.NumberU64())); err != nil {
+			return err
+		}
 		if err := bc.reorg(current, block); err != nil {
 			return err
 		}
@@ -1267,11 +1366,19 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	localTd := bc.GetTd(currentBlock.Hash(), currentBlock.NumberU64())
 	externTd := new(big.Int).Add(block.Difficulty(), ptd)
 
+	// Refuse to reorg onto a competing segment not heavy enough to overcome the
+	// reorg penalty, before persisting anything of it
+	if externTd.Cmp(localTd) > 0 {
+		if err := bc.checkReorgPenalty(currentBlock, block, externTd); err != nil {
+			return NonStatTy, err
+		}
+	}
 	// Irrelevant of the canonical status, write the block itself to the database
 	if err := bc.hc.WriteTd(block.Hash(), block.NumberU64(), externTd); err != nil {
 		return NonStatTy, err
 	}
 	rawdb.WriteBlock(bc.db, block)
+	bc.markArrival(block)
 
 	root, err := state.Commit(bc.chainConfig.IsEIP161F(block.Number()))
 	if err != nil {
@@ -1341,9 +1448,10 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	// Please refer to http://www.cs.cornell.edu/~ie53/publications/btcProcFC.pdf
 	reorg := externTd.Cmp(localTd) > 0
 	currentBlock = bc.CurrentBlock()
-	if !reorg && externTd.Cmp(localTd) == 0 {
+	if !reorg && externTd.Cmp(localTd) == 0 && bc.reorgPenalty == nil {
 		// Split same-difficulty blocks by number, then preferentially select
-		// the block generated by the local miner as the canonical block.
+		// the block generated by the local miner as the canonical block. With
+		// the reorg penalty enabled, the first seen block is always kept.
 		if block.NumberU64() < currentBlock.NumberU64() {
 			reorg = true
 		} else if block.NumberU64() == currentBlock.NumberU64() {
@@ -1598,7 +1706,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		if parent == nil {
 			parent = bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
 		}
-		statedb, err := state.New(parent.Root, bc.stateCache)
+		statedb, err := state.New(parent.Root, bc.stateCache, bc.snaps)
 		if err != nil {
 			return it.index, events, coalescedLogs, err
 		}
@@ -1609,7 +1717,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		if !bc.cacheConfig.TrieCleanNoPrefetch {
 			if followup, err := it.peek(); followup != nil && err == nil {
 				go func(start time.Time) {
-					throwaway, _ := state.New(parent.Root, bc.stateCache)
+					throwaway, _ := state.New(parent.Root, bc.stateCache, bc.snaps)
 					bc.prefetcher.Prefetch(followup, throwaway, bc.vmConfig, &followupInterrupt)
 
 					blockPrefetchExecuteTimer.Update(time.Since(start))
@@ -1633,9 +1741,12 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		accountUpdateTimer.Update(statedb.AccountUpdates) // Account updates are complete, we can mark them
 		storageUpdateTimer.Update(statedb.StorageUpdates) // Storage updates are complete, we can mark them
 
+		snapshotAccountReadTimer.Update(statedb.SnapshotAccountReads) // Account reads are complete, we can mark them
+		snapshotStorageReadTimer.Update(statedb.SnapshotStorageReads) // Storage reads are complete, we can mark them
+
 		triehash := statedb.AccountHashes + statedb.StorageHashes // Save to not double count in validation
-		trieproc := statedb.AccountReads + statedb.AccountUpdates
-		trieproc += statedb.StorageReads + statedb.StorageUpdates
+		trieproc := statedb.SnapshotAccountReads + statedb.AccountReads + statedb.AccountUpdates
+		trieproc += statedb.SnapshotStorageReads + statedb.StorageReads + statedb.StorageUpdates
 
 		blockExecutionTimer.Update(time.Since(substart) - trieproc - triehash)
 
@@ -1664,10 +1775,11 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool) (int, []
 		atomic.StoreUint32(&followupInterrupt, 1)
 
 		// Update the metrics touched during block commit
-		accountCommitTimer.Update(statedb.AccountCommits) // Account commits are complete, we can mark them
-		storageCommitTimer.Update(statedb.StorageCommits) // Storage commits are complete, we can mark them
+		accountCommitTimer.Update(statedb.AccountCommits)   // Account commits are complete, we can mark them
+		storageCommitTimer.Update(statedb.StorageCommits)   // Storage commits are complete, we can mark them
+		snapshotCommitTimer.Update(statedb.SnapshotCommits) // Snapshot commits are complete, we can mark them
 
-		blockWriteTimer.Update(time.Since(substart) - statedb.AccountCommits - statedb.StorageCommits)
+		blockWriteTimer.Update(time.Since(substart) - statedb.AccountCommits - statedb.StorageCommits - statedb.SnapshotCommits)
 		blockInsertTimer.UpdateSince(start)
 
 		switch status {
@@ -0,0 +1,197 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	reorgPenaltyAcceptMeter = metrics.NewRegisteredMeter("chain/reorg/penalty/accepted", nil)
+	reorgPenaltyRejectMeter = metrics.NewRegisteredMeter("chain/reorg/penalty/rejected", nil)
+	reorgPenaltyDepthGauge  = metrics.NewRegisteredGauge("chain/reorg/penalty/depth", nil)
+
+	// ErrReorgPenalty is returned if a block would reorganise the chain onto a
+	// competing segment not heavy enough to overcome the reorg penalty.
+	ErrReorgPenalty = errors.New("reorg rejected by finality penalty")
+)
+
+// ReorgPenaltyConfig configures a subjective, node local resistance against
+// deep chain reorganisations, modelled after the modified exponential subjective
+// scoring (MESS) proposed for Ethereum Classic as ECBP-1100.
+//
+// A competing chain segment only replaces the local one if its difficulty since
+// the common ancestor exceeds the local segment's by a factor growing with the
+// time since the node first saw the local segment:
+//
+//   required = 1 + Amplitude * (3x^2 - 2x^3), x = min(age/Window, 1)
+//
+// Competing blocks of equal total difficulty never replace the local ones. The
+// age is measured by the local arrival time of blocks instead of their header
+// timestamps, which are chosen by the miners. Blocks not seen live since the node
+// started (e.g. imported before a restart) fall back to their header timestamps.
+//
+// The policy is not part of consensus. Nodes enforcing it may diverge from the
+// network during an attack and need to be resolved manually if the attacking
+// chain wins in the end.
+type ReorgPenaltyConfig struct {
+	Amplitude  uint64 // Extra multiple of the local segment difficulty required at full penalty
+	Window     uint64 // Age of the local segment (seconds) at which the full penalty applies
+	MaxHeadAge uint64 // Maximum age of the local head (seconds) for the penalty to apply, 0 for no limit
+}
+
+// DefaultReorgPenaltyConfig contains the ECBP-1100 penalty parameters, requiring
+// at most a 31 times heavier segment after about 7 hours.
+var DefaultReorgPenaltyConfig = ReorgPenaltyConfig{
+	Amplitude:  30,
+	Window:     25132,
+	MaxHeadAge: 600,
+}
+
+// sanitize returns a copy of the config with invalid fields replaced by the
+// defaults.
+func (c *ReorgPenaltyConfig) sanitize() *ReorgPenaltyConfig {
+	conf := *c
+	if conf.Window == 0 {
+		log.Warn("Sanitizing invalid reorg penalty window", "provided", conf.Window, "updated", DefaultReorgPenaltyConfig.Window)
+		conf.Window = DefaultReorgPenaltyConfig.Window
+	}
+	return &conf
+}
+
+// penalty computes the factor by which a competing segment's difficulty needs to
+// exceed the local segment's of the given age, as a fraction.
+func (c *ReorgPenaltyConfig) penalty(age uint64) (num *big.Int, denom *big.Int) {
+	if age > c.Window {
+		age = c.Window
+	}
+	var (
+		x = new(big.Int).SetUint64(age)
+		w = new(big.Int).SetUint64(c.Window)
+	)
+	denom = new(big.Int).Mul(w, w)
+	denom.Mul(denom, w)
+
+	// num = w^3 + amplitude * x^2 * (3w - 2x)
+	num = new(big.Int).Mul(w, big.NewInt(3))
+	num.Sub(num, new(big.Int).Lsh(x, 1))
+	num.Mul(num, new(big.Int).Mul(x, x))
+	num.Mul(num, new(big.Int).SetUint64(c.Amplitude))
+	num.Add(num, denom)
+
+	return num, denom
+}
+
+// markArrival records the local time the block was first seen at.
+func (bc *BlockChain) markArrival(block *types.Block) {
+	bc.blockArrivals.ContainsOrAdd(block.Hash(), uint64(time.Now().Unix()))
+}
+
+// arrivalTime returns the local time the block with the given header was first
+// seen at, or its timestamp if it arrived before the node was started.
+func (bc *BlockChain) arrivalTime(header *types.Header) uint64 {
+	if arrival, ok := bc.blockArrivals.Get(header.Hash()); ok {
+		return arrival.(uint64)
+	}
+	return header.Time
+}
+
+// SetReorgPenalty enables the given reorg penalty policy on the chain, or
+// disables it if nil.
+func (bc *BlockChain) SetReorgPenalty(config *ReorgPenaltyConfig) {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	if config != nil {
+		config = config.sanitize()
+		log.Info("Enabled reorg finality penalty", "amplitude", config.Amplitude, "window", time.Duration(config.Window)*time.Second, "maxheadage", time.Duration(config.MaxHeadAge)*time.Second)
+	}
+	bc.reorgPenalty = config
+}
+
+// checkReorgPenalty verifies that reorganising the chain from the current head
+// onto the given block is allowed by the reorg penalty, if any is configured.
+// The total difficulty of the block needs to be passed in, as it may not yet be
+// written into the database.
+func (bc *BlockChain) checkReorgPenalty(current *types.Block, block *types.Block, td *big.Int) error {
+	config := bc.reorgPenalty
+	if config == nil || block.ParentHash() == current.Hash() {
+		return nil
+	}
+	// The penalty only protects a chain the node followed live, don't get stuck
+	// on a stale chain after being offline
+	now := uint64(time.Now().Unix())
+	if config.MaxHeadAge != 0 && bc.arrivalTime(current.Header())+config.MaxHeadAge < now {
+		return nil
+	}
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil
+	}
+	ancestor := rawdb.FindCommonAncestor(bc.db, current.Header(), parent)
+	if ancestor == nil {
+		return nil
+	}
+	var (
+		ancestorTd = bc.GetTd(ancestor.Hash(), ancestor.Number.Uint64())
+		localTd    = bc.GetTd(current.Hash(), current.NumberU64())
+	)
+	if ancestorTd == nil || localTd == nil {
+		return nil
+	}
+	localDiff := new(big.Int).Sub(localTd, ancestorTd)
+	externDiff := new(big.Int).Sub(td, ancestorTd)
+
+	// Measure the age of the local segment by the time its first block arrived,
+	// ignoring blocks from the future
+	var age uint64
+	if ancestor.Number.Uint64() < current.NumberU64() {
+		if first := bc.GetHeaderByNumber(ancestor.Number.Uint64() + 1); first != nil {
+			if arrival := bc.arrivalTime(first); arrival < now {
+				age = now - arrival
+			}
+		}
+	}
+	num, denom := config.penalty(age)
+	if new(big.Int).Mul(externDiff, denom).Cmp(new(big.Int).Mul(localDiff, num)) >= 0 {
+		reorgPenaltyAcceptMeter.Mark(1)
+		return nil
+	}
+	var (
+		drop = current.NumberU64() - ancestor.Number.Uint64()
+		add  = block.NumberU64() - ancestor.Number.Uint64()
+	)
+	reorgPenaltyRejectMeter.Mark(1)
+	reorgPenaltyDepthGauge.Update(int64(drop))
+
+	required, _ := new(big.Float).Quo(new(big.Float).SetInt(num), new(big.Float).SetInt(denom)).Float64()
+	actual, _ := new(big.Float).Quo(new(big.Float).SetInt(externDiff), new(big.Float).SetInt(localDiff)).Float64()
+
+	log.Warn("Rejected chain reorg by finality penalty", "number", ancestor.Number, "hash", ancestor.Hash(),
+		"drop", drop, "add", add, "addhash", block.Hash(), "age", time.Duration(age)*time.Second,
+		"ratio", fmt.Sprintf("%.4f", actual), "required", fmt.Sprintf("%.4f", required))
+
+	return ErrReorgPenalty
+}
@@ -0,0 +1,156 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests the shape of the reorg penalty curve.
+func TestReorgPenaltyCurve(t *testing.T) {
+	config := &ReorgPenaltyConfig{Amplitude: 30, Window: 1000}
+
+	tests := []struct {
+		age  uint64
+		want *big.Rat
+	}{
+		{0, big.NewRat(1, 1)},
+		{500, big.NewRat(16, 1)},
+		{1000, big.NewRat(31, 1)},
+		{5000, big.NewRat(31, 1)},
+		{100, big.NewRat(1000+30*28, 1000)},
+	}
+	for _, tt := range tests {
+		num, denom := config.penalty(tt.age)
+		if have := new(big.Rat).SetFrac(num, denom); have.Cmp(tt.want) != 0 {
+			t.Errorf("age %d: penalty mismatch: have %v, want %v", tt.age, have, tt.want)
+		}
+	}
+	// Ensure the penalty never decreases with the age
+	prev := big.NewRat(1, 1)
+	for age := uint64(0); age <= config.Window; age += 10 {
+		have := new(big.Rat).SetFrac(config.penalty(age))
+		if have.Cmp(prev) < 0 {
+			t.Fatalf("age %d: penalty decreased: have %v, prev %v", age, have, prev)
+		}
+		prev = have
+	}
+}
+
+// backdateArrivals pretends the given blocks arrived live, one every 10 seconds
+// with the last one arriving just now.
+func backdateArrivals(blockchain *BlockChain, blocks []*types.Block) {
+	now := uint64(time.Now().Unix())
+	for i, block := range blocks {
+		blockchain.blockArrivals.Add(block.Hash(), now-uint64(10*(len(blocks)-1-i)))
+	}
+}
+
+// Tests that deep reorgs onto a barely heavier chain are rejected if the reorg
+// penalty is enabled, but shallow ones still go through.
+func TestReorgPenalty(t *testing.T) {
+	db, blockchain, err := newCanonical(ethash.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	blockchain.SetReorgPenalty(&ReorgPenaltyConfig{Amplitude: 30, Window: 1000})
+
+	// Import a canonical chain spanning more than half the penalty window
+	genesis := blockchain.CurrentBlock()
+	local, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 64, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+	})
+	if _, err := blockchain.InsertChain(local); err != nil {
+		t.Fatalf("failed to insert local chain: %v", err)
+	}
+	backdateArrivals(blockchain, local)
+
+	// A longer, hence heavier, competing chain from genesis must not be accepted
+	attack, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 70, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{2})
+	})
+	if _, err := blockchain.InsertChain(attack); err != ErrReorgPenalty {
+		t.Fatalf("deep reorg error mismatch: have %v, want %v", err, ErrReorgPenalty)
+	}
+	if head := blockchain.CurrentBlock().Hash(); head != local[len(local)-1].Hash() {
+		t.Fatalf("head mismatch after rejected reorg: have %x, want %x", head, local[len(local)-1].Hash())
+	}
+	// A shallow reorg needs to be barely heavier
+	shallow, _ := GenerateChain(params.TestChainConfig, local[len(local)-2], ethash.NewFaker(), db, 2, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{2})
+	})
+	backdateArrivals(blockchain, local)
+	if _, err := blockchain.InsertChain(shallow); err != nil {
+		t.Fatalf("failed to insert shallow reorg: %v", err)
+	}
+	if head := blockchain.CurrentBlock().Hash(); head != shallow[len(shallow)-1].Hash() {
+		t.Fatalf("head mismatch after shallow reorg: have %x, want %x", head, shallow[len(shallow)-1].Hash())
+	}
+	// Without the penalty the heavier chain wins
+	blockchain.SetReorgPenalty(nil)
+	if _, err := blockchain.InsertChain(attack); err != nil {
+		t.Fatalf("failed to insert heavier chain without penalty: %v", err)
+	}
+	if head := blockchain.CurrentBlock().Hash(); head != attack[len(attack)-1].Hash() {
+		t.Fatalf("head mismatch without penalty: have %x, want %x", head, attack[len(attack)-1].Hash())
+	}
+}
+
+// Tests that the age of the local segment is measured by the local arrival time
+// of its blocks, not by the timestamps chosen by their miners.
+func TestReorgPenaltyArrivalTime(t *testing.T) {
+	db, blockchain, err := newCanonical(ethash.NewFaker(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	blockchain.SetReorgPenalty(&ReorgPenaltyConfig{Amplitude: 30, Window: 1000})
+
+	// Import a local chain with timestamps spanning the full penalty window, but
+	// which has only just arrived
+	genesis := blockchain.CurrentBlock()
+	local, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 64, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+		b.OffsetTime(20)
+	})
+	if _, err := blockchain.InsertChain(local); err != nil {
+		t.Fatalf("failed to insert local chain: %v", err)
+	}
+	if age := local[len(local)-1].Time() - genesis.Time(); age < 1000 {
+		t.Fatalf("local chain timestamps too close: %d seconds", age)
+	}
+	// A heavier competing chain needs to be accepted, as the local segment is new
+	attack, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 70, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{2})
+	})
+	if _, err := blockchain.InsertChain(attack); err != nil {
+		t.Fatalf("failed to reorg onto heavier chain: %v", err)
+	}
+	if head := blockchain.CurrentBlock().Hash(); head != attack[len(attack)-1].Hash() {
+		t.Fatalf("head mismatch after reorg: have %x, want %x", head, attack[len(attack)-1].Hash())
+	}
+}
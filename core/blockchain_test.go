@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -143,7 +144,7 @@ func testBlockChainImport(chain types.Blocks, blockchain *BlockChain) error {
 			}
 			return err
 		}
-		statedb, err := state.New(blockchain.GetBlockByHash(block.ParentHash()).Root(), blockchain.stateCache)
+		statedb, err := state.New(blockchain.GetBlockByHash(block.ParentHash()).Root(), blockchain.stateCache, nil)
 		if err != nil {
 			return err
 		}
@@ -2241,3 +2242,79 @@ func BenchmarkBlockChain_1x1000Executions(b *testing.B) {
 	}
 	benchmarkLargeNumberOfValueToNonexisting(b, numTxs, numBlocks, recipientFn, dataFn)
 }
+
+// Tests that a node sharing a read-only ancient store refuses to start if its
+// local chain diverged from the shared one, instead of rewinding either.
+func TestReadOnlyAncientsDivergence(t *testing.T) {
+	var (
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(rawdb.NewMemoryDatabase())
+	)
+	shared, receipts := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 64, nil)
+	forked, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 64, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	// Freeze the first half of the shared chain into the ancient store
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writerDb, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), dir, "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	gspec.MustCommit(writerDb)
+	writer, _ := NewBlockChain(writerDb, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	headers := make([]*types.Header, len(shared))
+	for i, block := range shared {
+		headers[i] = block.Header()
+	}
+	if n, err := writer.InsertHeaderChain(headers, 1); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := writer.InsertReceiptChain(shared, receipts, uint64(len(shared)/2)); err != nil {
+		t.Fatalf("failed to insert receipt %d: %v", n, err)
+	}
+	writer.Stop()
+	writerDb.Close()
+
+	tests := []struct {
+		blocks types.Blocks
+		fail   bool
+	}{
+		{shared[:10], false}, // local head within the shared store
+		{shared, false},      // local head beyond the shared store
+		{forked[:10], true},  // diverged local head within the shared store
+		{forked, true},       // diverged local head beyond the shared store
+	}
+	for i, tt := range tests {
+		// Import the local chain without the shared store
+		kvdb := memorydb.New()
+		db := rawdb.NewDatabase(kvdb)
+		gspec.MustCommit(db)
+		chain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+		if n, err := chain.InsertChain(tt.blocks); err != nil {
+			t.Fatalf("test %d: failed to insert block %d: %v", i, n, err)
+		}
+		chain.Stop()
+
+		// Reopen it on top of the shared store and check the consistency
+		readerDb, err := rawdb.NewDatabaseWithFreezerConfig(kvdb, dir, rawdb.FreezerConfig{ReadOnly: true}, "")
+		if err != nil {
+			t.Fatalf("test %d: failed to open read-only freezer db: %v", i, err)
+		}
+		chain, err = NewBlockChain(readerDb, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+		if tt.fail != (err != nil) {
+			t.Errorf("test %d: failure mismatch: have %v, want %v", i, err, tt.fail)
+		}
+		if err == nil {
+			if head := chain.CurrentBlock(); head.Hash() != tt.blocks[len(tt.blocks)-1].Hash() {
+				t.Errorf("test %d: head block rewound to #%d", i, head.NumberU64())
+			}
+			chain.Stop()
+		}
+		readerDb.Close()
+	}
+}
@@ -0,0 +1,957 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package chainspec converts between go-ethereum genesis specifications and the
+// chain specification formats of other Ethereum clients.
+package chainspec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// errUnsupportedEngine is returned if a chain configuration or specification
+	// uses a consensus engine that cannot be expressed in the other format.
+	errUnsupportedEngine = errors.New("unsupported consensus engine")
+
+	// errUnsupportedRewards is returned if the block reward rules of a chain cannot
+	// be expressed in the other format.
+	errUnsupportedRewards = errors.New("unsupported block reward schedule")
+
+	// errUnsupportedBombDelays is returned if the difficulty bomb delays of a chain
+	// cannot be expressed in the other format.
+	errUnsupportedBombDelays = errors.New("unsupported difficulty bomb delay schedule")
+)
+
+// Difficulty bomb delays (in blocks) introduced by EIP-649 and, cumulatively,
+// by EIP-1234.
+const (
+	eip649BombDelay  = 3000000
+	eip1234BombDelay = 5000000
+)
+
+// ParityChainSpec is the chain specification format used by Parity.
+type ParityChainSpec struct {
+	Name    string `json:"name"`
+	Datadir string `json:"dataDir,omitempty"`
+	Engine  struct {
+		Ethash *ParityEthash `json:"Ethash,omitempty"`
+		Clique *ParityClique `json:"clique,omitempty"`
+	} `json:"engine"`
+
+	Params  ParityParams  `json:"params"`
+	Genesis ParityGenesis `json:"genesis"`
+
+	Nodes    []string                                    `json:"nodes,omitempty"`
+	Accounts map[common.UnprefixedAddress]*ParityAccount `json:"accounts"`
+}
+
+// ParityEthash is the proof-of-work engine section of a Parity chain spec.
+type ParityEthash struct {
+	Params struct {
+		MinimumDifficulty      *math.HexOrDecimal256 `json:"minimumDifficulty,omitempty"`
+		DifficultyBoundDivisor *math.HexOrDecimal256 `json:"difficultyBoundDivisor,omitempty"`
+		DurationLimit          *math.HexOrDecimal256 `json:"durationLimit,omitempty"`
+
+		BlockReward          ParitySchedule `json:"blockReward,omitempty"`
+		DifficultyBombDelays ParitySchedule `json:"difficultyBombDelays,omitempty"`
+		BombDefuseTransition *ParityUint64  `json:"bombDefuseTransition,omitempty"`
+
+		HomesteadTransition        *ParityUint64 `json:"homesteadTransition,omitempty"`
+		EIP100bTransition          *ParityUint64 `json:"eip100bTransition,omitempty"`
+		ECIP1010PauseTransition    *ParityUint64 `json:"ecip1010PauseTransition,omitempty"`
+		ECIP1010ContinueTransition *ParityUint64 `json:"ecip1010ContinueTransition,omitempty"`
+		ECIP1017EraRounds          *ParityUint64 `json:"ecip1017EraRounds,omitempty"`
+		ECIP1099Transition         *ParityUint64 `json:"ecip1099Transition,omitempty"`
+
+		DaoHardforkTransition  *ParityUint64    `json:"daoHardforkTransition,omitempty"`
+		DaoHardforkBeneficiary *common.Address  `json:"daoHardforkBeneficiary,omitempty"`
+		DaoHardforkAccounts    []common.Address `json:"daoHardforkAccounts,omitempty"`
+	} `json:"params"`
+}
+
+// ParityClique is the proof-of-authority engine section of a Parity chain spec.
+type ParityClique struct {
+	Params struct {
+		Period uint64 `json:"period"`
+		Epoch  uint64 `json:"epoch"`
+	} `json:"params"`
+}
+
+// ParityParams is the section of a Parity chain spec holding the chain wide
+// parameters and the activation blocks of the non consensus engine EIPs.
+type ParityParams struct {
+	AccountStartNonce    *ParityUint64 `json:"accountStartNonce,omitempty"`
+	MaximumExtraDataSize *ParityUint64 `json:"maximumExtraDataSize,omitempty"`
+	MinGasLimit          *ParityUint64 `json:"minGasLimit,omitempty"`
+	GasLimitBoundDivisor *ParityUint64 `json:"gasLimitBoundDivisor,omitempty"`
+	NetworkID            *ParityUint64 `json:"networkID,omitempty"`
+	ChainID              *ParityUint64 `json:"chainID,omitempty"`
+
+	MaxCodeSize           *ParityUint64 `json:"maxCodeSize,omitempty"`
+	MaxCodeSizeTransition *ParityUint64 `json:"maxCodeSizeTransition,omitempty"`
+
+	EIP150Transition    *ParityUint64 `json:"eip150Transition,omitempty"`
+	EIP155Transition    *ParityUint64 `json:"eip155Transition,omitempty"`
+	EIP160Transition    *ParityUint64 `json:"eip160Transition,omitempty"`
+	EIP161abcTransition *ParityUint64 `json:"eip161abcTransition,omitempty"`
+	EIP161dTransition   *ParityUint64 `json:"eip161dTransition,omitempty"`
+
+	EIP140Transition *ParityUint64 `json:"eip140Transition,omitempty"`
+	EIP211Transition *ParityUint64 `json:"eip211Transition,omitempty"`
+	EIP214Transition *ParityUint64 `json:"eip214Transition,omitempty"`
+	EIP658Transition *ParityUint64 `json:"eip658Transition,omitempty"`
+
+	EIP145Transition          *ParityUint64 `json:"eip145Transition,omitempty"`
+	EIP1014Transition         *ParityUint64 `json:"eip1014Transition,omitempty"`
+	EIP1052Transition         *ParityUint64 `json:"eip1052Transition,omitempty"`
+	EIP1283Transition         *ParityUint64 `json:"eip1283Transition,omitempty"`
+	EIP1283DisableTransition  *ParityUint64 `json:"eip1283DisableTransition,omitempty"`
+	EIP1283ReenableTransition *ParityUint64 `json:"eip1283ReenableTransition,omitempty"`
+
+	EIP1344Transition *ParityUint64 `json:"eip1344Transition,omitempty"`
+	EIP1706Transition *ParityUint64 `json:"eip1706Transition,omitempty"`
+	EIP1884Transition *ParityUint64 `json:"eip1884Transition,omitempty"`
+	EIP2028Transition *ParityUint64 `json:"eip2028Transition,omitempty"`
+}
+
+// ParityGenesis is the genesis header section of a Parity chain spec.
+type ParityGenesis struct {
+	Seal struct {
+		Ethereum struct {
+			Nonce   hexutil.Bytes `json:"nonce"`
+			MixHash hexutil.Bytes `json:"mixHash"`
+		} `json:"ethereum"`
+	} `json:"seal"`
+
+	Difficulty *math.HexOrDecimal256 `json:"difficulty"`
+	Author     common.Address        `json:"author"`
+	Timestamp  ParityUint64          `json:"timestamp"`
+	ParentHash common.Hash           `json:"parentHash"`
+	ExtraData  hexutil.Bytes         `json:"extraData"`
+	GasLimit   ParityUint64          `json:"gasLimit"`
+}
+
+// ParityAccount is a genesis account of a Parity chain spec, optionally marking
+// the address as a precompiled contract.
+type ParityAccount struct {
+	Balance *math.HexOrDecimal256       `json:"balance,omitempty"`
+	Nonce   ParityUint64                `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+	Builtin *ParityBuiltin              `json:"builtin,omitempty"`
+}
+
+// ParityBuiltin is the definition of a precompiled contract in a Parity chain
+// spec. Only the activation blocks are interpreted on import, the pricing is
+// exported for Parity's benefit and fixed by the EIPs within go-ethereum.
+type ParityBuiltin struct {
+	Name              string         `json:"name"`
+	ActivateAt        *ParityUint64  `json:"activate_at,omitempty"`
+	EIP1108Transition *ParityUint64  `json:"eip1108_transition,omitempty"`
+	Pricing           *ParityPricing `json:"pricing"`
+}
+
+// ParityPricing is the gas pricing scheme of a Parity builtin contract.
+type ParityPricing struct {
+	Linear              *ParityLinearPricing       `json:"linear,omitempty"`
+	ModExp              *ParityModExpPricing       `json:"modexp,omitempty"`
+	AltBnConstOperation *ParityAltBnConstOpPricing `json:"alt_bn128_const_operations,omitempty"`
+	AltBnPairing        *ParityAltBnPairingPricing `json:"alt_bn128_pairing,omitempty"`
+	Blake2F             *ParityBlake2FPricing      `json:"blake2_f,omitempty"`
+}
+
+// ParityLinearPricing is a base plus per word gas price.
+type ParityLinearPricing struct {
+	Base uint64 `json:"base"`
+	Word uint64 `json:"word"`
+}
+
+// ParityModExpPricing is the EIP-198 gas price.
+type ParityModExpPricing struct {
+	Divisor uint64 `json:"divisor"`
+}
+
+// ParityAltBnConstOpPricing is the constant gas price of the alt_bn128 addition
+// and multiplication, before and after EIP-1108.
+type ParityAltBnConstOpPricing struct {
+	Price                  uint64 `json:"price"`
+	EIP1108TransitionPrice uint64 `json:"eip1108_transition_price,omitempty"`
+}
+
+// ParityAltBnPairingPricing is the gas price of the alt_bn128 pairing check,
+// before and after EIP-1108.
+type ParityAltBnPairingPricing struct {
+	Base                  uint64 `json:"base"`
+	Pair                  uint64 `json:"pair"`
+	EIP1108TransitionBase uint64 `json:"eip1108_transition_base,omitempty"`
+	EIP1108TransitionPair uint64 `json:"eip1108_transition_pair,omitempty"`
+}
+
+// ParityBlake2FPricing is the EIP-152 gas price.
+type ParityBlake2FPricing struct {
+	GasPerRound uint64 `json:"gas_per_round"`
+}
+
+// ParityUint64 is a 64 bit unsigned integer of a Parity chain spec, which may be
+// given as a JSON number or as a hex or decimal string. It is always encoded as
+// a hex string.
+type ParityUint64 uint64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *ParityUint64) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '"' {
+		var text string
+		if err := json.Unmarshal(input, &text); err != nil {
+			return err
+		}
+		return i.UnmarshalText([]byte(text))
+	}
+	num, err := strconv.ParseUint(string(input), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %s", input)
+	}
+	*i = ParityUint64(num)
+	return nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (i *ParityUint64) UnmarshalText(input []byte) error {
+	num, ok := math.ParseUint64(string(input))
+	if !ok {
+		return fmt.Errorf("invalid hex or decimal integer %q", input)
+	}
+	*i = ParityUint64(num)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (i ParityUint64) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%#x", uint64(i))), nil
+}
+
+// ParitySchedule is a block number indexed list of values, as used by Parity for
+// block rewards and difficulty bomb delays. A single value is also accepted on
+// import, denoting a schedule with one entry at the genesis block.
+type ParitySchedule map[ParityUint64]*math.HexOrDecimal256
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *ParitySchedule) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] != '{' {
+		value := new(math.HexOrDecimal256)
+		if err := json.Unmarshal(input, value); err != nil {
+			return err
+		}
+		*s = ParitySchedule{0: value}
+		return nil
+	}
+	sched := make(map[ParityUint64]*math.HexOrDecimal256)
+	if err := json.Unmarshal(input, &sched); err != nil {
+		return err
+	}
+	*s = sched
+	return nil
+}
+
+// set inserts a new value into the schedule.
+func (s ParitySchedule) set(block *big.Int, value *big.Int) {
+	s[ParityUint64(block.Uint64())] = (*math.HexOrDecimal256)(new(big.Int).Set(value))
+}
+
+// equal reports whether two schedules contain the same values.
+func (s ParitySchedule) equal(other ParitySchedule) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for block, value := range s {
+		if v, ok := other[block]; !ok || (*big.Int)(v).Cmp((*big.Int)(value)) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// blocks returns the blocks of the schedule in ascending order.
+func (s ParitySchedule) blocks() []ParityUint64 {
+	blocks := make([]ParityUint64, 0, len(s))
+	for block := range s {
+		blocks = append(blocks, block)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+	return blocks
+}
+
+// NewParityChainSpec converts a go-ethereum genesis block into a Parity specific
+// chain specification format. The network ID is announced to peers and differs
+// from the chain ID on some networks (e.g. Ethereum Classic); if zero, the chain
+// ID is used instead.
+//
+// Transitions that Parity has no notion of are lost in the conversion: the DAO
+// fork block of chains opposing the fork and the EIP-150 hash. Chains using
+// bespoke block reward rules (Ethereum Social, Ethersocial, Musicoin) cannot be
+// converted at all.
+func NewParityChainSpec(network string, networkID uint64, genesis *core.Genesis, bootnodes []string) (*ParityChainSpec, error) {
+	config := genesis.Config
+	if config == nil {
+		config = params.AllEthashProtocolChanges
+	}
+	if (config.SocialBlock != nil || config.EthersocialBlock != nil || config.MCIP0Block != nil) && config.BlockRewardAt(common.Big0) == nil {
+		return nil, errUnsupportedRewards
+	}
+	if config.EWASMBlock != nil {
+		return nil, errors.New("unsupported EWASM transition")
+	}
+	spec := &ParityChainSpec{
+		Name:  network,
+		Nodes: bootnodes,
+	}
+	// Convert the homestead transition, which Parity handles in one go within
+	// the ethash engine and applies from genesis otherwise
+	homestead := firstFork(config.HomesteadBlock, config.EIP2FBlock)
+	if !configNumEqual(homestead, firstFork(config.HomesteadBlock, config.EIP7FBlock)) {
+		return nil, errors.New("unsupported split of EIP-2 and EIP-7")
+	}
+	// Convert the consensus engine specific fields
+	switch {
+	case config.Clique != nil:
+		spec.Engine.Clique = new(ParityClique)
+		spec.Engine.Clique.Params.Period = config.Clique.Period
+		spec.Engine.Clique.Params.Epoch = config.Clique.Epoch
+
+	default:
+		spec.Engine.Ethash = new(ParityEthash)
+		engine := &spec.Engine.Ethash.Params
+
+		engine.MinimumDifficulty = (*math.HexOrDecimal256)(params.MinimumDifficulty)
+		engine.DifficultyBoundDivisor = (*math.HexOrDecimal256)(params.DifficultyBoundDivisor)
+		engine.DurationLimit = (*math.HexOrDecimal256)(params.DurationLimit)
+		engine.HomesteadTransition = hexOrDecimal(homestead)
+		engine.EIP100bTransition = hexOrDecimal(firstFork(config.ByzantiumBlock, config.ConstantinopleBlock, config.EIP100FBlock))
+		engine.BombDefuseTransition = hexOrDecimal(config.DisposalBlock)
+		if config.DifficultyBombDisabled {
+			engine.BombDefuseTransition = hexOrDecimal(common.Big0)
+		}
+
+		if config.DAOForkSupport && config.DAOForkBlock != nil {
+			engine.DaoHardforkTransition = hexOrDecimal(config.DAOForkBlock)
+			engine.DaoHardforkBeneficiary = &params.DAORefundContract
+			engine.DaoHardforkAccounts = params.DAODrainList()
+		}
+		if config.ECIP1010PauseBlock != nil {
+			if config.ECIP1010Length == nil {
+				return nil, errors.New("ECIP-1010 pause without length")
+			}
+			engine.ECIP1010PauseTransition = hexOrDecimal(config.ECIP1010PauseBlock)
+			engine.ECIP1010ContinueTransition = hexOrDecimal(new(big.Int).Add(config.ECIP1010PauseBlock, config.ECIP1010Length))
+		}
+		engine.ECIP1099Transition = hexOrDecimal(config.ECIP1099Block)
+		// Convert the difficulty bomb delays and the block rewards associated with them
+		eip649 := firstFork(config.ByzantiumBlock, config.EIP649FBlock)
+		eip1234 := firstFork(config.ConstantinopleBlock, config.EIP1234FBlock)
+		if eip649 != nil && eip1234 != nil && eip649.Cmp(eip1234) > 0 {
+			return nil, errUnsupportedBombDelays
+		}
+		delays, err := parityBombDelays(config, eip649, eip1234)
+		if err != nil {
+			return nil, err
+		}
+		engine.DifficultyBombDelays = delays
+
+		rewards, err := parityBlockRewards(config, eip649, eip1234)
+		if err != nil {
+			return nil, err
+		}
+		engine.BlockReward = rewards
+		if config.HasECIP1017() && config.BlockRewardAt(common.Big0) == nil {
+			engine.ECIP1017EraRounds = hexOrDecimal(config.ECIP1017EraRounds)
+		}
+	}
+	// Convert the chain wide parameters and the EVM transitions
+	spec.Params.AccountStartNonce = hexOrDecimal(common.Big0)
+	spec.Params.MaximumExtraDataSize = hexOrDecimal(new(big.Int).SetUint64(params.MaximumExtraDataSize))
+	spec.Params.MinGasLimit = hexOrDecimal(new(big.Int).SetUint64(params.MinGasLimit))
+	spec.Params.GasLimitBoundDivisor = hexOrDecimal(new(big.Int).SetUint64(params.GasLimitBoundDivisor))
+	if config.ChainID != nil {
+		spec.Params.NetworkID = hexOrDecimal(config.ChainID)
+		spec.Params.ChainID = hexOrDecimal(config.ChainID)
+	}
+	if networkID != 0 {
+		spec.Params.NetworkID = hexOrDecimal(new(big.Int).SetUint64(networkID))
+	}
+	spec.Params.MaxCodeSize = hexOrDecimal(big.NewInt(params.MaxCodeSize))
+	spec.Params.MaxCodeSizeTransition = hexOrDecimal(firstFork(config.EIP158Block, config.EIP170FBlock))
+
+	spec.Params.EIP150Transition = hexOrDecimal(config.EIP150Block)
+	spec.Params.EIP155Transition = hexOrDecimal(config.EIP155Block)
+	spec.Params.EIP160Transition = hexOrDecimal(firstFork(config.EIP158Block, config.EIP160FBlock))
+	spec.Params.EIP161abcTransition = hexOrDecimal(firstFork(config.EIP158Block, config.EIP161FBlock))
+	spec.Params.EIP161dTransition = spec.Params.EIP161abcTransition
+
+	spec.Params.EIP140Transition = hexOrDecimal(firstFork(config.ByzantiumBlock, config.EIP140FBlock))
+	spec.Params.EIP211Transition = hexOrDecimal(firstFork(config.ByzantiumBlock, config.EIP211FBlock))
+	spec.Params.EIP214Transition = hexOrDecimal(firstFork(config.ByzantiumBlock, config.EIP214FBlock))
+	spec.Params.EIP658Transition = hexOrDecimal(firstFork(config.ByzantiumBlock, config.EIP658FBlock))
+
+	spec.Params.EIP145Transition = hexOrDecimal(firstFork(config.ConstantinopleBlock, config.EIP145FBlock))
+	spec.Params.EIP1014Transition = hexOrDecimal(firstFork(config.ConstantinopleBlock, config.EIP1014FBlock))
+	spec.Params.EIP1052Transition = hexOrDecimal(firstFork(config.ConstantinopleBlock, config.EIP1052FBlock))
+
+	petersburg := config.PetersburgBlock
+	if petersburg == nil {
+		petersburg = config.ConstantinopleBlock
+	}
+	eip1283 := firstFork(config.ConstantinopleBlock, config.EIP1283FBlock)
+	eip2200 := firstFork(config.IstanbulBlock, config.EIP2200FBlock)
+
+	spec.Params.EIP1283Transition = hexOrDecimal(eip1283)
+	spec.Params.EIP1283DisableTransition = hexOrDecimal(petersburg)
+	if eip2200 != nil {
+		// Parity implements EIP-2200 as net gas metering (re)enabled along
+		// with the EIP-1706 reentrancy sentry.
+		if eip1283 == nil {
+			spec.Params.EIP1283Transition = hexOrDecimal(eip2200)
+		}
+		spec.Params.EIP1283ReenableTransition = hexOrDecimal(eip2200)
+		spec.Params.EIP1706Transition = hexOrDecimal(eip2200)
+	}
+	spec.Params.EIP1344Transition = hexOrDecimal(firstFork(config.IstanbulBlock, config.EIP1344FBlock))
+	spec.Params.EIP1884Transition = hexOrDecimal(firstFork(config.IstanbulBlock, config.EIP1884FBlock))
+	spec.Params.EIP2028Transition = hexOrDecimal(firstFork(config.IstanbulBlock, config.EIP2028FBlock))
+
+	// Convert the genesis header and state
+	spec.Genesis.Seal.Ethereum.Nonce = make(hexutil.Bytes, 8)
+	binary.BigEndian.PutUint64(spec.Genesis.Seal.Ethereum.Nonce, genesis.Nonce)
+	spec.Genesis.Seal.Ethereum.MixHash = genesis.Mixhash[:]
+
+	spec.Genesis.Difficulty = (*math.HexOrDecimal256)(genesis.Difficulty)
+	spec.Genesis.Author = genesis.Coinbase
+	spec.Genesis.Timestamp = (ParityUint64)(genesis.Timestamp)
+	spec.Genesis.ParentHash = genesis.ParentHash
+	spec.Genesis.ExtraData = genesis.ExtraData
+	spec.Genesis.GasLimit = (ParityUint64)(genesis.GasLimit)
+
+	spec.Accounts = make(map[common.UnprefixedAddress]*ParityAccount)
+	for address, account := range genesis.Alloc {
+		balance := account.Balance
+		if balance == nil {
+			balance = new(big.Int)
+		}
+		spec.Accounts[common.UnprefixedAddress(address)] = &ParityAccount{
+			Balance: (*math.HexOrDecimal256)(balance),
+			Nonce:   ParityUint64(account.Nonce),
+			Code:    account.Code,
+			Storage: account.Storage,
+		}
+	}
+	// Add the precompiled contracts with their activation blocks
+	spec.setPrecompile(1, &ParityBuiltin{Name: "ecrecover", Pricing: &ParityPricing{Linear: &ParityLinearPricing{Base: 3000}}})
+	spec.setPrecompile(2, &ParityBuiltin{Name: "sha256", Pricing: &ParityPricing{Linear: &ParityLinearPricing{Base: 60, Word: 12}}})
+	spec.setPrecompile(3, &ParityBuiltin{Name: "ripemd160", Pricing: &ParityPricing{Linear: &ParityLinearPricing{Base: 600, Word: 120}}})
+	spec.setPrecompile(4, &ParityBuiltin{Name: "identity", Pricing: &ParityPricing{Linear: &ParityLinearPricing{Base: 15, Word: 3}}})
+
+	eip1108 := hexOrDecimal(firstFork(config.IstanbulBlock, config.EIP1108FBlock))
+	if num := firstFork(config.ByzantiumBlock, config.EIP198FBlock); num != nil {
+		spec.setPrecompile(5, &ParityBuiltin{
+			Name: "modexp", ActivateAt: hexOrDecimal(num), Pricing: &ParityPricing{ModExp: &ParityModExpPricing{Divisor: 20}},
+		})
+	}
+	if num := firstFork(config.ByzantiumBlock, config.EIP213FBlock); num != nil {
+		spec.setPrecompile(6, &ParityBuiltin{
+			Name: "alt_bn128_add", ActivateAt: hexOrDecimal(num), EIP1108Transition: eip1108,
+			Pricing: &ParityPricing{AltBnConstOperation: &ParityAltBnConstOpPricing{Price: 500, EIP1108TransitionPrice: 150}},
+		})
+		spec.setPrecompile(7, &ParityBuiltin{
+			Name: "alt_bn128_mul", ActivateAt: hexOrDecimal(num), EIP1108Transition: eip1108,
+			Pricing: &ParityPricing{AltBnConstOperation: &ParityAltBnConstOpPricing{Price: 40000, EIP1108TransitionPrice: 6000}},
+		})
+	}
+	if num := firstFork(config.ByzantiumBlock, config.EIP212FBlock); num != nil {
+		spec.setPrecompile(8, &ParityBuiltin{
+			Name: "alt_bn128_pairing", ActivateAt: hexOrDecimal(num), EIP1108Transition: eip1108,
+			Pricing: &ParityPricing{AltBnPairing: &ParityAltBnPairingPricing{Base: 100000, Pair: 80000, EIP1108TransitionBase: 45000, EIP1108TransitionPair: 34000}},
+		})
+	}
+	if num := firstFork(config.IstanbulBlock, config.EIP152FBlock); num != nil {
+		spec.setPrecompile(9, &ParityBuiltin{
+			Name: "blake2_f", ActivateAt: hexOrDecimal(num), Pricing: &ParityPricing{Blake2F: &ParityBlake2FPricing{GasPerRound: 1}},
+		})
+	}
+	return spec, nil
+}
+
+// setPrecompile marks the given address as a builtin contract, retaining any
+// genesis allocation it might have.
+func (spec *ParityChainSpec) setPrecompile(address byte, data *ParityBuiltin) {
+	a := common.UnprefixedAddress(common.BytesToAddress([]byte{address}))
+	if _, exist := spec.Accounts[a]; !exist {
+		spec.Accounts[a] = new(ParityAccount)
+	}
+	spec.Accounts[a].Builtin = data
+}
+
+// NetworkID returns the peer-to-peer network identifier of the chain spec,
+// falling back to the chain ID if none is specified.
+func (spec *ParityChainSpec) NetworkID() uint64 {
+	if spec.Params.NetworkID != nil {
+		return uint64(*spec.Params.NetworkID)
+	}
+	if spec.Params.ChainID != nil {
+		return uint64(*spec.Params.ChainID)
+	}
+	return 0
+}
+
+// ToGenesis converts a Parity chain specification into a go-ethereum genesis
+// block. Transitions are imported individually per EIP rather than bundled by
+// hard fork name, which go-ethereum considers equivalent.
+func (spec *ParityChainSpec) ToGenesis() (*core.Genesis, error) {
+	config := new(params.ChainConfig)
+
+	// Ensure the chain wide parameters match the constants of go-ethereum
+	if err := checkParam("accountStartNonce", spec.Params.AccountStartNonce, 0); err != nil {
+		return nil, err
+	}
+	if err := checkParam("maximumExtraDataSize", spec.Params.MaximumExtraDataSize, params.MaximumExtraDataSize); err != nil {
+		return nil, err
+	}
+	if err := checkParam("minGasLimit", spec.Params.MinGasLimit, params.MinGasLimit); err != nil {
+		return nil, err
+	}
+	if err := checkParam("gasLimitBoundDivisor", spec.Params.GasLimitBoundDivisor, params.GasLimitBoundDivisor); err != nil {
+		return nil, err
+	}
+	if spec.Params.MaxCodeSizeTransition != nil {
+		if err := checkParam("maxCodeSize", spec.Params.MaxCodeSize, params.MaxCodeSize); err != nil {
+			return nil, err
+		}
+	}
+	if spec.Params.ChainID != nil {
+		config.ChainID = new(big.Int).SetUint64(uint64(*spec.Params.ChainID))
+	} else if spec.Params.NetworkID != nil {
+		config.ChainID = new(big.Int).SetUint64(uint64(*spec.Params.NetworkID))
+	}
+	// Convert the consensus engine specific fields
+	switch {
+	case spec.Engine.Ethash != nil && spec.Engine.Clique != nil:
+		return nil, errors.New("multiple consensus engines specified")
+
+	case spec.Engine.Clique != nil:
+		config.Clique = &params.CliqueConfig{
+			Period: spec.Engine.Clique.Params.Period,
+			Epoch:  spec.Engine.Clique.Params.Epoch,
+		}
+		config.HomesteadBlock = new(big.Int)
+
+	case spec.Engine.Ethash != nil:
+		config.Ethash = new(params.EthashConfig)
+		engine := &spec.Engine.Ethash.Params
+
+		if err := checkBigParam("minimumDifficulty", engine.MinimumDifficulty, params.MinimumDifficulty); err != nil {
+			return nil, err
+		}
+		if err := checkBigParam("difficultyBoundDivisor", engine.DifficultyBoundDivisor, params.DifficultyBoundDivisor); err != nil {
+			return nil, err
+		}
+		if err := checkBigParam("durationLimit", engine.DurationLimit, params.DurationLimit); err != nil {
+			return nil, err
+		}
+		config.HomesteadBlock = bigBlock(engine.HomesteadTransition)
+		config.EIP100FBlock = bigBlock(engine.EIP100bTransition)
+		config.DisposalBlock = bigBlock(engine.BombDefuseTransition)
+		config.ECIP1017EraRounds = bigBlock(engine.ECIP1017EraRounds)
+
+		if engine.DaoHardforkTransition != nil {
+			if engine.DaoHardforkBeneficiary == nil || *engine.DaoHardforkBeneficiary != params.DAORefundContract {
+				return nil, errors.New("unsupported DAO hard-fork beneficiary")
+			}
+			if !addressesEqual(engine.DaoHardforkAccounts, params.DAODrainList()) {
+				return nil, errors.New("unsupported DAO hard-fork accounts")
+			}
+			config.DAOForkBlock = bigBlock(engine.DaoHardforkTransition)
+			config.DAOForkSupport = true
+		}
+		if engine.ECIP1010PauseTransition != nil {
+			if engine.ECIP1010ContinueTransition == nil || *engine.ECIP1010ContinueTransition < *engine.ECIP1010PauseTransition {
+				return nil, errors.New("invalid ECIP-1010 continue transition")
+			}
+			config.ECIP1010PauseBlock = bigBlock(engine.ECIP1010PauseTransition)
+			config.ECIP1010Length = new(big.Int).SetUint64(uint64(*engine.ECIP1010ContinueTransition - *engine.ECIP1010PauseTransition))
+		}
+		config.ECIP1099Block = bigBlock(engine.ECIP1099Transition)
+		// Map the cumulative bomb delays onto the EIPs that introduced them if
+		// possible, falling back to a generic delay schedule otherwise
+		if eip649, eip1234, ok := legacyBombDelays(engine.DifficultyBombDelays); ok {
+			config.EIP649FBlock, config.EIP1234FBlock = eip649, eip1234
+		} else {
+			for _, block := range engine.DifficultyBombDelays.blocks() {
+				config.DifficultyBombDelays = append(config.DifficultyBombDelays, params.BombDelay{
+					Block: new(big.Int).SetUint64(uint64(block)),
+					Delay: new(big.Int).Set((*big.Int)(engine.DifficultyBombDelays[block])),
+				})
+			}
+		}
+		// Use a generic block reward schedule unless the rewards match the ones
+		// implied by the delays
+		want := legacyBlockRewards(config.EIP649FBlock, config.EIP1234FBlock)
+		if config.HasECIP1017() {
+			want = legacyBlockRewards(nil, nil)
+		}
+		if !engine.BlockReward.equal(want) {
+			if config.HasECIP1017() {
+				return nil, errUnsupportedRewards
+			}
+			for _, block := range engine.BlockReward.blocks() {
+				config.BlockRewardSchedule = append(config.BlockRewardSchedule, params.BlockReward{
+					Block:  new(big.Int).SetUint64(uint64(block)),
+					Reward: new(big.Int).Set((*big.Int)(engine.BlockReward[block])),
+				})
+			}
+		}
+
+	default:
+		return nil, errUnsupportedEngine
+	}
+	// Convert the EVM transitions
+	config.EIP150Block = bigBlock(spec.Params.EIP150Transition)
+	config.EIP155Block = bigBlock(spec.Params.EIP155Transition)
+	config.EIP160FBlock = bigBlock(spec.Params.EIP160Transition)
+	if !blockEqual(spec.Params.EIP161abcTransition, spec.Params.EIP161dTransition) {
+		return nil, errors.New("unsupported split of EIP-161abc and EIP-161d")
+	}
+	config.EIP161FBlock = bigBlock(spec.Params.EIP161abcTransition)
+	config.EIP170FBlock = bigBlock(spec.Params.MaxCodeSizeTransition)
+
+	config.EIP140FBlock = bigBlock(spec.Params.EIP140Transition)
+	config.EIP211FBlock = bigBlock(spec.Params.EIP211Transition)
+	config.EIP214FBlock = bigBlock(spec.Params.EIP214Transition)
+	config.EIP658FBlock = bigBlock(spec.Params.EIP658Transition)
+
+	config.EIP145FBlock = bigBlock(spec.Params.EIP145Transition)
+	config.EIP1014FBlock = bigBlock(spec.Params.EIP1014Transition)
+	config.EIP1052FBlock = bigBlock(spec.Params.EIP1052Transition)
+
+	config.PetersburgBlock = bigBlock(spec.Params.EIP1283DisableTransition)
+	if eip1706 := spec.Params.EIP1706Transition; eip1706 != nil {
+		if disable := spec.Params.EIP1283DisableTransition; disable != nil && *disable <= *eip1706 {
+			if !blockEqual(spec.Params.EIP1283ReenableTransition, eip1706) {
+				return nil, errors.New("unsupported split of EIP-1283 re-enablement and EIP-1706")
+			}
+		}
+		config.EIP2200FBlock = bigBlock(eip1706)
+	} else if spec.Params.EIP1283ReenableTransition != nil {
+		return nil, errors.New("unsupported EIP-1283 re-enablement without EIP-1706")
+	}
+	// Net gas metering only introduced along with EIP-1706 is EIP-2200 proper
+	if eip1283 := spec.Params.EIP1283Transition; eip1283 != nil {
+		if config.EIP2200FBlock == nil || *eip1283 < ParityUint64(config.EIP2200FBlock.Uint64()) {
+			config.EIP1283FBlock = bigBlock(eip1283)
+		}
+	}
+	config.EIP1344FBlock = bigBlock(spec.Params.EIP1344Transition)
+	config.EIP1884FBlock = bigBlock(spec.Params.EIP1884Transition)
+	config.EIP2028FBlock = bigBlock(spec.Params.EIP2028Transition)
+
+	// Convert the genesis header and state, collecting the builtin activations
+	if len(spec.Genesis.Seal.Ethereum.Nonce) > 8 {
+		return nil, errors.New("invalid genesis seal nonce")
+	}
+	genesis := &core.Genesis{
+		Config:     config,
+		Nonce:      new(big.Int).SetBytes(spec.Genesis.Seal.Ethereum.Nonce).Uint64(),
+		Timestamp:  uint64(spec.Genesis.Timestamp),
+		ExtraData:  spec.Genesis.ExtraData,
+		GasLimit:   uint64(spec.Genesis.GasLimit),
+		Difficulty: (*big.Int)(spec.Genesis.Difficulty),
+		Mixhash:    common.BytesToHash(spec.Genesis.Seal.Ethereum.MixHash),
+		Coinbase:   spec.Genesis.Author,
+		Alloc:      make(core.GenesisAlloc),
+		ParentHash: spec.Genesis.ParentHash,
+	}
+	if genesis.Difficulty == nil {
+		return nil, errors.New("genesis difficulty missing")
+	}
+	for address, account := range spec.Accounts {
+		if account.Builtin != nil {
+			if err := setBuiltin(config, account.Builtin); err != nil {
+				return nil, fmt.Errorf("builtin %x: %v", address, err)
+			}
+		}
+		balance := (*big.Int)(account.Balance)
+		if account.Builtin != nil && (balance == nil || balance.Sign() == 0) && account.Nonce == 0 && len(account.Code) == 0 && len(account.Storage) == 0 {
+			continue
+		}
+		if balance == nil {
+			balance = new(big.Int)
+		}
+		genesis.Alloc[common.Address(address)] = core.GenesisAccount{
+			Code:    account.Code,
+			Storage: account.Storage,
+			Balance: balance,
+			Nonce:   uint64(account.Nonce),
+		}
+	}
+	return genesis, nil
+}
+
+// parityBombDelays converts the difficulty bomb delays of a chain config, be they
+// scheduled or implied by EIP-649 and EIP-1234, into Parity's cumulative format.
+func parityBombDelays(config *params.ChainConfig, eip649, eip1234 *big.Int) (ParitySchedule, error) {
+	if len(config.DifficultyBombDelays) > 0 && config.ECIP1010PauseBlock != nil {
+		return nil, errUnsupportedBombDelays
+	}
+	// Gather all the blocks at which the total delay may change
+	var blocks []*big.Int
+	for _, block := range []*big.Int{eip649, eip1234} {
+		if block != nil {
+			blocks = append(blocks, block)
+		}
+	}
+	for _, delay := range config.DifficultyBombDelays {
+		blocks = append(blocks, delay.Block)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Cmp(blocks[j]) < 0 })
+
+	// Emit the increments of the total delay, which may only grow
+	var (
+		delays = make(ParitySchedule)
+		total  = new(big.Int)
+	)
+	for _, block := range blocks {
+		delay := config.BombDelay(block)
+		if delay == nil {
+			switch {
+			case eip1234 != nil && eip1234.Cmp(block) <= 0:
+				delay = big.NewInt(eip1234BombDelay)
+			case eip649 != nil && eip649.Cmp(block) <= 0:
+				delay = big.NewInt(eip649BombDelay)
+			default:
+				delay = new(big.Int)
+			}
+		}
+		switch diff := new(big.Int).Sub(delay, total); diff.Sign() {
+		case -1:
+			return nil, errUnsupportedBombDelays
+		case 1:
+			delays.set(block, diff)
+			total = delay
+		}
+	}
+	return delays, nil
+}
+
+// parityBlockRewards converts the block rewards of a chain config, be they
+// scheduled or implied by EIP-649 and EIP-1234, into Parity's format. Only the
+// standard uncle reward rule is supported, without eras or reward splits.
+func parityBlockRewards(config *params.ChainConfig, eip649, eip1234 *big.Int) (ParitySchedule, error) {
+	// Start with the legacy rewards up to the first scheduled one
+	rewards := legacyBlockRewards(eip649, eip1234)
+	if config.HasECIP1017() {
+		rewards = legacyBlockRewards(nil, nil)
+	}
+	if len(config.BlockRewardSchedule) == 0 {
+		return rewards, nil
+	}
+	var first *big.Int
+	for _, reward := range config.BlockRewardSchedule {
+		first = firstFork(first, reward.Block)
+	}
+	if first.Sign() > 0 && config.HasECIP1017() {
+		return nil, errUnsupportedRewards
+	}
+	for block := range rewards {
+		if uint64(block) >= first.Uint64() {
+			delete(rewards, block)
+		}
+	}
+	// Append the scheduled rewards, as long as Parity can express them
+	for _, reward := range config.BlockRewardSchedule {
+		if (reward.UncleRule != "" && reward.UncleRule != params.UncleRewardStandard) || reward.EraLength != nil || len(reward.Splits) > 0 {
+			return nil, errUnsupportedRewards
+		}
+		if reward.UncleBase != nil && reward.UncleBase.Cmp(reward.Reward) != 0 {
+			return nil, errUnsupportedRewards
+		}
+		rewards.set(reward.Block, reward.Reward)
+	}
+	return rewards, nil
+}
+
+// legacyBombDelays checks whether a Parity delay schedule consists of the EIP-649
+// and EIP-1234 delays only, returning their activation blocks if so.
+func legacyBombDelays(delays ParitySchedule) (eip649, eip1234 *big.Int, ok bool) {
+	total := new(big.Int)
+	for _, block := range delays.blocks() {
+		total.Add(total, (*big.Int)(delays[block]))
+		switch {
+		case total.Cmp(big.NewInt(eip649BombDelay)) == 0 && eip649 == nil && eip1234 == nil:
+			eip649 = new(big.Int).SetUint64(uint64(block))
+		case total.Cmp(big.NewInt(eip1234BombDelay)) == 0 && eip1234 == nil:
+			eip1234 = new(big.Int).SetUint64(uint64(block))
+		default:
+			return nil, nil, false
+		}
+	}
+	return eip649, eip1234, true
+}
+
+// legacyBlockRewards returns the block reward schedule of the Ethereum mainnet,
+// with the reductions of EIP-649 and EIP-1234 at the given (optional) blocks.
+func legacyBlockRewards(eip649, eip1234 *big.Int) ParitySchedule {
+	rewards := make(ParitySchedule)
+	rewards.set(common.Big0, ethash.FrontierBlockReward)
+	if eip649 != nil {
+		rewards.set(eip649, ethash.EIP649FBlockReward)
+	}
+	if eip1234 != nil {
+		rewards.set(eip1234, ethash.EIP1234FBlockReward)
+	}
+	return rewards
+}
+
+// setBuiltin maps the activation block of a Parity builtin contract onto the
+// EIP that introduced it.
+func setBuiltin(config *params.ChainConfig, builtin *ParityBuiltin) error {
+	var target **big.Int
+	switch builtin.Name {
+	case "ecrecover", "sha256", "ripemd160", "identity":
+		if builtin.ActivateAt != nil && *builtin.ActivateAt != 0 {
+			return fmt.Errorf("unsupported activation of %s at %d", builtin.Name, *builtin.ActivateAt)
+		}
+		return nil
+	case "modexp":
+		target = &config.EIP198FBlock
+	case "alt_bn128_add", "alt_bn128_mul":
+		target = &config.EIP213FBlock
+	case "alt_bn128_pairing":
+		target = &config.EIP212FBlock
+	case "blake2_f":
+		target = &config.EIP152FBlock
+	default:
+		return fmt.Errorf("unsupported builtin %q", builtin.Name)
+	}
+	activation := bigBlock(builtin.ActivateAt)
+	if activation == nil {
+		activation = new(big.Int)
+	}
+	if *target != nil && (*target).Cmp(activation) != 0 {
+		return fmt.Errorf("conflicting activation of %s at %v and %v", builtin.Name, *target, activation)
+	}
+	*target = activation
+
+	if builtin.EIP1108Transition != nil {
+		eip1108 := bigBlock(builtin.EIP1108Transition)
+		if config.EIP1108FBlock != nil && config.EIP1108FBlock.Cmp(eip1108) != 0 {
+			return fmt.Errorf("conflicting EIP-1108 transitions at %v and %v", config.EIP1108FBlock, eip1108)
+		}
+		config.EIP1108FBlock = eip1108
+	}
+	return nil
+}
+
+// checkParam ensures that an optional chain spec parameter, if set, has the value
+// go-ethereum is hard coded to.
+func checkParam(name string, have *ParityUint64, want uint64) error {
+	if have != nil && uint64(*have) != want {
+		return fmt.Errorf("unsupported %s %d, want %d", name, *have, want)
+	}
+	return nil
+}
+
+// checkBigParam is the big integer counterpart of checkParam.
+func checkBigParam(name string, have *math.HexOrDecimal256, want *big.Int) error {
+	if have != nil && (*big.Int)(have).Cmp(want) != 0 {
+		return fmt.Errorf("unsupported %s %v, want %v", name, (*big.Int)(have), want)
+	}
+	return nil
+}
+
+// firstFork returns the earliest of the given (optional) fork blocks.
+func firstFork(blocks ...*big.Int) *big.Int {
+	var first *big.Int
+	for _, block := range blocks {
+		if block != nil && (first == nil || block.Cmp(first) < 0) {
+			first = block
+		}
+	}
+	return first
+}
+
+// hexOrDecimal converts an optional fork block to its chain spec representation.
+func hexOrDecimal(block *big.Int) *ParityUint64 {
+	if block == nil {
+		return nil
+	}
+	num := ParityUint64(block.Uint64())
+	return &num
+}
+
+// bigBlock converts an optional chain spec transition to a fork block.
+func bigBlock(block *ParityUint64) *big.Int {
+	if block == nil {
+		return nil
+	}
+	return new(big.Int).SetUint64(uint64(*block))
+}
+
+// blockEqual reports whether two optional chain spec transitions are equal.
+func blockEqual(a, b *ParityUint64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// configNumEqual reports whether two optional fork blocks are equal.
+func configNumEqual(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
+
+// addressesEqual reports whether two address lists contain the same elements,
+// disregarding their order.
+func addressesEqual(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sorted := func(list []common.Address) []common.Address {
+		list = append([]common.Address{}, list...)
+		sort.Slice(list, func(i, j int) bool { return bytes.Compare(list[i][:], list[j][:]) < 0 })
+		return list
+	}
+	a, b = sorted(a), sorted(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
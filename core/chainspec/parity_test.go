@@ -0,0 +1,260 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package chainspec
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// forkBlocks returns every fork block of a chain config, along with the blocks
+// right before them, so that rules can be compared across all transitions.
+func forkBlocks(config *params.ChainConfig) []*big.Int {
+	blocks := []*big.Int{new(big.Int)}
+
+	kind := reflect.TypeOf(params.ChainConfig{})
+	conf := reflect.ValueOf(config).Elem()
+	for i := 0; i < kind.NumField(); i++ {
+		if !strings.HasSuffix(kind.Field(i).Name, "Block") {
+			continue
+		}
+		if block, ok := conf.Field(i).Interface().(*big.Int); ok && block != nil {
+			blocks = append(blocks, block, new(big.Int).Sub(block, big.NewInt(1)))
+		}
+	}
+	return blocks
+}
+
+// comparableRules returns the rules of a chain config at the given block, with
+// the flags that are not expressible in the Parity format cleared.
+func comparableRules(config *params.ChainConfig, num *big.Int) params.Rules {
+	rules := config.Rules(num)
+
+	// Hard fork names are only bundles of EIPs, which is what gets converted
+	rules.IsIstanbul = false
+
+	// Clique has no difficulty adjustment, bomb or block reward, and Parity runs
+	// it with the Homestead rules from genesis
+	if config.Clique != nil {
+		rules.IsEIP100F, rules.IsEIP649F, rules.IsEIP1234F = false, false, false
+		rules.IsEIP2F, rules.IsEIP7F = false, false
+		rules.IsBombDisposal, rules.IsECIP1010 = false, false
+	}
+	return rules
+}
+
+// Tests that the bundled networks survive a conversion to the Parity chain spec
+// format (including JSON encoding) and back, retaining their genesis block and
+// the rules in effect at every transition.
+func TestParityRoundTrip(t *testing.T) {
+	for _, name := range []string{"mainnet", "testnet", "rinkeby", "goerli", "classic", "mordor", "kotti", "mix"} {
+		network, err := core.LookupNetwork(name)
+		if err != nil {
+			t.Fatalf("%s: failed to look up network: %v", name, err)
+		}
+		spec, err := NewParityChainSpec(network.Name, network.NetworkID, network.Genesis, network.Bootnodes)
+		if err != nil {
+			t.Errorf("%s: failed to create chain spec: %v", name, err)
+			continue
+		}
+		blob, err := json.Marshal(spec)
+		if err != nil {
+			t.Errorf("%s: failed to encode chain spec: %v", name, err)
+			continue
+		}
+		decoded := new(ParityChainSpec)
+		if err := json.Unmarshal(blob, decoded); err != nil {
+			t.Errorf("%s: failed to decode chain spec: %v", name, err)
+			continue
+		}
+		if id := decoded.NetworkID(); id != network.NetworkID {
+			t.Errorf("%s: network ID mismatch: have %d, want %d", name, id, network.NetworkID)
+		}
+		genesis, err := decoded.ToGenesis()
+		if err != nil {
+			t.Errorf("%s: failed to convert chain spec: %v", name, err)
+			continue
+		}
+		if have, want := genesis.ToBlock(nil).Hash(), network.Genesis.ToBlock(nil).Hash(); have != want {
+			t.Errorf("%s: genesis hash mismatch: have %x, want %x", name, have, want)
+		}
+		have, want := genesis.Config, network.Genesis.Config
+		for _, num := range append(forkBlocks(want), forkBlocks(have)...) {
+			if num.Sign() < 0 {
+				continue
+			}
+			if h, w := comparableRules(have, num), comparableRules(want, num); !reflect.DeepEqual(h, w) {
+				t.Errorf("%s: rules mismatch at block %v:\nhave %+v\nwant %+v", name, num, h, w)
+			}
+		}
+		if want.DAOForkSupport && want.DAOForkBlock != nil && (!have.DAOForkSupport || have.DAOForkBlock.Cmp(want.DAOForkBlock) != 0) {
+			t.Errorf("%s: DAO fork mismatch: have %v, want %v", name, have.DAOForkBlock, want.DAOForkBlock)
+		}
+		if want.Clique != nil {
+			if !reflect.DeepEqual(have.Clique, want.Clique) {
+				t.Errorf("%s: clique config mismatch: have %v, want %v", name, have.Clique, want.Clique)
+			}
+			continue
+		}
+		if !configNumEqual(have.ECIP1017EraRounds, want.ECIP1017EraRounds) {
+			t.Errorf("%s: ECIP-1017 era mismatch: have %v, want %v", name, have.ECIP1017EraRounds, want.ECIP1017EraRounds)
+		}
+		if !configNumEqual(have.ECIP1010PauseBlock, want.ECIP1010PauseBlock) || !configNumEqual(have.ECIP1010Length, want.ECIP1010Length) {
+			t.Errorf("%s: ECIP-1010 mismatch: have %v+%v, want %v+%v", name, have.ECIP1010PauseBlock, have.ECIP1010Length, want.ECIP1010PauseBlock, want.ECIP1010Length)
+		}
+		if !configNumEqual(have.ECIP1099Block, want.ECIP1099Block) {
+			t.Errorf("%s: ECIP-1099 mismatch: have %v, want %v", name, have.ECIP1099Block, want.ECIP1099Block)
+		}
+	}
+}
+
+// Tests that networks with block reward rules Parity cannot express are rejected.
+func TestParityUnsupported(t *testing.T) {
+	for _, name := range []string{"social", "ethersocial", "musicoin"} {
+		network, err := core.LookupNetwork(name)
+		if err != nil {
+			t.Fatalf("%s: failed to look up network: %v", name, err)
+		}
+		if _, err := NewParityChainSpec(network.Name, network.NetworkID, network.Genesis, nil); err != errUnsupportedRewards {
+			t.Errorf("%s: error mismatch: have %v, want %v", name, err, errUnsupportedRewards)
+		}
+	}
+}
+
+// Tests that hand written Parity chain specs, using decimal numbers and a single
+// block reward, are interpreted correctly.
+func TestParityImport(t *testing.T) {
+	blob := `{
+		"name": "test",
+		"engine": {"Ethash": {"params": {
+			"minimumDifficulty": "131072",
+			"blockReward": "0x4563918244f40000",
+			"homesteadTransition": 5,
+			"ecip1017EraRounds": "0x1e8480",
+			"bombDefuseTransition": "10"
+		}}},
+		"params": {"networkID": "0x2a", "eip150Transition": 7, "eip161abcTransition": 8, "eip161dTransition": 8},
+		"genesis": {
+			"seal": {"ethereum": {"nonce": "0x0000000000000042", "mixHash": "0x0000000000000000000000000000000000000000000000000000000000000000"}},
+			"difficulty": "0x20000",
+			"gasLimit": "0x1388"
+		},
+		"accounts": {
+			"0000000000000000000000000000000000000001": {"builtin": {"name": "ecrecover", "pricing": {"linear": {"base": 3000, "word": 0}}}},
+			"0000000000000000000000000000000000000005": {"builtin": {"name": "modexp", "activate_at": 9, "pricing": {"modexp": {"divisor": 20}}}},
+			"0000000000000000000000000000000000000010": {"balance": "100"}
+		}
+	}`
+	spec := new(ParityChainSpec)
+	if err := json.Unmarshal([]byte(blob), spec); err != nil {
+		t.Fatalf("failed to decode chain spec: %v", err)
+	}
+	genesis, err := spec.ToGenesis()
+	if err != nil {
+		t.Fatalf("failed to convert chain spec: %v", err)
+	}
+	config := genesis.Config
+	if config.ChainID.Uint64() != 42 || config.HomesteadBlock.Uint64() != 5 || config.EIP150Block.Uint64() != 7 ||
+		config.EIP161FBlock.Uint64() != 8 || config.EIP198FBlock.Uint64() != 9 || config.DisposalBlock.Uint64() != 10 ||
+		config.ECIP1017EraRounds.Uint64() != 2000000 || config.Ethash == nil {
+		t.Errorf("chain config mismatch: %v", config)
+	}
+	if genesis.Nonce != 0x42 || genesis.GasLimit != 5000 || genesis.Difficulty.Uint64() != 0x20000 {
+		t.Errorf("genesis header mismatch: nonce %x, gas limit %d, difficulty %v", genesis.Nonce, genesis.GasLimit, genesis.Difficulty)
+	}
+	if len(genesis.Alloc) != 1 {
+		t.Errorf("genesis alloc mismatch: have %d accounts, want 1", len(genesis.Alloc))
+	}
+}
+
+// Tests that generic difficulty bomb delay schedules are converted to and from
+// the Parity format, separately from the EIP-649 and EIP-1234 delays.
+func TestParityBombDelays(t *testing.T) {
+	genesis := &core.Genesis{
+		Config: &params.ChainConfig{
+			ChainID:        big.NewInt(1337),
+			HomesteadBlock: big.NewInt(0),
+			DifficultyBombDelays: params.BombDelaySchedule{
+				{Block: big.NewInt(100), Delay: big.NewInt(1000000)},
+				{Block: big.NewInt(200), Delay: big.NewInt(500000)},
+			},
+			Ethash: new(params.EthashConfig),
+		},
+		Difficulty: big.NewInt(131072),
+		GasLimit:   5000,
+	}
+	spec, err := NewParityChainSpec("test", 0, genesis, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain spec: %v", err)
+	}
+	if delay := spec.Engine.Ethash.Params.DifficultyBombDelays[200]; delay == nil || (*big.Int)(delay).Int64() != 500000 {
+		t.Errorf("cumulative delay mismatch: have %v, want 500000", (*big.Int)(delay))
+	}
+	imported, err := spec.ToGenesis()
+	if err != nil {
+		t.Fatalf("failed to convert chain spec: %v", err)
+	}
+	if !reflect.DeepEqual(imported.Config.DifficultyBombDelays, genesis.Config.DifficultyBombDelays) {
+		t.Errorf("delay schedule mismatch: have %v, want %v", imported.Config.DifficultyBombDelays, genesis.Config.DifficultyBombDelays)
+	}
+	if imported.Config.EIP649FBlock != nil || imported.Config.EIP1234FBlock != nil {
+		t.Errorf("generic delays imported as EIP-649/EIP-1234: %v, %v", imported.Config.EIP649FBlock, imported.Config.EIP1234FBlock)
+	}
+}
+
+// Tests that block reward schedules are converted to and from the Parity format,
+// as long as Parity can express them.
+func TestParityBlockRewards(t *testing.T) {
+	schedule := []params.BlockReward{
+		{Block: big.NewInt(0), Reward: big.NewInt(4e18)},
+		{Block: big.NewInt(100), Reward: big.NewInt(1e18)},
+	}
+	genesis := &core.Genesis{
+		Config: &params.ChainConfig{
+			ChainID:             big.NewInt(1337),
+			HomesteadBlock:      big.NewInt(0),
+			BlockRewardSchedule: schedule,
+			Ethash:              new(params.EthashConfig),
+		},
+		Difficulty: big.NewInt(131072),
+		GasLimit:   5000,
+	}
+	spec, err := NewParityChainSpec("test", 0, genesis, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain spec: %v", err)
+	}
+	imported, err := spec.ToGenesis()
+	if err != nil {
+		t.Fatalf("failed to convert chain spec: %v", err)
+	}
+	if !reflect.DeepEqual(imported.Config.BlockRewardSchedule, schedule) {
+		t.Errorf("block reward schedule mismatch: have %v, want %v", imported.Config.BlockRewardSchedule, schedule)
+	}
+	// Reward splits have no Parity equivalent
+	genesis.Config.BlockRewardSchedule = []params.BlockReward{
+		{Block: big.NewInt(0), Reward: big.NewInt(4e18), Splits: []params.RewardSplit{{Amount: big.NewInt(1)}}},
+	}
+	if _, err := NewParityChainSpec("test", 0, genesis, nil); err != errUnsupportedRewards {
+		t.Errorf("error mismatch: have %v, want %v", err, errUnsupportedRewards)
+	}
+}
@@ -59,6 +59,18 @@ func NewID(chain *core.BlockChain) ID {
 	)
 }
 
+// NewStaticID calculates the Ethereum fork ID from a chain config, genesis hash
+// and head, without requiring an existing chain.
+func NewStaticID(config *params.ChainConfig, genesis common.Hash, head uint64) ID {
+	return newID(config, genesis, head)
+}
+
+// Forks returns the block numbers of all the forks of a chain config that are
+// included in its fork IDs, in ascending order and without duplicates.
+func Forks(config *params.ChainConfig) []uint64 {
+	return gatherForks(config)
+}
+
 // newID is the internal version of NewID, which takes extracted values as its
 // arguments instead of a chain. The reason is to allow testing the IDs without
 // having to simulate an entire blockchain.
@@ -213,6 +225,17 @@ func gatherForks(config *params.ChainConfig) []uint64 {
 			forks = append(forks, rule.Uint64())
 		}
 	}
+	// Difficulty bomb delays and block reward changes are forks too, but scheduled in lists
+	for _, delay := range config.DifficultyBombDelays {
+		if delay.Block != nil {
+			forks = append(forks, delay.Block.Uint64())
+		}
+	}
+	for _, reward := range config.BlockRewardSchedule {
+		if reward.Block != nil {
+			forks = append(forks, reward.Block.Uint64())
+		}
+	}
 	// Sort the fork block numbers to permit chronologival XOR
 	for i := 0; i < len(forks); i++ {
 		for j := i + 1; j < len(forks); j++ {
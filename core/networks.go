@@ -0,0 +1,267 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	// errNetworkNoGenesis is returned if a network definition lacks a genesis
+	// specification (and consequently a chain configuration).
+	errNetworkNoGenesis = errors.New("network definition has no genesis")
+
+	// errNetworkNoConfig is returned if a network definition's genesis lacks a
+	// chain configuration.
+	errNetworkNoConfig = errors.New("network genesis has no chain config")
+)
+
+// Network is a self contained definition of an Ethereum network: everything a
+// node needs to know to join it, apart from user preferences. Networks can be
+// defined in Go and registered by name, or loaded from a JSON file.
+type Network struct {
+	Name      string   `json:"name"`              // Short identifier, also used as the data directory suffix
+	NetworkID uint64   `json:"networkId"`         // Peer-to-peer network identifier (0 = use the chain ID)
+	Genesis   *Genesis `json:"genesis"`           // Genesis block and chain configuration
+	DataDir   string   `json:"dataDir,omitempty"` // Subdirectory of the data directory to use (empty = use Name)
+
+	Bootnodes   []string `json:"bootnodes,omitempty"`   // Enode URLs of the discovery v4 bootstrap nodes
+	BootnodesV5 []string `json:"bootnodesV5,omitempty"` // Enode URLs of the discovery v5 bootstrap nodes
+
+	Checkpoint       *params.TrustedCheckpoint      `json:"checkpoint,omitempty"`       // Overrides the chain config's checkpoint
+	CheckpointOracle *params.CheckpointOracleConfig `json:"checkpointOracle,omitempty"` // Overrides the chain config's oracle
+}
+
+// validate checks that the network definition is complete enough to be used
+// and fills in any defaults.
+func (n *Network) validate() error {
+	if n.Genesis == nil {
+		return errNetworkNoGenesis
+	}
+	if n.Genesis.Config == nil {
+		return errNetworkNoConfig
+	}
+	if n.NetworkID == 0 {
+		if n.Genesis.Config.ChainID == nil || !n.Genesis.Config.ChainID.IsUint64() {
+			return fmt.Errorf("network %q has neither a network ID nor a chain ID", n.Name)
+		}
+		n.NetworkID = n.Genesis.Config.ChainID.Uint64()
+	}
+	return nil
+}
+
+// DataDirName returns the name of the subdirectory within the data directory
+// that the network's databases and keys should be stored in. An empty string
+// means the root of the data directory.
+func (n *Network) DataDirName() string {
+	if n.DataDir == "." {
+		return ""
+	}
+	if n.DataDir != "" {
+		return n.DataDir
+	}
+	return n.Name
+}
+
+var (
+	networksLock sync.RWMutex
+	networks     = make(map[string]func() *Network)
+)
+
+// RegisterNetwork adds a named network definition to the registry of networks
+// that can be selected by name. The constructor is invoked on every lookup, so
+// callers are free to modify the returned definition. Registering a name twice
+// replaces the previous definition.
+func RegisterNetwork(name string, fn func() *Network) {
+	networksLock.Lock()
+	defer networksLock.Unlock()
+
+	networks[strings.ToLower(name)] = fn
+}
+
+// LookupNetwork retrieves a registered network definition by name.
+func LookupNetwork(name string) (*Network, error) {
+	networksLock.RLock()
+	fn, ok := networks[strings.ToLower(name)]
+	networksLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown network %q", name)
+	}
+	network := fn()
+	if err := network.validate(); err != nil {
+		return nil, err
+	}
+	return network, nil
+}
+
+// NetworkNames returns the sorted names of all the registered networks.
+func NetworkNames() []string {
+	networksLock.RLock()
+	defer networksLock.RUnlock()
+
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadNetwork reads a JSON network definition from the given file. If the file
+// does not name the network, the name is derived from the file name.
+func LoadNetwork(path string) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	network := new(Network)
+	if err := json.NewDecoder(file).Decode(network); err != nil {
+		return nil, fmt.Errorf("invalid network file %s: %v", path, err)
+	}
+	if network.Name == "" {
+		base := filepath.Base(path)
+		network.Name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	if err := network.validate(); err != nil {
+		return nil, fmt.Errorf("invalid network file %s: %v", path, err)
+	}
+	return network, nil
+}
+
+// ResolveNetwork interprets the given string first as the name of a registered
+// network, falling back to treating it as the path of a network definition file.
+func ResolveNetwork(nameOrPath string) (*Network, error) {
+	networksLock.RLock()
+	_, ok := networks[strings.ToLower(nameOrPath)]
+	networksLock.RUnlock()
+
+	if ok {
+		return LookupNetwork(nameOrPath)
+	}
+	if _, err := os.Stat(nameOrPath); err != nil {
+		return nil, fmt.Errorf("unknown network %q (available: %s)", nameOrPath, strings.Join(NetworkNames(), ", "))
+	}
+	return LoadNetwork(nameOrPath)
+}
+
+func init() {
+	RegisterNetwork("mainnet", func() *Network {
+		return &Network{
+			Name:        "mainnet",
+			NetworkID:   params.NetworkIDFoundation,
+			Genesis:     DefaultGenesisBlock(),
+			DataDir:     ".",
+			Bootnodes:   params.MainnetBootnodes,
+			BootnodesV5: params.DiscoveryV5Bootnodes,
+		}
+	})
+	RegisterNetwork("testnet", func() *Network {
+		return &Network{
+			Name:      "testnet",
+			NetworkID: params.NetworkIDTestnet,
+			Genesis:   DefaultTestnetGenesisBlock(),
+			Bootnodes: params.TestnetBootnodes,
+		}
+	})
+	RegisterNetwork("rinkeby", func() *Network {
+		return &Network{
+			Name:        "rinkeby",
+			NetworkID:   params.NetworkIDRinkeby,
+			Genesis:     DefaultRinkebyGenesisBlock(),
+			Bootnodes:   params.RinkebyBootnodes,
+			BootnodesV5: params.RinkebyBootnodes,
+		}
+	})
+	RegisterNetwork("goerli", func() *Network {
+		return &Network{
+			Name:        "goerli",
+			NetworkID:   params.NetworkIDGoerli,
+			Genesis:     DefaultGoerliGenesisBlock(),
+			Bootnodes:   params.GoerliBootnodes,
+			BootnodesV5: params.GoerliBootnodes,
+		}
+	})
+	RegisterNetwork("classic", func() *Network {
+		return &Network{
+			Name:      "classic",
+			NetworkID: params.NetworkIDClassic,
+			Genesis:   DefaultClassicGenesisBlock(),
+			Bootnodes: params.ClassicBootnodes,
+		}
+	})
+	RegisterNetwork("mordor", func() *Network {
+		return &Network{
+			Name:      "mordor",
+			NetworkID: params.NetworkIDMordor,
+			Genesis:   DefaultMordorGenesisBlock(),
+			Bootnodes: params.MordorBootnodes,
+		}
+	})
+	RegisterNetwork("kotti", func() *Network {
+		return &Network{
+			Name:        "kotti",
+			NetworkID:   params.NetworkIDKotti,
+			Genesis:     DefaultKottiGenesisBlock(),
+			Bootnodes:   params.KottiBootnodes,
+			BootnodesV5: params.KottiBootnodes,
+		}
+	})
+	RegisterNetwork("social", func() *Network {
+		return &Network{
+			Name:      "social",
+			NetworkID: params.NetworkIDSocial,
+			Genesis:   DefaultSocialGenesisBlock(),
+			Bootnodes: params.SocialBootnodes,
+		}
+	})
+	RegisterNetwork("mix", func() *Network {
+		return &Network{
+			Name:      "mix",
+			NetworkID: params.NetworkIDMix,
+			Genesis:   DefaultMixGenesisBlock(),
+			Bootnodes: params.MixBootnodes,
+		}
+	})
+	RegisterNetwork("ethersocial", func() *Network {
+		return &Network{
+			Name:      "ethersocial",
+			NetworkID: params.NetworkIDEthersocial,
+			Genesis:   DefaultEthersocialGenesisBlock(),
+			Bootnodes: params.EthersocialBootnodes,
+		}
+	})
+	RegisterNetwork("musicoin", func() *Network {
+		return &Network{
+			Name:      "musicoin",
+			NetworkID: params.NetworkIDMusicoin,
+			Genesis:   DefaultMusicoinGenesisBlock(),
+			Bootnodes: params.MusicoinBootnodes,
+		}
+	})
+}
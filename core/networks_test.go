@@ -0,0 +1,117 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the built-in networks are all registered and resolvable.
+func TestBuiltinNetworks(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      uint64
+		datadir string
+		genesis *Genesis
+	}{
+		{"mainnet", params.NetworkIDFoundation, "", DefaultGenesisBlock()},
+		{"testnet", params.NetworkIDTestnet, "testnet", DefaultTestnetGenesisBlock()},
+		{"rinkeby", params.NetworkIDRinkeby, "rinkeby", DefaultRinkebyGenesisBlock()},
+		{"goerli", params.NetworkIDGoerli, "goerli", DefaultGoerliGenesisBlock()},
+		{"classic", params.NetworkIDClassic, "classic", DefaultClassicGenesisBlock()},
+		{"mordor", params.NetworkIDMordor, "mordor", DefaultMordorGenesisBlock()},
+		{"kotti", params.NetworkIDKotti, "kotti", DefaultKottiGenesisBlock()},
+		{"social", params.NetworkIDSocial, "social", DefaultSocialGenesisBlock()},
+		{"mix", params.NetworkIDMix, "mix", DefaultMixGenesisBlock()},
+		{"ethersocial", params.NetworkIDEthersocial, "ethersocial", DefaultEthersocialGenesisBlock()},
+		{"musicoin", params.NetworkIDMusicoin, "musicoin", DefaultMusicoinGenesisBlock()},
+	}
+	if len(NetworkNames()) != len(tests) {
+		t.Errorf("registered network count mismatch: have %d, want %d", len(NetworkNames()), len(tests))
+	}
+	for _, tt := range tests {
+		network, err := ResolveNetwork(tt.name)
+		if err != nil {
+			t.Errorf("%s: failed to resolve network: %v", tt.name, err)
+			continue
+		}
+		if network.NetworkID != tt.id {
+			t.Errorf("%s: network id mismatch: have %d, want %d", tt.name, network.NetworkID, tt.id)
+		}
+		if dir := network.DataDirName(); dir != tt.datadir {
+			t.Errorf("%s: data directory mismatch: have %q, want %q", tt.name, dir, tt.datadir)
+		}
+		if have, want := network.Genesis.ToBlock(nil).Hash(), tt.genesis.ToBlock(nil).Hash(); have != want {
+			t.Errorf("%s: genesis hash mismatch: have %x, want %x", tt.name, have, want)
+		}
+	}
+	if _, err := LookupNetwork("nonexistent"); err == nil {
+		t.Errorf("unknown network resolved")
+	}
+}
+
+// Tests that network definitions can be round-tripped through files.
+func TestLoadNetwork(t *testing.T) {
+	dir, err := ioutil.TempDir("", "networks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"classic", "kotti", "mordor"} {
+		want, _ := LookupNetwork(name)
+		want.Name = ""
+
+		blob, err := json.Marshal(want)
+		if err != nil {
+			t.Fatalf("%s: failed to encode network: %v", name, err)
+		}
+		path := filepath.Join(dir, "private-"+name+".json")
+		if err := ioutil.WriteFile(path, blob, 0600); err != nil {
+			t.Fatalf("%s: failed to write network: %v", name, err)
+		}
+		have, err := ResolveNetwork(path)
+		if err != nil {
+			t.Fatalf("%s: failed to load network: %v", name, err)
+		}
+		if have.Name != "private-"+name {
+			t.Errorf("%s: name mismatch: have %q, want %q", name, have.Name, "private-"+name)
+		}
+		if have.NetworkID != want.NetworkID || !reflect.DeepEqual(have.Bootnodes, want.Bootnodes) {
+			t.Errorf("%s: network mismatch: have %d/%v, want %d/%v", name, have.NetworkID, have.Bootnodes, want.NetworkID, want.Bootnodes)
+		}
+		if !reflect.DeepEqual(have.Genesis.Config, want.Genesis.Config) {
+			t.Errorf("%s: chain config mismatch: have %v, want %v", name, have.Genesis.Config, want.Genesis.Config)
+		}
+		if have, want := have.Genesis.ToBlock(nil).Hash(), want.Genesis.ToBlock(nil).Hash(); have != want {
+			t.Errorf("%s: genesis hash mismatch: have %x, want %x", name, have, want)
+		}
+	}
+	// Network files without a genesis should be rejected
+	path := filepath.Join(dir, "broken.json")
+	ioutil.WriteFile(path, []byte(`{"networkId": 1}`), 0600)
+	if _, err := LoadNetwork(path); err == nil {
+		t.Errorf("broken network loaded: %v", err)
+	}
+}
@@ -515,6 +515,18 @@ func WriteAncientBlock(db ethdb.AncientWriter, block *types.Block, receipts type
 	return len(headerBlob) + len(bodyBlob) + len(receiptBlob) + len(tdBlob) + common.HashLength
 }
 
+// ReadAncientTails retrieves the number of the earliest block whose data is still
+// available for each kind of ancient chain data. Data older than these blocks was
+// pruned from the freezer. Without a freezer, nothing was pruned.
+func ReadAncientTails(db ethdb.AncientReader) map[string]uint64 {
+	tails := make(map[string]uint64)
+	for kind := range freezerNoSnappy {
+		tail, _ := db.AncientTail(kind)
+		tails[kind] = tail
+	}
+	return tails
+}
+
 // DeleteBlock removes all block data associated with a hash.
 func DeleteBlock(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	DeleteReceipts(db, hash, number)
@@ -0,0 +1,86 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ReadInternalTxs retrieves the internal transactions of a block from the trace
+// index. Nil is returned if the block was not indexed.
+func ReadInternalTxs(db ethdb.KeyValueReader, hash common.Hash, number uint64) []*types.InternalTx {
+	data, _ := db.Get(internalTxsKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	txs := []*types.InternalTx{}
+	if err := rlp.DecodeBytes(data, &txs); err != nil {
+		log.Error("Invalid internal transactions RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return txs
+}
+
+// WriteInternalTxs stores the internal transactions of a block into the trace
+// index, along with the lookup entries of all the involved accounts.
+func WriteInternalTxs(db ethdb.KeyValueWriter, hash common.Hash, number uint64, txs []*types.InternalTx) {
+	data, err := rlp.EncodeToBytes(txs)
+	if err != nil {
+		log.Crit("Failed to encode internal transactions", "err", err)
+	}
+	if err := db.Put(internalTxsKey(number, hash), data); err != nil {
+		log.Crit("Failed to store internal transactions", "err", err)
+	}
+	for _, tx := range txs {
+		for _, addr := range []common.Address{tx.From, tx.To} {
+			if err := db.Put(internalTxAddressKey(addr, number), nil); err != nil {
+				log.Crit("Failed to store internal transaction lookup entry", "err", err)
+			}
+		}
+	}
+}
+
+// ReadInternalTxBlocks retrieves the numbers of the blocks within the given
+// range which contain internal transactions involving an account. Entries of
+// blocks reorged out of the canonical chain may be included.
+func ReadInternalTxBlocks(db ethdb.Iteratee, address common.Address, from, to uint64) []uint64 {
+	prefix := append(append([]byte{}, internalTxAddressPrefix...), address.Bytes()...)
+
+	it := db.NewIteratorWithStart(internalTxAddressKey(address, from))
+	defer it.Release()
+
+	var numbers []uint64
+	for it.Next() {
+		key := it.Key()
+		if !bytes.HasPrefix(key, prefix) || len(key) != len(prefix)+8 {
+			break
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			break
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Tests internal transaction storage and retrieval operations.
+func TestInternalTxStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	var (
+		alice = common.Address{0x01}
+		bob   = common.Address{0x02}
+		carol = common.Address{0x03}
+		hash  = common.Hash{0xff}
+	)
+	if txs := ReadInternalTxs(db, hash, 10); txs != nil {
+		t.Fatalf("non existent internal transactions returned: %v", txs)
+	}
+	txs := []*types.InternalTx{
+		{Kind: types.InternalTxCall, TxIndex: 1, From: alice, To: bob, Value: big.NewInt(1)},
+		{Kind: types.InternalTxCreate, TxIndex: 2, From: bob, To: carol, Value: new(big.Int)},
+	}
+	WriteInternalTxs(db, hash, 10, txs)
+	WriteInternalTxs(db, common.Hash{0xee}, 20, txs[:1])
+	WriteInternalTxs(db, common.Hash{0xdd}, 30, txs[1:])
+
+	if have := ReadInternalTxs(db, hash, 10); !reflect.DeepEqual(have, txs) {
+		t.Fatalf("internal transactions mismatch: have %v, want %v", have, txs)
+	}
+	tests := []struct {
+		addr     common.Address
+		from, to uint64
+		want     []uint64
+	}{
+		{alice, 0, 100, []uint64{10, 20}},
+		{bob, 0, 100, []uint64{10, 20, 30}},
+		{bob, 11, 29, []uint64{20}},
+		{bob, 20, 30, []uint64{20, 30}},
+		{carol, 0, 9, nil},
+		{common.Address{0x04}, 0, 100, nil},
+	}
+	for i, tt := range tests {
+		if have := ReadInternalTxBlocks(db, tt.addr, tt.from, tt.to); !reflect.DeepEqual(have, tt.want) {
+			t.Errorf("test %d: block numbers mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
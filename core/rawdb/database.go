@@ -21,10 +21,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/badger"
 	"github.com/ethereum/go-ethereum/ethdb/leveldb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/log"
@@ -73,6 +75,11 @@ func (db *nofreezedb) Ancients() (uint64, error) {
 	return 0, errNotSupported
 }
 
+// AncientTail returns an error as we don't have a backing chain freezer.
+func (db *nofreezedb) AncientTail(kind string) (uint64, error) {
+	return 0, errNotSupported
+}
+
 // AncientSize returns an error as we don't have a backing chain freezer.
 func (db *nofreezedb) AncientSize(kind string) (uint64, error) {
 	return 0, errNotSupported
@@ -105,8 +112,15 @@ func NewDatabase(db ethdb.KeyValueStore) ethdb.Database {
 // value data store with a freezer moving immutable chain segments into cold
 // storage.
 func NewDatabaseWithFreezer(db ethdb.KeyValueStore, freezer string, namespace string) (ethdb.Database, error) {
+	return NewDatabaseWithFreezerConfig(db, freezer, FreezerConfig{}, namespace)
+}
+
+// NewDatabaseWithFreezerConfig creates a high level database on top of a given
+// key-value data store with a freezer of the given per-table settings moving
+// immutable chain segments into cold storage.
+func NewDatabaseWithFreezerConfig(db ethdb.KeyValueStore, freezer string, config FreezerConfig, namespace string) (ethdb.Database, error) {
 	// Create the idle freezer instance
-	frdb, err := newFreezer(freezer, namespace)
+	frdb, err := newFreezer(freezer, namespace, config)
 	if err != nil {
 		return nil, err
 	}
@@ -172,8 +186,13 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, freezer string, namespace st
 			}
 		}
 	}
-	// Freezer is consistent with the key-value database, permit combining the two
-	go frdb.freeze(db)
+	// Freezer is consistent with the key-value database, permit combining the two.
+	// A read-only freezer is maintained by a different node, only follow it.
+	if frdb.readonly {
+		go frdb.follow(db)
+	} else {
+		go frdb.freeze(db)
+	}
 
 	return &freezerdb{
 		KeyValueStore: db,
@@ -181,6 +200,21 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, freezer string, namespace st
 	}, nil
 }
 
+// ReadOnlyAncients reports whether the ancient store backing the database is
+// shared in read-only mode. Such a store is maintained by a different node, so
+// it cannot be appended to or truncated locally.
+func ReadOnlyAncients(db ethdb.Database) bool {
+	switch db := db.(type) {
+	case *freezerdb:
+		if frdb, ok := db.AncientStore.(*freezer); ok {
+			return frdb.readonly
+		}
+	case *table:
+		return ReadOnlyAncients(db.db)
+	}
+	return false
+}
+
 // NewMemoryDatabase creates an ephemeral in-memory key-value database without a
 // freezer moving immutable chain segments into cold storage.
 func NewMemoryDatabase() ethdb.Database {
@@ -219,6 +253,66 @@ func NewLevelDBDatabaseWithFreezer(file string, cache int, handles int, freezer
 	return frdb, nil
 }
 
+// Supported persistent key-value store engines.
+const (
+	DBLevelDB = "leveldb"
+	DBBadger  = "badger"
+)
+
+// PreexistingDatabase checks the given data directory whether a database is
+// already instantiated at that location, and if so, returns the engine of the
+// database (or the empty string).
+func PreexistingDatabase(path string) string {
+	if _, err := os.Stat(filepath.Join(path, "CURRENT")); err == nil {
+		return DBLevelDB
+	}
+	if _, err := os.Stat(filepath.Join(path, "MANIFEST")); err == nil {
+		return DBBadger
+	}
+	return ""
+}
+
+// NewKeyValueStore creates a persistent key-value store of the requested engine.
+// If a database already exists at the given location, its engine is used and an
+// error is returned if it conflicts with the requested one. An empty engine
+// selects the pre-existing one, or LevelDB for new databases.
+func NewKeyValueStore(engine string, file string, cache int, handles int, namespace string) (ethdb.KeyValueStore, error) {
+	existing := PreexistingDatabase(file)
+	if engine != "" && existing != "" && engine != existing {
+		return nil, fmt.Errorf("database engine choice was %s but found pre-existing %s database at %s", engine, existing, file)
+	}
+	if existing != "" {
+		engine = existing
+	}
+	switch engine {
+	case DBLevelDB, "":
+		return leveldb.New(file, cache, handles, namespace)
+	case DBBadger:
+		return badger.New(file, cache, handles, namespace)
+	default:
+		return nil, fmt.Errorf("unknown database engine %q", engine)
+	}
+}
+
+// NewDatabaseWithEngine creates a persistent key-value database of the given
+// engine with a freezer moving immutable chain segments into cold storage. If
+// the freezer is empty, no freezer is attached.
+func NewDatabaseWithEngine(engine string, file string, cache int, handles int, freezer string, config FreezerConfig, namespace string) (ethdb.Database, error) {
+	kvdb, err := NewKeyValueStore(engine, file, cache, handles, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if freezer == "" {
+		return NewDatabase(kvdb), nil
+	}
+	frdb, err := NewDatabaseWithFreezerConfig(kvdb, freezer, config, namespace)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
+
 // InspectDatabase traverses the entire database and checks the size
 // of all different categories of data.
 func InspectDatabase(db ethdb.Database) error {
@@ -242,7 +336,10 @@ func InspectDatabase(db ethdb.Database) error {
 		txlookupSize    common.StorageSize
 		preimageSize    common.StorageSize
 		bloomBitsSize   common.StorageSize
+		traceIndexSize  common.StorageSize
 		cliqueSnapsSize common.StorageSize
+		accountSnapSize common.StorageSize
+		storageSnapSize common.StorageSize
 
 		// Ancient store statistics
 		ancientHeaders  common.StorageSize
@@ -285,6 +382,14 @@ func InspectDatabase(db ethdb.Database) error {
 			preimageSize += size
 		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
 			bloomBitsSize += size
+		case bytes.HasPrefix(key, internalTxsPrefix) && len(key) == (len(internalTxsPrefix)+8+common.HashLength):
+			traceIndexSize += size
+		case bytes.HasPrefix(key, internalTxAddressPrefix) && len(key) == (len(internalTxAddressPrefix)+common.AddressLength+8):
+			traceIndexSize += size
+		case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
+			accountSnapSize += size
+		case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
+			storageSnapSize += size
 		case bytes.HasPrefix(key, []byte("clique-")) && len(key) == 7+common.HashLength:
 			cliqueSnapsSize += size
 		case bytes.HasPrefix(key, []byte("cht-")) && len(key) == 4+common.HashLength:
@@ -295,7 +400,7 @@ func InspectDatabase(db ethdb.Database) error {
 			trieSize += size
 		default:
 			var accounted bool
-			for _, meta := range [][]byte{databaseVerisionKey, headHeaderKey, headBlockKey, headFastBlockKey, fastTrieProgressKey} {
+			for _, meta := range [][]byte{databaseVerisionKey, headHeaderKey, headBlockKey, headFastBlockKey, fastTrieProgressKey, snapshotRootKey, snapshotJournalKey, snapshotGeneratorKey} {
 				if bytes.Equal(key, meta) {
 					metadata += size
 					accounted = true
@@ -330,8 +435,11 @@ func InspectDatabase(db ethdb.Database) error {
 		{"Key-Value store", "Block hash->number", hashNumPairing.String()},
 		{"Key-Value store", "Transaction index", txlookupSize.String()},
 		{"Key-Value store", "Bloombit index", bloomBitsSize.String()},
+		{"Key-Value store", "Trace index", traceIndexSize.String()},
 		{"Key-Value store", "Trie nodes", trieSize.String()},
 		{"Key-Value store", "Trie preimages", preimageSize.String()},
+		{"Key-Value store", "Account snapshot", accountSnapSize.String()},
+		{"Key-Value store", "Storage snapshot", storageSnapSize.String()},
 		{"Key-Value store", "Clique snapshots", cliqueSnapsSize.String()},
 		{"Key-Value store", "Singleton metadata", metadata.String()},
 		{"Ancient store", "Headers", ancientHeaders.String()},
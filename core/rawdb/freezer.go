@@ -22,6 +22,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync/atomic"
 	"time"
 
@@ -45,8 +46,17 @@ var (
 	// errSymlinkDatadir is returned if the ancient directory specified by user
 	// is a symbolic link.
 	errSymlinkDatadir = errors.New("symbolic link datadir is not supported")
+
+	// errReadOnly is returned if the user attempts to modify a freezer opened in
+	// read-only mode.
+	errReadOnly = errors.New("read-only ancient store")
 )
 
+// freezerWipeMargin is the number of most recently frozen blocks whose local
+// copies a read-only freezer retains, so they remain available if the writer of
+// the shared store truncates it (e.g. on a chain rewind).
+var freezerWipeMargin = uint64(10000)
+
 const (
 	// freezerRecheckInterval is the frequency to check the key-value database for
 	// chain progression that might permit new blocks to be frozen into immutable
@@ -58,6 +68,27 @@ const (
 	freezerBatchLimit = 30000
 )
 
+// FreezerConfig contains the per-table settings of the chain freezer.
+type FreezerConfig struct {
+	// NoSnappy overrides whether snappy compression is disabled for individual
+	// ancient tables. The setting does not work retroactively, tables already
+	// present on disk keep the encoding they were created with, and requesting a
+	// different one for them is an error.
+	NoSnappy map[string]bool `toml:",omitempty"`
+
+	// Retention is the number of most recent blocks, counted back from the chain
+	// head, whose data is kept in the individual ancient tables. Anything older
+	// is deleted from the tail of the table. Only bodies and receipts can be
+	// pruned, zero or a missing entry retains everything.
+	Retention map[string]uint64 `toml:",omitempty"`
+
+	// ReadOnly opens an existing ancient store without locking or modifying it,
+	// allowing multiple nodes to share the same directory (e.g. a network mount)
+	// with a single designated node doing the freezing. Newly frozen items are
+	// picked up periodically.
+	ReadOnly bool `toml:",omitempty"`
+}
+
 // freezer is an memory mapped append-only database to store immutable chain data
 // into flat files:
 //
@@ -72,12 +103,30 @@ type freezer struct {
 	frozen uint64 // Number of blocks already frozen
 
 	tables       map[string]*freezerTable // Data tables for storing everything
-	instanceLock fileutil.Releaser        // File-system lock to prevent double opens
+	retention    map[string]uint64        // Number of recent blocks to retain per table
+	readonly     bool                     // Whether the freezer is maintained by a different node
+	instanceLock fileutil.Releaser        // File-system lock to prevent double opens (nil if read-only)
+
+	quit chan struct{} // Quit channel to stop following a read-only freezer
 }
 
 // newFreezer creates a chain freezer that moves ancient chain data into
 // append-only flat file containers.
-func newFreezer(datadir string, namespace string) (*freezer, error) {
+func newFreezer(datadir string, namespace string, config FreezerConfig) (*freezer, error) {
+	// Ensure the table settings refer to existing and prunable tables
+	for name := range config.NoSnappy {
+		if _, ok := freezerNoSnappy[name]; !ok {
+			return nil, fmt.Errorf("unknown ancient table %s", name)
+		}
+	}
+	for name := range config.Retention {
+		if !freezerPrunable[name] {
+			return nil, fmt.Errorf("ancient table %s cannot be pruned", name)
+		}
+		if config.ReadOnly && config.Retention[name] != 0 {
+			return nil, fmt.Errorf("ancient table %s cannot be pruned in read-only mode", name)
+		}
+	}
 	// Create the initial freezer object
 	var (
 		readMeter   = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -92,48 +141,87 @@ func newFreezer(datadir string, namespace string) (*freezer, error) {
 		}
 	}
 	// Leveldb uses LOCK as the filelock filename. To prevent the
-	// name collision, we use FLOCK as the lock name.
-	lock, _, err := fileutil.Flock(filepath.Join(datadir, "FLOCK"))
-	if err != nil {
-		return nil, err
+	// name collision, we use FLOCK as the lock name. Read-only freezers
+	// are shared, so they can't hold the lock.
+	var lock fileutil.Releaser
+	if !config.ReadOnly {
+		flock, _, err := fileutil.Flock(filepath.Join(datadir, "FLOCK"))
+		if err != nil {
+			return nil, err
+		}
+		lock = flock
 	}
 	// Open all the supported data tables
 	freezer := &freezer{
 		tables:       make(map[string]*freezerTable),
+		retention:    config.Retention,
+		readonly:     config.ReadOnly,
 		instanceLock: lock,
+		quit:         make(chan struct{}),
 	}
 	for name, disableSnappy := range freezerNoSnappy {
-		table, err := newTable(datadir, name, readMeter, writeMeter, sizeCounter, disableSnappy)
-		if err != nil {
-			for _, table := range freezer.tables {
-				table.Close()
+		// Compression can't be changed for existing tables, stick to the old one
+		// and reject any explicit request for a different one
+		existing, exists := tableCompression(datadir, name)
+		if override, ok := config.NoSnappy[name]; ok {
+			if exists && existing != override {
+				freezer.close()
+				return nil, fmt.Errorf("ancient table %s compression can't be changed (nosnappy %v, requested %v)", name, existing, override)
 			}
-			lock.Release()
+			disableSnappy = override
+		} else if exists {
+			disableSnappy = existing
+		}
+		var (
+			table *freezerTable
+			err   error
+		)
+		switch {
+		case !config.ReadOnly:
+			table, err = newTable(datadir, name, readMeter, writeMeter, sizeCounter, disableSnappy)
+		case exists:
+			table, err = newReadOnlyTable(datadir, name, readMeter, writeMeter, sizeCounter, disableSnappy)
+		default:
+			err = fmt.Errorf("ancient table %s missing from read-only store", name)
+		}
+		if err != nil {
+			freezer.close()
 			return nil, err
 		}
 		freezer.tables[name] = table
 	}
-	if err := freezer.repair(); err != nil {
-		for _, table := range freezer.tables {
-			table.Close()
-		}
-		lock.Release()
+	var err error
+	if freezer.readonly {
+		err = freezer.refresh()
+	} else {
+		err = freezer.repair()
+	}
+	if err != nil {
+		freezer.close()
 		return nil, err
 	}
-	log.Info("Opened ancient database", "database", datadir)
+	log.Info("Opened ancient database", "database", datadir, "readonly", freezer.readonly)
 	return freezer, nil
 }
 
 // Close terminates the chain freezer, unmapping all the data files.
 func (f *freezer) Close() error {
+	close(f.quit)
+	return f.close()
+}
+
+// close closes all the data tables and releases the instance lock.
+func (f *freezer) close() error {
 	var errs []error
 	for _, table := range f.tables {
 		if err := table.Close(); err != nil {
 			errs = append(errs, err)
 		}
 	}
-	if err := f.instanceLock.Release(); err != nil {
-		errs = append(errs, err)
+	if f.instanceLock != nil {
+		if err := f.instanceLock.Release(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 	if errs != nil {
 		return fmt.Errorf("%v", errs)
@@ -163,6 +251,15 @@ func (f *freezer) Ancients() (uint64, error) {
 	return atomic.LoadUint64(&f.frozen), nil
 }
 
+// AncientTail returns the number of the first item still available in the
+// specified category, anything below it was deleted by the retention policy.
+func (f *freezer) AncientTail(kind string) (uint64, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.tail(), nil
+	}
+	return 0, errUnknownTable
+}
+
 // AncientSize returns the ancient size of the specified category.
 func (f *freezer) AncientSize(kind string) (uint64, error) {
 	if table := f.tables[kind]; table != nil {
@@ -178,6 +275,9 @@ func (f *freezer) AncientSize(kind string) (uint64, error) {
 // injection will be rejected. But if two injections with same number happen at
 // the same time, we can get into the trouble.
 func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td []byte) (err error) {
+	if f.readonly {
+		return errReadOnly
+	}
 	// Ensure the binary blobs we are appending is continuous with freezer.
 	if atomic.LoadUint64(&f.frozen) != number {
 		return errOutOrderInsertion
@@ -220,6 +320,9 @@ func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td
 
 // Truncate discards any recent data above the provided threshold number.
 func (f *freezer) TruncateAncients(items uint64) error {
+	if f.readonly {
+		return errReadOnly
+	}
 	if atomic.LoadUint64(&f.frozen) <= items {
 		return nil
 	}
@@ -234,6 +337,9 @@ func (f *freezer) TruncateAncients(items uint64) error {
 
 // sync flushes all data tables to disk.
 func (f *freezer) Sync() error {
+	if f.readonly {
+		return errReadOnly
+	}
 	var errs []error
 	for _, table := range f.tables {
 		if err := table.Sync(); err != nil {
@@ -358,6 +464,9 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 		if err := batch.Write(); err != nil {
 			log.Crit("Failed to delete frozen side blocks", "err", err)
 		}
+		// Drop any ancient data fallen out of the retention window
+		f.prune(*number)
+
 		// Log something friendly for the user
 		context := []interface{}{
 			"blocks", f.frozen - first, "elapsed", common.PrettyDuration(time.Since(start)), "number", f.frozen - 1,
@@ -374,6 +483,122 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 	}
 }
 
+// prune deletes the ancient data of the tables with a retention policy which is
+// older than the retained number of blocks counted back from the given head.
+func (f *freezer) prune(head uint64) {
+	for name, retention := range f.retention {
+		if retention == 0 || head <= retention {
+			continue
+		}
+		limit := head - retention
+		if frozen := atomic.LoadUint64(&f.frozen); limit > frozen {
+			limit = frozen
+		}
+		table := f.tables[name]
+
+		tail := table.tail()
+		if err := table.truncateTail(limit); err != nil {
+			log.Error("Failed to prune ancient table", "table", name, "limit", limit, "err", err)
+			continue
+		}
+		if pruned := table.tail(); pruned > tail {
+			log.Info("Pruned ancient chain segment", "table", name, "items", pruned-tail, "tail", pruned)
+		}
+	}
+}
+
+// follow is a background thread of a read-only freezer that periodically picks
+// up the chain segments frozen by the writer of the shared ancient store, and
+// deletes the block data made redundant by them from the key-value database.
+func (f *freezer) follow(db ethdb.KeyValueStore) {
+	nfdb := &nofreezedb{KeyValueStore: db}
+
+	// Everything below the first non-genesis block present in the key-value
+	// store was deleted in a previous run already, start wiping from there
+	next := uint64(1)
+	if head := ReadHeaderNumber(nfdb, ReadHeadHeaderHash(nfdb)); head != nil {
+		next += uint64(sort.Search(int(*head), func(n int) bool {
+			return ReadCanonicalHash(nfdb, uint64(n)+1) != (common.Hash{})
+		}))
+	}
+	for {
+		if err := f.refresh(); err != nil {
+			log.Error("Failed to refresh read-only ancient store", "err", err)
+		} else {
+			next = f.wipe(db, next)
+		}
+		select {
+		case <-f.quit:
+			return
+		case <-time.After(freezerRecheckInterval):
+		}
+	}
+}
+
+// wipe deletes the blocks from the key-value database which are available from
+// the shared ancient store, starting with the given number. The number of the
+// next block to wipe is returned, which is the first one not yet deleted from
+// the database if a write fails midway.
+func (f *freezer) wipe(db ethdb.KeyValueStore, next uint64) uint64 {
+	var (
+		nfdb    = &nofreezedb{KeyValueStore: db}
+		frozen  = atomic.LoadUint64(&f.frozen)
+		first   = next
+		flushed = next
+		batch   = db.NewBatch()
+	)
+	for ; next+freezerWipeMargin < frozen; next++ {
+		// Stop at the local chain head, or if the two stores diverged
+		hash := ReadCanonicalHash(nfdb, next)
+		if hash == (common.Hash{}) {
+			break
+		}
+		if blob, _ := f.Ancient(freezerHashTable, next); common.BytesToHash(blob) != hash {
+			log.Error("Local chain diverged from ancient store", "number", next, "hash", hash, "ancient", common.BytesToHash(blob))
+			break
+		}
+		DeleteBlockWithoutNumber(batch, hash, next)
+		DeleteCanonicalHash(batch, next)
+		for _, side := range ReadAllHashes(db, next) {
+			if side != hash {
+				DeleteBlock(batch, side, next)
+			}
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Error("Failed to delete shared ancient blocks", "err", err)
+				return flushed
+			}
+			batch.Reset()
+			flushed = next + 1
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to delete shared ancient blocks", "err", err)
+		return flushed
+	}
+	if next > first {
+		log.Info("Dropped blocks available from ancient store", "blocks", next-first, "number", next-1)
+	}
+	return next
+}
+
+// refresh reloads all the data tables of a read-only freezer, picking up the
+// items frozen by the writer of the shared ancient store since the last call.
+func (f *freezer) refresh() error {
+	min := uint64(math.MaxUint64)
+	for _, table := range f.tables {
+		if err := table.refresh(); err != nil {
+			return err
+		}
+		if items := atomic.LoadUint64(&table.items); min > items {
+			min = items
+		}
+	}
+	atomic.StoreUint64(&f.frozen, min)
+	return nil
+}
+
 // repair truncates all data tables to the same length.
 func (f *freezer) repair() error {
 	min := uint64(math.MaxUint64)
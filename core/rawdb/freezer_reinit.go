@@ -59,6 +59,14 @@ func InitDatabaseFromFreezer(db ethdb.Database) error {
 				// number from the freezer). If successful, pre-cache the block hash and
 				// the individual transaction hashes for storing into the database.
 				block := ReadBlock(db, common.Hash{}, n)
+				if block == nil {
+					// The body might have been pruned already, the hash mapping
+					// can still be restored from the header, but the transaction
+					// lookups are gone for good
+					if header := ReadHeader(db, common.Hash{}, n); header != nil {
+						block = types.NewBlockWithHeader(header)
+					}
+				}
 				if block != nil {
 					block.Hash()
 					for _, tx := range block.Transactions() {
@@ -23,6 +23,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"sync/atomic"
 
@@ -43,11 +44,19 @@ var (
 
 	// errNotSupported is returned if the database doesn't support the required operation.
 	errNotSupported = errors.New("this operation is not supported")
+
+	// ErrAncientPruned is returned if the item requested was contained within the
+	// freezer table once, but got deleted from its tail by the retention policy.
+	ErrAncientPruned = errors.New("ancient data pruned")
 )
 
 // indexEntry contains the number/id of the file that the data resides in, aswell as the
 // offset within the file to the end of the data
 // In serialized form, the filenum is stored as uint16.
+//
+// The first entry of the index file is special: it doesn't mark the end of any
+// item, rather it holds the number of the earliest data file in filenum and the
+// number of items deleted from the tail in offset.
 type indexEntry struct {
 	filenum uint32 // stored as uint16 ( 2 bytes)
 	offset  uint32 // stored as uint32 ( 4 bytes)
@@ -80,6 +89,7 @@ type freezerTable struct {
 	items uint64 // Number of items stored in the table (including items removed from tail)
 
 	noCompression bool   // if true, disables snappy compression. Note: does not work retroactively
+	readonly      bool   // if true, the table is maintained by a different process and only read
 	maxFileSize   uint32 // Max file size for data-files
 	name          string
 	path          string
@@ -146,6 +156,18 @@ func truncateFreezerFile(file *os.File, size int64) error {
 	return nil
 }
 
+// tableCompression checks whether a freezer table already exists at the given
+// location, and if so, reports whether it was created with compression disabled.
+func tableCompression(path string, name string) (bool, bool) {
+	if _, err := os.Stat(filepath.Join(path, fmt.Sprintf("%s.ridx", name))); err == nil {
+		return true, true
+	}
+	if _, err := os.Stat(filepath.Join(path, fmt.Sprintf("%s.cidx", name))); err == nil {
+		return false, true
+	}
+	return false, false
+}
+
 // newCustomTable opens a freezer table, creating the data and index files if they are
 // non existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
@@ -194,6 +216,145 @@ func newCustomTable(path string, name string, readMeter metrics.Meter, writeMete
 	return tab, nil
 }
 
+// newReadOnlyTable opens an existing freezer table without ever modifying it, so
+// the files can be shared with a single writer process maintaining the table.
+// Instead of repairing the files, the table metadata is loaded by refresh, which
+// needs to be called periodically to pick up the changes done by the writer.
+func newReadOnlyTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeCounter metrics.Counter, noCompression bool) (*freezerTable, error) {
+	var idxName string
+	if noCompression {
+		idxName = fmt.Sprintf("%s.ridx", name)
+	} else {
+		idxName = fmt.Sprintf("%s.cidx", name)
+	}
+	offsets, err := openFreezerFileForReadOnly(filepath.Join(path, idxName))
+	if err != nil {
+		return nil, err
+	}
+	tab := &freezerTable{
+		index:         offsets,
+		files:         make(map[uint32]*os.File),
+		readMeter:     readMeter,
+		writeMeter:    writeMeter,
+		sizeCounter:   sizeCounter,
+		name:          name,
+		path:          path,
+		logger:        log.New("database", path, "table", name),
+		noCompression: noCompression,
+		readonly:      true,
+		maxFileSize:   2 * 1000 * 1000 * 1000,
+	}
+	if err := tab.refresh(); err != nil {
+		tab.Close()
+		return nil, err
+	}
+	// Initialize the starting size counter
+	size, err := tab.sizeNolock()
+	if err != nil {
+		tab.Close()
+		return nil, err
+	}
+	tab.sizeCounter.Inc(int64(size))
+
+	tab.logger.Debug("Read-only freezer table opened", "items", tab.items, "tail", tab.itemOffset)
+	return tab, nil
+}
+
+// refresh reloads the metadata of a read-only table from disk, picking up the
+// items appended by the writer since the last refresh, as well as any deletion
+// from the head or the tail of the table.
+func (t *freezerTable) refresh() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil {
+		return errClosed
+	}
+	var oldSize uint64
+	if t.head != nil {
+		oldSize, _ = t.sizeNolock()
+	}
+	// Tail deletions replace the index file, reopen it if that happened
+	name := t.index.Name()
+	stat, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	if current, err := t.index.Stat(); err != nil {
+		return err
+	} else if !os.SameFile(current, stat) {
+		index, err := openFreezerFileForReadOnly(name)
+		if err != nil {
+			return err
+		}
+		t.index.Close()
+		t.index = index
+	}
+	// Only consider whole index entries, the writer might be amidst adding one
+	entries := stat.Size() / indexEntrySize
+	if entries == 0 {
+		return fmt.Errorf("freezer table %s not initialized", t.name)
+	}
+	var (
+		buffer      = make([]byte, indexEntrySize)
+		first, last indexEntry
+	)
+	if _, err := t.index.ReadAt(buffer, 0); err != nil {
+		return err
+	}
+	first.unmarshalBinary(buffer)
+
+	// The index entries of the writer might become visible before their data,
+	// e.g. on network mounts, only expose the items with their data present
+	for ; entries > 1; entries-- {
+		if _, err := t.index.ReadAt(buffer, (entries-1)*indexEntrySize); err != nil {
+			return err
+		}
+		last.unmarshalBinary(buffer)
+		if stat, err := os.Stat(t.fileName(last.filenum)); err == nil && stat.Size() >= int64(last.offset) {
+			break
+		}
+	}
+	if entries == 1 {
+		last = indexEntry{filenum: first.filenum} // No items in the table
+	}
+	// Drop the data files deleted by the writer and open any new ones. Files
+	// might also be recreated after a head truncation, so reopen those too.
+	t.releaseFilesBefore(first.filenum, false)
+	t.releaseFilesAfter(last.filenum, false)
+
+	for num := first.filenum; num <= last.filenum; num++ {
+		if f, exist := t.files[num]; exist {
+			current, err := f.Stat()
+			if err != nil {
+				return err
+			}
+			if latest, err := os.Stat(t.fileName(num)); err != nil || !os.SameFile(current, latest) {
+				t.releaseFile(num)
+			}
+		}
+		if _, err := t.openFile(num, openFreezerFileForReadOnly); err != nil {
+			return err
+		}
+	}
+	t.head = t.files[last.filenum]
+
+	atomic.StoreUint32(&t.tailId, first.filenum)
+	atomic.StoreUint32(&t.itemOffset, first.offset)
+	atomic.StoreUint32(&t.headId, last.filenum)
+	atomic.StoreUint32(&t.headBytes, last.offset)
+	atomic.StoreUint64(&t.items, uint64(first.offset)+uint64(entries-1))
+
+	if oldSize != 0 {
+		newSize, err := t.sizeNolock()
+		if err != nil {
+			return err
+		}
+		t.sizeCounter.Inc(int64(newSize) - int64(oldSize))
+	}
+	return nil
+}
+
 // repair cross checks the head and the index file and truncates them to
 // be in sync with each other after a potential crash / data loss.
 func (t *freezerTable) repair() error {
@@ -232,11 +393,14 @@ func (t *freezerTable) repair() error {
 	t.index.ReadAt(buffer, 0)
 	firstIndex.unmarshalBinary(buffer)
 
-	t.tailId = firstIndex.offset
-	t.itemOffset = firstIndex.filenum
+	t.tailId = firstIndex.filenum
+	t.itemOffset = firstIndex.offset
 
 	t.index.ReadAt(buffer, offsetsSize-indexEntrySize)
 	lastIndex.unmarshalBinary(buffer)
+	if offsetsSize == indexEntrySize {
+		lastIndex.offset = 0 // No items in the table, the tail file is empty
+	}
 	t.head, err = t.openFile(lastIndex.filenum, openFreezerFileForAppend)
 	if err != nil {
 		return err
@@ -268,6 +432,9 @@ func (t *freezerTable) repair() error {
 			t.index.ReadAt(buffer, offsetsSize-indexEntrySize)
 			var newLastIndex indexEntry
 			newLastIndex.unmarshalBinary(buffer)
+			if offsetsSize == indexEntrySize {
+				newLastIndex.offset = 0 // No items left in the table
+			}
 			// We might have slipped back into an earlier head-file here
 			if newLastIndex.filenum != lastIndex.filenum {
 				// Release earlier opened file
@@ -338,18 +505,29 @@ func (t *freezerTable) truncate(items uint64) error {
 	if err != nil {
 		return err
 	}
+	// If the threshold is below the tail, none of the retained items survive.
+	// Drop all the data files and restart the table empty from the threshold.
+	if items < uint64(t.itemOffset) {
+		t.logger.Warn("Truncating freezer table below tail", "items", t.items, "tail", t.itemOffset, "limit", items)
+		return t.reset(items, oldSize)
+	}
 	// Something's out of sync, truncate the table's offset index
 	t.logger.Warn("Truncating freezer table", "items", t.items, "limit", items)
-	if err := truncateFreezerFile(t.index, int64(items+1)*indexEntrySize); err != nil {
+
+	position := items - uint64(t.itemOffset)
+	if err := truncateFreezerFile(t.index, int64(position+1)*indexEntrySize); err != nil {
 		return err
 	}
 	// Calculate the new expected size of the data file and truncate it
 	buffer := make([]byte, indexEntrySize)
-	if _, err := t.index.ReadAt(buffer, int64(items*indexEntrySize)); err != nil {
+	if _, err := t.index.ReadAt(buffer, int64(position*indexEntrySize)); err != nil {
 		return err
 	}
 	var expected indexEntry
 	expected.unmarshalBinary(buffer)
+	if position == 0 {
+		expected.offset = 0 // All items truncated, the tail file is emptied
+	}
 
 	// We might need to truncate back to older files
 	if expected.filenum != t.headId {
@@ -383,6 +561,162 @@ func (t *freezerTable) truncate(items uint64) error {
 	return nil
 }
 
+// truncateTail discards any data below the provided threshold number. Deletion
+// is done on a data file granularity, so items sharing a data file with the
+// first retained item are kept too.
+func (t *freezerTable) truncateTail(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// Ensure the table is still accessible and there's something to delete
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	offset := uint64(t.itemOffset)
+	if items <= offset {
+		return nil
+	}
+	total := atomic.LoadUint64(&t.items)
+	if items > total {
+		items = total
+	}
+	// Find the data file holding the first retained item, all files before it
+	// can be dropped. If nothing is retained, that is the current head file.
+	buffer := make([]byte, indexEntrySize)
+	readEntry := func(position uint64) (indexEntry, error) {
+		var entry indexEntry
+		if _, err := t.index.ReadAt(buffer, int64(position*indexEntrySize)); err != nil {
+			return entry, err
+		}
+		entry.unmarshalBinary(buffer)
+		return entry, nil
+	}
+	tailId := t.headId
+	if items < total {
+		entry, err := readEntry(items - offset + 1)
+		if err != nil {
+			return err
+		}
+		tailId = entry.filenum
+	}
+	if tailId == t.tailId {
+		return nil
+	}
+	// Locate the first item stored in the new tail file. Items are ordered by
+	// data file in the index, so a binary search finds the boundary.
+	var ierr error
+	deleted := uint64(sort.Search(int(total-offset), func(n int) bool {
+		entry, err := readEntry(uint64(n) + 1)
+		if err != nil {
+			ierr = err
+			return true
+		}
+		return entry.filenum >= tailId
+	}))
+	if ierr != nil {
+		return ierr
+	}
+	oldSize, err := t.sizeNolock()
+	if err != nil {
+		return err
+	}
+	// Persist the new tail into the index before dropping the data files, so a
+	// crash in between only leaves a few dangling files behind
+	tail := indexEntry{filenum: tailId, offset: uint32(offset + deleted)}
+	if err := t.rewriteIndex(tail, deleted+1); err != nil {
+		return err
+	}
+	t.releaseFilesBefore(tailId, true)
+
+	atomic.StoreUint32(&t.tailId, tailId)
+	atomic.StoreUint32(&t.itemOffset, tail.offset)
+
+	newSize, err := t.sizeNolock()
+	if err != nil {
+		return err
+	}
+	t.sizeCounter.Dec(int64(oldSize - newSize))
+
+	t.logger.Debug("Truncated freezer table tail", "items", deleted, "tail", tail.offset)
+	return nil
+}
+
+// reset discards all the data files of the table, restarting it empty with the
+// given number of items considered deleted from the tail. The caller must hold
+// the write lock.
+func (t *freezerTable) reset(items uint64, oldSize uint64) error {
+	// Open a fresh head file past all the existing ones
+	headId := t.headId + 1
+	head, err := openFreezerFileTruncated(t.fileName(headId))
+	if err != nil {
+		return err
+	}
+	entries := atomic.LoadUint64(&t.items) - uint64(t.itemOffset) + 1
+	if err := t.rewriteIndex(indexEntry{filenum: headId, offset: uint32(items)}, entries); err != nil {
+		head.Close()
+		return err
+	}
+	t.releaseFilesBefore(headId, true)
+	t.files[headId] = head
+	t.head = head
+
+	atomic.StoreUint32(&t.headId, headId)
+	atomic.StoreUint32(&t.tailId, headId)
+	atomic.StoreUint32(&t.itemOffset, uint32(items))
+	atomic.StoreUint32(&t.headBytes, 0)
+	atomic.StoreUint64(&t.items, items)
+
+	newSize, err := t.sizeNolock()
+	if err != nil {
+		return err
+	}
+	t.sizeCounter.Dec(int64(oldSize - newSize))
+	return nil
+}
+
+// rewriteIndex replaces the index file with one starting with the given tail
+// entry, followed by the existing index entries from the given position on. The
+// new index is written aside and moved in place atomically. The caller must hold
+// the write lock.
+func (t *freezerTable) rewriteIndex(tail indexEntry, position uint64) error {
+	name := t.index.Name()
+	temp, err := openFreezerFileTruncated(name + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := temp.Write(tail.marshallBinary()); err != nil {
+		temp.Close()
+		return err
+	}
+	stat, err := t.index.Stat()
+	if err != nil {
+		temp.Close()
+		return err
+	}
+	if start := int64(position * indexEntrySize); start < stat.Size() {
+		if _, err := io.Copy(temp, io.NewSectionReader(t.index, start, stat.Size()-start)); err != nil {
+			temp.Close()
+			return err
+		}
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	// Swap the index files and reopen the new one for appending
+	if err := t.index.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(name+".tmp", name); err != nil {
+		return err
+	}
+	t.index, err = openFreezerFileForAppend(name)
+	return err
+}
+
 // Close closes all opened files.
 func (t *freezerTable) Close() error {
 	t.lock.Lock()
@@ -407,17 +741,22 @@ func (t *freezerTable) Close() error {
 	return nil
 }
 
+// fileName returns the path of the data file with the given number.
+func (t *freezerTable) fileName(num uint32) string {
+	var name string
+	if t.noCompression {
+		name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
+	} else {
+		name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
+	}
+	return filepath.Join(t.path, name)
+}
+
 // openFile assumes that the write-lock is held by the caller
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
-		}
-		f, err = opener(filepath.Join(t.path, name))
+		f, err = opener(t.fileName(num))
 		if err != nil {
 			return nil, err
 		}
@@ -448,6 +787,20 @@ func (t *freezerTable) releaseFilesAfter(num uint32, remove bool) {
 	}
 }
 
+// releaseFilesBefore closes all open files with a lower number, and optionally also
+// deletes the files
+func (t *freezerTable) releaseFilesBefore(num uint32, remove bool) {
+	for fnum, f := range t.files {
+		if fnum < num {
+			delete(t.files, fnum)
+			f.Close()
+			if remove {
+				os.Remove(f.Name())
+			}
+		}
+	}
+}
+
 // Append injects a binary blob at the end of the freezer table. The item number
 // is a precautionary parameter to ensure data correctness, but the table will
 // reject already existing data.
@@ -529,6 +882,11 @@ func (t *freezerTable) getBounds(item uint64) (uint32, uint32, uint32, error) {
 		return 0, 0, 0, err
 	}
 	endIdx.unmarshalBinary(buffer)
+	if item == 0 {
+		// The first entry holds the tail metadata, the first retained item
+		// always starts at the beginning of the tail file
+		startIdx.offset = 0
+	}
 	if startIdx.filenum != endIdx.filenum {
 		// If a piece of data 'crosses' a data-file,
 		// it's actually in one piece on the second data-file.
@@ -549,11 +907,12 @@ func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
 		return nil, errOutOfBounds
 	}
 	// Ensure the item was not deleted from the tail either
+	t.lock.RLock()
 	offset := atomic.LoadUint32(&t.itemOffset)
 	if uint64(offset) > item {
-		return nil, errOutOfBounds
+		t.lock.RUnlock()
+		return nil, ErrAncientPruned
 	}
-	t.lock.RLock()
 	startOffset, endOffset, filenum, err := t.getBounds(item - uint64(offset))
 	if err != nil {
 		t.lock.RUnlock()
@@ -582,7 +941,13 @@ func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
 // has returns an indicator whether the specified number data
 // exists in the freezer table.
 func (t *freezerTable) has(number uint64) bool {
-	return atomic.LoadUint64(&t.items) > number
+	return atomic.LoadUint64(&t.items) > number && uint64(atomic.LoadUint32(&t.itemOffset)) <= number
+}
+
+// tail returns the number of the first item still available in the freezer
+// table, or in other words, the number of items deleted from its tail.
+func (t *freezerTable) tail() uint64 {
+	return uint64(atomic.LoadUint32(&t.itemOffset))
 }
 
 // size returns the total data size in the freezer table.
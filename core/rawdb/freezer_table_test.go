@@ -560,8 +560,8 @@ func TestOffset(t *testing.T) {
 		tailId := uint32(2)     // First file is 2
 		itemOffset := uint32(4) // We have removed four items
 		zeroIndex := indexEntry{
-			offset:  tailId,
-			filenum: itemOffset,
+			filenum: tailId,
+			offset:  itemOffset,
 		}
 		buf := zeroIndex.marshallBinary()
 		// Overwrite index zero
@@ -610,6 +610,259 @@ func TestOffset(t *testing.T) {
 	}
 }
 
+// TestFreezerTruncateTail tests that items can be deleted from the tail of a table
+// on a data file granularity, and that the deletion survives a reopen.
+func TestFreezerTruncateTail(t *testing.T) {
+	t.Parallel()
+	rm, wm, sc := metrics.NewMeter(), metrics.NewMeter(), metrics.NewCounter()
+	fname := fmt.Sprintf("truncatetail-%d", rand.Uint64())
+
+	// Write 7 x 20 bytes, splitting out into four files
+	f, err := newCustomTable(os.TempDir(), fname, rm, wm, sc, 40, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < 7; x++ {
+		f.Append(uint64(x), getChunk(20, x))
+	}
+	// Item 3 is in the second file, so only the first one can be dropped
+	if err := f.truncateTail(3); err != nil {
+		t.Fatal(err)
+	}
+	if tail := f.tail(); tail != 2 {
+		t.Fatalf("tail mismatch: have %d, want %d", tail, 2)
+	}
+	if _, err := os.Stat(filepath.Join(os.TempDir(), fmt.Sprintf("%s.0000.rdat", fname))); !os.IsNotExist(err) {
+		t.Fatalf("tail data file not deleted: %v", err)
+	}
+	f.Close()
+
+	// Reopen the table and check that the pruned items are gone, the rest intact
+	f, err = newCustomTable(os.TempDir(), fname, rm, wm, sc, 40, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for x := 0; x < 2; x++ {
+		if f.has(uint64(x)) {
+			t.Fatalf("item %d: pruned item reported present", x)
+		}
+		if _, err := f.Retrieve(uint64(x)); err != ErrAncientPruned {
+			t.Fatalf("item %d: error mismatch: have %v, want %v", x, err, ErrAncientPruned)
+		}
+	}
+	for x := 2; x < 7; x++ {
+		if got, err := f.Retrieve(uint64(x)); err != nil {
+			t.Fatal(err)
+		} else if exp := getChunk(20, x); !bytes.Equal(got, exp) {
+			t.Fatalf("item %d: expected %x got %x", x, exp, got)
+		}
+	}
+	// Appending and truncating the head should still work with a tail
+	if err := f.Append(7, getChunk(20, 7)); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.truncate(5); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := f.Retrieve(4); err != nil {
+		t.Fatal(err)
+	} else if exp := getChunk(20, 4); !bytes.Equal(got, exp) {
+		t.Fatalf("expected %x got %x", exp, got)
+	}
+	if _, err := f.Retrieve(5); err != errOutOfBounds {
+		t.Fatalf("error mismatch: have %v, want %v", err, errOutOfBounds)
+	}
+	// Delete everything, the next item should still be appendable
+	if err := f.truncateTail(5); err != nil {
+		t.Fatal(err)
+	}
+	if tail := f.tail(); tail != 4 {
+		t.Fatalf("tail mismatch: have %d, want %d", tail, 4)
+	}
+	if err := f.Append(5, getChunk(20, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := f.Retrieve(5); err != nil {
+		t.Fatal(err)
+	} else if exp := getChunk(20, 5); !bytes.Equal(got, exp) {
+		t.Fatalf("expected %x got %x", exp, got)
+	}
+}
+
+// TestFreezerTruncateBelowTail tests that truncating a table below its tail
+// resets it into an empty table continuing from the truncation point.
+func TestFreezerTruncateBelowTail(t *testing.T) {
+	t.Parallel()
+	rm, wm, sc := metrics.NewMeter(), metrics.NewMeter(), metrics.NewCounter()
+	fname := fmt.Sprintf("truncatebelowtail-%d", rand.Uint64())
+
+	f, err := newCustomTable(os.TempDir(), fname, rm, wm, sc, 40, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for x := 0; x < 7; x++ {
+		f.Append(uint64(x), getChunk(20, x))
+	}
+	if err := f.truncateTail(6); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.truncate(3); err != nil {
+		t.Fatal(err)
+	}
+	if f.items != 3 || f.tail() != 3 {
+		t.Fatalf("table mismatch: have items %d tail %d, want 3 and 3", f.items, f.tail())
+	}
+	if err := f.Append(3, getChunk(20, 0xaa)); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	f, err = newCustomTable(os.TempDir(), fname, rm, wm, sc, 40, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if f.items != 4 || f.tail() != 3 {
+		t.Fatalf("reopened table mismatch: have items %d tail %d, want 4 and 3", f.items, f.tail())
+	}
+	if got, err := f.Retrieve(3); err != nil {
+		t.Fatal(err)
+	} else if exp := getChunk(20, 0xaa); !bytes.Equal(got, exp) {
+		t.Fatalf("expected %x got %x", exp, got)
+	}
+}
+
+// TestFreezerReadOnlyRefresh tests that a read-only table picks up the changes
+// done by the writer of the same table upon refresh.
+func TestFreezerReadOnlyRefresh(t *testing.T) {
+	t.Parallel()
+	rm, wm, sc := metrics.NewMeter(), metrics.NewMeter(), metrics.NewCounter()
+	fname := fmt.Sprintf("readonly-%d", rand.Uint64())
+
+	writer, err := newCustomTable(os.TempDir(), fname, rm, wm, sc, 40, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	for x := 0; x < 3; x++ {
+		writer.Append(uint64(x), getChunk(20, x))
+	}
+	writer.Sync()
+
+	reader, err := newReadOnlyTable(os.TempDir(), fname, rm, wm, sc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	check := func(items uint64, tail uint64, data map[uint64]int) {
+		t.Helper()
+		if err := reader.refresh(); err != nil {
+			t.Fatalf("failed to refresh table: %v", err)
+		}
+		if reader.items != items || reader.tail() != tail {
+			t.Fatalf("table mismatch: have items %d tail %d, want %d and %d", reader.items, reader.tail(), items, tail)
+		}
+		for item, b := range data {
+			if got, err := reader.Retrieve(item); err != nil {
+				t.Fatalf("item %d: %v", item, err)
+			} else if exp := getChunk(20, b); !bytes.Equal(got, exp) {
+				t.Fatalf("item %d: expected %x got %x", item, exp, got)
+			}
+		}
+	}
+	check(3, 0, map[uint64]int{0: 0, 2: 2})
+
+	// Appends spanning new data files should be picked up
+	for x := 3; x < 7; x++ {
+		writer.Append(uint64(x), getChunk(20, x))
+	}
+	writer.Sync()
+	check(7, 0, map[uint64]int{0: 0, 6: 6})
+
+	// Tail deletions replace the index file, which should be followed
+	if err := writer.truncateTail(4); err != nil {
+		t.Fatal(err)
+	}
+	check(7, 4, map[uint64]int{4: 4, 6: 6})
+	if _, err := reader.Retrieve(3); err != ErrAncientPruned {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrAncientPruned)
+	}
+	// Head truncations followed by new appends should be picked up
+	if err := writer.truncate(5); err != nil {
+		t.Fatal(err)
+	}
+	writer.Append(5, getChunk(20, 0xaa))
+	writer.Sync()
+	check(6, 4, map[uint64]int{4: 4, 5: 0xaa})
+}
+
+// TestFreezerReadOnlyRefreshMissingData tests that a read-only table doesn't
+// expose the items whose index entries are visible before their data.
+func TestFreezerReadOnlyRefreshMissingData(t *testing.T) {
+	t.Parallel()
+	rm, wm, sc := metrics.NewMeter(), metrics.NewMeter(), metrics.NewCounter()
+	fname := fmt.Sprintf("readonly-missing-%d", rand.Uint64())
+
+	writer, err := newCustomTable(os.TempDir(), fname, rm, wm, sc, 40, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	for x := 0; x < 5; x++ {
+		writer.Append(uint64(x), getChunk(20, x))
+	}
+	writer.Sync()
+
+	// Cut the data of the last items, item 3 partially and item 4 completely
+	if err := os.Truncate(writer.fileName(1), 30); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(writer.fileName(2), 0); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := newReadOnlyTable(os.TempDir(), fname, rm, wm, sc, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	if reader.items != 3 {
+		t.Fatalf("item count mismatch: have %d, want 3", reader.items)
+	}
+	if _, err := reader.Retrieve(3); err == nil {
+		t.Fatalf("retrieved item without data")
+	}
+	// The items should be exposed once their data arrives
+	for file, item := range map[uint32]int{1: 3, 2: 4} {
+		f, err := os.OpenFile(writer.fileName(file), os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = f.WriteAt(getChunk(20, item), int64(20*(item%2)))
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := reader.refresh(); err != nil {
+		t.Fatalf("failed to refresh table: %v", err)
+	}
+	if reader.items != 5 {
+		t.Fatalf("item count mismatch: have %d, want 5", reader.items)
+	}
+	for item := 3; item < 5; item++ {
+		if got, err := reader.Retrieve(uint64(item)); err != nil {
+			t.Fatalf("item %d: %v", item, err)
+		} else if exp := getChunk(20, item); !bytes.Equal(got, exp) {
+			t.Fatalf("item %d: expected %x got %x", item, exp, got)
+		}
+	}
+}
+
 // TODO (?)
 // - test that if we remove several head-files, aswell as data last data-file,
 //   the index is truncated accordingly
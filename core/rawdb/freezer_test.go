@@ -0,0 +1,320 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+// Tests that the per-table settings of the freezer are validated.
+func TestFreezerConfigValidation(t *testing.T) {
+	tests := []struct {
+		config FreezerConfig
+		fail   bool
+	}{
+		{FreezerConfig{}, false},
+		{FreezerConfig{NoSnappy: map[string]bool{freezerReceiptTable: true}}, false},
+		{FreezerConfig{NoSnappy: map[string]bool{"unknown": true}}, true},
+		{FreezerConfig{Retention: map[string]uint64{freezerBodiesTable: 1000, freezerReceiptTable: 1000}}, false},
+		{FreezerConfig{Retention: map[string]uint64{freezerHeaderTable: 1000}}, true},
+		{FreezerConfig{Retention: map[string]uint64{freezerHashTable: 1000}}, true},
+	}
+	for i, tt := range tests {
+		dir, err := ioutil.TempDir("", "freezer-")
+		if err != nil {
+			t.Fatalf("failed to create temporary directory: %v", err)
+		}
+		f, err := newFreezer(dir, "", tt.config)
+		if tt.fail && err == nil {
+			t.Errorf("test %d: invalid config accepted", i)
+		}
+		if !tt.fail && err != nil {
+			t.Errorf("test %d: valid config rejected: %v", i, err)
+		}
+		if f != nil {
+			f.Close()
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+// Tests that the compression of existing ancient tables is retained if it's not
+// configured, and that requesting a different one fails since it does not work
+// retroactively.
+func TestFreezerCompressionRetained(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := newFreezer(dir, "", FreezerConfig{NoSnappy: map[string]bool{freezerReceiptTable: true}})
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	if err := f.AppendAncient(0, []byte{0x01}, []byte{0x02}, []byte{0x03}, []byte{0x04}, []byte{0x05}); err != nil {
+		t.Fatalf("failed to append ancient: %v", err)
+	}
+	f.Close()
+
+	// Requesting a different compression for the existing table must fail
+	if _, err := newFreezer(dir, "", FreezerConfig{NoSnappy: map[string]bool{freezerReceiptTable: false}}); err == nil {
+		t.Fatalf("changed compression of existing table")
+	}
+	// Requesting the same one is fine, and so is not requesting any
+	f, err = newFreezer(dir, "", FreezerConfig{NoSnappy: map[string]bool{freezerReceiptTable: true}})
+	if err != nil {
+		t.Fatalf("failed to reopen freezer with matching compression: %v", err)
+	}
+	f.Close()
+
+	f, err = newFreezer(dir, "", FreezerConfig{})
+	if err != nil {
+		t.Fatalf("failed to reopen freezer: %v", err)
+	}
+	defer f.Close()
+
+	if !f.tables[freezerReceiptTable].noCompression {
+		t.Errorf("receipt table compression changed")
+	}
+	if frozen, _ := f.Ancients(); frozen != 1 {
+		t.Errorf("ancients mismatch: have %d, want %d", frozen, 1)
+	}
+	if blob, err := f.Ancient(freezerReceiptTable, 0); err != nil || len(blob) != 1 || blob[0] != 0x04 {
+		t.Errorf("receipt mismatch: have %x (err %v), want %x", blob, err, []byte{0x04})
+	}
+	if tail, err := f.AncientTail(freezerBodiesTable); err != nil || tail != 0 {
+		t.Errorf("body tail mismatch: have %d (err %v), want %d", tail, err, 0)
+	}
+}
+
+// Tests that a read-only freezer can be opened next to its writer, rejects any
+// modifications and picks up the newly frozen items.
+func TestFreezerReadOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A read-only freezer can't be created from scratch, nor pruned
+	if _, err := newFreezer(dir, "", FreezerConfig{ReadOnly: true}); err == nil {
+		t.Fatalf("opened missing read-only freezer")
+	}
+	writer, err := newFreezer(dir, "", FreezerConfig{})
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	defer writer.Close()
+
+	if _, err := newFreezer(dir, "", FreezerConfig{ReadOnly: true, Retention: map[string]uint64{freezerBodiesTable: 1}}); err == nil {
+		t.Fatalf("opened read-only freezer with retention")
+	}
+	for i := byte(0); i < 2; i++ {
+		if err := writer.AppendAncient(uint64(i), []byte{i}, []byte{i}, []byte{i}, []byte{i}, []byte{i}); err != nil {
+			t.Fatalf("failed to append ancient: %v", err)
+		}
+	}
+	writer.Sync()
+
+	reader, err := newFreezer(dir, "", FreezerConfig{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("failed to open read-only freezer: %v", err)
+	}
+	defer reader.Close()
+
+	if frozen, _ := reader.Ancients(); frozen != 2 {
+		t.Fatalf("ancients mismatch: have %d, want %d", frozen, 2)
+	}
+	if err := reader.AppendAncient(2, []byte{2}, []byte{2}, []byte{2}, []byte{2}, []byte{2}); err != errReadOnly {
+		t.Fatalf("append error mismatch: have %v, want %v", err, errReadOnly)
+	}
+	if err := reader.TruncateAncients(0); err != errReadOnly {
+		t.Fatalf("truncate error mismatch: have %v, want %v", err, errReadOnly)
+	}
+	// Newly frozen items should show up after a refresh
+	if err := writer.AppendAncient(2, []byte{2}, []byte{2}, []byte{2}, []byte{2}, []byte{2}); err != nil {
+		t.Fatalf("failed to append ancient: %v", err)
+	}
+	writer.Sync()
+
+	if err := reader.refresh(); err != nil {
+		t.Fatalf("failed to refresh read-only freezer: %v", err)
+	}
+	if frozen, _ := reader.Ancients(); frozen != 3 {
+		t.Fatalf("ancients mismatch: have %d, want %d", frozen, 3)
+	}
+	if blob, err := reader.Ancient(freezerBodiesTable, 2); err != nil || len(blob) != 1 || blob[0] != 2 {
+		t.Fatalf("body mismatch: have %x (err %v), want %x", blob, err, []byte{2})
+	}
+}
+
+// Tests that a read-only freezer deletes the blocks frozen by the writer of the
+// shared store from the local key-value database, except for the most recent ones.
+func TestFreezerReadOnlyWipe(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writer, err := newFreezer(dir, "", FreezerConfig{})
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	defer writer.Close()
+
+	// Create a local chain of 5 blocks, the first 3 of which are shared
+	db := memorydb.New()
+	var hashes []common.Hash
+	for i := 0; i < 5; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Extra: []byte("test")}
+		WriteHeader(db, header)
+		WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+		hashes = append(hashes, header.Hash())
+
+		if i < 3 {
+			if err := writer.AppendAncient(uint64(i), header.Hash().Bytes(), []byte{0x01}, []byte{0x02}, []byte{0x03}, []byte{0x04}); err != nil {
+				t.Fatalf("failed to append ancient: %v", err)
+			}
+		}
+	}
+	writer.Sync()
+	WriteHeadHeaderHash(db, hashes[4])
+
+	reader, err := newFreezer(dir, "", FreezerConfig{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("failed to open read-only freezer: %v", err)
+	}
+	defer reader.Close()
+
+	// The most recently frozen block should be retained locally
+	defer func(margin uint64) { freezerWipeMargin = margin }(freezerWipeMargin)
+	freezerWipeMargin = 1
+
+	if next := reader.wipe(db, 1); next != 2 {
+		t.Fatalf("wipe progress mismatch: have %d, want %d", next, 2)
+	}
+	nfdb := &nofreezedb{KeyValueStore: db}
+	for i, hash := range hashes {
+		if have := ReadCanonicalHash(nfdb, uint64(i)); (i == 0 || i >= 2) != (have == hash) {
+			t.Errorf("block %d: canonical hash presence mismatch: have %x", i, have)
+		}
+		if number := ReadHeaderNumber(nfdb, hash); number == nil || *number != uint64(i) {
+			t.Errorf("block %d: hash to number mapping lost", i)
+		}
+	}
+	// Nothing else should be wiped until more blocks are frozen
+	if next := reader.wipe(db, 2); next != 2 {
+		t.Fatalf("wipe progress mismatch: have %d, want %d", next, 2)
+	}
+}
+
+// failingDatabase is a key-value store whose batches are full after every write,
+// with all but the given number of batch writes failing.
+type failingDatabase struct {
+	ethdb.KeyValueStore
+	writes int
+}
+
+func (db *failingDatabase) NewBatch() ethdb.Batch {
+	return &failingBatch{Batch: db.KeyValueStore.NewBatch(), db: db}
+}
+
+type failingBatch struct {
+	ethdb.Batch
+	db *failingDatabase
+}
+
+func (b *failingBatch) ValueSize() int {
+	if b.Batch.ValueSize() > 0 {
+		return ethdb.IdealBatchSize
+	}
+	return 0
+}
+
+func (b *failingBatch) Write() error {
+	if b.db.writes == 0 {
+		return errors.New("write failed")
+	}
+	b.db.writes--
+	return b.Batch.Write()
+}
+
+// Tests that a failed wipe of the shared ancient blocks reports the progress
+// actually persisted, so the remaining blocks are wiped later on.
+func TestFreezerReadOnlyWipeFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writer, err := newFreezer(dir, "", FreezerConfig{})
+	if err != nil {
+		t.Fatalf("failed to open freezer: %v", err)
+	}
+	defer writer.Close()
+
+	// Create a local chain of 5 blocks, all of which are shared
+	db := memorydb.New()
+	var hashes []common.Hash
+	for i := 0; i < 5; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Extra: []byte("test")}
+		WriteHeader(db, header)
+		WriteCanonicalHash(db, header.Hash(), header.Number.Uint64())
+		hashes = append(hashes, header.Hash())
+
+		if err := writer.AppendAncient(uint64(i), header.Hash().Bytes(), []byte{0x01}, []byte{0x02}, []byte{0x03}, []byte{0x04}); err != nil {
+			t.Fatalf("failed to append ancient: %v", err)
+		}
+	}
+	writer.Sync()
+
+	reader, err := newFreezer(dir, "", FreezerConfig{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("failed to open read-only freezer: %v", err)
+	}
+	defer reader.Close()
+
+	defer func(margin uint64) { freezerWipeMargin = margin }(freezerWipeMargin)
+	freezerWipeMargin = 1
+
+	// Only the deletion of the first block is persisted
+	if next := reader.wipe(&failingDatabase{KeyValueStore: db, writes: 1}, 1); next != 2 {
+		t.Fatalf("wipe progress mismatch: have %d, want %d", next, 2)
+	}
+	nfdb := &nofreezedb{KeyValueStore: db}
+	for i, hash := range hashes {
+		if have := ReadCanonicalHash(nfdb, uint64(i)); (i != 1) != (have == hash) {
+			t.Errorf("block %d: canonical hash presence mismatch: have %x", i, have)
+		}
+	}
+	// The remaining blocks should be wiped on the next attempt
+	if next := reader.wipe(db, 2); next != 4 {
+		t.Fatalf("wipe progress mismatch: have %d, want %d", next, 4)
+	}
+}
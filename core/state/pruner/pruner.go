@@ -0,0 +1,358 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements the offline pruning of stale state trie nodes.
+package pruner
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// stateBloomFilePrefix is the filename prefix of state bloom filter.
+	stateBloomFilePrefix = "statebloom"
+
+	// stateBloomFileSuffix is the filename suffix of state bloom filter.
+	stateBloomFileSuffix = "bf.gz"
+
+	// stateBloomFileTempSuffix is the filename suffix of state bloom filter
+	// while it is being written out to detect write aborts.
+	stateBloomFileTempSuffix = ".tmp"
+
+	// rangeCompactionThreshold is the minimal deleted entry number for
+	// triggering range compaction. It's a quite arbitrary number but just
+	// to avoid triggering range compaction because of small deletion.
+	rangeCompactionThreshold = 100000
+)
+
+// errStateIncomplete is returned if the state of the pruning target is not
+// fully available in the database, in which case nothing can be pruned.
+var errStateIncomplete = errors.New("state incomplete")
+
+// Pruner is an offline tool to prune the stale state with the help of a bloom
+// filter. The live state of the target block (and the genesis) is walked and
+// recorded in the bloom, after which every trie node and contract code in the
+// database which is not in the bloom is deleted.
+//
+// The pruning can only be run while the node is stopped, as the database is
+// assumed not to change underneath. If it's interrupted during the deletion
+// phase, the persisted bloom allows resuming it on the next run.
+type Pruner struct {
+	db         ethdb.Database
+	stateBloom *stateBloom
+	datadir    string
+	headHeader *types.Header
+}
+
+// NewPruner creates the pruner instance.
+func NewPruner(db ethdb.Database, datadir string, bloomSize uint64) (*Pruner, error) {
+	headBlockHash := rawdb.ReadHeadBlockHash(db)
+	if headBlockHash == (common.Hash{}) {
+		return nil, errors.New("failed to load head block")
+	}
+	var headHeader *types.Header
+	if number := rawdb.ReadHeaderNumber(db, headBlockHash); number != nil {
+		headHeader = rawdb.ReadHeader(db, headBlockHash, *number)
+	}
+	if headHeader == nil {
+		return nil, errors.New("failed to load head header")
+	}
+	// Sanitize the bloom filter size if it's too small.
+	if bloomSize < 256 {
+		log.Warn("Sanitizing bloomfilter size", "provided(MB)", bloomSize, "updated(MB)", 256)
+		bloomSize = 256
+	}
+	stateBloom, err := newStateBloomWithSize(bloomSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Pruner{
+		db:         db,
+		stateBloom: stateBloom,
+		datadir:    datadir,
+		headHeader: headHeader,
+	}, nil
+}
+
+// Prune deletes all historical state nodes except the nodes belong to the
+// specified state root. If the root is empty, the state of the most recent
+// canonical block which is fully present in the database is retained.
+//
+// The chosen root must belong to a canonical block, as the chain will rewind
+// its head to that block on the next startup.
+func (p *Pruner) Prune(root common.Hash) error {
+	// If the state bloom filter is already committed previously, reuse it for
+	// pruning instead of generating a new one. It's mandatory because a part of
+	// state may already be deleted, the recovery procedure is necessary.
+	if bloomPath, _, err := findBloomFilter(p.datadir); err != nil {
+		return err
+	} else if bloomPath != "" {
+		return RecoverPruning(p.datadir, p.db)
+	}
+	// Resolve the target block, making sure it is part of the canonical chain
+	var target *types.Header
+	for header := p.headHeader; header != nil; header = rawdb.ReadHeader(p.db, header.ParentHash, header.Number.Uint64()-1) {
+		if root == (common.Hash{}) {
+			if ok, _ := p.db.Has(header.Root.Bytes()); ok {
+				target = header
+				break
+			}
+		} else if header.Root == root {
+			target = header
+			break
+		}
+		if header.Number.Uint64() == 0 {
+			break
+		}
+	}
+	switch {
+	case target == nil && root == (common.Hash{}):
+		return errors.New("no canonical block with state available")
+	case target == nil:
+		return fmt.Errorf("state root %x is not part of the canonical chain", root)
+	}
+	if target.Number.Cmp(p.headHeader.Number) < 0 {
+		log.Warn("Pruning to a historical state, chain head will be rewound", "head", p.headHeader.Number, "target", target.Number)
+	}
+	log.Info("Selected state for pruning", "number", target.Number, "hash", target.Hash(), "root", target.Root)
+
+	// Traverse the target state and the genesis state, recording all the live
+	// entries into the bloom. If any part of the target state is missing, abort
+	// before anything is deleted.
+	start := time.Now()
+	if err := extractState(p.db, target.Root, p.stateBloom); err != nil {
+		return err
+	}
+	genesisHash := rawdb.ReadCanonicalHash(p.db, 0)
+	if genesis := rawdb.ReadHeader(p.db, genesisHash, 0); genesis != nil && genesis.Root != target.Root {
+		if err := extractState(p.db, genesis.Root, p.stateBloom); err != nil {
+			log.Warn("Genesis state incomplete, skipping", "root", genesis.Root, "err", err)
+		}
+	}
+	// Persist the bloom filter to mark the beginning of the deletion phase. From
+	// here on the pruning must run to completion, resuming on the next startup
+	// if it's interrupted.
+	filterName := bloomFilterName(p.datadir, target.Root)
+
+	log.Info("Writing state bloom to disk", "name", filterName)
+	if err := p.stateBloom.Commit(filterName, filterName+stateBloomFileTempSuffix); err != nil {
+		return err
+	}
+	log.Info("State bloom filter committed", "name", filterName, "elapsed", common.PrettyDuration(time.Since(start)))
+	return prune(p.db, p.stateBloom, filterName, target.Root, start)
+}
+
+// RecoverPruning resumes an interrupted pruning if a committed state bloom is
+// found in the data directory. It must be called before the database is used
+// by anything else: once the bloom is committed, the pruning has to run to
+// completion, otherwise any state written in the meantime would be missing
+// from the bloom and deleted when the pruning is eventually resumed.
+func RecoverPruning(datadir string, db ethdb.Database) error {
+	stateBloomPath, stateBloomRoot, err := findBloomFilter(datadir)
+	if err != nil {
+		return err
+	}
+	if stateBloomPath == "" {
+		return nil // nothing to recover
+	}
+	stateBloom, err := newStateBloomFromDisk(stateBloomPath)
+	if err != nil {
+		return err
+	}
+	log.Info("Loaded state bloom filter", "path", stateBloomPath)
+	log.Info("Resuming state pruning", "root", stateBloomRoot)
+
+	return prune(db, stateBloom, stateBloomPath, stateBloomRoot, time.Now())
+}
+
+// extractState iterates over the entire state at the given root, including all
+// contract code and storage tries, adding every entry into the bloom.
+func extractState(db ethdb.Database, root common.Hash, bloom *stateBloom) error {
+	statedb, err := state.New(root, state.NewDatabase(db), nil)
+	if err != nil {
+		return fmt.Errorf("%v: %v", errStateIncomplete, err)
+	}
+	var (
+		nodes  int
+		start  = time.Now()
+		logged = time.Now()
+	)
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		if it.Hash == (common.Hash{}) {
+			continue // Embedded node, stored within its parent
+		}
+		bloom.Put(it.Hash.Bytes(), nil)
+		nodes++
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Traversing state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if it.Error != nil {
+		return fmt.Errorf("%v: %v", errStateIncomplete, it.Error)
+	}
+	log.Info("Traversed state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// prune deletes every trie node and contract code not contained in the bloom,
+// compacts the database and finally removes the bloom filter file.
+func prune(db ethdb.Database, bloom *stateBloom, bloomPath string, root common.Hash, start time.Time) error {
+	// Delete all stale trie nodes and contract codes in the disk. The key
+	// space is iterated in full, as the entries can't be located by prefix.
+	var (
+		count  int
+		size   common.StorageSize
+		pstart = time.Now()
+		logged = time.Now()
+		batch  = db.NewBatch()
+		iter   = db.NewIterator()
+	)
+	for iter.Next() {
+		key := iter.Key()
+
+		// Trie nodes and contract codes are the only 32 byte keys, delete all
+		// of them which don't belong to the retained states
+		if len(key) != common.HashLength || bloom.Contain(key) {
+			continue
+		}
+		size += common.StorageSize(len(key) + len(iter.Value()))
+		batch.Delete(key)
+
+		count++
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				iter.Release()
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			var (
+				done = binary.BigEndian.Uint64(key[:8])
+				eta  time.Duration
+			)
+			if done > 0 {
+				speed := float64(done) / float64(time.Since(pstart)/time.Millisecond+1)
+				eta = time.Duration(float64(math.MaxUint64-done)/speed) * time.Millisecond
+			}
+			log.Info("Pruning state data", "nodes", count, "size", size,
+				"elapsed", common.PrettyDuration(time.Since(pstart)), "eta", common.PrettyDuration(eta))
+			logged = time.Now()
+		}
+	}
+	err := iter.Error()
+	iter.Release()
+	if err != nil {
+		return err
+	}
+	if batch.ValueSize() > 0 {
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	log.Info("Pruned state data", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(pstart)))
+
+	// The snapshot generator walks the tries of its disk layer, which might
+	// be gone now. Drop any snapshot of a different state, it's rebuilt from
+	// the retained state on startup.
+	if snapRoot := rawdb.ReadSnapshotRoot(db); snapRoot != (common.Hash{}) && snapRoot != root {
+		log.Info("Dropping state snapshot for regeneration", "root", snapRoot)
+		rawdb.DeleteSnapshotRoot(db)
+	}
+	// The deletion is done, the bloom is not needed for recovery anymore
+	os.RemoveAll(bloomPath)
+
+	// Start compactions, will remove the deleted data from the disk immediately.
+	// Note for small pruning, the compaction is skipped.
+	if count >= rangeCompactionThreshold {
+		cstart := time.Now()
+		for b := 0x00; b <= 0xf0; b += 0x10 {
+			var (
+				start = []byte{byte(b)}
+				end   = []byte{byte(b + 0x10)}
+			)
+			if b == 0xf0 {
+				end = nil
+			}
+			log.Info("Compacting database", "range", fmt.Sprintf("%#x-%#x", start, end), "elapsed", common.PrettyDuration(time.Since(cstart)))
+			if err := db.Compact(start, end); err != nil {
+				log.Error("Database compaction failed", "error", err)
+				return err
+			}
+		}
+		log.Info("Database compaction finished", "elapsed", common.PrettyDuration(time.Since(cstart)))
+	}
+	log.Info("State pruning successful", "pruned", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// findBloomFilter looks for a persisted state bloom filter in the data
+// directory, returning its path and the state root it was built for.
+func findBloomFilter(datadir string) (string, common.Hash, error) {
+	files, err := ioutil.ReadDir(datadir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", common.Hash{}, nil
+		}
+		return "", common.Hash{}, err
+	}
+	for _, file := range files {
+		if file.IsDir() || !isBloomFilter(file.Name()) {
+			continue
+		}
+		if ok, root := parseBloomFilterName(file.Name()); ok {
+			return filepath.Join(datadir, file.Name()), root, nil
+		}
+	}
+	return "", common.Hash{}, nil
+}
+
+// bloomFilterName returns the path of the bloom filter built for a state root.
+func bloomFilterName(datadir string, hash common.Hash) string {
+	return filepath.Join(datadir, fmt.Sprintf("%s.%s.%s", stateBloomFilePrefix, hash.Hex(), stateBloomFileSuffix))
+}
+
+// isBloomFilter reports whether a file name is a completely written bloom.
+func isBloomFilter(filename string) bool {
+	return strings.HasPrefix(filename, stateBloomFilePrefix) && strings.HasSuffix(filename, stateBloomFileSuffix)
+}
+
+// parseBloomFilterName extracts the state root from a bloom filter file name.
+func parseBloomFilterName(filename string) (bool, common.Hash) {
+	parts := strings.Split(filename, ".")
+	if len(parts) != 4 || !strings.HasPrefix(parts[1], "0x") {
+		return false, common.Hash{}
+	}
+	return true, common.HexToHash(parts[1])
+}
@@ -0,0 +1,200 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newTestChain creates an archive chain with a number of blocks, each of them
+// modifying the state, and returns the database along with the blocks.
+func newTestChain(t *testing.T, n int) (ethdb.Database, *types.Block, []*types.Block) {
+	var (
+		key, _  = crypto.GenerateKey()
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				address:                           {Balance: big.NewInt(1000000000000000)},
+				common.HexToAddress("0xc0de"):     {Balance: big.NewInt(0), Code: []byte{0x60, 0x00}, Storage: map[common.Hash]common.Hash{{0x01}: {0x01}}},
+				common.HexToAddress("0xdeadbeef"): {Balance: big.NewInt(1)},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, n, func(i int, block *core.BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{byte(i + 1)}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		block.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	chain.Stop()
+
+	return db, genesis, blocks
+}
+
+// hasState reports whether the complete state of a root is available.
+func hasState(db ethdb.Database, root common.Hash) bool {
+	statedb, err := state.New(root, state.NewDatabase(db), nil)
+	if err != nil {
+		return false
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	return it.Error == nil
+}
+
+// Tests that pruning retains the state of the head and the genesis while
+// deleting the state of every other block.
+func TestPruneHeadState(t *testing.T) {
+	db, genesis, blocks := newTestChain(t, 8)
+
+	datadir, err := ioutil.TempDir("", "pruner-")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	for _, block := range blocks {
+		if !hasState(db, block.Root()) {
+			t.Fatalf("block %d: state missing before pruning", block.NumberU64())
+		}
+	}
+	pruner, err := NewPruner(db, datadir, 256)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if err := pruner.Prune(common.Hash{}); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	head := blocks[len(blocks)-1]
+	if !hasState(db, head.Root()) {
+		t.Errorf("head state pruned")
+	}
+	if !hasState(db, genesis.Root()) {
+		t.Errorf("genesis state pruned")
+	}
+	for _, block := range blocks[:len(blocks)-1] {
+		if ok, _ := db.Has(block.Root().Bytes()); ok {
+			t.Errorf("block %d: stale state root retained", block.NumberU64())
+		}
+	}
+	if path, _, _ := findBloomFilter(datadir); path != "" {
+		t.Errorf("state bloom not cleaned up: %s", path)
+	}
+}
+
+// Tests that pruning to a specific root retains that state, and that roots
+// outside of the canonical chain are rejected.
+func TestPruneTargetState(t *testing.T) {
+	db, _, blocks := newTestChain(t, 8)
+
+	datadir, err := ioutil.TempDir("", "pruner-")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	pruner, err := NewPruner(db, datadir, 256)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if err := pruner.Prune(common.HexToHash("0xdeadbeef")); err == nil {
+		t.Fatalf("pruned to non-canonical state root")
+	}
+	target := blocks[3]
+	if err := pruner.Prune(target.Root()); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	if !hasState(db, target.Root()) {
+		t.Errorf("target state pruned")
+	}
+	if ok, _ := db.Has(blocks[len(blocks)-1].Root().Bytes()); ok {
+		t.Errorf("head state root retained")
+	}
+}
+
+// Tests that an interrupted pruning is resumed from the persisted state bloom.
+func TestRecoverPruning(t *testing.T) {
+	db, genesis, blocks := newTestChain(t, 4)
+
+	datadir, err := ioutil.TempDir("", "pruner-")
+	if err != nil {
+		t.Fatalf("failed to create temporary datadir: %v", err)
+	}
+	defer os.RemoveAll(datadir)
+
+	// Nothing should happen without a state bloom
+	if err := RecoverPruning(datadir, db); err != nil {
+		t.Fatalf("failed to skip recovery: %v", err)
+	}
+	if ok, _ := db.Has(blocks[0].Root().Bytes()); !ok {
+		t.Fatalf("state pruned without a state bloom")
+	}
+	// Simulate a crash right after the bloom was committed
+	bloom, err := newStateBloomWithSize(256)
+	if err != nil {
+		t.Fatalf("failed to create state bloom: %v", err)
+	}
+	head := blocks[len(blocks)-1]
+	for _, root := range []common.Hash{head.Root(), genesis.Root()} {
+		if err := extractState(db, root, bloom); err != nil {
+			t.Fatalf("failed to extract state: %v", err)
+		}
+	}
+	name := bloomFilterName(datadir, head.Root())
+	if err := bloom.Commit(name, name+stateBloomFileTempSuffix); err != nil {
+		t.Fatalf("failed to commit state bloom: %v", err)
+	}
+	if err := RecoverPruning(datadir, db); err != nil {
+		t.Fatalf("failed to recover pruning: %v", err)
+	}
+	if !hasState(db, head.Root()) {
+		t.Errorf("head state pruned")
+	}
+	if ok, _ := db.Has(blocks[0].Root().Bytes()); ok {
+		t.Errorf("stale state root retained")
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Errorf("state bloom not cleaned up: %v", err)
+	}
+}
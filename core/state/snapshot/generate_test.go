@@ -0,0 +1,214 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// testAccount is the consensus representation of an account in the state trie.
+type testAccount struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+}
+
+// makeTestState creates a state trie with a number of accounts, some of them
+// holding storage, and returns the root along with the expected snapshot data.
+func makeTestState(t *testing.T, triedb *trie.Database) (common.Hash, map[common.Hash][]byte, map[common.Hash]map[common.Hash][]byte) {
+	accTrie, _ := trie.NewSecure(common.Hash{}, triedb)
+
+	var (
+		accounts = make(map[common.Hash][]byte)
+		storage  = make(map[common.Hash]map[common.Hash][]byte)
+	)
+	for i := byte(0); i < 50; i++ {
+		acc := testAccount{Nonce: uint64(i), Balance: big.NewInt(int64(i) * 1000), Root: emptyRoot, CodeHash: emptyCode[:]}
+		if i%5 == 0 {
+			stTrie, _ := trie.NewSecure(common.Hash{}, triedb)
+			slots := make(map[common.Hash][]byte)
+			for j := byte(1); j <= 10; j++ {
+				key := common.BytesToHash([]byte{i, j})
+				val, _ := rlp.EncodeToBytes([]byte{j})
+				stTrie.Update(key[:], val)
+				slots[crypto.Keccak256Hash(key[:])] = val
+			}
+			root, err := stTrie.Commit(nil)
+			if err != nil {
+				t.Fatalf("failed to commit storage trie: %v", err)
+			}
+			acc.Root = root
+			acc.CodeHash = randomHash().Bytes()
+			storage[crypto.Keccak256Hash([]byte{i})] = slots
+		}
+		blob, _ := rlp.EncodeToBytes(acc)
+		accTrie.Update([]byte{i}, blob)
+		accounts[crypto.Keccak256Hash([]byte{i})] = SlimAccountRLP(acc.Nonce, acc.Balance, acc.Root, acc.CodeHash)
+	}
+	root, err := accTrie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit account trie: %v", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to flush tries: %v", err)
+	}
+	return root, accounts, storage
+}
+
+// waitGeneration waits until the generator of a disk layer finishes.
+func waitGeneration(t *testing.T, dl *diskLayer) {
+	select {
+	case <-dl.genPending:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("snapshot generation timed out")
+	}
+}
+
+// countKeys counts the snapshot entries with the given prefix, skipping any
+// trie nodes which happen to share the prefix byte.
+func countKeys(db ethdb.Iteratee, prefix []byte, length int) int {
+	it := db.NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	var count int
+	for it.Next() {
+		if len(it.Key()) == len(prefix)+length {
+			count++
+		}
+	}
+	return count
+}
+
+// checkSnapshot verifies that the snapshot in the database matches the expected
+// accounts and storage slots exactly.
+func checkSnapshot(t *testing.T, db ethdb.KeyValueStore, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) {
+	t.Helper()
+
+	for hash, blob := range accounts {
+		if have := rawdb.ReadAccountSnapshot(db, hash); !bytes.Equal(have, blob) {
+			t.Errorf("account %x mismatch: have %x, want %x", hash, have, blob)
+		}
+	}
+	count := countKeys(db, rawdb.SnapshotAccountPrefix, common.HashLength)
+	if count != len(accounts) {
+		t.Errorf("account count mismatch: have %d, want %d", count, len(accounts))
+	}
+	count = 0
+	for accHash, slots := range storage {
+		for hash, blob := range slots {
+			if have := rawdb.ReadStorageSnapshot(db, accHash, hash); !bytes.Equal(have, blob) {
+				t.Errorf("slot %x:%x mismatch: have %x, want %x", accHash, hash, have, blob)
+			}
+		}
+		count += len(slots)
+	}
+	if have := countKeys(db, rawdb.SnapshotStoragePrefix, 2*common.HashLength); have != count {
+		t.Errorf("slot count mismatch: have %d, want %d", have, count)
+	}
+}
+
+// Tests that a snapshot is generated from the state tries, wiping any leftover
+// entries of a previous snapshot.
+func TestGeneration(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		triedb = trie.NewDatabase(db)
+	)
+	root, accounts, storage := makeTestState(t, triedb)
+
+	// Insert some junk to ensure it gets wiped
+	rawdb.WriteAccountSnapshot(db, randomHash(), randomAccount())
+	rawdb.WriteStorageSnapshot(db, randomHash(), randomHash(), []byte{0x01})
+
+	snaps := New(db, triedb, 16, root)
+	dl := snaps.layers[root].(*diskLayer)
+	waitGeneration(t, dl)
+
+	checkSnapshot(t, db, accounts, storage)
+	for hash, blob := range accounts {
+		if have, err := snaps.Snapshot(root).AccountRLP(hash); err != nil || !bytes.Equal(have, blob) {
+			t.Errorf("account %x mismatch: have %x (err %v), want %x", hash, have, err, blob)
+		}
+	}
+	// Journal and reload the generated snapshot, it should be considered complete
+	if _, err := snaps.Journal(root); err != nil {
+		t.Fatalf("failed to journal snapshot: %v", err)
+	}
+	head, err := loadSnapshot(db, triedb, 16, root)
+	if err != nil {
+		t.Fatalf("failed to load generated snapshot: %v", err)
+	}
+	if marker := head.(*diskLayer).genMarker; marker != nil {
+		t.Errorf("loaded snapshot still generating at %x", marker)
+	}
+}
+
+// Tests that an interrupted generation is resumed from its persisted marker
+// and that reads beyond the marker are reported as not yet covered.
+func TestGenerationResume(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		triedb = trie.NewDatabase(db)
+	)
+	root, accounts, storage := makeTestState(t, triedb)
+
+	// Pick a marker mid-way and persist only the accounts preceding it, as if
+	// the generator was interrupted there
+	hashes := make([]common.Hash, 0, len(accounts))
+	for hash := range accounts {
+		hashes = append(hashes, hash)
+	}
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+	marker := hashes[len(hashes)/2]
+
+	for _, hash := range hashes[:len(hashes)/2+1] {
+		rawdb.WriteAccountSnapshot(db, hash, accounts[hash])
+		for slot, val := range storage[hash] {
+			rawdb.WriteStorageSnapshot(db, hash, slot, val)
+		}
+	}
+	rawdb.WriteSnapshotRoot(db, root)
+	journalProgress(db, marker[:], &generatorStats{})
+
+	// Reads beyond the marker must be rejected until generation catches up
+	dl := &diskLayer{diskdb: db, triedb: triedb, cache: newCleanCache(16), root: root, genMarker: marker[:]}
+	if _, err := dl.AccountRLP(hashes[len(hashes)-1]); err != ErrNotCoveredYet {
+		t.Errorf("uncovered account read error mismatch: have %v, want %v", err, ErrNotCoveredYet)
+	}
+	if _, err := dl.AccountRLP(marker); err != nil {
+		t.Errorf("covered account read failed: %v", err)
+	}
+	// Load the snapshot, the generator should resume and complete it
+	head, err := loadSnapshot(db, triedb, 16, root)
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	waitGeneration(t, head.(*diskLayer))
+	checkSnapshot(t, db, accounts, storage)
+}
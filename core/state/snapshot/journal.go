@@ -0,0 +1,215 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// journalVersion is the version of the diff layer journal format. Journals of
+// a different version are discarded and the snapshot is regenerated.
+const journalVersion uint64 = 1
+
+// journalSnapshot is the persisted form of the in-memory diff layers, along with
+// the root of the disk layer they were stacked upon.
+type journalSnapshot struct {
+	Version uint64
+	Root    common.Hash
+	Layers  []journalLayer
+}
+
+// journalLayer is a diff layer entry, ordered bottom-up within the journal.
+type journalLayer struct {
+	Root      common.Hash
+	Destructs []common.Hash
+	Accounts  []journalAccount
+	Storage   []journalStorage
+}
+
+// journalAccount is an account entry in a diffLayer's disk journal.
+type journalAccount struct {
+	Hash common.Hash
+	Blob []byte
+}
+
+// journalStorage is an account's storage map in a diffLayer's disk journal.
+type journalStorage struct {
+	Hash common.Hash
+	Keys []common.Hash
+	Vals [][]byte
+}
+
+// encodeJournal serializes the diff layer journal.
+func encodeJournal(journal *journalSnapshot) ([]byte, error) {
+	return rlp.EncodeToBytes(journal)
+}
+
+// loadSnapshot loads a pre-existing state snapshot backed by a key-value store.
+func loadSnapshot(diskdb ethdb.KeyValueStore, triedb *trie.Database, cache int, root common.Hash) (snapshot, error) {
+	// Retrieve the block number and hash of the snapshot, failing if no snapshot
+	// is present in the database (or crashed mid-update).
+	baseRoot := rawdb.ReadSnapshotRoot(diskdb)
+	if baseRoot == (common.Hash{}) {
+		return nil, errors.New("missing or corrupted snapshot")
+	}
+	base := &diskLayer{
+		diskdb: diskdb,
+		triedb: triedb,
+		cache:  newCleanCache(cache),
+		root:   baseRoot,
+	}
+	// Retrieve the generator progress, failing if it was lost
+	var generator journalGenerator
+	blob := rawdb.ReadSnapshotGenerator(diskdb)
+	if len(blob) == 0 {
+		return nil, errors.New("missing snapshot generator")
+	}
+	if err := rlp.DecodeBytes(blob, &generator); err != nil {
+		return nil, fmt.Errorf("failed to load snapshot generator: %v", err)
+	}
+	if generator.Wiping {
+		return nil, errors.New("snapshot wipe interrupted")
+	}
+	if !generator.Done {
+		base.genMarker = generator.Marker
+		if base.genMarker == nil {
+			base.genMarker = []byte{}
+		}
+	}
+	// Load all the snapshot diffs from the journal
+	var snap snapshot = base
+	if blob := rawdb.ReadSnapshotJournal(diskdb); len(blob) > 0 {
+		var journal journalSnapshot
+		if err := rlp.DecodeBytes(blob, &journal); err != nil {
+			return nil, fmt.Errorf("failed to load snapshot journal: %v", err)
+		}
+		if journal.Version != journalVersion {
+			return nil, fmt.Errorf("journal version mismatch: have %d, want %d", journal.Version, journalVersion)
+		}
+		// The journal is only usable if it's stacked on the current disk layer.
+		// Otherwise the disk layer has progressed since and the diffs are lost.
+		if journal.Root == baseRoot {
+			for _, layer := range journal.Layers {
+				snap = layer.load(snap)
+			}
+		}
+	}
+	// Entire snapshot journal loaded, sanity check the head and return
+	if head := snap.Root(); head != root {
+		return nil, fmt.Errorf("head doesn't match snapshot: have %#x, want %#x", head, root)
+	}
+	// Everything loaded correctly, resume any suspended operations
+	if base.genMarker != nil {
+		stats := &generatorStats{
+			start:    time.Now(),
+			accounts: generator.Accounts,
+			slots:    generator.Slots,
+			storage:  common.StorageSize(generator.Storage),
+		}
+		if len(base.genMarker) >= 8 {
+			stats.origin = binary.BigEndian.Uint64(base.genMarker)
+		}
+		base.genPending = make(chan struct{})
+		base.genAbort = make(chan chan *generatorStats)
+
+		go base.generate(stats)
+	}
+	return snap, nil
+}
+
+// load recreates the diff layer from its journal entry on top of a parent.
+func (entry *journalLayer) load(parent snapshot) snapshot {
+	destructs := make(map[common.Hash]struct{}, len(entry.Destructs))
+	for _, hash := range entry.Destructs {
+		destructs[hash] = struct{}{}
+	}
+	accounts := make(map[common.Hash][]byte, len(entry.Accounts))
+	for _, account := range entry.Accounts {
+		accounts[account.Hash] = account.Blob
+	}
+	storage := make(map[common.Hash]map[common.Hash][]byte, len(entry.Storage))
+	for _, entry := range entry.Storage {
+		slots := make(map[common.Hash][]byte, len(entry.Keys))
+		for i, key := range entry.Keys {
+			slots[key] = entry.Vals[i]
+		}
+		storage[entry.Hash] = slots
+	}
+	return newDiffLayer(parent, entry.Root, destructs, accounts, storage)
+}
+
+// journal stops any running generator and persists its progress, so that it
+// can be resumed after the snapshot is loaded again.
+func (dl *diskLayer) journal() error {
+	// If the snapshot is currently being generated, abort it
+	var stats *generatorStats
+	if dl.genAbort != nil {
+		abort := make(chan *generatorStats)
+		dl.genAbort <- abort
+
+		if stats = <-abort; stats != nil {
+			stats.Log("Journalling in-progress snapshot", dl.root, dl.genMarker)
+		}
+		dl.genAbort = nil
+	}
+	// Ensure the layer didn't get stale
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return ErrSnapshotStale
+	}
+	// Persist the generator progress, the marker being nil if done
+	journalProgress(dl.diskdb, dl.genMarker, stats)
+	return nil
+}
+
+// journal creates the journal entry of a diff layer.
+func (dl *diffLayer) journal() (journalLayer, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	// Ensure the layer didn't get stale
+	if dl.Stale() {
+		return journalLayer{}, ErrSnapshotStale
+	}
+	entry := journalLayer{Root: dl.root}
+	for hash := range dl.destructSet {
+		entry.Destructs = append(entry.Destructs, hash)
+	}
+	for hash, blob := range dl.accountData {
+		entry.Accounts = append(entry.Accounts, journalAccount{Hash: hash, Blob: blob})
+	}
+	for hash, slots := range dl.storageData {
+		storage := journalStorage{Hash: hash}
+		for key, val := range slots {
+			storage.Keys = append(storage.Keys, key)
+			storage.Vals = append(storage.Vals, val)
+		}
+		entry.Storage = append(entry.Storage, storage)
+	}
+	return entry, nil
+}
@@ -0,0 +1,287 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// randomHash generates a random blob of data and returns it as a hash.
+func randomHash() common.Hash {
+	var hash common.Hash
+	if n, err := rand.Read(hash[:]); n != common.HashLength || err != nil {
+		panic(err)
+	}
+	return hash
+}
+
+// randomAccount generates a random account and returns it RLP encoded.
+func randomAccount() []byte {
+	return SlimAccountRLP(rand.Uint64(), big.NewInt(rand.Int63()), randomHash(), randomHash().Bytes())
+}
+
+// newTestTree creates a snapshot tree with an empty disk layer at the given root.
+func newTestTree(root common.Hash) *Tree {
+	db := rawdb.NewMemoryDatabase()
+	rawdb.WriteSnapshotRoot(db, root)
+	journalProgress(db, nil, nil)
+
+	return &Tree{
+		diskdb: db,
+		triedb: trie.NewDatabase(db),
+		layers: map[common.Hash]snapshot{
+			root: &diskLayer{diskdb: db, cache: newCleanCache(16), root: root},
+		},
+	}
+}
+
+// Tests that if a disk layer becomes stale, no active external references will
+// be returned with junk data. This version of the test flattens every diff layer
+// to check internal corner case around the bottom-most memory accumulator.
+func TestDiskLayerExternalInvalidationFullFlatten(t *testing.T) {
+	snaps := newTestTree(common.HexToHash("0x01"))
+
+	// Retrieve a reference to the base and commit a diff on top
+	ref := snaps.Snapshot(common.HexToHash("0x01"))
+
+	accounts := map[common.Hash][]byte{common.HexToHash("0xa1"): randomAccount()}
+	if err := snaps.Update(common.HexToHash("0x02"), common.HexToHash("0x01"), nil, accounts, nil); err != nil {
+		t.Fatalf("failed to create a diff layer: %v", err)
+	}
+	if n := len(snaps.layers); n != 2 {
+		t.Errorf("pre-cap layer count mismatch: have %d, want %d", n, 2)
+	}
+	// Commit the diff layer onto the disk and ensure it's persisted
+	if err := snaps.Cap(common.HexToHash("0x02"), 0); err != nil {
+		t.Fatalf("failed to merge diff layer onto disk: %v", err)
+	}
+	// Since the base layer was modified, ensure that data retrievals on the external reference fail
+	if acc, err := ref.Account(common.HexToHash("0x01")); err != ErrSnapshotStale {
+		t.Errorf("stale reference returned account: %#x (err: %v)", acc, err)
+	}
+	if slot, err := ref.Storage(common.HexToHash("0xa1"), common.HexToHash("0xb1")); err != ErrSnapshotStale {
+		t.Errorf("stale reference returned storage slot: %#x (err: %v)", slot, err)
+	}
+	if n := len(snaps.layers); n != 1 {
+		t.Errorf("post-cap layer count mismatch: have %d, want %d", n, 1)
+	}
+	// The merged data should be readable from the new disk layer and the database
+	if blob := rawdb.ReadAccountSnapshot(snaps.diskdb, common.HexToHash("0xa1")); !bytes.Equal(blob, accounts[common.HexToHash("0xa1")]) {
+		t.Errorf("persisted account mismatch: have %x, want %x", blob, accounts[common.HexToHash("0xa1")])
+	}
+	if root := rawdb.ReadSnapshotRoot(snaps.diskdb); root != common.HexToHash("0x02") {
+		t.Errorf("persisted root mismatch: have %x, want %x", root, common.HexToHash("0x02"))
+	}
+}
+
+// Tests that if a diff layer becomes stale, no active external references will
+// be returned with junk data. This version of the test retains the bottom diff
+// layer to check the usual mode of operation where the accumulator is retained.
+func TestDiffLayerExternalInvalidationPartialFlatten(t *testing.T) {
+	snaps := newTestTree(common.HexToHash("0x01"))
+
+	// Commit three diffs on top and retrieve a reference to the bottom one
+	for i := 2; i <= 4; i++ {
+		accounts := map[common.Hash][]byte{common.HexToHash("0xa1"): randomAccount()}
+		if err := snaps.Update(common.BigToHash(big.NewInt(int64(i))), common.BigToHash(big.NewInt(int64(i-1))), nil, accounts, nil); err != nil {
+			t.Fatalf("failed to create diff layer %d: %v", i, err)
+		}
+	}
+	if n := len(snaps.layers); n != 4 {
+		t.Errorf("pre-cap layer count mismatch: have %d, want %d", n, 4)
+	}
+	ref := snaps.Snapshot(common.HexToHash("0x02"))
+
+	// Doing a Cap operation with many allowed layers should be a no-op
+	exp := len(snaps.layers)
+	if err := snaps.Cap(common.HexToHash("0x04"), 2000); err != nil {
+		t.Fatalf("failed to flatten diff layer into accumulator: %v", err)
+	}
+	if got := len(snaps.layers); got != exp {
+		t.Errorf("layers modified, got %d exp %d", got, exp)
+	}
+	// Flatten the diff layer into the bottom accumulator
+	if err := snaps.Cap(common.HexToHash("0x04"), 2); err != nil {
+		t.Fatalf("failed to flatten diff layer into accumulator: %v", err)
+	}
+	// Since the accumulator diff layer was modified, ensure that data retrievals on the external reference fail
+	if acc, err := ref.Account(common.HexToHash("0x01")); err != ErrSnapshotStale {
+		t.Errorf("stale reference returned account: %#x (err: %v)", acc, err)
+	}
+	if slot, err := ref.Storage(common.HexToHash("0xa1"), common.HexToHash("0xb1")); err != ErrSnapshotStale {
+		t.Errorf("stale reference returned storage slot: %#x (err: %v)", slot, err)
+	}
+	if n := len(snaps.layers); n != 3 {
+		t.Errorf("post-cap layer count mismatch: have %d, want %d", n, 3)
+	}
+}
+
+// Tests that sibling layers forking off a flattened diff are discarded, as the
+// chain progressed along a different branch.
+func TestCapDiscardsStaleBranches(t *testing.T) {
+	snaps := newTestTree(common.HexToHash("0x01"))
+
+	// Create a canonical chain 0x01 <- 0x02 <- 0x03 <- 0x04 and a side branch
+	// 0x02 <- 0x13 forking off the soon to be flattened layer
+	chain := []struct{ root, parent string }{
+		{"0x02", "0x01"}, {"0x03", "0x02"}, {"0x04", "0x03"}, {"0x13", "0x02"},
+	}
+	for _, link := range chain {
+		accounts := map[common.Hash][]byte{randomHash(): randomAccount()}
+		if err := snaps.Update(common.HexToHash(link.root), common.HexToHash(link.parent), nil, accounts, nil); err != nil {
+			t.Fatalf("failed to create diff layer %s: %v", link.root, err)
+		}
+	}
+	if err := snaps.Cap(common.HexToHash("0x04"), 2); err != nil {
+		t.Fatalf("failed to cap snapshot tree: %v", err)
+	}
+	if snaps.Snapshot(common.HexToHash("0x13")) != nil {
+		t.Errorf("stale side branch retained")
+	}
+	for _, root := range []string{"0x01", "0x03", "0x04"} {
+		if snaps.Snapshot(common.HexToHash(root)) == nil {
+			t.Errorf("canonical layer %s missing", root)
+		}
+	}
+}
+
+// Tests that account and storage destructions shadow the data of the parent
+// layers, both in place and after the layers are flattened onto disk.
+func TestDiffLayerDestructs(t *testing.T) {
+	var (
+		snaps   = newTestTree(common.HexToHash("0x01"))
+		account = common.HexToHash("0xa1")
+		slot    = common.HexToHash("0xb1")
+		other   = common.HexToHash("0xb2")
+	)
+	// Create an account with some storage and delete it in a later layer, then
+	// resurrect it with a different slot set
+	blob := randomAccount()
+	if err := snaps.Update(common.HexToHash("0x02"), common.HexToHash("0x01"), nil,
+		map[common.Hash][]byte{account: blob},
+		map[common.Hash]map[common.Hash][]byte{account: {slot: []byte{0x01}}}); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	if err := snaps.Update(common.HexToHash("0x03"), common.HexToHash("0x02"),
+		map[common.Hash]struct{}{account: {}}, nil, nil); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	if err := snaps.Update(common.HexToHash("0x04"), common.HexToHash("0x03"),
+		map[common.Hash]struct{}{account: {}},
+		map[common.Hash][]byte{account: blob},
+		map[common.Hash]map[common.Hash][]byte{account: {other: []byte{0x02}}}); err != nil {
+		t.Fatalf("failed to create diff layer: %v", err)
+	}
+	check := func(root string, acc []byte, slotVal, otherVal []byte) {
+		t.Helper()
+
+		snap := snaps.Snapshot(common.HexToHash(root))
+		if have, err := snap.AccountRLP(account); err != nil || !bytes.Equal(have, acc) {
+			t.Errorf("layer %s: account mismatch: have %x (err %v), want %x", root, have, err, acc)
+		}
+		if have, err := snap.Storage(account, slot); err != nil || !bytes.Equal(have, slotVal) {
+			t.Errorf("layer %s: slot mismatch: have %x (err %v), want %x", root, have, err, slotVal)
+		}
+		if have, err := snap.Storage(account, other); err != nil || !bytes.Equal(have, otherVal) {
+			t.Errorf("layer %s: other slot mismatch: have %x (err %v), want %x", root, have, err, otherVal)
+		}
+	}
+	check("0x02", blob, []byte{0x01}, nil)
+	check("0x03", nil, nil, nil)
+	check("0x04", blob, nil, []byte{0x02})
+
+	// Flatten all the layers onto disk, the destructs must be persisted too
+	if err := snaps.Cap(common.HexToHash("0x04"), 0); err != nil {
+		t.Fatalf("failed to flatten the diff layers: %v", err)
+	}
+	check("0x04", blob, nil, []byte{0x02})
+
+	if have := rawdb.ReadStorageSnapshot(snaps.diskdb, account, slot); len(have) != 0 {
+		t.Errorf("destructed slot persisted: %x", have)
+	}
+}
+
+// Tests that the diff layers are journalled on shutdown and loaded back on top
+// of the disk layer afterwards.
+func TestJournal(t *testing.T) {
+	snaps := newTestTree(common.HexToHash("0x01"))
+
+	var (
+		accounts = make(map[common.Hash][]byte)
+		slots    = make(map[common.Hash][]byte)
+		account  = randomHash()
+	)
+	for i := 2; i <= 4; i++ {
+		layerAccounts := map[common.Hash][]byte{randomHash(): randomAccount()}
+		layerSlots := map[common.Hash][]byte{randomHash(): randomHash().Bytes()}
+		for hash, blob := range layerAccounts {
+			accounts[hash] = blob
+		}
+		for hash, blob := range layerSlots {
+			slots[hash] = blob
+		}
+		storage := map[common.Hash]map[common.Hash][]byte{account: layerSlots}
+		if err := snaps.Update(common.BigToHash(big.NewInt(int64(i))), common.BigToHash(big.NewInt(int64(i-1))), nil, layerAccounts, storage); err != nil {
+			t.Fatalf("failed to create diff layer %d: %v", i, err)
+		}
+	}
+	base, err := snaps.Journal(common.HexToHash("0x04"))
+	if err != nil {
+		t.Fatalf("failed to journal snapshot: %v", err)
+	}
+	if base != common.HexToHash("0x01") {
+		t.Errorf("journalled base mismatch: have %x, want %x", base, common.HexToHash("0x01"))
+	}
+	// Loading the snapshot at a different head should fail
+	if _, err := loadSnapshot(snaps.diskdb, snaps.triedb, 16, common.HexToHash("0x03")); err == nil {
+		t.Errorf("snapshot loaded with mismatching head")
+	}
+	// Loading the snapshot at the correct head should recreate all the layers
+	head, err := loadSnapshot(snaps.diskdb, snaps.triedb, 16, common.HexToHash("0x04"))
+	if err != nil {
+		t.Fatalf("failed to load snapshot: %v", err)
+	}
+	depth := 0
+	for layer := head; layer != nil; layer = layer.Parent() {
+		depth++
+	}
+	if depth != 4 {
+		t.Errorf("loaded layer count mismatch: have %d, want %d", depth, 4)
+	}
+	for hash, blob := range accounts {
+		if have, err := head.AccountRLP(hash); err != nil || !bytes.Equal(have, blob) {
+			t.Errorf("account %x mismatch: have %x (err %v), want %x", hash, have, err, blob)
+		}
+	}
+	for hash, blob := range slots {
+		if have, err := head.Storage(account, hash); err != nil || !bytes.Equal(have, blob) {
+			t.Errorf("slot %x mismatch: have %x (err %v), want %x", hash, have, err, blob)
+		}
+	}
+	// If the disk layer progresses, the journal must be discarded
+	rawdb.WriteSnapshotRoot(snaps.diskdb, common.HexToHash("0x02"))
+	if _, err := loadSnapshot(snaps.diskdb, snaps.triedb, 16, common.HexToHash("0x04")); err == nil {
+		t.Errorf("snapshot loaded with stale journal")
+	}
+}
@@ -239,7 +239,8 @@ type TxPool struct {
 	reorgShutdownCh chan struct{}  // requests shutdown of scheduleReorgLoop
 	wg              sync.WaitGroup // tracks loop, scheduleReorgLoop
 
-	eip2f bool
+	eip2f    bool
+	eip2028f bool
 }
 
 type txpoolResetRequest struct {
@@ -329,6 +330,9 @@ func (pool *TxPool) loop() {
 				if pool.chainconfig.IsEIP2F(ev.Block.Number()) {
 					pool.eip2f = true
 				}
+				if pool.chainconfig.IsEIP2028F(ev.Block.Number()) {
+					pool.eip2028f = true
+				}
 				pool.requestReset(head.Header(), ev.Block.Header())
 				head = ev.Block
 			}
@@ -544,7 +548,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
 		return ErrInsufficientFunds
 	}
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.eip2f)
+	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.eip2f, pool.eip2028f)
 	if err != nil {
 		return err
 	}
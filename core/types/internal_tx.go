@@ -0,0 +1,57 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// InternalTxKind is the kind of state transition an internal transaction made.
+type InternalTxKind uint8
+
+const (
+	InternalTxCall         InternalTxKind = iota // Value transfer of a nested message call
+	InternalTxCreate                             // Contract creation, nested or not
+	InternalTxSelfDestruct                       // Contract self destruct
+)
+
+// String implements fmt.Stringer, returning the Parity name of the trace kind.
+func (k InternalTxKind) String() string {
+	switch k {
+	case InternalTxCall:
+		return "call"
+	case InternalTxCreate:
+		return "create"
+	case InternalTxSelfDestruct:
+		return "suicide"
+	default:
+		return "unknown"
+	}
+}
+
+// InternalTx is a compact record of a state transition executed by a transaction
+// without being a transaction itself: a value transferring nested call, a created
+// contract or a self destruct.
+type InternalTx struct {
+	Kind    InternalTxKind
+	TxIndex uint64         // Index of the transaction within its block
+	From    common.Address // Caller, creator or destructed contract
+	To      common.Address // Callee, created contract or refund beneficiary
+	Value   *big.Int       // Value transferred, endowment or refunded balance
+}
@@ -18,12 +18,14 @@ package vm
 
 import (
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/crypto/blake2b"
 	"github.com/ethereum/go-ethereum/crypto/bn256"
 	"github.com/ethereum/go-ethereum/params"
 	"golang.org/x/crypto/ripemd160"
@@ -57,11 +59,23 @@ func PrecompiledContractsForConfig(config *params.ChainConfig, bn *big.Int) map[
 		precompileds[common.BytesToAddress([]byte{5})] = &bigModExp{}
 	}
 	if config.IsEIP213F(bn) {
-		precompileds[common.BytesToAddress([]byte{6})] = &bn256AddByzantium{}
-		precompileds[common.BytesToAddress([]byte{7})] = &bn256ScalarMulByzantium{}
+		if config.IsEIP1108F(bn) {
+			precompileds[common.BytesToAddress([]byte{6})] = &bn256AddIstanbul{}
+			precompileds[common.BytesToAddress([]byte{7})] = &bn256ScalarMulIstanbul{}
+		} else {
+			precompileds[common.BytesToAddress([]byte{6})] = &bn256AddByzantium{}
+			precompileds[common.BytesToAddress([]byte{7})] = &bn256ScalarMulByzantium{}
+		}
 	}
 	if config.IsEIP212F(bn) {
-		precompileds[common.BytesToAddress([]byte{8})] = &bn256PairingByzantium{}
+		if config.IsEIP1108F(bn) {
+			precompileds[common.BytesToAddress([]byte{8})] = &bn256PairingIstanbul{}
+		} else {
+			precompileds[common.BytesToAddress([]byte{8})] = &bn256PairingByzantium{}
+		}
+	}
+	if config.IsEIP152F(bn) {
+		precompileds[common.BytesToAddress([]byte{9})] = &blake2F{}
 	}
 
 	return precompileds
@@ -426,3 +440,67 @@ func (c *bn256PairingByzantium) RequiredGas(input []byte) uint64 {
 func (c *bn256PairingByzantium) Run(input []byte) ([]byte, error) {
 	return runBn256Pairing(input)
 }
+
+// blake2F implements the BLAKE2b F compression function precompile (EIP-152).
+type blake2F struct{}
+
+// RequiredGas returns the gas required to execute the pre-compiled contract,
+// which is one unit per round of the compression function.
+func (c *blake2F) RequiredGas(input []byte) uint64 {
+	// If the input is malformed, we can't calculate the gas, return 0 and let the
+	// actual call choke and fault.
+	if len(input) != blake2FInputLength {
+		return 0
+	}
+	return uint64(binary.BigEndian.Uint32(input[0:4]))
+}
+
+const (
+	blake2FInputLength        = 213
+	blake2FFinalBlockBytes    = byte(1)
+	blake2FNonFinalBlockBytes = byte(0)
+)
+
+var (
+	errBlake2FInvalidInputLength = errors.New("invalid input length")
+	errBlake2FInvalidFinalFlag   = errors.New("invalid final flag")
+)
+
+func (c *blake2F) Run(input []byte) ([]byte, error) {
+	// Make sure the input is valid (correct length and final flag)
+	if len(input) != blake2FInputLength {
+		return nil, errBlake2FInvalidInputLength
+	}
+	if input[212] != blake2FNonFinalBlockBytes && input[212] != blake2FFinalBlockBytes {
+		return nil, errBlake2FInvalidFinalFlag
+	}
+	// Parse the input into the Blake2b call parameters
+	var (
+		rounds = binary.BigEndian.Uint32(input[0:4])
+		final  = (input[212] == blake2FFinalBlockBytes)
+
+		h [8]uint64
+		m [16]uint64
+		t [2]uint64
+	)
+	for i := 0; i < 8; i++ {
+		offset := 4 + i*8
+		h[i] = binary.LittleEndian.Uint64(input[offset : offset+8])
+	}
+	for i := 0; i < 16; i++ {
+		offset := 68 + i*8
+		m[i] = binary.LittleEndian.Uint64(input[offset : offset+8])
+	}
+	t[0] = binary.LittleEndian.Uint64(input[196:204])
+	t[1] = binary.LittleEndian.Uint64(input[204:212])
+
+	// Execute the compression function, extract and return the result
+	blake2b.F(&h, m, t, final, rounds)
+
+	output := make([]byte, 64)
+	for i := 0; i < 8; i++ {
+		offset := i * 8
+		binary.LittleEndian.PutUint64(output[offset:offset+8], h[i])
+	}
+	return output, nil
+}
@@ -338,8 +338,36 @@ var bn256PairingTests = []precompiledTest{
 	},
 }
 
+// EIP-152 test vectors
+var blake2FTests = []precompiledTest{
+	{
+		input:    "0000000048c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		expected: "08c9bcf367e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d282e6ad7f520e511f6c3e2b8c68059b9442be0454267ce079217e1319cde05b",
+		name:     "vector 4",
+	},
+	{
+		input:    "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		expected: "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+		name:     "vector 5",
+	},
+	{
+		input:    "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000000",
+		expected: "75ab69d3190a562c51aef8d88f1c2775876944407270c42c9844252c26d2875298743e7f6d5ea2f2d3e8d226039cd31b4e426ac4f2d3d666a610c2116fde4735",
+		name:     "vector 6",
+	},
+	{
+		input:    "0000000148c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		expected: "b63a380cb2897d521994a85234ee2c181b5f844d2c624c002677e9703449d2fba551b3a8333bcdf5f2f7e08993d53923de3d64fcc68c034e717b9293fed7a421",
+		name:     "vector 7",
+	},
+}
+
 func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
-	p := PrecompiledContractsForConfig(params.AllEthashProtocolChanges, big.NewInt(0))[common.HexToAddress(addr)]
+	testPrecompiledWithConfig(params.AllEthashProtocolChanges, addr, test, t)
+}
+
+func testPrecompiledWithConfig(config *params.ChainConfig, addr string, test precompiledTest, t *testing.T) {
+	p := PrecompiledContractsForConfig(config, big.NewInt(0))[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
 
@@ -573,3 +601,35 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+func TestPrecompiledBlake2F(t *testing.T) {
+	config := *params.AllEthashProtocolChanges
+	config.EIP152FBlock = big.NewInt(0)
+
+	for _, test := range blake2FTests {
+		testPrecompiledWithConfig(&config, "09", test, t)
+	}
+}
+
+func TestPrecompiledEIP1108Gas(t *testing.T) {
+	config := *params.AllEthashProtocolChanges
+	config.EIP1108FBlock = big.NewInt(10)
+
+	for _, c := range []struct {
+		addr      byte
+		byzantium uint64
+		istanbul  uint64
+	}{
+		{6, params.Bn256AddGasByzantium, params.Bn256AddGasIstanbul},
+		{7, params.Bn256ScalarMulGasByzantium, params.Bn256ScalarMulGasIstanbul},
+		{8, params.Bn256PairingBaseGasByzantium, params.Bn256PairingBaseGasIstanbul},
+	} {
+		addr := common.BytesToAddress([]byte{c.addr})
+		if gas := PrecompiledContractsForConfig(&config, big.NewInt(9))[addr].RequiredGas(nil); gas != c.byzantium {
+			t.Errorf("address %x before EIP-1108: gas mismatch: have %d, want %d", addr, gas, c.byzantium)
+		}
+		if gas := PrecompiledContractsForConfig(&config, big.NewInt(10))[addr].RequiredGas(nil); gas != c.istanbul {
+			t.Errorf("address %x after EIP-1108: gas mismatch: have %d, want %d", addr, gas, c.istanbul)
+		}
+	}
+}
@@ -31,6 +31,8 @@ func EnableEIP(eipNum int, jt *JumpTable) error {
 		enable1884(jt)
 	case 1344:
 		enable1344(jt)
+	case 2200:
+		enable2200(jt)
 	default:
 		return fmt.Errorf("undefined eip %d", eipNum)
 	}
@@ -83,3 +85,8 @@ func opChainID(pc *uint64, interpreter *EVMInterpreter, contract *Contract, memo
 	stack.push(chainId)
 	return nil, nil
 }
+
+// enable2200 applies EIP-2200 (Rebalance net-metered SSTORE)
+func enable2200(jt *JumpTable) {
+	jt[SSTORE].dynamicGas = gasSStoreEIP2200
+}
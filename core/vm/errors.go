@@ -27,4 +27,8 @@ var (
 	ErrInsufficientBalance      = errors.New("insufficient balance for transfer")
 	ErrContractAddressCollision = errors.New("contract address collision")
 	ErrNoCompatibleInterpreter  = errors.New("no compatible interpreter")
+
+	// ErrExecutionReverted is returned if the execution was reverted by the REVERT
+	// opcode, keeping the gas left.
+	ErrExecutionReverted = errors.New("evm: execution reverted")
 )
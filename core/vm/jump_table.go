@@ -179,6 +179,16 @@ func instructionSetForConfig(config *params.ChainConfig, bn *big.Int) JumpTable
 			valid:       true,
 		}
 	}
+	// Istanbul
+	if config.IsEIP1884F(bn) {
+		enable1884(&instructionSet)
+	}
+	if config.IsEIP1344F(bn) {
+		enable1344(&instructionSet)
+	}
+	if config.IsEIP2200F(bn) {
+		enable2200(&instructionSet)
+	}
 	return instructionSet
 }
 
@@ -0,0 +1,52 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package blake2b
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Tests that a single, final, 12 round compression of a short message yields
+// the same digest as the reference BLAKE2b-512 implementation.
+func TestFMatchesBlake2b512(t *testing.T) {
+	msg := []byte("abc")
+
+	// Parameter block: 64 byte digest, no key, fanout and depth of 1
+	h := iv
+	h[0] ^= 0x01010000 ^ 64
+
+	var (
+		block [128]byte
+		m     [16]uint64
+	)
+	copy(block[:], msg)
+	for i := range m {
+		m[i] = binary.LittleEndian.Uint64(block[i*8:])
+	}
+	F(&h, m, [2]uint64{uint64(len(msg)), 0}, true, 12)
+
+	var have [64]byte
+	for i := range h {
+		binary.LittleEndian.PutUint64(have[i*8:], h[i])
+	}
+	if want := blake2b.Sum512(msg); have != want {
+		t.Errorf("digest mismatch: have %x, want %x", have, want)
+	}
+}
@@ -284,6 +284,12 @@ func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error
 	return stateDb.RawDump(false, false, true), nil
 }
 
+// ForkReadiness reports how the fork IDs announced by the connected peers relate
+// to the local fork schedule, and how many blocks are left until the next fork.
+func (api *PublicDebugAPI) ForkReadiness() *ForkReadiness {
+	return api.eth.protocolManager.forks.status()
+}
+
 // PrivateDebugAPI is the collection of Ethereum full node APIs exposed over
 // the private debugging endpoint.
 type PrivateDebugAPI struct {
@@ -0,0 +1,765 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// traceTypeTrace requests the flat call traces of a replayed transaction.
+	traceTypeTrace = "trace"
+
+	// traceTypeVMTrace requests the opcode level trace of a replayed transaction.
+	traceTypeVMTrace = "vmTrace"
+
+	// traceTypeStateDiff requests the state changes of a replayed transaction.
+	traceTypeStateDiff = "stateDiff"
+)
+
+var (
+	// maxTraceFilterBlocks is the maximum number of blocks a single trace filter
+	// may re-execute, to avoid a request keeping the node busy indefinitely.
+	maxTraceFilterBlocks = uint64(1000)
+
+	// errTraceFilterRange is returned if the block range of a trace filter is invalid.
+	errTraceFilterRange = errors.New("invalid trace filter block range")
+
+	// errTraceIndexDisabled is returned if internal transactions are requested
+	// without the trace indexer running.
+	errTraceIndexDisabled = errors.New("trace index not enabled")
+)
+
+// parityTxTrace is a single trace of a replayed transaction in the Parity trace
+// module, describing a call, a contract creation or a self destruct.
+type parityTxTrace struct {
+	Action       json.RawMessage `json:"action"`
+	Error        string          `json:"error,omitempty"`
+	Result       json.RawMessage `json:"result"`
+	Subtraces    int             `json:"subtraces"`
+	TraceAddress []int           `json:"traceAddress"`
+	Type         string          `json:"type"`
+}
+
+// parityTrace is a single trace of a block in the Parity trace module, either of
+// one of its transactions or of a block reward. Reward traces don't belong to a
+// transaction, their transaction fields are null as Parity reports them.
+type parityTrace struct {
+	parityTxTrace
+	BlockHash           *common.Hash `json:"blockHash"`
+	BlockNumber         *uint64      `json:"blockNumber"`
+	TransactionHash     *common.Hash `json:"transactionHash"`
+	TransactionPosition *uint64      `json:"transactionPosition"`
+}
+
+// parityRewardAction is the action of a block reward trace.
+type parityRewardAction struct {
+	Author     common.Address `json:"author"`
+	RewardType string         `json:"rewardType"`
+	Value      *hexutil.Big   `json:"value"`
+}
+
+// parityTraceAddresses are the accounts a trace's action and result refer to,
+// used to filter traces.
+type parityTraceAddresses struct {
+	Action struct {
+		Address       *common.Address `json:"address"`
+		Author        *common.Address `json:"author"`
+		From          *common.Address `json:"from"`
+		RefundAddress *common.Address `json:"refundAddress"`
+		To            *common.Address `json:"to"`
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"`
+	} `json:"result"`
+}
+
+// parityReplayResult is the outcome of replaying a single transaction.
+type parityReplayResult struct {
+	Output          hexutil.Bytes                         `json:"output"`
+	StateDiff       map[common.Address]*parityAccountDiff `json:"stateDiff"`
+	Trace           []*parityTxTrace                      `json:"trace"`
+	VMTrace         json.RawMessage                       `json:"vmTrace"`
+	TransactionHash common.Hash                           `json:"transactionHash"`
+}
+
+// parityAccountDiff is the change of an account caused by a transaction.
+type parityAccountDiff struct {
+	Balance *parityDiff                 `json:"balance"`
+	Code    *parityDiff                 `json:"code"`
+	Nonce   *parityDiff                 `json:"nonce"`
+	Storage map[common.Hash]*parityDiff `json:"storage"`
+}
+
+// parityDiff is the change of a single hex encoded value. Missing values denote
+// a created or deleted account.
+type parityDiff struct {
+	From *string
+	To   *string
+}
+
+// MarshalJSON encodes the change as "=" if the value didn't change, otherwise
+// as {"+": new}, {"-": old} or {"*": {"from": old, "to": new}}.
+func (d *parityDiff) MarshalJSON() ([]byte, error) {
+	switch {
+	case d.From == nil:
+		return json.Marshal(map[string]string{"+": *d.To})
+	case d.To == nil:
+		return json.Marshal(map[string]string{"-": *d.From})
+	case *d.From == *d.To:
+		return json.Marshal("=")
+	default:
+		return json.Marshal(map[string]map[string]string{"*": {"from": *d.From, "to": *d.To}})
+	}
+}
+
+// changed reports whether the value was modified.
+func (d *parityDiff) changed() bool {
+	return d.From == nil || d.To == nil || *d.From != *d.To
+}
+
+// internalTxResult is an internal transaction found in the trace index.
+type internalTxResult struct {
+	BlockHash        common.Hash    `json:"blockHash"`
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionHash  common.Hash    `json:"transactionHash"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+	Type             string         `json:"type"`
+	From             common.Address `json:"from"`
+	To               common.Address `json:"to"`
+	Value            *hexutil.Big   `json:"value"`
+}
+
+// TraceFilterArgs are the criteria of the traces returned by trace_filter.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// PrivateTraceAPI is the collection of Parity compatible tracing APIs exposed
+// over the private trace namespace.
+type PrivateTraceAPI struct {
+	eth   *Ethereum
+	debug *PrivateDebugAPI
+}
+
+// NewPrivateTraceAPI creates a new API definition for the Parity compatible
+// trace methods of the Ethereum service.
+func NewPrivateTraceAPI(eth *Ethereum) *PrivateTraceAPI {
+	return &PrivateTraceAPI{eth: eth, debug: NewPrivateDebugAPI(eth)}
+}
+
+// Block returns the traces of all the transactions and rewards of a block.
+func (api *PrivateTraceAPI) Block(ctx context.Context, number rpc.BlockNumber) ([]*parityTrace, error) {
+	block, err := api.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := api.parentState(block)
+	if err != nil {
+		return nil, err
+	}
+	return api.traceBlock(ctx, block, statedb)
+}
+
+// Transaction returns the traces of a single transaction.
+func (api *PrivateTraceAPI) Transaction(ctx context.Context, hash common.Hash) ([]*parityTrace, error) {
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(api.eth.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %#x not found", hash)
+	}
+	msg, vmctx, statedb, err := api.debug.computeTxEnv(blockHash, int(index), defaultTraceReexec)
+	if err != nil {
+		return nil, err
+	}
+	result, err := api.replayTx(ctx, msg, vmctx, statedb, true, false, false)
+	if err != nil {
+		return nil, err
+	}
+	traces := make([]*parityTrace, len(result.Trace))
+	for i, trace := range result.Trace {
+		traces[i] = &parityTrace{
+			parityTxTrace:       *trace,
+			BlockHash:           &blockHash,
+			BlockNumber:         &blockNumber,
+			TransactionHash:     &hash,
+			TransactionPosition: &index,
+		}
+	}
+	return traces, nil
+}
+
+// Filter returns the traces of a range of blocks matching the given addresses.
+func (api *PrivateTraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]*parityTrace, error) {
+	from, err := api.blockByNumber(blockNumberOrLatest(args.FromBlock))
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.blockByNumber(blockNumberOrLatest(args.ToBlock))
+	if err != nil {
+		return nil, err
+	}
+	if from.NumberU64() > to.NumberU64() {
+		return nil, errTraceFilterRange
+	}
+	if blocks := to.NumberU64() - from.NumberU64() + 1; blocks > maxTraceFilterBlocks {
+		return nil, fmt.Errorf("trace filter range of %d blocks exceeds limit of %d", blocks, maxTraceFilterBlocks)
+	}
+	fromAddrs := make(map[common.Address]struct{})
+	for _, addr := range args.FromAddress {
+		fromAddrs[addr] = struct{}{}
+	}
+	toAddrs := make(map[common.Address]struct{})
+	for _, addr := range args.ToAddress {
+		toAddrs[addr] = struct{}{}
+	}
+	// Trace the blocks one after the other, carrying the state along
+	statedb, err := api.parentState(from)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		matches []*parityTrace
+		skipped uint64
+	)
+	for number := from.NumberU64(); number <= to.NumberU64(); number++ {
+		block := from
+		if number != from.NumberU64() {
+			if block = api.eth.blockchain.GetBlockByNumber(number); block == nil {
+				return nil, fmt.Errorf("block #%d not found", number)
+			}
+		}
+		traces, err := api.traceBlock(ctx, block, statedb)
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range traces {
+			if !filterTrace(trace, fromAddrs, toAddrs) {
+				continue
+			}
+			if args.After != nil && skipped < *args.After {
+				skipped++
+				continue
+			}
+			matches = append(matches, trace)
+			if args.Count != nil && uint64(len(matches)) >= *args.Count {
+				return matches, nil
+			}
+		}
+		// Apply the block rewards to move the state to the next block
+		header := types.CopyHeader(block.Header())
+		api.eth.engine.Finalize(api.eth.blockchain, header, statedb, block.Transactions(), block.Uncles())
+		if header.Root != block.Root() {
+			return nil, fmt.Errorf("state root mismatch in block #%d: have %x, want %x", number, header.Root, block.Root())
+		}
+	}
+	return matches, nil
+}
+
+// ReplayBlockTransactions replays all the transactions of a block, returning the
+// requested trace types (trace, vmTrace and stateDiff) of each.
+func (api *PrivateTraceAPI) ReplayBlockTransactions(ctx context.Context, number rpc.BlockNumber, traceTypes []string) ([]*parityReplayResult, error) {
+	var trace, vmTrace, stateDiff bool
+	for _, typ := range traceTypes {
+		switch typ {
+		case traceTypeTrace:
+			trace = true
+		case traceTypeVMTrace:
+			vmTrace = true
+		case traceTypeStateDiff:
+			stateDiff = true
+		default:
+			return nil, fmt.Errorf("unknown trace type %q", typ)
+		}
+	}
+	block, err := api.blockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := api.parentState(block)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		signer  = types.MakeSigner(api.eth.blockchain.Config(), block.Number())
+		results = make([]*parityReplayResult, len(block.Transactions()))
+	)
+	for i, tx := range block.Transactions() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		msg, _ := tx.AsMessage(signer)
+		vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		if results[i], err = api.replayTx(ctx, msg, vmctx, statedb, trace, vmTrace, stateDiff); err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		results[i].TransactionHash = tx.Hash()
+	}
+	return results, nil
+}
+
+// InternalTransactions returns the internal transactions sent or received by an
+// account within a range of blocks, as recorded by the trace index. The range
+// needs to be fully indexed.
+func (api *PrivateTraceAPI) InternalTransactions(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber) ([]*internalTxResult, error) {
+	indexer := api.eth.traceIndexer
+	if indexer == nil {
+		return nil, errTraceIndexDisabled
+	}
+	from, err := api.blockByNumber(fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.blockByNumber(toBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from.NumberU64() > to.NumberU64() {
+		return nil, errTraceFilterRange
+	}
+	if blocks := to.NumberU64() - from.NumberU64() + 1; blocks > maxTraceFilterBlocks {
+		return nil, fmt.Errorf("trace filter range of %d blocks exceeds limit of %d", blocks, maxTraceFilterBlocks)
+	}
+	db := api.eth.ChainDb()
+	if sections, _, head := indexer.Sections(); sections == 0 {
+		return nil, fmt.Errorf("block #%d not yet indexed", to.NumberU64())
+	} else if indexed := rawdb.ReadHeaderNumber(db, head); indexed == nil || to.NumberU64() > *indexed {
+		return nil, fmt.Errorf("block #%d not yet indexed", to.NumberU64())
+	}
+
+	results := []*internalTxResult{}
+	for _, number := range rawdb.ReadInternalTxBlocks(db, address, from.NumberU64(), to.NumberU64()) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Skip stale entries of blocks reorged out of the chain
+		hash := rawdb.ReadCanonicalHash(db, number)
+		body := rawdb.ReadBody(db, hash, number)
+		if body == nil {
+			continue
+		}
+		for _, tx := range rawdb.ReadInternalTxs(db, hash, number) {
+			if tx.From != address && tx.To != address {
+				continue
+			}
+			if tx.TxIndex >= uint64(len(body.Transactions)) {
+				return nil, fmt.Errorf("corrupt trace index entry in block #%d", number)
+			}
+			results = append(results, &internalTxResult{
+				BlockHash:        hash,
+				BlockNumber:      hexutil.Uint64(number),
+				TransactionHash:  body.Transactions[tx.TxIndex].Hash(),
+				TransactionIndex: hexutil.Uint64(tx.TxIndex),
+				Type:             tx.Kind.String(),
+				From:             tx.From,
+				To:               tx.To,
+				Value:            (*hexutil.Big)(tx.Value),
+			})
+		}
+	}
+	return results, nil
+}
+
+// blockByNumber retrieves a canonical block by number.
+func (api *PrivateTraceAPI) blockByNumber(number rpc.BlockNumber) (*types.Block, error) {
+	var block *types.Block
+
+	switch number {
+	case rpc.PendingBlockNumber:
+		block = api.eth.miner.PendingBlock()
+	case rpc.LatestBlockNumber:
+		block = api.eth.blockchain.CurrentBlock()
+	default:
+		block = api.eth.blockchain.GetBlockByNumber(uint64(number))
+	}
+	if block == nil {
+		if number == rpc.PendingBlockNumber {
+			return nil, errors.New("pending block not found")
+		}
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return block, nil
+}
+
+// parentState retrieves the state the transactions of a block execute on top of.
+func (api *PrivateTraceAPI) parentState(block *types.Block) (*state.StateDB, error) {
+	if block.NumberU64() == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	parent := api.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	return api.debug.computeStateDB(parent, defaultTraceReexec)
+}
+
+// traceBlock executes all the transactions of a block on top of the given state,
+// returning their call traces followed by the block rewards.
+func (api *PrivateTraceAPI) traceBlock(ctx context.Context, block *types.Block, statedb *state.StateDB) ([]*parityTrace, error) {
+	var (
+		signer = types.MakeSigner(api.eth.blockchain.Config(), block.Number())
+		hash   = block.Hash()
+		number = block.NumberU64()
+		traces []*parityTrace
+	)
+	for i, tx := range block.Transactions() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		msg, _ := tx.AsMessage(signer)
+		vmctx := core.NewEVMContext(msg, block.Header(), api.eth.blockchain, nil)
+
+		statedb.Prepare(tx.Hash(), hash, i)
+		result, err := api.replayTx(ctx, msg, vmctx, statedb, true, false, false)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		txHash, index := tx.Hash(), uint64(i)
+		for _, trace := range result.Trace {
+			traces = append(traces, &parityTrace{
+				parityTxTrace:       *trace,
+				BlockHash:           &hash,
+				BlockNumber:         &number,
+				TransactionHash:     &txHash,
+				TransactionPosition: &index,
+			})
+		}
+	}
+	// Append the mining rewards if the chain is proof-of-work
+	if _, ok := api.eth.engine.(*ethash.Ethash); ok {
+		for _, reward := range ethash.BlockRewards(api.eth.blockchain.Config(), block.Header(), block.Uncles()) {
+			action, err := json.Marshal(&parityRewardAction{
+				Author:     reward.Beneficiary,
+				RewardType: string(reward.Kind),
+				Value:      (*hexutil.Big)(reward.Amount),
+			})
+			if err != nil {
+				return nil, err
+			}
+			traces = append(traces, &parityTrace{
+				parityTxTrace: parityTxTrace{
+					Action:       action,
+					Result:       json.RawMessage("null"),
+					TraceAddress: []int{},
+					Type:         "reward",
+				},
+				BlockHash:   &hash,
+				BlockNumber: &number,
+			})
+		}
+	}
+	return traces, nil
+}
+
+// replayTx executes a message on top of the given state, gathering the requested
+// trace types. The state is finalised afterwards so that further transactions
+// may be executed on top.
+func (api *PrivateTraceAPI) replayTx(ctx context.Context, message core.Message, vmctx vm.Context, statedb *state.StateDB, trace, vmTrace, stateDiff bool) (*parityReplayResult, error) {
+	var (
+		mux       multiTracer
+		callTrace tracers.TxTracer
+		opTrace   tracers.TxTracer
+		touched   *touchTracer
+		prestate  *state.StateDB
+	)
+	if trace {
+		callTrace, _ = tracers.NewTxTracer("flatCallTracer")
+		mux = append(mux, callTrace)
+	}
+	if vmTrace {
+		opTrace, _ = tracers.NewTxTracer("vmTracer")
+		mux = append(mux, opTrace)
+	}
+	if stateDiff {
+		touched = newTouchTracer(vmctx.Coinbase)
+		prestate = statedb.Copy()
+		mux = append(mux, touched)
+	}
+	// Abort the execution if the RPC request is cancelled
+	deadlineCtx, cancel := context.WithTimeout(ctx, defaultTraceTimeout)
+	defer cancel()
+	go func() {
+		<-deadlineCtx.Done()
+		for _, tracer := range []tracers.TxTracer{callTrace, opTrace} {
+			if tracer != nil {
+				tracer.Stop(errors.New("execution timeout"))
+			}
+		}
+	}()
+	// Run the transaction with the requested tracers enabled
+	vmenv := vm.NewEVM(vmctx, statedb, api.eth.blockchain.Config(), vm.Config{Debug: len(mux) > 0, Tracer: mux})
+
+	ret, _, _, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
+	if err != nil {
+		return nil, fmt.Errorf("tracing failed: %v", err)
+	}
+	statedb.Finalise(vmenv.ChainConfig().IsEIP161F(vmctx.BlockNumber))
+
+	// Assemble the requested results
+	result := &parityReplayResult{Output: ret, Trace: []*parityTxTrace{}}
+	if callTrace != nil {
+		blob, err := callTrace.GetResult()
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(blob, &result.Trace); err != nil {
+			return nil, err
+		}
+	}
+	if opTrace != nil {
+		if result.VMTrace, err = opTrace.GetResult(); err != nil {
+			return nil, err
+		}
+	}
+	if touched != nil {
+		result.StateDiff = touched.diff(prestate, statedb)
+	}
+	return result, nil
+}
+
+// filterTrace reports whether a trace matches the from and to address filters.
+// Empty filters match all traces.
+func filterTrace(trace *parityTrace, fromAddrs, toAddrs map[common.Address]struct{}) bool {
+	if len(fromAddrs) == 0 && len(toAddrs) == 0 {
+		return true
+	}
+	var addrs parityTraceAddresses
+	if err := json.Unmarshal(trace.Action, &addrs.Action); err != nil {
+		return false
+	}
+	if len(trace.Result) > 0 {
+		json.Unmarshal(trace.Result, &addrs.Result)
+	}
+	var from, to *common.Address
+	switch trace.Type {
+	case "call":
+		from, to = addrs.Action.From, addrs.Action.To
+	case "create":
+		from = addrs.Action.From
+		if addrs.Result != nil {
+			to = addrs.Result.Address
+		}
+	case "suicide":
+		from, to = addrs.Action.Address, addrs.Action.RefundAddress
+	case "reward":
+		to = addrs.Action.Author
+	}
+	return matchAddress(from, fromAddrs) && matchAddress(to, toAddrs)
+}
+
+// matchAddress reports whether an address is contained in a filter set. Empty
+// sets match any address.
+func matchAddress(addr *common.Address, set map[common.Address]struct{}) bool {
+	if len(set) == 0 {
+		return true
+	}
+	if addr == nil {
+		return false
+	}
+	_, ok := set[*addr]
+	return ok
+}
+
+// blockNumberOrLatest returns the given block number, or the latest one if unset.
+func blockNumberOrLatest(number *rpc.BlockNumber) rpc.BlockNumber {
+	if number == nil {
+		return rpc.LatestBlockNumber
+	}
+	return *number
+}
+
+// multiTracer is a vm.Tracer fanning out all events to a list of tracers.
+type multiTracer []vm.Tracer
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (m multiTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	for _, tracer := range m {
+		if err := tracer.CaptureStart(from, to, create, input, gas, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (m multiTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	for _, tracer := range m {
+		if err := tracer.CaptureState(env, pc, op, gas, cost, memory, stack, contract, depth, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (m multiTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	for _, tracer := range m {
+		if err := tracer.CaptureFault(env, pc, op, gas, cost, memory, stack, contract, depth, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (m multiTracer) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) error {
+	for _, tracer := range m {
+		if err := tracer.CaptureEnd(output, gasUsed, t, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// touchTracer gathers the accounts and storage slots accessed by a transaction,
+// which are the only ones that may change during its execution.
+type touchTracer struct {
+	accounts map[common.Address]map[common.Hash]struct{}
+}
+
+// newTouchTracer creates a tracer collecting accessed state, starting with the
+// miner of the block receiving the transaction fees.
+func newTouchTracer(coinbase common.Address) *touchTracer {
+	t := &touchTracer{accounts: make(map[common.Address]map[common.Hash]struct{})}
+	t.touch(coinbase)
+	return t
+}
+
+// touch marks an account as accessed.
+func (t *touchTracer) touch(addr common.Address) {
+	if _, ok := t.accounts[addr]; !ok {
+		t.accounts[addr] = make(map[common.Hash]struct{})
+	}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *touchTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.touch(from)
+	t.touch(to)
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *touchTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if err != nil {
+		return nil
+	}
+	switch op {
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.touch(common.BigToAddress(stack.Back(1)))
+
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.EXTCODEHASH, vm.SELFDESTRUCT:
+		t.touch(common.BigToAddress(stack.Back(0)))
+
+	case vm.CREATE:
+		t.touch(crypto.CreateAddress(contract.Address(), env.StateDB.GetNonce(contract.Address())))
+
+	case vm.CREATE2:
+		// The init code is already in memory, derive the address the EVM will use
+		if size := stack.Back(2); size.IsUint64() && stack.Back(1).IsUint64() && uint64(memory.Len()) >= stack.Back(1).Uint64()+size.Uint64() {
+			code := memory.Get(stack.Back(1).Int64(), size.Int64())
+			t.touch(crypto.CreateAddress2(contract.Address(), common.BigToHash(stack.Back(3)), crypto.Keccak256(code)))
+		}
+
+	case vm.SLOAD, vm.SSTORE:
+		t.touch(contract.Address())
+		t.accounts[contract.Address()][common.BigToHash(stack.Back(0))] = struct{}{}
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *touchTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *touchTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// diff compares the accessed accounts and storage slots between the states
+// before and after the transaction executed, returning the changed ones.
+func (t *touchTracer) diff(pre, post *state.StateDB) map[common.Address]*parityAccountDiff {
+	diffs := make(map[common.Address]*parityAccountDiff)
+	for addr, slots := range t.accounts {
+		var (
+			preExists  = pre.Exist(addr)
+			postExists = post.Exist(addr)
+		)
+		if !preExists && !postExists {
+			continue
+		}
+		field := func(have func(db *state.StateDB) string) *parityDiff {
+			diff := new(parityDiff)
+			if preExists {
+				from := have(pre)
+				diff.From = &from
+			}
+			if postExists {
+				to := have(post)
+				diff.To = &to
+			}
+			return diff
+		}
+		account := &parityAccountDiff{
+			Balance: field(func(db *state.StateDB) string { return hexutil.EncodeBig(db.GetBalance(addr)) }),
+			Code:    field(func(db *state.StateDB) string { return hexutil.Encode(db.GetCode(addr)) }),
+			Nonce:   field(func(db *state.StateDB) string { return hexutil.EncodeUint64(db.GetNonce(addr)) }),
+			Storage: make(map[common.Hash]*parityDiff),
+		}
+		for slot := range slots {
+			diff := field(func(db *state.StateDB) string { return db.GetState(addr, slot).Hex() })
+			// Created or deleted accounts only report their non-empty slots
+			if (diff.From == nil || *diff.From == (common.Hash{}).Hex()) && (diff.To == nil || *diff.To == (common.Hash{}).Hex()) {
+				continue
+			}
+			if diff.changed() {
+				account.Storage[slot] = diff
+			}
+		}
+		if account.Balance.changed() || account.Code.changed() || account.Nonce.changed() || len(account.Storage) > 0 {
+			diffs[addr] = account
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,218 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newTraceTestBackend creates a chain of a few blocks, each containing a value
+// transfer and a contract creation storing a value, and returns a trace API
+// operating on it.
+func newTraceTestBackend(t *testing.T, blocks int) (*PrivateTraceAPI, common.Address, []*types.Block) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		receiver = common.Address{0xaa}
+		db       = rawdb.NewMemoryDatabase()
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, blocks, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{0xcc})
+
+		// Transfer some value to the receiver
+		tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), receiver, big.NewInt(1000), params.TxGas, big.NewInt(1), nil), signer, key)
+		b.AddTx(tx)
+
+		// Deploy a contract storing 1 into slot 0: PUSH1 1 PUSH1 0 SSTORE STOP
+		tx, _ = types.SignTx(types.NewContractCreation(b.TxNonce(sender), new(big.Int), 100000, big.NewInt(1), common.FromHex("0x600160005500")), signer, key)
+		b.AddTx(tx)
+	})
+	blockchain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	eth := &Ethereum{blockchain: blockchain, chainDb: db, engine: ethash.NewFaker(), config: &Config{}, accountManager: accounts.NewManager(&accounts.Config{})}
+	return NewPrivateTraceAPI(eth), receiver, chain
+}
+
+// Tests that the traces of a block contain all its transactions and rewards.
+func TestTraceBlock(t *testing.T) {
+	api, receiver, chain := newTraceTestBackend(t, 2)
+	defer api.eth.blockchain.Stop()
+
+	traces, err := api.Block(context.Background(), rpc.BlockNumber(2))
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	if len(traces) != 3 {
+		t.Fatalf("trace count mismatch: have %d, want 3", len(traces))
+	}
+	for i, want := range []string{"call", "create", "reward"} {
+		if traces[i].Type != want {
+			t.Errorf("trace %d: type mismatch: have %s, want %s", i, traces[i].Type, want)
+		}
+		if *traces[i].BlockHash != chain[1].Hash() || *traces[i].BlockNumber != 2 {
+			t.Errorf("trace %d: block mismatch: have #%d [%x], want #2 [%x]", i, *traces[i].BlockNumber, *traces[i].BlockHash, chain[1].Hash())
+		}
+	}
+	var call struct {
+		To    common.Address `json:"to"`
+		Value string         `json:"value"`
+	}
+	if err := json.Unmarshal(traces[0].Action, &call); err != nil {
+		t.Fatalf("failed to decode call action: %v", err)
+	}
+	if call.To != receiver || call.Value != "0x3e8" {
+		t.Errorf("call action mismatch: have %x/%s, want %x/0x3e8", call.To, call.Value, receiver)
+	}
+	// Rewards should report null transaction fields, as Parity does
+	blob, err := json.Marshal(traces[2])
+	if err != nil {
+		t.Fatalf("failed to encode reward trace: %v", err)
+	}
+	var reward map[string]json.RawMessage
+	if err := json.Unmarshal(blob, &reward); err != nil {
+		t.Fatalf("failed to decode reward trace: %v", err)
+	}
+	for _, field := range []string{"transactionHash", "transactionPosition"} {
+		if value, ok := reward[field]; !ok || string(value) != "null" {
+			t.Errorf("reward trace field %s mismatch: have %s, want null", field, value)
+		}
+	}
+	// Tracing a single transaction should yield the same trace
+	txTraces, err := api.Transaction(context.Background(), chain[1].Transactions()[1].Hash())
+	if err != nil {
+		t.Fatalf("failed to trace transaction: %v", err)
+	}
+	if len(txTraces) != 1 || string(txTraces[0].Action) != string(traces[1].Action) {
+		t.Errorf("transaction trace mismatch: have %v, want %v", txTraces, traces[1:2])
+	}
+}
+
+// Tests that traces can be filtered by address over a range of blocks.
+func TestTraceFilter(t *testing.T) {
+	api, receiver, _ := newTraceTestBackend(t, 3)
+	defer api.eth.blockchain.Stop()
+
+	from, to := rpc.BlockNumber(1), rpc.BlockNumber(3)
+	after, count := uint64(7), uint64(5)
+	tests := []struct {
+		args TraceFilterArgs
+		want int
+	}{
+		{TraceFilterArgs{FromBlock: &from, ToBlock: &to}, 9},
+		{TraceFilterArgs{FromBlock: &from, ToBlock: &to, ToAddress: []common.Address{receiver}}, 3},
+		{TraceFilterArgs{FromBlock: &from, ToBlock: &to, ToAddress: []common.Address{{0xcc}}}, 3},
+		{TraceFilterArgs{FromBlock: &from, ToBlock: &to, FromAddress: []common.Address{{0xcc}}}, 0},
+		{TraceFilterArgs{FromBlock: &from, ToBlock: &to, After: &after, Count: &count}, 2},
+	}
+	for i, tt := range tests {
+		traces, err := api.Filter(context.Background(), tt.args)
+		if err != nil {
+			t.Fatalf("test %d: failed to filter traces: %v", i, err)
+		}
+		if len(traces) != tt.want {
+			t.Errorf("test %d: trace count mismatch: have %d, want %d", i, len(traces), tt.want)
+		}
+	}
+	if _, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &to, ToBlock: &from}); err != errTraceFilterRange {
+		t.Errorf("inverted range error mismatch: have %v, want %v", err, errTraceFilterRange)
+	}
+	// Ranges above the limit should be rejected
+	defer func(limit uint64) { maxTraceFilterBlocks = limit }(maxTraceFilterBlocks)
+	maxTraceFilterBlocks = 2
+
+	if _, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &from, ToBlock: &to}); err == nil {
+		t.Errorf("filtered range above the limit")
+	}
+	if _, err := api.Filter(context.Background(), TraceFilterArgs{FromBlock: &to, ToBlock: &to}); err != nil {
+		t.Errorf("failed to filter range within the limit: %v", err)
+	}
+}
+
+// Tests that replaying the transactions of a block reports the requested trace
+// types.
+func TestTraceReplayBlockTransactions(t *testing.T) {
+	api, receiver, chain := newTraceTestBackend(t, 1)
+	defer api.eth.blockchain.Stop()
+
+	results, err := api.ReplayBlockTransactions(context.Background(), rpc.BlockNumber(1), []string{"trace", "vmTrace", "stateDiff"})
+	if err != nil {
+		t.Fatalf("failed to replay block: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("result count mismatch: have %d, want 2", len(results))
+	}
+	for i, result := range results {
+		if result.TransactionHash != chain[0].Transactions()[i].Hash() {
+			t.Errorf("result %d: hash mismatch: have %x, want %x", i, result.TransactionHash, chain[0].Transactions()[i].Hash())
+		}
+		if len(result.Trace) != 1 {
+			t.Errorf("result %d: trace count mismatch: have %d, want 1", i, len(result.Trace))
+		}
+	}
+	// The value transfer creates the receiver
+	blob, _ := json.Marshal(results[0].StateDiff[receiver])
+	if want := `{"balance":{"+":"0x3e8"},"code":{"+":"0x"},"nonce":{"+":"0x0"},"storage":{}}`; string(blob) != want {
+		t.Errorf("receiver diff mismatch: have %s, want %s", blob, want)
+	}
+	if len(results[0].StateDiff) != 3 {
+		t.Errorf("transfer diff account count mismatch: have %d, want 3", len(results[0].StateDiff))
+	}
+	// The contract creation stores a value and executes four opcodes
+	sender, _ := types.Sender(types.HomesteadSigner{}, chain[0].Transactions()[1])
+	contract := crypto.CreateAddress(sender, 1)
+
+	blob, _ = json.Marshal(results[1].StateDiff[contract])
+	if want := `{"balance":{"+":"0x0"},"code":{"+":"0x"},"nonce":{"+":"0x1"},"storage":{"0x0000000000000000000000000000000000000000000000000000000000000000":{"+":"0x0000000000000000000000000000000000000000000000000000000000000001"}}}`; string(blob) != want {
+		t.Errorf("contract diff mismatch: have %s, want %s", blob, want)
+	}
+	var vmtrace struct {
+		Ops []struct {
+			Pc uint64 `json:"pc"`
+		} `json:"ops"`
+	}
+	if err := json.Unmarshal(results[1].VMTrace, &vmtrace); err != nil {
+		t.Fatalf("failed to decode vmTrace: %v", err)
+	}
+	if len(vmtrace.Ops) != 4 {
+		t.Errorf("vmTrace operation count mismatch: have %d, want 4", len(vmtrace.Ops))
+	}
+}
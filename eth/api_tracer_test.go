@@ -0,0 +1,254 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that a call can be traced with the default struct logger on top of the
+// selected block.
+func TestTraceCall(t *testing.T) {
+	tapi, receiver, chain := newTraceTestBackend(t, 2)
+	defer tapi.eth.blockchain.Stop()
+	api := NewPrivateDebugAPI(tapi.eth)
+
+	// A plain value transfer executes no code
+	var (
+		bob   = common.Address{0xbb}
+		gas   = hexutil.Uint64(100000)
+		price = (*hexutil.Big)(new(big.Int))
+		value = (*hexutil.Big)(big.NewInt(1000))
+		args  = ethapi.CallArgs{From: &receiver, To: &bob, Gas: &gas, GasPrice: price, Value: value}
+	)
+	result, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	res := result.(*ethapi.ExecutionResult)
+	if res.Failed || res.Gas != params.TxGas || len(res.StructLogs) != 0 {
+		t.Errorf("transfer trace mismatch: have %+v", res)
+	}
+	// The receiver is only funded from the first block on, so transferring its
+	// balance fails on top of genesis
+	if _, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(0), nil); err == nil {
+		t.Errorf("traced transfer of missing funds")
+	}
+	if _, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithHash(chain[0].Hash(), true), nil); err != nil {
+		t.Errorf("failed to trace transfer of available funds: %v", err)
+	}
+}
+
+// Tests that the block to trace on can be selected both by number and by hash,
+// and that unknown blocks are rejected.
+func TestTraceCallBlockSelection(t *testing.T) {
+	tapi, _, chain := newTraceTestBackend(t, 3)
+	defer tapi.eth.blockchain.Stop()
+	api := NewPrivateDebugAPI(tapi.eth)
+
+	// Return the number of the block executed on: NUMBER PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	contract := common.Address{0xbb}
+	code := hexutil.Bytes(common.FromHex("0x4360005260206000f3"))
+	config := &TraceCallConfig{StateOverrides: &ethapi.StateOverride{contract: {Code: &code}}}
+	args := ethapi.CallArgs{To: &contract, GasPrice: (*hexutil.Big)(new(big.Int))}
+
+	for i, block := range []rpc.BlockNumberOrHash{
+		rpc.BlockNumberOrHashWithNumber(2),
+		rpc.BlockNumberOrHashWithHash(chain[1].Hash(), false),
+		rpc.BlockNumberOrHashWithHash(chain[1].Hash(), true),
+	} {
+		result, err := api.TraceCall(context.Background(), args, block, config)
+		if err != nil {
+			t.Errorf("test %d: failed to trace call: %v", i, err)
+			continue
+		}
+		if have := result.(*ethapi.ExecutionResult).ReturnValue; new(big.Int).SetBytes(common.FromHex(have)).Uint64() != 2 {
+			t.Errorf("test %d: block number mismatch: have %s, want 2", i, have)
+		}
+	}
+	if _, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(10), config); err == nil {
+		t.Errorf("traced call on missing block number")
+	}
+	if _, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithHash(common.Hash{0x01}, false), config); err == nil {
+		t.Errorf("traced call on missing block hash")
+	}
+}
+
+// Tests that calls without a sender are traced from the first wallet account,
+// the same as eth_call would execute them.
+func TestTraceCallDefaultSender(t *testing.T) {
+	tapi, _, _ := newTraceTestBackend(t, 1)
+	defer tapi.eth.blockchain.Stop()
+	api := NewPrivateDebugAPI(tapi.eth)
+
+	dir, err := ioutil.TempDir("", "tracecall-keystore-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	ks := keystore.NewPlaintextKeyStore(dir)
+	account, err := ks.ImportECDSA(key, "")
+	if err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	tapi.eth.accountManager = accounts.NewManager(&accounts.Config{}, ks)
+
+	// Return the caller: CALLER PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	contract := common.Address{0xbb}
+	code := hexutil.Bytes(common.FromHex("0x3360005260206000f3"))
+	config := &TraceCallConfig{StateOverrides: &ethapi.StateOverride{contract: {Code: &code}}}
+	args := ethapi.CallArgs{To: &contract, GasPrice: (*hexutil.Big)(new(big.Int))}
+
+	result, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	if have := common.BytesToAddress(common.FromHex(result.(*ethapi.ExecutionResult).ReturnValue)); have != account.Address {
+		t.Errorf("sender mismatch: have %x, want %x", have, account.Address)
+	}
+}
+
+// Tests that state overrides are applied before tracing the call.
+func TestTraceCallStateOverride(t *testing.T) {
+	tapi, _, _ := newTraceTestBackend(t, 1)
+	defer tapi.eth.blockchain.Stop()
+	api := NewPrivateDebugAPI(tapi.eth)
+
+	// Return storage slot 0: PUSH1 0 SLOAD PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	var (
+		contract = common.Address{0xbb}
+		from     = common.Address{0xdd}
+		gas      = hexutil.Uint64(100000)
+		code     = hexutil.Bytes(common.FromHex("0x60005460005260206000f3"))
+		storage  = map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(7))}
+		balance  = (*hexutil.Big)(big.NewInt(params.Ether))
+	)
+	config := &TraceCallConfig{StateOverrides: &ethapi.StateOverride{
+		contract: {Code: &code, StateDiff: &storage},
+		from:     {Balance: &balance},
+	}}
+	args := ethapi.CallArgs{From: &from, To: &contract, Gas: &gas}
+
+	if _, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil); err == nil {
+		t.Fatalf("traced call from account unable to pay for gas")
+	}
+	result, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	res := result.(*ethapi.ExecutionResult)
+	if res.Failed || new(big.Int).SetBytes(common.FromHex(res.ReturnValue)).Uint64() != 7 {
+		t.Errorf("return value mismatch: have %s, want 7", res.ReturnValue)
+	}
+	if len(res.StructLogs) != 7 || res.StructLogs[1].Op != "SLOAD" {
+		t.Errorf("struct logs mismatch: have %+v", res.StructLogs)
+	}
+}
+
+// Tests that calls can be traced with named tracers.
+func TestTraceCallNamedTracer(t *testing.T) {
+	tapi, _, _ := newTraceTestBackend(t, 1)
+	defer tapi.eth.blockchain.Stop()
+	api := NewPrivateDebugAPI(tapi.eth)
+
+	contract := common.Address{0xbb}
+	code := hexutil.Bytes(common.FromHex("0x60005460005260206000f3"))
+	args := ethapi.CallArgs{To: &contract, GasPrice: (*hexutil.Big)(new(big.Int))}
+	tracer := "callTracer"
+	config := &TraceCallConfig{
+		TraceConfig:    TraceConfig{Tracer: &tracer},
+		StateOverrides: &ethapi.StateOverride{contract: {Code: &code}},
+	}
+	result, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	blob, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("failed to encode trace: %v", err)
+	}
+	var call struct {
+		Type   string         `json:"type"`
+		To     common.Address `json:"to"`
+		Output hexutil.Bytes  `json:"output"`
+	}
+	if err := json.Unmarshal(blob, &call); err != nil {
+		t.Fatalf("failed to decode call trace: %v", err)
+	}
+	if call.Type != "CALL" || call.To != contract || len(call.Output) != 32 {
+		t.Errorf("call trace mismatch: have %s", blob)
+	}
+	// Unknown tracers should be rejected
+	tracer = "missingTracer"
+	if _, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config); err == nil {
+		t.Errorf("traced call with unknown tracer")
+	}
+}
+
+// Tests that the reasons of reverted calls are only decoded into the struct logs
+// result if requested.
+func TestTraceCallRevertReason(t *testing.T) {
+	tapi, _, _ := newTraceTestBackend(t, 1)
+	defer tapi.eth.blockchain.Stop()
+	api := NewPrivateDebugAPI(tapi.eth)
+
+	// Revert with Error("abc"): store the ABI encoded reason and revert with it
+	contract := common.Address{0xbb}
+	code := hexutil.Bytes(common.FromHex("0x" +
+		"7f08c379a000000000000000000000000000000000000000000000000000000000600052" +
+		"602060045260036024527f6162630000000000000000000000000000000000000000000000000000000000604452" +
+		"60646000fd"))
+	args := ethapi.CallArgs{To: &contract, GasPrice: (*hexutil.Big)(new(big.Int))}
+
+	for _, decode := range []bool{false, true} {
+		config := &TraceCallConfig{
+			TraceConfig:    TraceConfig{RevertReason: decode},
+			StateOverrides: &ethapi.StateOverride{contract: {Code: &code}},
+		}
+		result, err := api.TraceCall(context.Background(), args, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), config)
+		if err != nil {
+			t.Fatalf("decode %v: failed to trace call: %v", decode, err)
+		}
+		res := result.(*ethapi.ExecutionResult)
+		if !res.Failed {
+			t.Errorf("decode %v: reverted call not failed", decode)
+		}
+		want := ""
+		if decode {
+			want = "abc"
+		}
+		if res.RevertReason != want {
+			t.Errorf("decode %v: revert reason mismatch: have %q, want %q", decode, res.RevertReason, want)
+		}
+	}
+}
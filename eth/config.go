@@ -27,6 +27,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/miner"
@@ -97,10 +98,14 @@ type Config struct {
 
 	NoPruning  bool // Whether to disable pruning and flush everything to disk
 	NoPrefetch bool // Whether to disable prefetching and only load state on demand
+	TraceIndex bool // Whether to index the internal transactions of all blocks (needs NoPruning)
 
 	// Whitelist of required block number -> hash values to accept
 	Whitelist map[uint64]common.Hash `toml:"-"`
 
+	// Subjective reorg penalty against deep reorgs, nil to disable
+	ReorgPenalty *core.ReorgPenaltyConfig `toml:",omitempty"`
+
 	// Light client options
 	LightServ    int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightIngress int `toml:",omitempty"` // Incoming bandwidth limit for light servers
@@ -113,14 +118,16 @@ type Config struct {
 	UltraLightOnlyAnnounce bool     `toml:",omitempty"` // Whether to only announce headers, or also serve them
 
 	// Database options
-	SkipBcVersionCheck bool `toml:"-"`
-	DatabaseHandles    int  `toml:"-"`
-	DatabaseCache      int
-	DatabaseFreezer    string
+	SkipBcVersionCheck    bool `toml:"-"`
+	DatabaseHandles       int  `toml:"-"`
+	DatabaseCache         int
+	DatabaseFreezer       string
+	DatabaseFreezerConfig rawdb.FreezerConfig // Per-table compression and retention of ancient data
 
 	TrieCleanCache int
 	TrieDirtyCache int
 	TrieTimeout    time.Duration
+	SnapshotCache  int // Megabytes of the state snapshot read cache, 0 disables snapshots
 
 	// Mining options
 	Miner miner.Config
@@ -0,0 +1,225 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	forkWatchInterval = time.Minute      // Time between two fork readiness evaluations
+	forkWarnInterval  = 10 * time.Minute // Minimum time between two repeated readiness warnings
+	forkWarnMinPeers  = 3                // Minimum number of peers with known fork IDs to warn about
+)
+
+var (
+	forkPeersMatchingGauge     = metrics.NewRegisteredGauge("eth/forkid/peers/matching", nil)
+	forkPeersUnknownGauge      = metrics.NewRegisteredGauge("eth/forkid/peers/unknown", nil)
+	forkPeersStaleGauge        = metrics.NewRegisteredGauge("eth/forkid/peers/stale", nil)
+	forkPeersIncompatibleGauge = metrics.NewRegisteredGauge("eth/forkid/peers/incompatible", nil)
+	forkNextRemainingGauge     = metrics.NewRegisteredGauge("eth/forkid/next/remaining", nil)
+)
+
+// ForkReadiness summarizes how the fork IDs announced by the connected peers
+// relate to the local one, and how far the next scheduled local fork is.
+type ForkReadiness struct {
+	Hash      hexutil.Bytes   `json:"hash"`      // Local fork checksum
+	Next      *hexutil.Uint64 `json:"next"`      // Next block activating local protocol changes, nil if none
+	Remaining *hexutil.Uint64 `json:"remaining"` // Blocks left until the next local fork
+	Changes   []string        `json:"changes"`   // Protocol changes activated by the next local fork
+
+	Peers        int                    `json:"peers"`        // Peers with a known fork ID
+	Matching     int                    `json:"matching"`     // Peers agreeing with the local fork schedule
+	Stale        int                    `json:"stale"`        // Peers lacking forks scheduled locally
+	Incompatible int                    `json:"incompatible"` // Peers on a diverged chain
+	Unknown      map[hexutil.Uint64]int `json:"unknown"`      // Peers per announced fork block missing locally
+
+	Warning string `json:"warning,omitempty"` // Set if enough peers announce an unknown fork
+}
+
+// forkWatcher aggregates the fork IDs announced by the connected peers in their
+// node records. Only peers with an "eth" ENR entry contribute, which in practice
+// means the ones found and dialed through discovery v4 with ENR support.
+type forkWatcher struct {
+	chain  *core.BlockChain
+	filter func(forkid.ID) error
+
+	peers map[string]forkid.ID
+	lock  sync.RWMutex
+
+	lastWarn  time.Time // Time of the last readiness warning
+	lastNext  uint64    // Fork block of the last logged fork countdown
+	lastScale int       // Order of magnitude of the last logged fork countdown
+}
+
+// newForkWatcher creates a peer fork ID aggregator for the given chain.
+func newForkWatcher(chain *core.BlockChain) *forkWatcher {
+	return &forkWatcher{
+		chain:  chain,
+		filter: forkid.NewFilter(chain),
+		peers:  make(map[string]forkid.ID),
+	}
+}
+
+// register tracks the fork ID announced by a peer.
+func (w *forkWatcher) register(id string, fid forkid.ID) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.peers[id] = fid
+}
+
+// unregister stops tracking the fork ID of a peer.
+func (w *forkWatcher) unregister(id string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	delete(w.peers, id)
+}
+
+// status evaluates the fork readiness of the local node against its peers.
+func (w *forkWatcher) status() *ForkReadiness {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	head := w.chain.CurrentHeader().Number
+	status := classifyForkIDs(forkid.NewID(w.chain), w.filter, w.peers)
+	scheduleNextFork(status, w.chain.Config(), head)
+
+	return status
+}
+
+// scheduleNextFork fills in the next fork of the status from the protocol changes
+// scheduled after head. The fork IDs aren't used for this, as they also include
+// forks not activating anything locally (e.g. an opposed DAO fork).
+func scheduleNextFork(status *ForkReadiness, config *params.ChainConfig, head *big.Int) {
+	next := config.NextActivations(head)
+	if len(next) == 0 {
+		return
+	}
+	block := hexutil.Uint64(next[0].Block.Uint64())
+	remaining := block - hexutil.Uint64(head.Uint64())
+	status.Next, status.Remaining = &block, &remaining
+
+	status.Changes = make([]string, 0, len(next))
+	for _, activation := range next {
+		status.Changes = append(status.Changes, activation.Name)
+	}
+}
+
+// classifyForkIDs compares the fork IDs of a set of peers against the local one.
+func classifyForkIDs(local forkid.ID, filter func(forkid.ID) error, peers map[string]forkid.ID) *ForkReadiness {
+	status := &ForkReadiness{
+		Hash:    local.Hash[:],
+		Peers:   len(peers),
+		Unknown: make(map[hexutil.Uint64]int),
+	}
+	var unknown int
+	for _, id := range peers {
+		if id.Hash != local.Hash {
+			// Different checksums are fine if one side is simply not synced yet
+			switch filter(id) {
+			case nil:
+				status.Matching++
+			case forkid.ErrRemoteStale:
+				status.Stale++
+			default:
+				status.Incompatible++
+			}
+			continue
+		}
+		switch {
+		case id.Next == local.Next:
+			status.Matching++
+		case local.Next == 0 || (id.Next != 0 && id.Next < local.Next):
+			status.Unknown[hexutil.Uint64(id.Next)]++
+			unknown++
+		default:
+			status.Stale++
+		}
+	}
+	// Warn if a significant share of the peers expects a fork we don't know about
+	if status.Peers >= forkWarnMinPeers && unknown*3 >= status.Peers {
+		status.Warning = "many peers announce an unknown upcoming fork, the local node is likely outdated"
+	}
+	return status
+}
+
+// loop periodically evaluates the fork readiness, updating the metrics and
+// logging warnings and the countdown to the next fork until quit is closed.
+func (w *forkWatcher) loop(quit chan struct{}) {
+	ticker := time.NewTicker(forkWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		w.report(w.status())
+
+		select {
+		case <-ticker.C:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// report publishes a fork readiness evaluation through metrics and logs.
+func (w *forkWatcher) report(status *ForkReadiness) {
+	forkPeersMatchingGauge.Update(int64(status.Matching))
+	forkPeersStaleGauge.Update(int64(status.Stale))
+	forkPeersIncompatibleGauge.Update(int64(status.Incompatible))
+
+	var unknown int
+	for _, count := range status.Unknown {
+		unknown += count
+	}
+	forkPeersUnknownGauge.Update(int64(unknown))
+
+	if status.Warning != "" && time.Since(w.lastWarn) >= forkWarnInterval {
+		var block hexutil.Uint64
+		for fork, count := range status.Unknown {
+			if count > status.Unknown[block] {
+				block = fork
+			}
+		}
+		log.Warn("Peers announce an unknown fork, check for a client update", "block", uint64(block), "peers", status.Unknown[block], "total", status.Peers)
+		w.lastWarn = time.Now()
+	}
+	// Count down to the next fork whenever the remaining blocks drop an order of magnitude
+	if status.Remaining == nil {
+		forkNextRemainingGauge.Update(0)
+		w.lastNext, w.lastScale = 0, 0
+		return
+	}
+	next, remaining := uint64(*status.Next), uint64(*status.Remaining)
+	forkNextRemainingGauge.Update(int64(remaining))
+
+	scale := len(strconv.FormatUint(remaining, 10))
+	if next != w.lastNext || scale < w.lastScale {
+		log.Info("Upcoming fork scheduled", "block", next, "remaining", remaining, "changes", status.Changes)
+	}
+	w.lastNext, w.lastScale = next, scale
+}
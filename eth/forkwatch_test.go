@@ -0,0 +1,159 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that peer fork IDs are classified against the local one, and that a
+// warning is raised if enough peers announce a fork unknown locally.
+func TestClassifyForkIDs(t *testing.T) {
+	var (
+		hash     = [4]byte{0x01}
+		stale    = forkid.ID{Hash: [4]byte{0x02}}
+		diverged = forkid.ID{Hash: [4]byte{0x03}}
+	)
+	filter := func(id forkid.ID) error {
+		switch id.Hash {
+		case stale.Hash:
+			return forkid.ErrRemoteStale
+		case diverged.Hash:
+			return forkid.ErrLocalIncompatibleOrStale
+		}
+		return nil
+	}
+	tests := []struct {
+		local   forkid.ID
+		peers   []forkid.ID
+		match   int
+		stale   int
+		incomp  int
+		unknown map[hexutil.Uint64]int
+		warn    bool
+	}{
+		// All peers agree with the local schedule
+		{
+			local: forkid.ID{Hash: hash, Next: 100},
+			peers: []forkid.ID{{Hash: hash, Next: 100}, {Hash: hash, Next: 100}, {Hash: [4]byte{0x04}}},
+			match: 3,
+		},
+		// Peers lacking the upcoming local fork, or behind it, are stale
+		{
+			local: forkid.ID{Hash: hash, Next: 100},
+			peers: []forkid.ID{{Hash: hash}, {Hash: hash, Next: 200}, stale},
+			stale: 3,
+		},
+		// Peers announcing a fork before the local one are ahead of us
+		{
+			local:   forkid.ID{Hash: hash, Next: 100},
+			peers:   []forkid.ID{{Hash: hash, Next: 50}, {Hash: hash, Next: 100}, {Hash: hash, Next: 100}, diverged},
+			match:   2,
+			incomp:  1,
+			unknown: map[hexutil.Uint64]int{50: 1},
+		},
+		// A significant share of the peers announcing an unknown fork warns
+		{
+			local:   forkid.ID{Hash: hash},
+			peers:   []forkid.ID{{Hash: hash, Next: 80}, {Hash: hash, Next: 80}, {Hash: hash}, {Hash: hash}, {Hash: hash}},
+			match:   3,
+			unknown: map[hexutil.Uint64]int{80: 2},
+			warn:    true,
+		},
+		// Too few peers to warn
+		{
+			local:   forkid.ID{Hash: hash},
+			peers:   []forkid.ID{{Hash: hash, Next: 80}, {Hash: hash, Next: 80}},
+			unknown: map[hexutil.Uint64]int{80: 2},
+		},
+	}
+	for i, tt := range tests {
+		peers := make(map[string]forkid.ID)
+		for j, id := range tt.peers {
+			peers[fmt.Sprint(j)] = id
+		}
+		status := classifyForkIDs(tt.local, filter, peers)
+		if status.Matching != tt.match || status.Stale != tt.stale || status.Incompatible != tt.incomp {
+			t.Errorf("test %d: classification mismatch: have %d/%d/%d, want %d/%d/%d", i,
+				status.Matching, status.Stale, status.Incompatible, tt.match, tt.stale, tt.incomp)
+		}
+		if len(status.Unknown) != len(tt.unknown) {
+			t.Errorf("test %d: unknown forks mismatch: have %v, want %v", i, status.Unknown, tt.unknown)
+		}
+		for block, count := range tt.unknown {
+			if status.Unknown[block] != count {
+				t.Errorf("test %d: unknown fork %d peers mismatch: have %d, want %d", i, block, status.Unknown[block], count)
+			}
+		}
+		if (status.Warning != "") != tt.warn {
+			t.Errorf("test %d: warning mismatch: have %q, want %v", i, status.Warning, tt.warn)
+		}
+	}
+}
+
+// Tests that the next fork is reported with all the changes activated by it,
+// merging coinciding forks and skipping ones activating nothing locally.
+func TestScheduleNextFork(t *testing.T) {
+	config := &params.ChainConfig{
+		ChainID:        big.NewInt(1),
+		HomesteadBlock: big.NewInt(0),
+		DAOForkBlock:   big.NewInt(5), // Opposed, so it never activates anything
+		EIP150Block:    big.NewInt(10),
+		EIP155Block:    big.NewInt(10),
+		EIP158Block:    big.NewInt(10),
+		ECIP1099Block:  big.NewInt(20),
+		ByzantiumBlock: big.NewInt(20),
+	}
+	tests := []struct {
+		head      int64
+		next      uint64
+		remaining uint64
+		changes   []string
+	}{
+		{2, 10, 8, []string{"EIP-150", "EIP-155", "EIP-160", "EIP-161", "EIP-170"}},
+		{5, 10, 5, []string{"EIP-150", "EIP-155", "EIP-160", "EIP-161", "EIP-170"}},
+		{10, 20, 10, []string{"EIP-100", "EIP-140", "EIP-198", "EIP-211", "EIP-212", "EIP-213", "EIP-214", "EIP-649", "EIP-658", "ECIP-1099"}},
+		{20, 0, 0, nil},
+	}
+	for i, tt := range tests {
+		status := new(ForkReadiness)
+		scheduleNextFork(status, config, big.NewInt(tt.head))
+
+		if tt.changes == nil {
+			if status.Next != nil || status.Remaining != nil || status.Changes != nil {
+				t.Errorf("test %d: unexpected next fork: block %v in %v, changes %v", i, status.Next, status.Remaining, status.Changes)
+			}
+			continue
+		}
+		if status.Next == nil || uint64(*status.Next) != tt.next {
+			t.Errorf("test %d: next fork mismatch: have %v, want %d", i, status.Next, tt.next)
+		}
+		if status.Remaining == nil || uint64(*status.Remaining) != tt.remaining {
+			t.Errorf("test %d: remaining blocks mismatch: have %v, want %d", i, status.Remaining, tt.remaining)
+		}
+		if !reflect.DeepEqual(status.Changes, tt.changes) {
+			t.Errorf("test %d: changes mismatch: have %v, want %v", i, status.Changes, tt.changes)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// maxFeeHistory is the maximum number of blocks a fee history can be requested
+// for at once.
+const maxFeeHistory = 1024
+
+// maxBlockFetchers is the maximum number of blocks retrieved concurrently while
+// gathering a fee history, to avoid flooding light clients with requests.
+const maxBlockFetchers = 4
+
+var (
+	errInvalidPercentile = errors.New("invalid reward percentile")
+	errRequestBeyondHead = errors.New("request beyond head block")
+)
+
+// blockFees is the gas used ratio and the gas price percentiles of a block.
+type blockFees struct {
+	index        int // position of the block within the requested range
+	reward       []*big.Int
+	gasUsedRatio float64
+	err          error
+}
+
+// txGasAndPrice is the gas used by a transaction along with its gas price.
+type txGasAndPrice struct {
+	gasUsed uint64
+	price   *big.Int
+}
+
+type txsByGasPrice []txGasAndPrice
+
+func (s txsByGasPrice) Len() int           { return len(s) }
+func (s txsByGasPrice) Less(i, j int) bool { return s[i].price.Cmp(s[j].price) < 0 }
+func (s txsByGasPrice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// FeeHistory returns the gas used ratio of a range of blocks ending with the
+// given one, along with the requested percentiles of the gas prices paid within
+// each of them. The percentiles are weighted by the gas used by the transactions,
+// so a percentile is the price at or below which that ratio of the gas in the
+// block was bought.
+//
+// The number of blocks is capped at maxFeeHistory and at the start of the chain.
+// The pending block is not known to every client, so it's treated as latest.
+// The first return value is the number of the oldest block of the range.
+func (gpo *Oracle) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	if blocks < 1 {
+		return new(big.Int), nil, []float64{}, nil
+	}
+	if blocks > maxFeeHistory {
+		blocks = maxFeeHistory
+	}
+	for i, p := range rewardPercentiles {
+		if p < 0 || p > 100 {
+			return nil, nil, nil, fmt.Errorf("%v: %f", errInvalidPercentile, p)
+		}
+		if i > 0 && p < rewardPercentiles[i-1] {
+			return nil, nil, nil, fmt.Errorf("%v: #%d:%f > #%d:%f", errInvalidPercentile, i-1, rewardPercentiles[i-1], i, p)
+		}
+	}
+	if lastBlock == rpc.PendingBlockNumber {
+		lastBlock = rpc.LatestBlockNumber
+	}
+	head, err := gpo.backend.HeaderByNumber(ctx, lastBlock)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if head == nil {
+		return nil, nil, nil, errRequestBeyondHead
+	}
+	last := head.Number.Uint64()
+	if uint64(blocks) > last+1 {
+		blocks = int(last + 1)
+	}
+	oldest := last + 1 - uint64(blocks)
+
+	// Gather the fees of the blocks with a limited number of concurrent fetchers
+	var (
+		next    = int64(-1)
+		results = make(chan blockFees, blocks)
+		quit    = make(chan struct{})
+	)
+	defer close(quit)
+
+	for i := 0; i < maxBlockFetchers && i < blocks; i++ {
+		go func() {
+			for {
+				// Stop fetching if the history was aborted due to an error
+				select {
+				case <-quit:
+					return
+				default:
+				}
+				index := int(atomic.AddInt64(&next, 1))
+				if index >= blocks {
+					return
+				}
+				fees := gpo.getBlockFees(ctx, oldest+uint64(index), rewardPercentiles)
+				fees.index = index
+				results <- fees
+			}
+		}()
+	}
+	var (
+		reward       [][]*big.Int
+		gasUsedRatio = make([]float64, blocks)
+	)
+	if len(rewardPercentiles) > 0 {
+		reward = make([][]*big.Int, blocks)
+	}
+	for i := 0; i < blocks; i++ {
+		fees := <-results
+		if fees.err != nil {
+			return nil, nil, nil, fees.err
+		}
+		gasUsedRatio[fees.index] = fees.gasUsedRatio
+		if reward != nil {
+			reward[fees.index] = fees.reward
+		}
+	}
+	return new(big.Int).SetUint64(oldest), reward, gasUsedRatio, nil
+}
+
+// getBlockFees calculates the gas used ratio and the gas price percentiles of a
+// given block.
+func (gpo *Oracle) getBlockFees(ctx context.Context, number uint64, percentiles []float64) blockFees {
+	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+	if block == nil {
+		if err == nil {
+			err = fmt.Errorf("block #%d not found", number)
+		}
+		return blockFees{err: err}
+	}
+	fees := blockFees{}
+	if block.GasLimit() > 0 {
+		fees.gasUsedRatio = float64(block.GasUsed()) / float64(block.GasLimit())
+	}
+	if len(percentiles) == 0 {
+		return fees
+	}
+	fees.reward = make([]*big.Int, len(percentiles))
+
+	txs := block.Transactions()
+	if len(txs) == 0 || block.GasUsed() == 0 {
+		// Return an all zero reward for empty blocks
+		for i := range fees.reward {
+			fees.reward[i] = new(big.Int)
+		}
+		return fees
+	}
+	receipts, err := gpo.backend.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return blockFees{err: err}
+	}
+	if len(receipts) != len(txs) {
+		return blockFees{err: fmt.Errorf("receipts of block #%d not available", number)}
+	}
+	sorted := make([]txGasAndPrice, len(txs))
+	for i, tx := range txs {
+		sorted[i] = txGasAndPrice{gasUsed: receipts[i].GasUsed, price: tx.GasPrice()}
+	}
+	sort.Sort(txsByGasPrice(sorted))
+
+	var (
+		txIndex int
+		sumUsed = sorted[0].gasUsed
+	)
+	for i, p := range percentiles {
+		threshold := uint64(float64(block.GasUsed()) * p / 100)
+		for sumUsed < threshold && txIndex < len(sorted)-1 {
+			txIndex++
+			sumUsed += sorted[txIndex].gasUsed
+		}
+		fees.reward[i] = new(big.Int).Set(sorted[txIndex].price)
+	}
+	return fees
+}
@@ -0,0 +1,201 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testBackend is an oracle backend serving a generated chain, implementing only
+// the methods needed to gather fee histories. It doesn't know about a pending
+// block, so requesting one fails.
+type testBackend struct {
+	ethapi.Backend
+	blocks   []*types.Block
+	receipts map[common.Hash]types.Receipts
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return params.TestChainConfig }
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	switch {
+	case number == rpc.LatestBlockNumber:
+		return b.blocks[len(b.blocks)-1], nil
+	case number < 0:
+		return nil, errors.New("unknown block")
+	case int(number) >= len(b.blocks):
+		return nil, nil
+	}
+	return b.blocks[number], nil
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	block, err := b.BlockByNumber(ctx, number)
+	if block == nil {
+		return nil, err
+	}
+	return block.Header(), nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.receipts[hash], nil
+}
+
+// newTestBackend creates a chain longer than the maximum fee history, with the
+// first block containing transfers at the given gas prices (in gwei) and all
+// others being empty.
+func newTestBackend(t *testing.T, prices []int64) *testBackend {
+	var (
+		key, _ = crypto.GenerateKey()
+		from   = crypto.PubkeyToAddress(key.PublicKey)
+		db     = rawdb.NewMemoryDatabase()
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer  = types.NewEIP155Signer(gspec.Config.ChainID)
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, receipts := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, maxFeeHistory+10, func(i int, b *core.BlockGen) {
+		if i > 0 {
+			return
+		}
+		for nonce, price := range prices {
+			tx := types.NewTransaction(uint64(nonce), common.Address{0xbb}, big.NewInt(1), params.TxGas, big.NewInt(price*params.GWei), nil)
+			tx, err := types.SignTx(tx, signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign transaction: %v", err)
+			}
+			b.AddTx(tx)
+		}
+	})
+	backend := &testBackend{
+		blocks:   append([]*types.Block{genesis}, blocks...),
+		receipts: make(map[common.Hash]types.Receipts),
+	}
+	for i, block := range blocks {
+		backend.receipts[block.Hash()] = receipts[i]
+	}
+	return backend
+}
+
+// Tests that the requested range of blocks is clamped at the start of the chain
+// and at the maximum history length, and that pending is treated as latest.
+func TestFeeHistoryRange(t *testing.T) {
+	backend := newTestBackend(t, nil)
+	oracle := NewOracle(backend, Config{Blocks: 20, Percentile: 60})
+	head := uint64(len(backend.blocks) - 1)
+
+	tests := []struct {
+		blocks int
+		last   rpc.BlockNumber
+		oldest uint64
+		count  int
+	}{
+		{blocks: 0, last: rpc.LatestBlockNumber, oldest: 0, count: 0},
+		{blocks: 1, last: rpc.LatestBlockNumber, oldest: head, count: 1},
+		{blocks: 5, last: rpc.PendingBlockNumber, oldest: head - 4, count: 5},
+		{blocks: 5, last: 10, oldest: 6, count: 5},
+		{blocks: 5, last: 2, oldest: 0, count: 3},
+		{blocks: 5, last: 0, oldest: 0, count: 1},
+		{blocks: maxFeeHistory + 5, last: rpc.LatestBlockNumber, oldest: head + 1 - maxFeeHistory, count: maxFeeHistory},
+	}
+	for i, tt := range tests {
+		oldest, reward, ratio, err := oracle.FeeHistory(context.Background(), tt.blocks, tt.last, nil)
+		if err != nil {
+			t.Errorf("test %d: failed to retrieve fee history: %v", i, err)
+			continue
+		}
+		if oldest.Uint64() != tt.oldest {
+			t.Errorf("test %d: oldest block mismatch: have %d, want %d", i, oldest, tt.oldest)
+		}
+		if ratio == nil || len(ratio) != tt.count {
+			t.Errorf("test %d: gas used ratio count mismatch: have %v, want %d", i, ratio, tt.count)
+		}
+		if reward != nil {
+			t.Errorf("test %d: reward returned without percentiles: %v", i, reward)
+		}
+	}
+	if _, _, _, err := oracle.FeeHistory(context.Background(), 1, rpc.BlockNumber(head+1), nil); err != errRequestBeyondHead {
+		t.Errorf("error mismatch for block beyond head: have %v, want %v", err, errRequestBeyondHead)
+	}
+}
+
+// Tests that reward percentiles must be in range and in ascending order.
+func TestFeeHistoryInvalidPercentiles(t *testing.T) {
+	oracle := NewOracle(newTestBackend(t, nil), Config{Blocks: 20, Percentile: 60})
+
+	for i, percentiles := range [][]float64{{-1}, {101}, {10, 5}, {0, 50, 40, 100}} {
+		_, _, _, err := oracle.FeeHistory(context.Background(), 1, rpc.LatestBlockNumber, percentiles)
+		if err == nil || !strings.HasPrefix(err.Error(), errInvalidPercentile.Error()) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, errInvalidPercentile)
+		}
+	}
+	for i, percentiles := range [][]float64{{0}, {100}, {10, 10}, {0, 50, 50, 100}} {
+		if _, _, _, err := oracle.FeeHistory(context.Background(), 1, rpc.LatestBlockNumber, percentiles); err != nil {
+			t.Errorf("test %d: failed to retrieve fee history: %v", i, err)
+		}
+	}
+}
+
+// Tests that the rewards are the gas prices paid, weighted by the gas used, and
+// that the gas used ratios are reported for each block.
+func TestFeeHistoryValues(t *testing.T) {
+	backend := newTestBackend(t, []int64{5, 1, 1, 1})
+	oracle := NewOracle(backend, Config{Blocks: 20, Percentile: 60})
+
+	percentiles := []float64{0, 50, 75, 80, 100}
+	oldest, reward, ratio, err := oracle.FeeHistory(context.Background(), 2, 2, percentiles)
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	if oldest.Uint64() != 1 {
+		t.Errorf("oldest block mismatch: have %d, want 1", oldest)
+	}
+	full := backend.blocks[1]
+	if want := float64(4*params.TxGas) / float64(full.GasLimit()); ratio[0] != want {
+		t.Errorf("gas used ratio mismatch: have %f, want %f", ratio[0], want)
+	}
+	if ratio[1] != 0 {
+		t.Errorf("empty block gas used ratio mismatch: have %f, want 0", ratio[1])
+	}
+	if len(reward) != 2 {
+		t.Fatalf("reward count mismatch: have %d, want 2", len(reward))
+	}
+	for i, want := range []int64{1, 1, 1, 5, 5} {
+		if have := reward[0][i]; have.Cmp(big.NewInt(want*params.GWei)) != 0 {
+			t.Errorf("percentile %v: reward mismatch: have %v, want %d gwei", percentiles[i], have, want)
+		}
+		if have := reward[1][i]; have.Sign() != 0 {
+			t.Errorf("percentile %v: empty block reward mismatch: have %v, want 0", percentiles[i], have)
+		}
+	}
+}
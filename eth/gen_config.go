@@ -9,6 +9,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/miner"
@@ -23,21 +24,25 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SyncMode                downloader.SyncMode
 		NoPruning               bool
 		NoPrefetch              bool
-		Whitelist               map[uint64]common.Hash `toml:"-"`
-		LightServ               int                    `toml:",omitempty"`
-		LightIngress            int                    `toml:",omitempty"`
-		LightEgress             int                    `toml:",omitempty"`
-		LightPeers              int                    `toml:",omitempty"`
-		UltraLightServers       []string               `toml:",omitempty"`
-		UltraLightFraction      int                    `toml:",omitempty"`
-		UltraLightOnlyAnnounce  bool                   `toml:",omitempty"`
-		SkipBcVersionCheck      bool                   `toml:"-"`
-		DatabaseHandles         int                    `toml:"-"`
+		TraceIndex              bool
+		Whitelist               map[uint64]common.Hash   `toml:"-"`
+		ReorgPenalty            *core.ReorgPenaltyConfig `toml:",omitempty"`
+		LightServ               int                      `toml:",omitempty"`
+		LightIngress            int                      `toml:",omitempty"`
+		LightEgress             int                      `toml:",omitempty"`
+		LightPeers              int                      `toml:",omitempty"`
+		UltraLightServers       []string                 `toml:",omitempty"`
+		UltraLightFraction      int                      `toml:",omitempty"`
+		UltraLightOnlyAnnounce  bool                     `toml:",omitempty"`
+		SkipBcVersionCheck      bool                     `toml:"-"`
+		DatabaseHandles         int                      `toml:"-"`
 		DatabaseCache           int
 		DatabaseFreezer         string
+		DatabaseFreezerConfig   rawdb.FreezerConfig
 		TrieCleanCache          int
 		TrieDirtyCache          int
 		TrieTimeout             time.Duration
+		SnapshotCache           int
 		Miner                   miner.Config
 		Ethash                  ethash.Config
 		TxPool                  core.TxPoolConfig
@@ -56,7 +61,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SyncMode = c.SyncMode
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
+	enc.TraceIndex = c.TraceIndex
 	enc.Whitelist = c.Whitelist
+	enc.ReorgPenalty = c.ReorgPenalty
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
 	enc.LightEgress = c.LightEgress
@@ -68,9 +75,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
 	enc.DatabaseFreezer = c.DatabaseFreezer
+	enc.DatabaseFreezerConfig = c.DatabaseFreezerConfig
 	enc.TrieCleanCache = c.TrieCleanCache
 	enc.TrieDirtyCache = c.TrieDirtyCache
 	enc.TrieTimeout = c.TrieTimeout
+	enc.SnapshotCache = c.SnapshotCache
 	enc.Miner = c.Miner
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
@@ -93,21 +102,25 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SyncMode                *downloader.SyncMode
 		NoPruning               *bool
 		NoPrefetch              *bool
-		Whitelist               map[uint64]common.Hash `toml:"-"`
-		LightServ               *int                   `toml:",omitempty"`
-		LightIngress            *int                   `toml:",omitempty"`
-		LightEgress             *int                   `toml:",omitempty"`
-		LightPeers              *int                   `toml:",omitempty"`
-		UltraLightServers       []string               `toml:",omitempty"`
-		UltraLightFraction      *int                   `toml:",omitempty"`
-		UltraLightOnlyAnnounce  *bool                  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool                  `toml:"-"`
-		DatabaseHandles         *int                   `toml:"-"`
+		TraceIndex              *bool
+		Whitelist               map[uint64]common.Hash   `toml:"-"`
+		ReorgPenalty            *core.ReorgPenaltyConfig `toml:",omitempty"`
+		LightServ               *int                     `toml:",omitempty"`
+		LightIngress            *int                     `toml:",omitempty"`
+		LightEgress             *int                     `toml:",omitempty"`
+		LightPeers              *int                     `toml:",omitempty"`
+		UltraLightServers       []string                 `toml:",omitempty"`
+		UltraLightFraction      *int                     `toml:",omitempty"`
+		UltraLightOnlyAnnounce  *bool                    `toml:",omitempty"`
+		SkipBcVersionCheck      *bool                    `toml:"-"`
+		DatabaseHandles         *int                     `toml:"-"`
 		DatabaseCache           *int
 		DatabaseFreezer         *string
+		DatabaseFreezerConfig   *rawdb.FreezerConfig
 		TrieCleanCache          *int
 		TrieDirtyCache          *int
 		TrieTimeout             *time.Duration
+		SnapshotCache           *int
 		Miner                   *miner.Config
 		Ethash                  *ethash.Config
 		TxPool                  *core.TxPoolConfig
@@ -139,9 +152,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPrefetch != nil {
 		c.NoPrefetch = *dec.NoPrefetch
 	}
+	if dec.TraceIndex != nil {
+		c.TraceIndex = *dec.TraceIndex
+	}
 	if dec.Whitelist != nil {
 		c.Whitelist = dec.Whitelist
 	}
+	if dec.ReorgPenalty != nil {
+		c.ReorgPenalty = dec.ReorgPenalty
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
@@ -175,6 +194,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseFreezer != nil {
 		c.DatabaseFreezer = *dec.DatabaseFreezer
 	}
+	if dec.DatabaseFreezerConfig != nil {
+		c.DatabaseFreezerConfig = *dec.DatabaseFreezerConfig
+	}
 	if dec.TrieCleanCache != nil {
 		c.TrieCleanCache = *dec.TrieCleanCache
 	}
@@ -184,6 +206,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TrieTimeout != nil {
 		c.TrieTimeout = *dec.TrieTimeout
 	}
+	if dec.SnapshotCache != nil {
+		c.SnapshotCache = *dec.SnapshotCache
+	}
 	if dec.Miner != nil {
 		c.Miner = *dec.Miner
 	}
@@ -85,6 +85,7 @@ type ProtocolManager struct {
 	minedBlockSub *event.TypeMuxSubscription
 
 	whitelist map[uint64]common.Hash
+	forks     *forkWatcher
 
 	// channels for fetcher, syncer, txsyncLoop
 	newPeerCh   chan *peer
@@ -108,6 +109,7 @@ func NewProtocolManager(config *params.ChainConfig, checkpoint *params.TrustedCh
 		blockchain:  blockchain,
 		peers:       newPeerSet(),
 		whitelist:   whitelist,
+		forks:       newForkWatcher(blockchain),
 		newPeerCh:   make(chan *peer),
 		noMorePeers: make(chan struct{}),
 		txsyncCh:    make(chan *txsync),
@@ -254,6 +256,9 @@ func (pm *ProtocolManager) Start(maxPeers int) {
 	// start sync handlers
 	go pm.syncer()
 	go pm.txsyncLoop()
+
+	// start fork readiness tracking
+	go pm.forks.loop(pm.quitSync)
 }
 
 func (pm *ProtocolManager) Stop() {
@@ -316,6 +321,13 @@ func (pm *ProtocolManager) handle(p *peer) error {
 	}
 	defer pm.removePeer(p.id)
 
+	// Track the fork ID announced in the peer's node record, if any
+	var entry ethEntry
+	if err := p.Node().Load(&entry); err == nil {
+		pm.forks.register(p.id, entry.ForkID)
+		defer pm.forks.unregister(p.id)
+	}
+
 	// Register the peer in the downloader. If the downloader considers it banned, we disconnect
 	if err := pm.downloader.RegisterPeer(p.id, p.version, p); err != nil {
 		return err
@@ -0,0 +1,178 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// traceIndexThrottling is the time to wait between processing two consecutive
+	// trace index sections, allowing block imports to proceed while indexing.
+	traceIndexThrottling = 100 * time.Millisecond
+)
+
+// TraceIndexer implements a core.ChainIndexer, re-executing the blocks of the
+// canonical chain and storing their internal transactions, permitting historical
+// queries by account without tracing the blocks again.
+//
+// The indexer needs the state of every block to be available, i.e. it's only
+// usable on archive nodes.
+type TraceIndexer struct {
+	api   *PrivateTraceAPI // Trace API used to execute the blocks
+	db    ethdb.Database   // Database instance to write index data into
+	batch ethdb.Batch      // Batch accumulating the index data of the current section
+}
+
+// NewTraceIndexer returns a chain indexer that generates the internal transaction
+// index of the canonical chain.
+func NewTraceIndexer(eth *Ethereum, size, confirms uint64) *core.ChainIndexer {
+	backend := &TraceIndexer{
+		api: NewPrivateTraceAPI(eth),
+		db:  eth.chainDb,
+	}
+	table := rawdb.NewTable(eth.chainDb, string(rawdb.TraceIndexPrefix))
+
+	return core.NewChainIndexer(eth.chainDb, table, backend, size, confirms, traceIndexThrottling, "traces")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new trace index section.
+func (b *TraceIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.batch = b.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, tracing a block and adding its
+// internal transactions to the index.
+func (b *TraceIndexer) Process(ctx context.Context, header *types.Header) error {
+	// The genesis block has nothing to execute
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	block := b.api.eth.blockchain.GetBlock(header.Hash(), number)
+	if block == nil {
+		return fmt.Errorf("block #%d [%x] not found", number, header.Hash())
+	}
+	statedb, err := b.api.parentState(block)
+	if err != nil {
+		return err
+	}
+	traces, err := b.api.traceBlock(ctx, block, statedb)
+	if err != nil {
+		return err
+	}
+	txs, err := internalTxs(traces)
+	if err != nil {
+		return err
+	}
+	rawdb.WriteInternalTxs(b.batch, block.Hash(), number, txs)
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing the internal transactions
+// of the section out into the database.
+func (b *TraceIndexer) Commit() error {
+	return b.batch.Write()
+}
+
+// internalTxs extracts the value transferring nested calls, the contract creations
+// and the self destructs from the traces of a block, skipping the ones reverted
+// by a failure of themselves or of any of their callers.
+func internalTxs(traces []*parityTrace) ([]*types.InternalTx, error) {
+	var (
+		txs    = []*types.InternalTx{}
+		failed = make(map[string]bool) // Trace addresses of failed calls within the current transaction
+		txIdx  = ^uint64(0)
+	)
+	for _, trace := range traces {
+		if trace.TransactionPosition == nil {
+			continue // block rewards
+		}
+		if *trace.TransactionPosition != txIdx {
+			txIdx, failed = *trace.TransactionPosition, make(map[string]bool)
+		}
+		if trace.Error != "" {
+			failed[fmt.Sprint(trace.TraceAddress)] = true
+			continue
+		}
+		reverted := false
+		for i := 0; i < len(trace.TraceAddress) && !reverted; i++ {
+			reverted = failed[fmt.Sprint(trace.TraceAddress[:i])]
+		}
+		if reverted {
+			continue
+		}
+		var (
+			action struct {
+				CallType      string         `json:"callType"`
+				From          common.Address `json:"from"`
+				To            common.Address `json:"to"`
+				Value         *hexutil.Big   `json:"value"`
+				Address       common.Address `json:"address"`
+				RefundAddress common.Address `json:"refundAddress"`
+				Balance       *hexutil.Big   `json:"balance"`
+			}
+			result struct {
+				Address common.Address `json:"address"`
+			}
+		)
+		if err := json.Unmarshal(trace.Action, &action); err != nil {
+			return nil, err
+		}
+		tx := &types.InternalTx{TxIndex: txIdx, Value: new(big.Int)}
+		switch trace.Type {
+		case "call":
+			// Only nested calls transferring value to the callee are internal transactions
+			if len(trace.TraceAddress) == 0 || action.CallType != "call" || action.Value == nil || action.Value.ToInt().Sign() == 0 {
+				continue
+			}
+			tx.Kind, tx.From, tx.To, tx.Value = types.InternalTxCall, action.From, action.To, action.Value.ToInt()
+
+		case "create":
+			if err := json.Unmarshal(trace.Result, &result); err != nil {
+				return nil, err
+			}
+			tx.Kind, tx.From, tx.To = types.InternalTxCreate, action.From, result.Address
+			if action.Value != nil {
+				tx.Value = action.Value.ToInt()
+			}
+
+		case "suicide":
+			tx.Kind, tx.From, tx.To = types.InternalTxSelfDestruct, action.Address, action.RefundAddress
+			if action.Balance != nil {
+				tx.Value = action.Balance.ToInt()
+			}
+
+		default:
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	return txs, nil
+}
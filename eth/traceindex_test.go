@@ -0,0 +1,166 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that the internal transactions extracted from the traces of a block skip
+// the top level calls and everything reverted.
+func TestInternalTxsExtraction(t *testing.T) {
+	var (
+		tx0, tx1 = uint64(0), uint64(1)
+		call     = func(from, to byte, value string) json.RawMessage {
+			blob, _ := json.Marshal(map[string]interface{}{
+				"callType": "call",
+				"from":     common.Address{from},
+				"to":       common.Address{to},
+				"value":    value,
+			})
+			return blob
+		}
+	)
+	traces := []*parityTrace{
+		{parityTxTrace: parityTxTrace{Type: "call", Action: call(1, 2, "0x1"), TraceAddress: []int{}}, TransactionPosition: &tx0},
+		{parityTxTrace: parityTxTrace{Type: "call", Action: call(2, 3, "0x2"), TraceAddress: []int{0}}, TransactionPosition: &tx0},
+		{parityTxTrace: parityTxTrace{Type: "call", Action: call(2, 4, "0x3"), TraceAddress: []int{1}, Error: "Reverted"}, TransactionPosition: &tx0},
+		{parityTxTrace: parityTxTrace{Type: "call", Action: call(4, 5, "0x4"), TraceAddress: []int{1, 0}}, TransactionPosition: &tx0},
+		{parityTxTrace: parityTxTrace{Type: "call", Action: call(2, 6, "0x0"), TraceAddress: []int{2}}, TransactionPosition: &tx0},
+		{parityTxTrace: parityTxTrace{Type: "call", Action: call(1, 2, "0x1"), TraceAddress: []int{}}, TransactionPosition: &tx1},
+		{parityTxTrace: parityTxTrace{Type: "call", Action: call(2, 7, "0x5"), TraceAddress: []int{1, 0}}, TransactionPosition: &tx1},
+		{parityTxTrace: parityTxTrace{Type: "reward", Action: json.RawMessage(`{}`), TraceAddress: []int{}}},
+	}
+	txs, err := internalTxs(traces)
+	if err != nil {
+		t.Fatalf("failed to extract internal transactions: %v", err)
+	}
+	want := []*types.InternalTx{
+		{Kind: types.InternalTxCall, TxIndex: 0, From: common.Address{2}, To: common.Address{3}, Value: big.NewInt(2)},
+		{Kind: types.InternalTxCall, TxIndex: 1, From: common.Address{2}, To: common.Address{7}, Value: big.NewInt(5)},
+	}
+	if len(txs) != len(want) {
+		t.Fatalf("internal transaction count mismatch: have %d, want %d", len(txs), len(want))
+	}
+	for i := range want {
+		if txs[i].Kind != want[i].Kind || txs[i].TxIndex != want[i].TxIndex || txs[i].From != want[i].From || txs[i].To != want[i].To || txs[i].Value.Cmp(want[i].Value) != 0 {
+			t.Errorf("internal transaction %d mismatch: have %+v, want %+v", i, txs[i], want[i])
+		}
+	}
+}
+
+// Tests that the trace indexer records the internal transactions of the chain
+// and that they can be queried by account.
+func TestTraceIndexer(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender   = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.Address{0xbb}
+		receiver = common.Address{0xaa}
+		db       = rawdb.NewMemoryDatabase()
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				sender: {Balance: big.NewInt(params.Ether)},
+				// CALL the receiver with 1 wei, no input and no output
+				contract: {
+					Balance: big.NewInt(params.Ether),
+					Code:    append(append(common.FromHex("0x6000600060006000600173"), receiver.Bytes()...), common.FromHex("0x5af100")...),
+				},
+			},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.HomesteadSigner{}
+	)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 6, func(i int, b *core.BlockGen) {
+		if i%2 == 0 {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(sender), contract, new(big.Int), 100000, big.NewInt(1), nil), signer, key)
+			b.AddTx(tx)
+		}
+	})
+	blockchain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	eth := &Ethereum{blockchain: blockchain, chainDb: db, engine: ethash.NewFaker(), config: &Config{}}
+	api := NewPrivateTraceAPI(eth)
+
+	if _, err := api.InternalTransactions(context.Background(), receiver, 0, 4); err != errTraceIndexDisabled {
+		t.Fatalf("disabled index error mismatch: have %v, want %v", err, errTraceIndexDisabled)
+	}
+	eth.traceIndexer = NewTraceIndexer(eth, 2, 0)
+	defer eth.traceIndexer.Close()
+	eth.traceIndexer.Start(blockchain)
+
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if sections, _, _ := eth.traceIndexer.Sections(); sections == 3 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("trace index not generated in time")
+		}
+	}
+	txs, err := api.InternalTransactions(context.Background(), receiver, 0, 5)
+	if err != nil {
+		t.Fatalf("failed to retrieve internal transactions: %v", err)
+	}
+	if len(txs) != 3 {
+		t.Fatalf("internal transaction count mismatch: have %d, want 3", len(txs))
+	}
+	for i, tx := range txs {
+		block := chain[2*i]
+		if tx.BlockHash != block.Hash() || tx.TransactionHash != block.Transactions()[0].Hash() {
+			t.Errorf("internal transaction %d: origin mismatch: have %x/%x, want %x/%x", i, tx.BlockHash, tx.TransactionHash, block.Hash(), block.Transactions()[0].Hash())
+		}
+		if tx.Type != "call" || tx.From != contract || tx.To != receiver || tx.Value.ToInt().Cmp(big.NewInt(1)) != 0 {
+			t.Errorf("internal transaction %d: mismatch: have %+v", i, tx)
+		}
+	}
+	// Ranges only partially covering the transactions should be filtered
+	if txs, err := api.InternalTransactions(context.Background(), contract, 2, 4); err != nil || len(txs) != 1 {
+		t.Errorf("ranged internal transactions mismatch: have %d (%v), want 1", len(txs), err)
+	}
+	if txs, err := api.InternalTransactions(context.Background(), sender, 0, 4); err != nil || len(txs) != 0 {
+		t.Errorf("sender internal transactions mismatch: have %d (%v), want 0", len(txs), err)
+	}
+	if _, err := api.InternalTransactions(context.Background(), receiver, 4, 2); err != errTraceFilterRange {
+		t.Errorf("inverted range error mismatch: have %v, want %v", err, errTraceFilterRange)
+	}
+	// Blocks beyond the last indexed section should be rejected
+	if _, err := api.InternalTransactions(context.Background(), receiver, 0, rpc.LatestBlockNumber); err == nil {
+		t.Errorf("unindexed block range accepted")
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// TxTracer is a transaction tracer usable through the tracing APIs, producing a
+// JSON result once the transaction is executed. Both the JavaScript and native
+// Go tracers implement it.
+type TxTracer interface {
+	vm.Tracer
+
+	// GetResult returns the JSON encoded result of the trace, or the first error
+	// encountered while tracing.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates the tracing at the first opportune moment, failing the
+	// trace with the given error.
+	Stop(err error)
+}
+
+var (
+	// natives contains the constructors of the native tracers by name.
+	natives     = make(map[string]func() TxTracer)
+	nativesLock sync.RWMutex
+)
+
+// RegisterNative makes a native Go tracer available under the given name. If a
+// JavaScript tracer with the same name is bundled, the native one overrides it.
+// Registering the same name twice panics.
+func RegisterNative(name string, ctor func() TxTracer) {
+	nativesLock.Lock()
+	defer nativesLock.Unlock()
+
+	if _, ok := natives[name]; ok {
+		panic(fmt.Sprintf("native tracer %q already registered", name))
+	}
+	natives[name] = ctor
+}
+
+// native retrieves the constructor of a native tracer by name.
+func native(name string) (func() TxTracer, bool) {
+	nativesLock.RLock()
+	defer nativesLock.RUnlock()
+
+	ctor, ok := natives[name]
+	return ctor, ok
+}
+
+// NewTxTracer creates a tracer from the given name or code. Registered native
+// tracers are preferred, otherwise the input is handed to the JavaScript engine
+// either as the name of a bundled tracer or as the tracer code itself.
+func NewTxTracer(code string) (TxTracer, error) {
+	if ctor, ok := native(code); ok {
+		return ctor(), nil
+	}
+	return New(code)
+}
+
+func init() {
+	RegisterNative("callTracer", newCallTracer)
+	RegisterNative("prestateTracer", newPrestateTracer)
+	RegisterNative("4byteTracer", newFourByteTracer)
+	RegisterNative("noopTracer", newNoopTracer)
+	RegisterNative("flatCallTracer", newFlatCallTracer)
+	RegisterNative("vmTracer", newVMTracer)
+}
+
+// interrupter implements the Stop method of the native tracers.
+type interrupter struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (i *interrupter) Stop(err error) {
+	i.reason = err
+	atomic.StoreUint32(&i.interrupt, 1)
+}
+
+// stopped reports whether the tracer was interrupted.
+func (i *interrupter) stopped() bool {
+	return atomic.LoadUint32(&i.interrupt) > 0
+}
+
+// encodeResult JSON encodes a native tracer result the same way the JavaScript
+// engine would, without escaping HTML characters.
+func encodeResult(result interface{}) (json.RawMessage, error) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(result); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(bytes.TrimSuffix(buf.Bytes(), []byte("\n"))), nil
+}
+
+// The helpers below mirror the JavaScript tracer environment, so that native
+// tracers produce the exact same output as their JavaScript counterparts.
+
+// peekStack returns the nth-from-the-top element of the stack, or zero if the
+// stack is not deep enough.
+func peekStack(stack *vm.Stack, n int) *big.Int {
+	data := stack.Data()
+	if len(data) <= n {
+		return new(big.Int)
+	}
+	return data[len(data)-n-1]
+}
+
+// sliceMemory returns the requested range of memory, or nothing if it's out of
+// bounds.
+func sliceMemory(memory *vm.Memory, offset, size *big.Int) []byte {
+	if !offset.IsUint64() || !size.IsUint64() {
+		return nil
+	}
+	begin, length := offset.Uint64(), size.Uint64()
+	if begin+length < begin || uint64(memory.Len()) < begin+length {
+		return nil
+	}
+	return memory.Get(int64(begin), int64(length))
+}
+
+// isPrecompiled reports whether the address is a precompiled contract on any
+// network, as seen by the JavaScript tracers.
+func isPrecompiled(addr common.Address) bool {
+	_, ok := vm.PrecompiledContractsForConfig(params.AllEthashProtocolChanges, common.Big0)[addr]
+	return ok
+}
+
+// toHex encodes a byte blob as a lowercase 0x prefixed hex string.
+func toHex(b []byte) string {
+	return hexutil.Encode(b)
+}
+
+// toHexBig encodes a big integer as a 0x prefixed hex string. Negative numbers
+// are rendered as the JavaScript bigInt library would.
+func toHexBig(n *big.Int) string {
+	if n == nil {
+		return "0x0"
+	}
+	return "0x" + n.Text(16)
+}
+
+// toHexInt encodes a signed integer as a 0x prefixed hex string. Negative numbers
+// are rendered as the JavaScript bigInt library would.
+func toHexInt(n int64) string {
+	return toHexBig(big.NewInt(n))
+}
+
+// noopTracer is the native counterpart of the JavaScript noopTracer, doing
+// nothing at all.
+type noopTracer struct {
+	interrupter
+}
+
+// newNoopTracer creates a native tracer that does nothing.
+func newNoopTracer() TxTracer {
+	return new(noopTracer)
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *noopTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *noopTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *noopTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *noopTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns an empty JSON object.
+func (t *noopTracer) GetResult() (json.RawMessage, error) {
+	if t.stopped() {
+		return nil, t.reason
+	}
+	return json.RawMessage("{}"), nil
+}
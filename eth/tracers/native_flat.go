@@ -0,0 +1,154 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// flatCallAction is the action of a Parity style trace. Depending on the type
+// of the trace, only a subset of the fields is set.
+type flatCallAction struct {
+	Address       string `json:"address,omitempty"`
+	Balance       string `json:"balance,omitempty"`
+	CallType      string `json:"callType,omitempty"`
+	From          string `json:"from,omitempty"`
+	Gas           string `json:"gas,omitempty"`
+	Init          string `json:"init,omitempty"`
+	Input         string `json:"input,omitempty"`
+	RefundAddress string `json:"refundAddress,omitempty"`
+	To            string `json:"to,omitempty"`
+	Value         string `json:"value,omitempty"`
+}
+
+// flatCallResult is the outcome of a successful call or contract creation.
+type flatCallResult struct {
+	Address string `json:"address,omitempty"`
+	Code    string `json:"code,omitempty"`
+	GasUsed string `json:"gasUsed"`
+	Output  string `json:"output,omitempty"`
+}
+
+// flatCallFrame is a single Parity style trace of a call, contract creation or
+// self destruct.
+type flatCallFrame struct {
+	Action       *flatCallAction `json:"action"`
+	Error        string          `json:"error,omitempty"`
+	Result       *flatCallResult `json:"result"`
+	Subtraces    int             `json:"subtraces"`
+	TraceAddress []int           `json:"traceAddress"`
+	Type         string          `json:"type"`
+}
+
+// flatCallErrors maps the EVM errors to the messages reported by Parity.
+var flatCallErrors = map[string]string{
+	"execution reverted":            "Reverted",
+	"evm: execution reverted":       "Reverted",
+	"out of gas":                    "Out of gas",
+	"evm: invalid jump destination": "Bad jump destination",
+	"evm: write protection":         "Mutable Call In Static Context",
+	"max call depth exceeded":       "Out of stack",
+}
+
+// flatCallTracer reports the calls made by a transaction as the flat list of
+// traces of the Parity trace module, ordered depth first.
+type flatCallTracer struct {
+	*callTracer
+}
+
+// newFlatCallTracer creates a native flat call tracer.
+func newFlatCallTracer() TxTracer {
+	return &flatCallTracer{callTracer: newCallTracer().(*callTracer)}
+}
+
+// GetResult returns the flattened call tree of the transaction.
+func (t *flatCallTracer) GetResult() (json.RawMessage, error) {
+	root, err := t.result()
+	if err != nil {
+		return nil, err
+	}
+	return encodeResult(flattenCall(root, []int{}, nil))
+}
+
+// flattenCall appends the Parity style trace of a call and all its subcalls to
+// the given list.
+func flattenCall(call *callFrame, address []int, traces []*flatCallFrame) []*flatCallFrame {
+	frame := &flatCallFrame{
+		Action:       new(flatCallAction),
+		Subtraces:    len(call.Calls),
+		TraceAddress: address,
+	}
+	switch call.Type {
+	case "CREATE", "CREATE2":
+		frame.Type = "create"
+		frame.Action.From = call.From
+		frame.Action.Gas = orZero(call.Gas)
+		frame.Action.Init = call.Input
+		frame.Action.Value = orZero(call.Value)
+		frame.Result = &flatCallResult{
+			Address: call.To,
+			Code:    orEmpty(call.Output),
+			GasUsed: orZero(call.GasUsed),
+		}
+	case "SELFDESTRUCT":
+		frame.Type = "suicide"
+		frame.Action.Address = toHex(call.address.Bytes())
+		frame.Action.RefundAddress = toHex(call.refund.Bytes())
+		frame.Action.Balance = toHexBig(call.balance)
+	default:
+		frame.Type = "call"
+		frame.Action.CallType = strings.ToLower(call.Type)
+		frame.Action.From = call.From
+		frame.Action.Gas = orZero(call.Gas)
+		frame.Action.Input = orEmpty(call.Input)
+		frame.Action.To = call.To
+		frame.Action.Value = orZero(call.Value)
+		frame.Result = &flatCallResult{
+			GasUsed: orZero(call.GasUsed),
+			Output:  orEmpty(call.Output),
+		}
+	}
+	if call.Error != "" {
+		frame.Error = call.Error
+		if msg, ok := flatCallErrors[call.Error]; ok {
+			frame.Error = msg
+		}
+		frame.Result = nil
+	}
+	traces = append(traces, frame)
+	for i, sub := range call.Calls {
+		traces = flattenCall(sub, append(append([]int{}, address...), i), traces)
+	}
+	return traces
+}
+
+// orZero returns the given hex quantity, or zero if it's not known.
+func orZero(s string) string {
+	if s == "" {
+		return "0x0"
+	}
+	return s
+}
+
+// orEmpty returns the given hex blob, or an empty one if it's not known.
+func orEmpty(s string) string {
+	if s == "" {
+		return "0x"
+	}
+	return s
+}
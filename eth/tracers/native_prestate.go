@@ -0,0 +1,190 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// errNoPrestate is returned by the prestate tracer if the transaction did not
+// execute any code, mirroring the failure of the JavaScript prestateTracer.
+var errNoPrestate = errors.New("no state accessed during execution")
+
+// prestateAccount is the state of an account before a transaction executed.
+type prestateAccount struct {
+	Balance string            `json:"balance"`
+	Nonce   uint64            `json:"nonce"`
+	Code    string            `json:"code"`
+	Storage map[string]string `json:"storage"`
+
+	balance *big.Int
+}
+
+// prestateTracer is the native counterpart of the JavaScript prestateTracer,
+// gathering the state of all the accounts touched by a transaction as it was
+// before the execution.
+type prestateTracer struct {
+	interrupter
+
+	prestate map[common.Address]*prestateAccount // Genesis like allocation being built
+	db       vm.StateDB                          // State database of the last traced step
+	err      error                               // Error encountered while tracing
+
+	create bool
+	from   common.Address
+	to     common.Address
+	value  *big.Int
+}
+
+// newPrestateTracer creates a native prestate tracer.
+func newPrestateTracer() TxTracer {
+	return new(prestateTracer)
+}
+
+// lookupAccount injects the specified account into the prestate.
+func (t *prestateTracer) lookupAccount(addr common.Address, db vm.StateDB) {
+	if _, ok := t.prestate[addr]; ok {
+		return
+	}
+	t.prestate[addr] = &prestateAccount{
+		Nonce:   db.GetNonce(addr),
+		Code:    toHex(db.GetCode(addr)),
+		Storage: make(map[string]string),
+		balance: new(big.Int).Set(db.GetBalance(addr)),
+	}
+}
+
+// lookupStorage injects the specified storage entry of the given account into
+// the prestate.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash, db vm.StateDB) {
+	t.lookupAccount(addr, db)
+
+	idx := toHex(key.Bytes())
+	if _, ok := t.prestate[addr].Storage[idx]; ok {
+		return
+	}
+	t.prestate[addr].Storage[idx] = toHex(db.GetState(addr, key).Bytes())
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.create, t.from, t.to, t.value = create, from, to, value
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.err != nil {
+		return nil
+	}
+	if t.stopped() {
+		t.err = t.reason
+		return nil
+	}
+	db := env.StateDB
+	t.db = db
+
+	// Add the current account if we just started tracing. Balance will potentially
+	// be wrong here, since this will include the value sent along with the message.
+	// We fix that in GetResult.
+	if t.prestate == nil {
+		t.prestate = make(map[common.Address]*prestateAccount)
+		t.lookupAccount(contract.Address(), db)
+	}
+	// Whenever new state is accessed, add it to the prestate
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(common.BigToAddress(peekStack(stack, 0)), db)
+
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, db.GetNonce(from)), db)
+
+	case vm.CREATE2:
+		// stack: salt, size, offset, endowment
+		offset, size := peekStack(stack, 1), peekStack(stack, 2)
+		code := sliceMemory(memory, offset, size)
+		salt := common.BigToHash(peekStack(stack, 3))
+		t.lookupAccount(crypto.CreateAddress2(contract.Address(), salt, crypto.Keccak256(code)), db)
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(common.BigToAddress(peekStack(stack, 1)), db)
+
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract.Address(), common.BigToHash(peekStack(stack, 0)), db)
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the assembled prestate of the touched accounts.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if t.prestate == nil {
+		return nil, errNoPrestate
+	}
+	// At this point, we need to deduct the 'value' from the outer transaction,
+	// and move it back to the origin
+	t.lookupAccount(t.from, t.db)
+
+	to, ok := t.prestate[t.to]
+	if !ok {
+		return nil, errNoPrestate
+	}
+	value := t.value
+	if value == nil {
+		value = new(big.Int)
+	}
+	from := t.prestate[t.from]
+	fromBal, toBal := new(big.Int).Set(from.balance), new(big.Int).Set(to.balance)
+
+	to.balance = toBal.Sub(toBal, value)
+	from.balance = fromBal.Add(fromBal, value)
+
+	// Decrement the caller's nonce, and remove empty create targets
+	from.Nonce--
+	if t.create {
+		// We can blindly delete the contract prestate, as any existing state would
+		// have caused the transaction to be rejected as invalid in the first place.
+		delete(t.prestate, t.to)
+	}
+	result := make(map[string]*prestateAccount, len(t.prestate))
+	for addr, account := range t.prestate {
+		account.Balance = toHexBig(account.balance)
+		result[toHex(addr.Bytes())] = account
+	}
+	return encodeResult(result)
+}
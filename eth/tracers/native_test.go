@@ -0,0 +1,314 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// timeField matches the execution time in a call tracer result, which differs
+// between runs.
+var timeField = regexp.MustCompile(`,"time":"[^"]*"`)
+
+// runTracerTest executes the transaction of a tracer test case with the given
+// tracer attached, returning the raw tracer output.
+func runTracerTest(t *testing.T, test *callTracerTest, tracer TxTracer) json.RawMessage {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, new(big.Int).SetUint64(uint64(test.Context.Number)))
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc)
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, _, _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// runs the native tracers against them, comparing the results to the ones of
+// the JavaScript tracers with the same name.
+func TestNativeTracers(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			for _, name := range []string{"callTracer", "prestateTracer", "4byteTracer", "noopTracer"} {
+				ctor, ok := native(name)
+				if !ok {
+					t.Fatalf("%s: native tracer missing", name)
+				}
+				jst, err := New(name)
+				if err != nil {
+					t.Fatalf("%s: failed to create JavaScript tracer: %v", name, err)
+				}
+				have := runTracerTest(t, test, ctor())
+				want := runTracerTest(t, test, jst)
+
+				// The call tracer must produce the exact same output, others may differ
+				// in the order of their object keys
+				if name == "callTracer" {
+					have, want = timeField.ReplaceAll(have, nil), timeField.ReplaceAll(want, nil)
+					if string(have) != string(want) {
+						t.Errorf("%s: trace mismatch:\nhave %s\nwant %s", name, have, want)
+					}
+					ret := new(callTrace)
+					if err := json.Unmarshal(have, ret); err != nil {
+						t.Fatalf("%s: failed to unmarshal trace result: %v", name, err)
+					}
+					if !reflect.DeepEqual(ret, test.Result) {
+						t.Errorf("%s: trace mismatch: \nhave %+v\nwant %+v", name, ret, test.Result)
+					}
+					continue
+				}
+				var haveObj, wantObj interface{}
+				if err := json.Unmarshal(have, &haveObj); err != nil {
+					t.Fatalf("%s: failed to unmarshal native result: %v", name, err)
+				}
+				if err := json.Unmarshal(want, &wantObj); err != nil {
+					t.Fatalf("%s: failed to unmarshal JavaScript result: %v", name, err)
+				}
+				if !reflect.DeepEqual(haveObj, wantObj) {
+					t.Errorf("%s: trace mismatch:\nhave %s\nwant %s", name, have, want)
+				}
+			}
+		})
+	}
+}
+
+// Tests that native tracers can be registered and are preferred over the bundled
+// JavaScript ones.
+func TestNativeTracerRegistry(t *testing.T) {
+	tracer, err := NewTxTracer("callTracer")
+	if err != nil {
+		t.Fatalf("failed to create call tracer: %v", err)
+	}
+	if _, ok := tracer.(*callTracer); !ok {
+		t.Fatalf("call tracer type mismatch: have %T, want %T", tracer, new(callTracer))
+	}
+	if tracer, err = NewTxTracer("opcountTracer"); err != nil {
+		t.Fatalf("failed to create opcount tracer: %v", err)
+	}
+	if _, ok := tracer.(*Tracer); !ok {
+		t.Fatalf("opcount tracer type mismatch: have %T, want %T", tracer, new(Tracer))
+	}
+	RegisterNative("testNoopTracer", newNoopTracer)
+	if tracer, err = NewTxTracer("testNoopTracer"); err != nil {
+		t.Fatalf("failed to create registered tracer: %v", err)
+	}
+	if _, ok := tracer.(*noopTracer); !ok {
+		t.Fatalf("registered tracer type mismatch: have %T, want %T", tracer, new(noopTracer))
+	}
+	// Interrupted tracers should report the reason
+	tracer.Stop(errors.New("stopped"))
+	if _, err := tracer.GetResult(); err == nil || err.Error() != "stopped" {
+		t.Fatalf("interrupted tracer error mismatch: have %v, want stopped", err)
+	}
+}
+
+// flattenTrace flattens an expected call tree the way the Parity trace module
+// would, returning the trace addresses and types in order.
+func flattenTrace(call callTrace, address []int, types []string, addresses [][]int) ([]string, [][]int) {
+	typ := "call"
+	switch call.Type {
+	case "CREATE", "CREATE2":
+		typ = "create"
+	case "SELFDESTRUCT":
+		typ = "suicide"
+	}
+	types, addresses = append(types, typ), append(addresses, address)
+	for i, sub := range call.Calls {
+		types, addresses = flattenTrace(sub, append(append([]int{}, address...), i), types, addresses)
+	}
+	return types, addresses
+}
+
+// Tests that the Parity style tracers report the same call structure as the
+// call tracer test cases, and that the vmTrace covers every executed call.
+func TestParityTracers(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+		if err != nil {
+			t.Fatalf("%s: failed to read testcase: %v", file.Name(), err)
+		}
+		test := new(callTracerTest)
+		if err := json.Unmarshal(blob, test); err != nil {
+			t.Fatalf("%s: failed to parse testcase: %v", file.Name(), err)
+		}
+		// Ensure the flat traces match the expected call tree
+		var flat []struct {
+			Error        string          `json:"error"`
+			Result       json.RawMessage `json:"result"`
+			Subtraces    int             `json:"subtraces"`
+			TraceAddress []int           `json:"traceAddress"`
+			Type         string          `json:"type"`
+		}
+		if err := json.Unmarshal(runTracerTest(t, test, newFlatCallTracer()), &flat); err != nil {
+			t.Fatalf("%s: failed to unmarshal flat traces: %v", file.Name(), err)
+		}
+		types, addresses := flattenTrace(*test.Result, []int{}, nil, nil)
+		if len(flat) != len(types) {
+			t.Fatalf("%s: trace count mismatch: have %d, want %d", file.Name(), len(flat), len(types))
+		}
+		for i, trace := range flat {
+			if trace.Type != types[i] {
+				t.Errorf("%s: trace %d: type mismatch: have %s, want %s", file.Name(), i, trace.Type, types[i])
+			}
+			if !reflect.DeepEqual(trace.TraceAddress, addresses[i]) {
+				t.Errorf("%s: trace %d: address mismatch: have %v, want %v", file.Name(), i, trace.TraceAddress, addresses[i])
+			}
+			if trace.Error != "" && string(trace.Result) != "null" {
+				t.Errorf("%s: trace %d: failed trace has result %s", file.Name(), i, trace.Result)
+			}
+		}
+		// Ensure the vmTrace starts with the executed code
+		var vmtrace struct {
+			Code hexutil.Bytes `json:"code"`
+			Ops  []struct {
+				Ex *struct {
+					Used uint64 `json:"used"`
+				} `json:"ex"`
+			} `json:"ops"`
+		}
+		if err := json.Unmarshal(runTracerTest(t, test, newVMTracer()), &vmtrace); err != nil {
+			t.Fatalf("%s: failed to unmarshal vmTrace: %v", file.Name(), err)
+		}
+		if len(vmtrace.Code) == 0 || len(vmtrace.Ops) == 0 {
+			t.Fatalf("%s: empty vmTrace", file.Name())
+		}
+		for i, op := range vmtrace.Ops {
+			if op.Ex == nil {
+				t.Errorf("%s: operation %d: missing outcome", file.Name(), i)
+			}
+		}
+	}
+}
+
+// Tests that the call tracer decodes the reason of reverted transactions.
+func TestCallTracerRevertReason(t *testing.T) {
+	var (
+		from     = common.Address{0x01}
+		contract = common.Address{0x02}
+
+		// Error("hi") as returned by a Solidity revert
+		reason = common.FromHex("0x08c379a0" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"0000000000000000000000000000000000000000000000000000000000000002" +
+			"6869000000000000000000000000000000000000000000000000000000000000")
+	)
+	// PUSH1 100 PUSH1 12 PUSH1 0 CODECOPY PUSH1 100 PUSH1 0 REVERT <reason>
+	code := append(common.FromHex("0x6064600c60003960646000fd"), reason...)
+
+	statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), core.GenesisAlloc{
+		from:     {Balance: big.NewInt(params.Ether)},
+		contract: {Code: code},
+	})
+	tracer := newCallTracer()
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      from,
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(1),
+		Difficulty:  big.NewInt(1),
+		GasLimit:    1000000,
+		GasPrice:    big.NewInt(1),
+	}
+	evm := vm.NewEVM(context, statedb, params.TestChainConfig, vm.Config{Debug: true, Tracer: tracer})
+	if _, _, err := evm.Call(vm.AccountRef(from), contract, nil, 100000, new(big.Int)); err == nil {
+		t.Fatalf("reverting call succeeded")
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	var call struct {
+		Error        string `json:"error"`
+		RevertReason string `json:"revertReason"`
+	}
+	if err := json.Unmarshal(res, &call); err != nil {
+		t.Fatalf("failed to unmarshal trace result: %v", err)
+	}
+	if call.Error != "execution reverted" || call.RevertReason != "hi" {
+		t.Errorf("revert mismatch: have %q/%q, want %q/%q", call.Error, call.RevertReason, "execution reverted", "hi")
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// vmTrace is the Parity style trace of the code executed within a single call.
+type vmTrace struct {
+	Code string         `json:"code"`
+	Ops  []*vmOperation `json:"ops"`
+}
+
+// vmOperation is a single executed opcode of a vmTrace.
+type vmOperation struct {
+	Cost uint64      `json:"cost"`
+	Ex   *vmExecuted `json:"ex"`
+	Pc   uint64      `json:"pc"`
+	Sub  *vmTrace    `json:"sub"`
+
+	op      vm.OpCode // Opcode executed, to know the number of pushed items
+	gas     uint64    // Gas available before the opcode executed
+	memOff  *big.Int  // Offset of the memory written by the opcode
+	memSize *big.Int  // Size of the memory written by the opcode
+	store   *vmStore  // Storage slot written by the opcode
+}
+
+// vmExecuted is the outcome of an executed opcode.
+type vmExecuted struct {
+	Mem   *vmMemory `json:"mem"`
+	Push  []string  `json:"push"`
+	Store *vmStore  `json:"store"`
+	Used  uint64    `json:"used"`
+}
+
+// vmMemory is a memory region written by an opcode.
+type vmMemory struct {
+	Data string `json:"data"`
+	Off  uint64 `json:"off"`
+}
+
+// vmStore is a storage slot written by an opcode.
+type vmStore struct {
+	Key string `json:"key"`
+	Val string `json:"val"`
+}
+
+// vmTracer reports the opcodes executed by a transaction along with their
+// effects on the stack, memory and storage, in the format of the vmTrace of
+// the Parity trace module.
+type vmTracer struct {
+	interrupter
+
+	root   *vmTrace   // Trace of the outermost call
+	frames []*vmTrace // Traces of the calls currently executing
+	err    error      // Error encountered while tracing
+}
+
+// newVMTracer creates a native Parity vmTrace tracer.
+func newVMTracer() TxTracer {
+	return new(vmTracer)
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *vmTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *vmTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.err != nil {
+		return nil
+	}
+	if t.stopped() {
+		t.err = t.reason
+		return nil
+	}
+	// Operations failing before execution are not reported
+	if err != nil {
+		return nil
+	}
+	// Close any calls that returned since the last step
+	for len(t.frames) > depth {
+		t.exitFrame()
+	}
+	// Open a new trace if we've just descended into an inner call
+	if len(t.frames) < depth {
+		trace := &vmTrace{Code: toHex(contract.Code), Ops: []*vmOperation{}}
+		if len(t.frames) == 0 {
+			t.root = trace
+		} else if parent := t.frames[len(t.frames)-1]; len(parent.Ops) > 0 {
+			parent.Ops[len(parent.Ops)-1].Sub = trace
+		}
+		t.frames = append(t.frames, trace)
+	}
+	// The previous operation of this call finished, gather its outcome
+	frame := t.frames[len(t.frames)-1]
+	if len(frame.Ops) > 0 {
+		executed(frame.Ops[len(frame.Ops)-1], gas, memory, stack)
+	}
+	operation := &vmOperation{Cost: cost, Pc: pc, op: op, gas: gas}
+	switch op {
+	case vm.MSTORE:
+		operation.memOff, operation.memSize = peekStack(stack, 0), big.NewInt(32)
+	case vm.MSTORE8:
+		operation.memOff, operation.memSize = peekStack(stack, 0), big.NewInt(1)
+	case vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY:
+		operation.memOff, operation.memSize = peekStack(stack, 0), peekStack(stack, 2)
+	case vm.EXTCODECOPY:
+		operation.memOff, operation.memSize = peekStack(stack, 1), peekStack(stack, 3)
+	case vm.CALL, vm.CALLCODE:
+		operation.memOff, operation.memSize = peekStack(stack, 5), peekStack(stack, 6)
+	case vm.DELEGATECALL, vm.STATICCALL:
+		operation.memOff, operation.memSize = peekStack(stack, 4), peekStack(stack, 5)
+	case vm.SSTORE:
+		operation.store = &vmStore{Key: toHexBig(peekStack(stack, 0)), Val: toHexBig(peekStack(stack, 1))}
+	}
+	// Copy the big integers, the stack items are reused by the interpreter
+	if operation.memOff != nil {
+		operation.memOff, operation.memSize = new(big.Int).Set(operation.memOff), new(big.Int).Set(operation.memSize)
+	}
+	frame.Ops = append(frame.Ops, operation)
+	return nil
+}
+
+// exitFrame closes the innermost executing call. The outcome of its last
+// operation is unknown, so only its gas usage is reported.
+func (t *vmTracer) exitFrame() {
+	frame := t.frames[len(t.frames)-1]
+	if len(frame.Ops) > 0 {
+		if last := frame.Ops[len(frame.Ops)-1]; last.Ex == nil {
+			used := uint64(0)
+			if last.gas > last.Cost {
+				used = last.gas - last.Cost
+			}
+			last.Ex = &vmExecuted{Push: []string{}, Store: last.store, Used: used}
+		}
+	}
+	t.frames = t.frames[:len(t.frames)-1]
+}
+
+// executed fills in the outcome of an operation, given the state of the EVM
+// right after its execution.
+func executed(operation *vmOperation, gas uint64, memory *vm.Memory, stack *vm.Stack) {
+	ex := &vmExecuted{Push: []string{}, Store: operation.store, Used: gas}
+
+	data := stack.Data()
+	if n := stackPushes(operation.op); n <= len(data) {
+		for _, item := range data[len(data)-n:] {
+			ex.Push = append(ex.Push, toHexBig(item))
+		}
+	}
+	if operation.memOff != nil && operation.memSize.Sign() > 0 && operation.memOff.IsUint64() {
+		if blob := sliceMemory(memory, operation.memOff, operation.memSize); blob != nil {
+			ex.Mem = &vmMemory{Data: toHex(blob), Off: operation.memOff.Uint64()}
+		}
+	}
+	operation.Ex = ex
+}
+
+// stackPushes returns the number of stack items reported as pushed by an
+// opcode. Duplications and swaps report all the items they touched.
+func stackPushes(op vm.OpCode) int {
+	switch {
+	case op >= vm.PUSH1 && op <= vm.PUSH32:
+		return 1
+	case op >= vm.DUP1 && op <= vm.DUP16:
+		return int(op-vm.DUP1) + 2
+	case op >= vm.SWAP1 && op <= vm.SWAP16:
+		return int(op-vm.SWAP1) + 2
+	case op >= vm.LOG0 && op <= vm.LOG4:
+		return 0
+	}
+	switch op {
+	case vm.STOP, vm.POP, vm.MSTORE, vm.MSTORE8, vm.SSTORE, vm.JUMP, vm.JUMPI,
+		vm.JUMPDEST, vm.RETURN, vm.REVERT, vm.SELFDESTRUCT, vm.CALLDATACOPY,
+		vm.CODECOPY, vm.EXTCODECOPY, vm.RETURNDATACOPY:
+		return 0
+	}
+	return 1
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *vmTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *vmTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	for len(t.frames) > 0 {
+		t.exitFrame()
+	}
+	return nil
+}
+
+// GetResult returns the vmTrace of the transaction.
+func (t *vmTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	if t.root == nil {
+		return encodeResult(&vmTrace{Code: "0x", Ops: []*vmOperation{}})
+	}
+	return encodeResult(t.root)
+}
@@ -278,6 +278,17 @@ func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*
 	return r, err
 }
 
+// BlockReceipts returns the receipts of all the transactions included in the
+// given block. Note that the receipts are not available for the pending block.
+func (ec *Client) BlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	var r []*types.Receipt
+	err := ec.c.CallContext(ctx, &r, "eth_getBlockReceipts", toBlockNumberOrHashArg(blockNrOrHash))
+	if err == nil && r == nil {
+		return nil, ethereum.NotFound
+	}
+	return r, err
+}
+
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
@@ -285,6 +296,19 @@ func toBlockNumArg(number *big.Int) string {
 	return hexutil.EncodeBig(number)
 }
 
+func toBlockNumberOrHashArg(blockNrOrHash rpc.BlockNumberOrHash) interface{} {
+	if number, ok := blockNrOrHash.Number(); ok {
+		switch number {
+		case rpc.LatestBlockNumber:
+			return "latest"
+		case rpc.PendingBlockNumber:
+			return "pending"
+		}
+		return hexutil.EncodeUint64(uint64(number))
+	}
+	return blockNrOrHash
+}
+
 type rpcProgress struct {
 	StartingBlock hexutil.Uint64
 	CurrentBlock  hexutil.Uint64
@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // Verify that Client implements the ethereum interfaces.
@@ -171,7 +172,10 @@ var (
 func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
 	// Generate test chain.
 	genesis, blocks := generateTestChain()
+	return newTestBackendWithChain(t, genesis, blocks)
+}
 
+func newTestBackendWithChain(t *testing.T, genesis *core.Genesis, blocks []*types.Block) (*node.Node, []*types.Block) {
 	// Start Ethereum service.
 	var ethservice *eth.Ethereum
 	n, err := node.New(&node.Config{})
@@ -193,6 +197,12 @@ func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
 }
 
 func generateTestChain() (*core.Genesis, []*types.Block) {
+	return generateTestChainWithTxs(false)
+}
+
+// generateTestChainWithTxs generates the test chain, optionally including a free
+// transaction in each block to have a receipt without changing balances.
+func generateTestChainWithTxs(txs bool) (*core.Genesis, []*types.Block) {
 	db := rawdb.NewMemoryDatabase()
 	config := params.AllEthashProtocolChanges
 	genesis := &core.Genesis{
@@ -204,6 +214,11 @@ func generateTestChain() (*core.Genesis, []*types.Block) {
 	generate := func(i int, g *core.BlockGen) {
 		g.OffsetTime(5)
 		g.SetExtra([]byte("test"))
+
+		if txs {
+			tx, _ := types.SignTx(types.NewTransaction(g.TxNonce(testAddr), testAddr, new(big.Int), params.TxGas, new(big.Int), nil), types.HomesteadSigner{}, testKey)
+			g.AddTx(tx)
+		}
 	}
 	gblock := genesis.ToBlock(db)
 	engine := ethash.NewFaker()
@@ -335,3 +350,55 @@ func TestChainID(t *testing.T) {
 		t.Fatalf("ChainID returned wrong number: %+v", id)
 	}
 }
+
+func TestBlockReceipts(t *testing.T) {
+	genesis, blocks := generateTestChainWithTxs(true)
+	backend, chain := newTestBackendWithChain(t, genesis, blocks)
+	client, _ := backend.Attach()
+	defer backend.Stop()
+	defer client.Close()
+	ec := NewClient(client)
+
+	tests := map[string]struct {
+		block   rpc.BlockNumberOrHash
+		want    int
+		wantErr error
+	}{
+		"genesis": {
+			block: rpc.BlockNumberOrHashWithNumber(0),
+			want:  0,
+		},
+		"latest": {
+			block: rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber),
+			want:  1,
+		},
+		"by_hash": {
+			block: rpc.BlockNumberOrHashWithHash(chain[1].Hash(), true),
+			want:  1,
+		},
+		"unknown_hash": {
+			block:   rpc.BlockNumberOrHashWithHash(common.Hash{1}, false),
+			wantErr: ethereum.NotFound,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			got, err := ec.BlockReceipts(ctx, tt.block)
+			if err != tt.wantErr {
+				t.Fatalf("BlockReceipts error = %q, want %q", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("BlockReceipts returned %d receipts, want %d", len(got), tt.want)
+			}
+			for i, receipt := range got {
+				tx := chain[1].Transactions()[i]
+				if receipt.TxHash != tx.Hash() || receipt.BlockHash != chain[1].Hash() || receipt.Status != types.ReceiptStatusSuccessful {
+					t.Fatalf("BlockReceipts receipt %d mismatch: %+v", i, receipt)
+				}
+			}
+		})
+	}
+}
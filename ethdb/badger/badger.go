@@ -0,0 +1,474 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// +build !js
+
+// Package badger implements the key-value database layer based on Badger.
+package badger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+const (
+	// minCache is the minimum amount of memory in megabytes to allocate to the
+	// badger memtables.
+	minCache = 16
+
+	// maxTableSize is the maximum size of a single memtable (and level zero
+	// table) in megabytes. Badger keeps several of these in memory.
+	maxTableSize = 64
+
+	// numMemtables is the number of memtables badger keeps in memory, the
+	// allowed cache is split evenly between them.
+	numMemtables = 4
+
+	// gcDiscardRatio is the ratio of stale data in a value log file above which
+	// the file is rewritten during garbage collection.
+	gcDiscardRatio = 0.5
+
+	// metricsGatheringInterval specifies the interval to retrieve the badger
+	// database sizes to report to the user.
+	metricsGatheringInterval = 3 * time.Second
+
+	// gcInterval specifies how often the value log garbage collector runs.
+	gcInterval = 5 * time.Minute
+)
+
+// Database is a persistent key-value store based on Badger. Apart from basic
+// data storage functionality it also supports batch writes and iterating over
+// the keyspace in binary-alphabetical order.
+type Database struct {
+	fn string     // filename for reporting
+	db *badger.DB // Badger instance
+
+	lsmSizeGauge  metrics.Gauge // Gauge for tracking the size of the LSM tree
+	vlogSizeGauge metrics.Gauge // Gauge for tracking the size of the value logs
+	diskSizeGauge metrics.Gauge // Gauge for tracking the size of all the database files
+
+	quitLock sync.Mutex      // Mutex protecting the quit channel access
+	quitChan chan chan error // Quit channel to stop the background maintenance before closing the database
+
+	log log.Logger // Contextual logger tracking the database path
+}
+
+// New returns a wrapped Badger object. The namespace is the prefix that the
+// metrics reporting should use for surfacing internal stats.
+//
+// Badger memory maps its files instead of keeping open file handles, so the
+// handles allowance is only reported, but not enforced.
+func New(file string, cache int, handles int, namespace string) (*Database, error) {
+	// Ensure we have some minimal caching guarantees
+	if cache < minCache {
+		cache = minCache
+	}
+	tableSize := cache / numMemtables
+	if tableSize > maxTableSize {
+		tableSize = maxTableSize
+	}
+	logger := log.New("database", file)
+	logger.Info("Allocated cache and file handles", "cache", common.StorageSize(cache*1024*1024), "handles", handles)
+
+	// Open the db, truncating any partially written value log after a crash
+	opts := badger.DefaultOptions(file).
+		WithSyncWrites(false).
+		WithTruncate(true).
+		WithNumMemtables(numMemtables).
+		WithMaxTableSize(int64(tableSize) * 1024 * 1024).
+		WithLogger(&logAdapter{logger: logger})
+
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, err
+	}
+	// Assemble the wrapper with all the registered metrics
+	bdb := &Database{
+		fn:       file,
+		db:       db,
+		log:      logger,
+		quitChan: make(chan chan error),
+	}
+	bdb.lsmSizeGauge = metrics.NewRegisteredGauge(namespace+"disk/lsm", nil)
+	bdb.vlogSizeGauge = metrics.NewRegisteredGauge(namespace+"disk/vlog", nil)
+	bdb.diskSizeGauge = metrics.NewRegisteredGauge(namespace+"disk/size", nil)
+
+	// Start up the maintenance loop and return
+	go bdb.maintain(metricsGatheringInterval, gcInterval)
+	return bdb, nil
+}
+
+// Close stops the background maintenance, flushes any pending data to disk and
+// closes all io accesses to the underlying key-value store.
+func (db *Database) Close() error {
+	db.quitLock.Lock()
+	defer db.quitLock.Unlock()
+
+	if db.quitChan != nil {
+		errc := make(chan error)
+		db.quitChan <- errc
+		if err := <-errc; err != nil {
+			db.log.Error("Database maintenance failed", "err", err)
+		}
+		db.quitChan = nil
+	}
+	return db.db.Close()
+}
+
+// Has retrieves if a key is present in the key-value store.
+func (db *Database) Has(key []byte) (bool, error) {
+	err := db.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(key)
+		return err
+	})
+	switch err {
+	case nil:
+		return true, nil
+	case badger.ErrKeyNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// Get retrieves the given key if it's present in the key-value store.
+func (db *Database) Get(key []byte) ([]byte, error) {
+	var dat []byte
+	err := db.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		dat, err = item.ValueCopy(nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return dat, nil
+}
+
+// Put inserts the given value into the key-value store.
+func (db *Database) Put(key []byte, value []byte) error {
+	return db.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(common.CopyBytes(key), common.CopyBytes(value))
+	})
+}
+
+// Delete removes the key from the key-value store.
+func (db *Database) Delete(key []byte) error {
+	return db.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(common.CopyBytes(key))
+	})
+}
+
+// NewBatch creates a write-only key-value store that buffers changes to its host
+// database until a final write is called.
+func (db *Database) NewBatch() ethdb.Batch {
+	return &batch{db: db.db}
+}
+
+// NewIterator creates a binary-alphabetical iterator over the entire keyspace
+// contained within the badger database.
+func (db *Database) NewIterator() ethdb.Iterator {
+	return newIterator(db.db, nil, nil)
+}
+
+// NewIteratorWithStart creates a binary-alphabetical iterator over a subset of
+// database content starting at a particular initial key (or after, if it does
+// not exist).
+func (db *Database) NewIteratorWithStart(start []byte) ethdb.Iterator {
+	return newIterator(db.db, nil, start)
+}
+
+// NewIteratorWithPrefix creates a binary-alphabetical iterator over a subset
+// of database content with a particular key prefix.
+func (db *Database) NewIteratorWithPrefix(prefix []byte) ethdb.Iterator {
+	return newIterator(db.db, prefix, prefix)
+}
+
+// Stat returns a particular internal stat of the database. The supported
+// properties are "badger.size" and "badger.tables".
+func (db *Database) Stat(property string) (string, error) {
+	switch property {
+	case "badger.size":
+		lsm, vlog := db.db.Size()
+		return fmt.Sprintf("LSM:%v Vlog:%v", common.StorageSize(lsm), common.StorageSize(vlog)), nil
+	case "badger.tables":
+		var stats string
+		for _, table := range db.db.Tables(false) {
+			stats += fmt.Sprintf("Level:%d ID:%d Left:%x Right:%x\n", table.Level, table.ID, table.Left, table.Right)
+		}
+		return stats, nil
+	default:
+		return "", fmt.Errorf("unknown property %q", property)
+	}
+}
+
+// Compact flattens the underlying data store and garbage collects the value
+// logs. In essence, deleted and overwritten versions are discarded, and the
+// data is rearranged to reduce the cost of operations needed to access them.
+//
+// Badger cannot compact a key range on its own, so the entire data store is
+// compacted regardless of the requested range.
+func (db *Database) Compact(start []byte, limit []byte) error {
+	if err := db.db.Flatten(1); err != nil {
+		return err
+	}
+	for {
+		if err := db.db.RunValueLogGC(gcDiscardRatio); err != nil {
+			if err == badger.ErrNoRewrite {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Path returns the path to the database directory.
+func (db *Database) Path() string {
+	return db.fn
+}
+
+// maintain periodically reports the database sizes to the metrics subsystem and
+// garbage collects the value logs, which badger doesn't do on its own.
+func (db *Database) maintain(refresh time.Duration, gc time.Duration) {
+	var (
+		errc chan error
+		merr error
+
+		lastGC = time.Now()
+	)
+	for errc == nil && merr == nil {
+		lsm, vlog := db.db.Size()
+		db.lsmSizeGauge.Update(lsm)
+		db.vlogSizeGauge.Update(vlog)
+		db.diskSizeGauge.Update(lsm + vlog)
+
+		// Rewrite any value log files which are mostly stale
+		if time.Since(lastGC) > gc {
+			for {
+				if err := db.db.RunValueLogGC(gcDiscardRatio); err != nil {
+					if err != badger.ErrNoRewrite && err != badger.ErrRejected {
+						db.log.Error("Value log garbage collection failed", "err", err)
+						merr = err
+					}
+					break
+				}
+			}
+			lastGC = time.Now()
+		}
+		// Sleep a bit, then repeat the maintenance
+		select {
+		case errc = <-db.quitChan:
+			// Quit requesting, stop hammering the database
+		case <-time.After(refresh):
+			// Timeout, gather a new set of stats
+		}
+	}
+	if errc == nil {
+		errc = <-db.quitChan
+	}
+	errc <- merr
+}
+
+// keyvalue is a key-value tuple tagged with a deletion field to allow creating
+// badger write batches.
+type keyvalue struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// batch is a write-only badger batch that commits changes to its host database
+// when Write is called. A batch cannot be used concurrently.
+type batch struct {
+	db     *badger.DB
+	writes []keyvalue
+	size   int
+}
+
+// Put inserts the given value into the batch for later committing.
+func (b *batch) Put(key, value []byte) error {
+	b.writes = append(b.writes, keyvalue{common.CopyBytes(key), common.CopyBytes(value), false})
+	b.size += len(value)
+	return nil
+}
+
+// Delete inserts the a key removal into the batch for later committing.
+func (b *batch) Delete(key []byte) error {
+	b.writes = append(b.writes, keyvalue{common.CopyBytes(key), nil, true})
+	b.size++
+	return nil
+}
+
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *batch) ValueSize() int {
+	return b.size
+}
+
+// Write flushes any accumulated data to disk. Badger splits the writes into
+// as many transactions as needed to stay within its transaction limits.
+func (b *batch) Write() error {
+	wb := b.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	for _, kv := range b.writes {
+		var err error
+		if kv.delete {
+			err = wb.Delete(kv.key)
+		} else {
+			err = wb.Set(kv.key, kv.value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
+}
+
+// Reset resets the batch for reuse.
+func (b *batch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
+
+// Replay replays the batch contents.
+func (b *batch) Replay(w ethdb.KeyValueWriter) error {
+	for _, kv := range b.writes {
+		if kv.delete {
+			if err := w.Delete(kv.key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.Put(kv.key, kv.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iterator is a binary-alphabetical iterator over a consistent view of the
+// badger database, optionally restricted to a key prefix.
+type iterator struct {
+	txn    *badger.Txn
+	it     *badger.Iterator
+	prefix []byte
+	start  []byte
+
+	started bool
+	value   []byte
+	err     error
+}
+
+// newIterator creates an iterator over the keys with the given prefix, starting
+// at the given key.
+func newIterator(db *badger.DB, prefix []byte, start []byte) *iterator {
+	txn := db.NewTransaction(false)
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = prefix
+
+	return &iterator{
+		txn:    txn,
+		it:     txn.NewIterator(opts),
+		prefix: prefix,
+		start:  start,
+	}
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted.
+func (it *iterator) Next() bool {
+	if it.it == nil || it.err != nil {
+		return false
+	}
+	if !it.started {
+		it.it.Seek(it.start)
+		it.started = true
+	} else {
+		it.it.Next()
+	}
+	if !it.it.ValidForPrefix(it.prefix) {
+		it.value = nil
+		return false
+	}
+	it.value, it.err = it.it.Item().ValueCopy(it.value[:0])
+	return it.err == nil
+}
+
+// Error returns any accumulated error. Exhausting all the key/value pairs
+// is not considered to be an error.
+func (it *iterator) Error() error {
+	return it.err
+}
+
+// Key returns the key of the current key/value pair, or nil if done. The caller
+// should not modify the contents of the returned slice, and its contents may
+// change on the next call to Next.
+func (it *iterator) Key() []byte {
+	if it.it == nil || !it.started || !it.it.ValidForPrefix(it.prefix) {
+		return nil
+	}
+	return it.it.Item().Key()
+}
+
+// Value returns the value of the current key/value pair, or nil if done. The
+// caller should not modify the contents of the returned slice, and its contents
+// may change on the next call to Next.
+func (it *iterator) Value() []byte {
+	return it.value
+}
+
+// Release releases associated resources. Release should always succeed and can
+// be called multiple times without causing error.
+func (it *iterator) Release() {
+	if it.it != nil {
+		it.it.Close()
+		it.txn.Discard()
+		it.it, it.value = nil, nil
+	}
+}
+
+// logAdapter forwards the internal badger logs into the contextual logger of
+// the database, demoting the chatty informational messages to debug level.
+type logAdapter struct {
+	logger log.Logger
+}
+
+func (l *logAdapter) Errorf(format string, args ...interface{}) {
+	l.logger.Error(strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
+
+func (l *logAdapter) Warningf(format string, args ...interface{}) {
+	l.logger.Warn(strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
+
+func (l *logAdapter) Infof(format string, args ...interface{}) {
+	l.logger.Debug(strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
+
+func (l *logAdapter) Debugf(format string, args ...interface{}) {
+	l.logger.Trace(strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
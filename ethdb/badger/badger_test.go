@@ -0,0 +1,183 @@
+// Copyright 2020 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package badger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// newTestDatabase creates a badger database in a temporary directory, returning
+// a cleanup function to close and delete it.
+func newTestDatabase(t *testing.T) (*Database, func()) {
+	dir, err := ioutil.TempDir("", "badger-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	db, err := New(dir, 16, 16, "")
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to open database: %v", err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// Tests that key-value iteration on top of a badger database works.
+func TestBadgerIterator(t *testing.T) {
+	tests := []struct {
+		content map[string]string
+		prefix  string
+		start   string
+		order   []string
+	}{
+		// Empty databases should be iterable
+		{map[string]string{}, "", "", nil},
+		{map[string]string{}, "non-existent-prefix", "", nil},
+
+		// Single-item databases should be iterable
+		{map[string]string{"key": "val"}, "", "", []string{"key"}},
+		{map[string]string{"key": "val"}, "k", "", []string{"key"}},
+		{map[string]string{"key": "val"}, "l", "", nil},
+
+		// Multi-item databases should be fully iterable
+		{
+			map[string]string{"k1": "v1", "k5": "v5", "k2": "v2", "k4": "v4", "k3": "v3"},
+			"", "",
+			[]string{"k1", "k2", "k3", "k4", "k5"},
+		},
+		{
+			map[string]string{"k1": "v1", "k5": "v5", "k2": "v2", "k4": "v4", "k3": "v3"},
+			"l", "",
+			nil,
+		},
+		// Multi-item databases should be prefix-iterable
+		{
+			map[string]string{
+				"ka1": "va1", "ka5": "va5", "ka2": "va2", "ka4": "va4", "ka3": "va3",
+				"kb1": "vb1", "kb5": "vb5", "kb2": "vb2", "kb4": "vb4", "kb3": "vb3",
+			},
+			"ka", "",
+			[]string{"ka1", "ka2", "ka3", "ka4", "ka5"},
+		},
+		// Multi-item databases should be iterable from a starting key
+		{
+			map[string]string{
+				"ka1": "va1", "ka5": "va5", "ka2": "va2", "ka4": "va4", "ka3": "va3",
+				"kb1": "vb1", "kb5": "vb5", "kb2": "vb2", "kb4": "vb4", "kb3": "vb3",
+			},
+			"", "ka45",
+			[]string{"ka5", "kb1", "kb2", "kb3", "kb4", "kb5"},
+		},
+	}
+	for i, tt := range tests {
+		db, cleanup := newTestDatabase(t)
+
+		for key, val := range tt.content {
+			if err := db.Put([]byte(key), []byte(val)); err != nil {
+				t.Fatalf("test %d: failed to insert item %s:%s into database: %v", i, key, val, err)
+			}
+		}
+		// Iterate over the database with the given configs and verify the results
+		it := db.NewIteratorWithPrefix([]byte(tt.prefix))
+		if tt.start != "" {
+			it = db.NewIteratorWithStart([]byte(tt.start))
+		}
+		idx := 0
+		for it.Next() {
+			if idx >= len(tt.order) {
+				t.Errorf("test %d: unexpected item: %s", i, it.Key())
+				break
+			}
+			if !bytes.Equal(it.Key(), []byte(tt.order[idx])) {
+				t.Errorf("test %d: item %d: key mismatch: have %s, want %s", i, idx, string(it.Key()), tt.order[idx])
+			}
+			if !bytes.Equal(it.Value(), []byte(tt.content[tt.order[idx]])) {
+				t.Errorf("test %d: item %d: value mismatch: have %s, want %s", i, idx, string(it.Value()), tt.content[tt.order[idx]])
+			}
+			idx++
+		}
+		if err := it.Error(); err != nil {
+			t.Errorf("test %d: iteration failed: %v", i, err)
+		}
+		if idx != len(tt.order) {
+			t.Errorf("test %d: iteration terminated prematurely: have %d, want %d", i, idx, len(tt.order))
+		}
+		it.Release()
+		cleanup()
+	}
+}
+
+// Tests that batches are only applied on write, can be replayed and reused.
+func TestBadgerBatch(t *testing.T) {
+	db, cleanup := newTestDatabase(t)
+	defer cleanup()
+
+	if err := db.Put([]byte("k0"), []byte("v0")); err != nil {
+		t.Fatalf("failed to insert item: %v", err)
+	}
+	batch := db.NewBatch()
+	batch.Put([]byte("k1"), []byte("v1"))
+	batch.Put([]byte("k2"), []byte("v2"))
+	batch.Delete([]byte("k0"))
+
+	if ok, _ := db.Has([]byte("k1")); ok {
+		t.Fatalf("batch applied before write")
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	for key, want := range map[string]string{"k1": "v1", "k2": "v2"} {
+		if have, err := db.Get([]byte(key)); err != nil || string(have) != want {
+			t.Errorf("item %s mismatch: have %s (err %v), want %s", key, have, err, want)
+		}
+	}
+	if ok, err := db.Has([]byte("k0")); ok || err != nil {
+		t.Errorf("deleted item still present (err %v)", err)
+	}
+	if _, err := db.Get([]byte("k0")); err == nil {
+		t.Errorf("deleted item retrievable")
+	}
+	// Replay the batch into a fresh database and reuse it afterwards
+	other, cleanupOther := newTestDatabase(t)
+	defer cleanupOther()
+
+	if err := batch.Replay(other); err != nil {
+		t.Fatalf("failed to replay batch: %v", err)
+	}
+	if have, _ := other.Get([]byte("k2")); string(have) != "v2" {
+		t.Errorf("replayed item mismatch: have %s, want %s", have, "v2")
+	}
+	batch.Reset()
+	if batch.ValueSize() != 0 {
+		t.Errorf("batch size not reset: %d", batch.ValueSize())
+	}
+	batch.Put([]byte("k3"), []byte("v3"))
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	if ok, _ := db.Has([]byte("k1")); !ok {
+		t.Errorf("earlier batch content lost")
+	}
+	if err := db.Compact(nil, nil); err != nil {
+		t.Errorf("failed to compact database: %v", err)
+	}
+}
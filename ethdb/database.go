@@ -79,6 +79,10 @@ type AncientReader interface {
 	// Ancients returns the ancient item numbers in the ancient store.
 	Ancients() (uint64, error)
 
+	// AncientTail returns the number of the first item still available in the
+	// specified category, older items were pruned from the ancient store.
+	AncientTail(kind string) (uint64, error)
+
 	// AncientSize returns the ancient size of the specified category.
 	AncientSize(kind string) (uint64, error)
 }
@@ -19,27 +19,61 @@ package graphql
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+const (
+	// maxStorageRange is the maximum number of storage slots returned at once.
+	maxStorageRange = 1024
+
+	// chainEventChanSize is the size of channel listening to ChainEvent.
+	chainEventChanSize = 10
 )
 
 var (
-	errOnlyOnMainChain = errors.New("this operation is only available for blocks on the canonical chain")
-	errBlockInvariant  = errors.New("block objects must be instantiated with at least one of num or hash")
+	errOnlyOnMainChain    = errors.New("this operation is only available for blocks on the canonical chain")
+	errBlockInvariant     = errors.New("block objects must be instantiated with at least one of num or hash")
+	errInvalidCursor      = errors.New("invalid cursor")
+	errNegativeCount      = errors.New("count must not be negative")
+	errTracingUnsupported = errors.New("transaction tracing not supported by this node")
+	errTracingDisabled    = errors.New("transaction tracing disabled, enable it with --graphql.tracing")
 )
 
+// tracingBackend is implemented by the backends able to re-execute historical
+// transactions, resolving the traces of transactions.
+type tracingBackend interface {
+	TraceTransaction(ctx context.Context, hash common.Hash, tracer string) (json.RawMessage, error)
+}
+
+// untracedBackend is a backend refusing to trace transactions, preventing clients
+// from re-executing them unless explicitly allowed.
+type untracedBackend struct {
+	ethapi.Backend
+}
+
+func (b untracedBackend) TraceTransaction(ctx context.Context, hash common.Hash, tracer string) (json.RawMessage, error) {
+	return nil, errTracingDisabled
+}
+
 // Account represents an Ethereum account at a particular block.
 type Account struct {
 	backend     ethapi.Backend
@@ -89,6 +123,91 @@ func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash })
 	return state.GetState(a.address, args.Slot), nil
 }
 
+func (a *Account) StorageRange(ctx context.Context, args struct {
+	Start *common.Hash
+	Count int32
+}) (*StorageRange, error) {
+	if args.Count < 0 {
+		return nil, errNegativeCount
+	}
+	count := int(args.Count)
+	if count > maxStorageRange {
+		count = maxStorageRange
+	}
+	state, err := a.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &StorageRange{entries: []*StorageEntry{}}
+
+	st := state.StorageTrie(a.address)
+	if st == nil {
+		return result, nil
+	}
+	var start []byte
+	if args.Start != nil {
+		start = args.Start.Bytes()
+	}
+	it := trie.NewIterator(st.NodeIterator(start))
+	for len(result.entries) < count && it.Next() {
+		_, content, _, err := rlp.Split(it.Value)
+		if err != nil {
+			return nil, err
+		}
+		entry := &StorageEntry{
+			hash:  common.BytesToHash(it.Key),
+			value: common.BytesToHash(content),
+		}
+		if preimage := st.GetKey(it.Key); preimage != nil {
+			slot := common.BytesToHash(preimage)
+			entry.slot = &slot
+		}
+		result.entries = append(result.entries, entry)
+	}
+	// Add the next hash so clients can continue iterating
+	if it.Next() {
+		next := common.BytesToHash(it.Key)
+		result.next = &next
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	return result, nil
+}
+
+// StorageEntry represents a single slot of the storage of an account.
+type StorageEntry struct {
+	hash  common.Hash
+	slot  *common.Hash
+	value common.Hash
+}
+
+func (e *StorageEntry) Hash(ctx context.Context) common.Hash {
+	return e.hash
+}
+
+func (e *StorageEntry) Slot(ctx context.Context) *common.Hash {
+	return e.slot
+}
+
+func (e *StorageEntry) Value(ctx context.Context) common.Hash {
+	return e.value
+}
+
+// StorageRange represents a range of the storage of an account.
+type StorageRange struct {
+	entries []*StorageEntry
+	next    *common.Hash
+}
+
+func (r *StorageRange) Entries(ctx context.Context) []*StorageEntry {
+	return r.entries
+}
+
+func (r *StorageRange) NextHash(ctx context.Context) *common.Hash {
+	return r.next
+}
+
 // Log represents an individual log message. All arguments are mandatory.
 type Log struct {
 	backend     ethapi.Backend
@@ -120,6 +239,10 @@ func (l *Log) Data(ctx context.Context) hexutil.Bytes {
 	return hexutil.Bytes(l.log.Data)
 }
 
+func (l *Log) Cursor(ctx context.Context) string {
+	return logCursor(l.log.BlockNumber, l.log.Index)
+}
+
 // Transaction represents an Ethereum transaction.
 // backend and hash are mandatory; all others will be fetched when required.
 type Transaction struct {
@@ -315,6 +438,166 @@ func (t *Transaction) Logs(ctx context.Context) (*[]*Log, error) {
 	return &ret, nil
 }
 
+func (t *Transaction) Trace(ctx context.Context) (*CallFrame, error) {
+	if _, err := t.resolve(ctx); err != nil || t.block == nil {
+		return nil, err
+	}
+	tracer, ok := t.backend.(tracingBackend)
+	if !ok {
+		return nil, errTracingUnsupported
+	}
+	result, err := tracer.TraceTransaction(ctx, t.hash, "callTracer")
+	if err != nil {
+		return nil, err
+	}
+	frame := new(callFrame)
+	if err := json.Unmarshal(result, frame); err != nil {
+		return nil, err
+	}
+	return &CallFrame{frame}, nil
+}
+
+func (t *Transaction) Cursor(ctx context.Context) (string, error) {
+	if _, err := t.resolve(ctx); err != nil {
+		return "", err
+	}
+	return txCursor(t.index), nil
+}
+
+// callFrame is the JSON encoding of a call reported by the call tracer.
+type callFrame struct {
+	Type         string          `json:"type"`
+	From         common.Address  `json:"from"`
+	To           *common.Address `json:"to"`
+	Value        *hexutil.Big    `json:"value"`
+	Gas          hexutil.Uint64  `json:"gas"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	Input        hexutil.Bytes   `json:"input"`
+	Output       hexutil.Bytes   `json:"output"`
+	Error        string          `json:"error"`
+	RevertReason string          `json:"revertReason"`
+	Calls        []*callFrame    `json:"calls"`
+}
+
+// CallFrame represents a call made during the execution of a transaction.
+type CallFrame struct {
+	frame *callFrame
+}
+
+func (c *CallFrame) Type(ctx context.Context) string {
+	return c.frame.Type
+}
+
+func (c *CallFrame) From(ctx context.Context) common.Address {
+	return c.frame.From
+}
+
+func (c *CallFrame) To(ctx context.Context) *common.Address {
+	return c.frame.To
+}
+
+func (c *CallFrame) Value(ctx context.Context) *hexutil.Big {
+	return c.frame.Value
+}
+
+func (c *CallFrame) Gas(ctx context.Context) hexutil.Uint64 {
+	return c.frame.Gas
+}
+
+func (c *CallFrame) GasUsed(ctx context.Context) hexutil.Uint64 {
+	return c.frame.GasUsed
+}
+
+func (c *CallFrame) Input(ctx context.Context) hexutil.Bytes {
+	return c.frame.Input
+}
+
+func (c *CallFrame) Output(ctx context.Context) hexutil.Bytes {
+	if c.frame.Output == nil {
+		return hexutil.Bytes{}
+	}
+	return c.frame.Output
+}
+
+func (c *CallFrame) Error(ctx context.Context) *string {
+	if c.frame.Error == "" {
+		return nil
+	}
+	return &c.frame.Error
+}
+
+func (c *CallFrame) RevertReason(ctx context.Context) *string {
+	if c.frame.RevertReason == "" {
+		return nil
+	}
+	return &c.frame.RevertReason
+}
+
+func (c *CallFrame) Calls(ctx context.Context) []*CallFrame {
+	ret := make([]*CallFrame, 0, len(c.frame.Calls))
+	for _, call := range c.frame.Calls {
+		ret = append(ret, &CallFrame{call})
+	}
+	return ret
+}
+
+// Receipt represents the receipt of a transaction included in a block.
+type Receipt struct {
+	transaction *Transaction
+	receipt     *types.Receipt
+}
+
+func (r *Receipt) Transaction(ctx context.Context) *Transaction {
+	return r.transaction
+}
+
+func (r *Receipt) Status(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.Status)
+}
+
+func (r *Receipt) Root(ctx context.Context) *hexutil.Bytes {
+	if len(r.receipt.PostState) == 0 {
+		return nil
+	}
+	root := hexutil.Bytes(r.receipt.PostState)
+	return &root
+}
+
+func (r *Receipt) GasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.GasUsed)
+}
+
+func (r *Receipt) CumulativeGasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(r.receipt.CumulativeGasUsed)
+}
+
+func (r *Receipt) CreatedContract(ctx context.Context, args BlockNumberArgs) *Account {
+	if r.receipt.ContractAddress == (common.Address{}) {
+		return nil
+	}
+	return &Account{
+		backend:     r.transaction.backend,
+		address:     r.receipt.ContractAddress,
+		blockNumber: args.Number(),
+	}
+}
+
+func (r *Receipt) Logs(ctx context.Context) []*Log {
+	ret := make([]*Log, 0, len(r.receipt.Logs))
+	for _, log := range r.receipt.Logs {
+		ret = append(ret, &Log{
+			backend:     r.transaction.backend,
+			transaction: r.transaction,
+			log:         log,
+		})
+	}
+	return ret
+}
+
+func (r *Receipt) LogsBloom(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(r.receipt.Bloom.Bytes())
+}
+
 type BlockType int
 
 const (
@@ -601,6 +884,89 @@ func (b *Block) TotalDifficulty(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*b.backend.GetTd(h)), nil
 }
 
+// PageArgs encapsulates arguments to accessors returning lists that can be paged.
+type PageArgs struct {
+	First *int32  // The maximum number of items to return
+	After *string // The cursor of the item to start after
+}
+
+// limit returns the number of items of a list of the given length that fit into
+// the requested page.
+func (a PageArgs) limit(n int) (int, error) {
+	if a.First == nil {
+		return n, nil
+	}
+	if *a.First < 0 {
+		return 0, errNegativeCount
+	}
+	if int(*a.First) < n {
+		return int(*a.First), nil
+	}
+	return n, nil
+}
+
+// pageLogs returns the page of a list of logs, sorted by block and index,
+// requested by the given arguments.
+func pageLogs(logs []*Log, args PageArgs) ([]*Log, error) {
+	if args.After != nil {
+		number, index, err := parseLogCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		for len(logs) > 0 && (logs[0].log.BlockNumber < number || (logs[0].log.BlockNumber == number && logs[0].log.Index <= index)) {
+			logs = logs[1:]
+		}
+	}
+	n, err := args.limit(len(logs))
+	if err != nil {
+		return nil, err
+	}
+	return logs[:n], nil
+}
+
+// The cursors identifying the items of paged lists are opaque to the clients,
+// so their encoding may change without breaking them.
+
+// txCursor returns the cursor of the transaction at the given index of a block.
+func txCursor(index uint64) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("tx:%d", index)))
+}
+
+// parseTxCursor returns the index of the transaction identified by a cursor.
+func parseTxCursor(cursor string) (uint64, error) {
+	blob, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errInvalidCursor
+	}
+	var index uint64
+	if _, err := fmt.Sscanf(string(blob), "tx:%d", &index); err != nil {
+		return 0, errInvalidCursor
+	}
+	return index, nil
+}
+
+// logCursor returns the cursor of the log at the given index of a block.
+func logCursor(number uint64, index uint) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("log:%d:%d", number, index)))
+}
+
+// parseLogCursor returns the block number and index of the log identified by
+// a cursor.
+func parseLogCursor(cursor string) (uint64, uint, error) {
+	blob, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, errInvalidCursor
+	}
+	var (
+		number uint64
+		index  uint
+	)
+	if _, err := fmt.Sscanf(string(blob), "log:%d:%d", &number, &index); err != nil {
+		return 0, 0, errInvalidCursor
+	}
+	return number, index, nil
+}
+
 // BlockNumberArgs encapsulates arguments to accessors that specify a block number.
 type BlockNumberArgs struct {
 	Block *hexutil.Uint64
@@ -636,7 +1002,35 @@ func (b *Block) TransactionCount(ctx context.Context) (*int32, error) {
 	return &count, err
 }
 
-func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
+func (b *Block) Transactions(ctx context.Context, args PageArgs) (*[]*Transaction, error) {
+	txs, err := b.transactions(ctx)
+	if err != nil || txs == nil {
+		return nil, err
+	}
+	start := uint64(0)
+	if args.After != nil {
+		index, err := parseTxCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		start = index + 1
+	}
+	if start > uint64(len(txs)) {
+		start = uint64(len(txs))
+	}
+	txs = txs[start:]
+
+	n, err := args.limit(len(txs))
+	if err != nil {
+		return nil, err
+	}
+	txs = txs[:n]
+	return &txs, nil
+}
+
+// transactions returns all the transactions of the block, or nil if they are
+// unavailable.
+func (b *Block) transactions(ctx context.Context) ([]*Transaction, error) {
 	block, err := b.resolve(ctx)
 	if err != nil || block == nil {
 		return nil, err
@@ -651,6 +1045,28 @@ func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
 			index:   uint64(i),
 		})
 	}
+	return ret, nil
+}
+
+func (b *Block) Receipts(ctx context.Context) (*[]*Receipt, error) {
+	txs, err := b.transactions(ctx)
+	if err != nil || txs == nil {
+		return nil, err
+	}
+	receipts, err := b.resolveReceipts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipt count mismatch: have %d, want %d", len(receipts), len(txs))
+	}
+	ret := make([]*Receipt, 0, len(receipts))
+	for i, receipt := range receipts {
+		ret = append(ret, &Receipt{
+			transaction: txs[i],
+			receipt:     receipt,
+		})
+	}
 	return &ret, nil
 }
 
@@ -712,6 +1128,19 @@ type BlockFilterCriteria struct {
 	Topics *[][]common.Hash
 }
 
+// criteria returns the addresses and topics the filter matches.
+func (c BlockFilterCriteria) criteria() ([]common.Address, [][]common.Hash) {
+	var addresses []common.Address
+	if c.Addresses != nil {
+		addresses = *c.Addresses
+	}
+	var topics [][]common.Hash
+	if c.Topics != nil {
+		topics = *c.Topics
+	}
+	return addresses, topics
+}
+
 // runFilter accepts a filter and executes it, returning all its results as
 // `Log` objects.
 func runFilter(ctx context.Context, be ethapi.Backend, filter *filters.Filter) ([]*Log, error) {
@@ -730,15 +1159,12 @@ func runFilter(ctx context.Context, be ethapi.Backend, filter *filters.Filter) (
 	return ret, nil
 }
 
-func (b *Block) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) ([]*Log, error) {
-	var addresses []common.Address
-	if args.Filter.Addresses != nil {
-		addresses = *args.Filter.Addresses
-	}
-	var topics [][]common.Hash
-	if args.Filter.Topics != nil {
-		topics = *args.Filter.Topics
-	}
+func (b *Block) Logs(ctx context.Context, args struct {
+	Filter BlockFilterCriteria
+	PageArgs
+}) ([]*Log, error) {
+	addresses, topics := args.Filter.criteria()
+
 	hash := b.hash
 	if hash == (common.Hash{}) {
 		header, err := b.resolveHeader(ctx)
@@ -750,8 +1176,12 @@ func (b *Block) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteri
 	// Construct the range filter
 	filter := filters.NewBlockFilter(b.backend, hash, addresses, topics)
 
-	// Run the filter and return all the logs
-	return runFilter(ctx, b.backend, filter)
+	// Run the filter and return the requested page of logs
+	logs, err := runFilter(ctx, b.backend, filter)
+	if err != nil {
+		return nil, err
+	}
+	return pageLogs(logs, args.PageArgs)
 }
 
 func (b *Block) Account(ctx context.Context, args struct {
@@ -817,16 +1247,19 @@ func (b *Block) Call(ctx context.Context, args struct {
 			return nil, err
 		}
 	}
-	result, gas, failed, err := ethapi.DoCall(ctx, b.backend, args.Data, *b.num, nil, vm.Config{}, 5*time.Second, b.backend.RPCGasCap())
+	out, err := ethapi.DoCall(ctx, b.backend, args.Data, *b.num, nil, vm.Config{}, 5*time.Second, b.backend.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
 	status := hexutil.Uint64(1)
-	if failed {
+	if out.Failed() {
 		status = 0
 	}
 	return &CallResult{
-		data:    hexutil.Bytes(result),
-		gasUsed: hexutil.Uint64(gas),
+		data:    hexutil.Bytes(out.ReturnData),
+		gasUsed: hexutil.Uint64(out.UsedGas),
 		status:  status,
-	}, err
+	}, nil
 }
 
 func (b *Block) EstimateGas(ctx context.Context, args struct {
@@ -885,16 +1318,19 @@ func (p *Pending) Account(ctx context.Context, args struct {
 func (p *Pending) Call(ctx context.Context, args struct {
 	Data ethapi.CallArgs
 }) (*CallResult, error) {
-	result, gas, failed, err := ethapi.DoCall(ctx, p.backend, args.Data, rpc.PendingBlockNumber, nil, vm.Config{}, 5*time.Second, p.backend.RPCGasCap())
+	out, err := ethapi.DoCall(ctx, p.backend, args.Data, rpc.PendingBlockNumber, nil, vm.Config{}, 5*time.Second, p.backend.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
 	status := hexutil.Uint64(1)
-	if failed {
+	if out.Failed() {
 		status = 0
 	}
 	return &CallResult{
-		data:    hexutil.Bytes(result),
-		gasUsed: hexutil.Uint64(gas),
+		data:    hexutil.Bytes(out.ReturnData),
+		gasUsed: hexutil.Uint64(out.UsedGas),
 		status:  status,
-	}, err
+	}, nil
 }
 
 func (p *Pending) EstimateGas(ctx context.Context, args struct {
@@ -1021,7 +1457,10 @@ type FilterCriteria struct {
 	Topics *[][]common.Hash
 }
 
-func (r *Resolver) Logs(ctx context.Context, args struct{ Filter FilterCriteria }) ([]*Log, error) {
+func (r *Resolver) Logs(ctx context.Context, args struct {
+	Filter FilterCriteria
+	PageArgs
+}) ([]*Log, error) {
 	// Convert the RPC block numbers into internal representations
 	begin := rpc.LatestBlockNumber.Int64()
 	if args.Filter.FromBlock != nil {
@@ -1039,9 +1478,24 @@ func (r *Resolver) Logs(ctx context.Context, args struct{ Filter FilterCriteria
 	if args.Filter.Topics != nil {
 		topics = *args.Filter.Topics
 	}
+	// Skip the blocks preceding the cursor, if any
+	if args.After != nil {
+		number, _, err := parseLogCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		if begin >= 0 && int64(number) > begin {
+			begin = int64(number)
+		}
+	}
 	// Construct the range filter
 	filter := filters.NewRangeFilter(filters.Backend(r.backend), begin, end, addresses, topics)
-	return runFilter(ctx, r.backend, filter)
+
+	logs, err := runFilter(ctx, r.backend, filter)
+	if err != nil {
+		return nil, err
+	}
+	return pageLogs(logs, args.PageArgs)
 }
 
 func (r *Resolver) GasPrice(ctx context.Context) (hexutil.Big, error) {
@@ -1097,3 +1551,75 @@ func (r *Resolver) Syncing() (*SyncState, error) {
 	// Otherwise gather the block sync stats
 	return &SyncState{progress}, nil
 }
+
+func (r *Resolver) NewBlocks(ctx context.Context) (<-chan *Block, error) {
+	events := make(chan core.ChainEvent, chainEventChanSize)
+	sub := r.backend.SubscribeChainEvent(events)
+
+	blocks := make(chan *Block)
+	go func() {
+		defer close(blocks)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				number := rpc.BlockNumber(ev.Block.NumberU64())
+				block := &Block{
+					backend:   r.backend,
+					num:       &number,
+					hash:      ev.Hash,
+					header:    ev.Block.Header(),
+					canonical: unknown,
+				}
+				select {
+				case blocks <- block:
+				case <-ctx.Done():
+					return
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return blocks, nil
+}
+
+func (r *Resolver) NewLogs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (<-chan *Log, error) {
+	addresses, topics := args.Filter.criteria()
+
+	events := make(chan core.ChainEvent, chainEventChanSize)
+	sub := r.backend.SubscribeChainEvent(events)
+
+	logs := make(chan *Log)
+	go func() {
+		defer close(logs)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				// Light clients don't deliver logs with the event, filter them uniformly
+				matches, err := runFilter(ctx, r.backend, filters.NewBlockFilter(r.backend, ev.Hash, addresses, topics))
+				if err != nil {
+					log.Debug("Failed to filter subscribed logs", "hash", ev.Hash, "err", err)
+					continue
+				}
+				for _, match := range matches {
+					select {
+					case logs <- match:
+					case <-ctx.Done():
+						return
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return logs, nil
+}
@@ -17,12 +17,371 @@
 package graphql
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/gorilla/websocket"
 )
 
 func TestBuildSchema(t *testing.T) {
 	// Make sure the schema can be parsed and matched up to the object model.
-	if _, err := newHandler(nil); err != nil {
+	if _, err := newHandler(nil, nil, false); err != nil {
 		t.Errorf("Could not construct GraphQL handler: %v", err)
 	}
 }
+
+var (
+	testKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr     = crypto.PubkeyToAddress(testKey.PublicKey)
+	testContract = common.Address{0xcc}
+	testCallee   = common.Address{0xaa}
+)
+
+// newTestService starts a node with a chain of three blocks, each containing two
+// transactions invoking a contract that stores to slot 0, emits a log and calls
+// another account. It returns the GraphQL endpoint of the node along with a
+// fourth block, not yet imported.
+func newTestService(t *testing.T) (*node.Node, *eth.Ethereum, *httptest.Server, *types.Block) {
+	// SSTORE(0, 1), LOG0(0, 0), CALL(gas, 0xaa, 0, 0, 0, 0, 0)
+	code := append(common.FromHex("0x600160005560006000a0600060006000600060006000"), byte(0x60+len(testCallee)-1))
+	code = append(code, testCallee.Bytes()...)
+	code = append(code, common.FromHex("0x5af100")...)
+
+	genesis := &core.Genesis{
+		Config: params.AllEthashProtocolChanges,
+		Alloc: core.GenesisAlloc{
+			testAddr:     {Balance: big.NewInt(params.Ether)},
+			testContract: {Balance: new(big.Int), Code: code},
+		},
+	}
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(genesis.Config, genesis.MustCommit(db), ethash.NewFaker(), db, 4, func(i int, b *core.BlockGen) {
+		for j := 0; j < 2; j++ {
+			tx, _ := types.SignTx(types.NewTransaction(b.TxNonce(testAddr), testContract, new(big.Int), 100000, big.NewInt(1), nil), types.HomesteadSigner{}, testKey)
+			b.AddTx(tx)
+		}
+	})
+	var ethservice *eth.Ethereum
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		config := &eth.Config{Genesis: genesis}
+		config.Ethash.PowMode = ethash.ModeFake
+		ethservice, err = eth.New(ctx, config)
+		return ethservice, err
+	})
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	if _, err := ethservice.BlockChain().InsertChain(blocks[:3]); err != nil {
+		t.Fatalf("failed to import test chain: %v", err)
+	}
+	handler, err := newHandler(ethservice.APIBackend, nil, true)
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	return stack, ethservice, httptest.NewServer(handler), blocks[3]
+}
+
+// query runs a GraphQL query against an endpoint, decoding the response data into
+// the result.
+func query(t *testing.T, url string, query string, result interface{}) {
+	req, _ := json.Marshal(map[string]string{"query": query})
+	res, err := http.Post(url+"/graphql", "application/json", bytes.NewReader(req))
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer res.Body.Close()
+
+	var response struct {
+		Data   json.RawMessage
+		Errors []struct{ Message string }
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Errors) > 0 {
+		t.Fatalf("query %q failed: %v", query, response.Errors)
+	}
+	if err := json.Unmarshal(response.Data, result); err != nil {
+		t.Fatalf("failed to decode data: %v", err)
+	}
+}
+
+// Tests that transactions and logs can be retrieved page by page.
+func TestPagination(t *testing.T) {
+	stack, _, server, _ := newTestService(t)
+	defer stack.Stop()
+	defer server.Close()
+
+	var txs struct {
+		Block struct {
+			Transactions []struct {
+				Index  int
+				Cursor string
+			}
+		}
+	}
+	query(t, server.URL, `{ block(number: 1) { transactions(first: 1) { index cursor } } }`, &txs)
+	if len(txs.Block.Transactions) != 1 || txs.Block.Transactions[0].Index != 0 {
+		t.Fatalf("first transaction page mismatch: have %+v", txs.Block.Transactions)
+	}
+	query(t, server.URL, fmt.Sprintf(`{ block(number: 1) { transactions(after: %q) { index cursor } } }`, txs.Block.Transactions[0].Cursor), &txs)
+	if len(txs.Block.Transactions) != 1 || txs.Block.Transactions[0].Index != 1 {
+		t.Fatalf("second transaction page mismatch: have %+v", txs.Block.Transactions)
+	}
+	// Page through the logs of the whole chain
+	type logPage struct {
+		Logs []struct {
+			Cursor      string
+			Transaction struct {
+				Block struct{ Number hexutil.Uint64 }
+			}
+		}
+	}
+	var (
+		page   logPage
+		after  string
+		blocks []hexutil.Uint64
+	)
+	for i := 0; i < 4; i++ {
+		q := `{ logs(filter: {fromBlock: 1, toBlock: 3}, first: 4) { cursor transaction { block { number } } } }`
+		if after != "" {
+			q = fmt.Sprintf(`{ logs(filter: {fromBlock: 1, toBlock: 3}, first: 4, after: %q) { cursor transaction { block { number } } } }`, after)
+		}
+		query(t, server.URL, q, &page)
+		if len(page.Logs) == 0 {
+			break
+		}
+		for _, log := range page.Logs {
+			blocks = append(blocks, log.Transaction.Block.Number)
+		}
+		after = page.Logs[len(page.Logs)-1].Cursor
+	}
+	if want := []hexutil.Uint64{1, 1, 2, 2, 3, 3}; fmt.Sprint(blocks) != fmt.Sprint(want) {
+		t.Fatalf("paged log blocks mismatch: have %v, want %v", blocks, want)
+	}
+	// Page through the logs of a single block
+	var blockLogs struct {
+		Block struct {
+			Logs []struct {
+				Index  int
+				Cursor string
+			}
+		}
+	}
+	query(t, server.URL, `{ block(number: 2) { logs(filter: {}, first: 1) { index cursor } } }`, &blockLogs)
+	if len(blockLogs.Block.Logs) != 1 || blockLogs.Block.Logs[0].Index != 0 {
+		t.Fatalf("first block log page mismatch: have %+v", blockLogs.Block.Logs)
+	}
+	query(t, server.URL, fmt.Sprintf(`{ block(number: 2) { logs(filter: {}, after: %q) { index cursor } } }`, blockLogs.Block.Logs[0].Cursor), &blockLogs)
+	if len(blockLogs.Block.Logs) != 1 || blockLogs.Block.Logs[0].Index != 1 {
+		t.Fatalf("second block log page mismatch: have %+v", blockLogs.Block.Logs)
+	}
+}
+
+// Tests that the storage of accounts can be iterated.
+func TestStorageRange(t *testing.T) {
+	stack, _, server, _ := newTestService(t)
+	defer stack.Stop()
+	defer server.Close()
+
+	var result struct {
+		Block struct {
+			Account struct {
+				StorageRange struct {
+					Entries []struct {
+						Hash  common.Hash
+						Slot  *common.Hash
+						Value common.Hash
+					}
+					NextHash *common.Hash
+				}
+			}
+		}
+	}
+	query(t, server.URL, fmt.Sprintf(`{ block(number: 3) { account(address: "%s") { storageRange(count: 10) { entries { hash slot value } nextHash } } } }`, testContract.Hex()), &result)
+
+	storage := result.Block.Account.StorageRange
+	if len(storage.Entries) != 1 || storage.NextHash != nil {
+		t.Fatalf("storage range mismatch: have %+v", storage)
+	}
+	entry := storage.Entries[0]
+	if entry.Hash != crypto.Keccak256Hash(common.Hash{}.Bytes()) || entry.Slot == nil || *entry.Slot != (common.Hash{}) || entry.Value != common.BigToHash(big.NewInt(1)) {
+		t.Errorf("storage entry mismatch: have %+v", entry)
+	}
+	query(t, server.URL, fmt.Sprintf(`{ block(number: 3) { account(address: "%s") { storageRange(count: 0) { entries { hash } nextHash } } } }`, testContract.Hex()), &result)
+	if storage := result.Block.Account.StorageRange; len(storage.Entries) != 0 || storage.NextHash == nil || *storage.NextHash != entry.Hash {
+		t.Errorf("empty storage range mismatch: have %+v", storage)
+	}
+}
+
+// Tests that transactions are traced into their call trees.
+func TestTransactionTrace(t *testing.T) {
+	stack, ethservice, server, _ := newTestService(t)
+	defer stack.Stop()
+	defer server.Close()
+
+	hash := ethservice.BlockChain().GetBlockByNumber(2).Transactions()[1].Hash()
+
+	var result struct {
+		Transaction struct {
+			Trace struct {
+				Type  string
+				From  common.Address
+				To    common.Address
+				Error *string
+				Calls []struct {
+					Type string
+					From common.Address
+					To   common.Address
+				}
+			}
+		}
+	}
+	query(t, server.URL, fmt.Sprintf(`{ transaction(hash: "%s") { trace { type from to error calls { type from to } } } }`, hash.Hex()), &result)
+
+	trace := result.Transaction.Trace
+	if trace.Type != "CALL" || trace.From != testAddr || trace.To != testContract || trace.Error != nil {
+		t.Fatalf("trace mismatch: have %+v", trace)
+	}
+	if len(trace.Calls) != 1 || trace.Calls[0].Type != "CALL" || trace.Calls[0].From != testContract || trace.Calls[0].To != testCallee {
+		t.Fatalf("internal calls mismatch: have %+v", trace.Calls)
+	}
+}
+
+// Tests that transactions can't be traced unless tracing was enabled.
+func TestTransactionTraceDisabled(t *testing.T) {
+	stack, ethservice, server, _ := newTestService(t)
+	defer stack.Stop()
+	server.Close()
+
+	handler, err := newHandler(ethservice.APIBackend, nil, false)
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	server = httptest.NewServer(handler)
+	defer server.Close()
+
+	hash := ethservice.BlockChain().GetBlockByNumber(2).Transactions()[1].Hash()
+	req, _ := json.Marshal(map[string]string{"query": fmt.Sprintf(`{ transaction(hash: "%s") { trace { type } } }`, hash.Hex())})
+	res, err := http.Post(server.URL+"/graphql", "application/json", bytes.NewReader(req))
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer res.Body.Close()
+
+	var response struct {
+		Errors []struct{ Message string }
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Errors) != 1 || response.Errors[0].Message != errTracingDisabled.Error() {
+		t.Fatalf("error mismatch: have %v, want %v", response.Errors, errTracingDisabled)
+	}
+}
+
+// Tests that new blocks and logs are delivered to subscribers over websockets.
+func TestSubscriptions(t *testing.T) {
+	stack, ethservice, server, block := newTestService(t)
+	defer stack.Stop()
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("failed to dial websocket: %v", err)
+	}
+	defer conn.Close()
+
+	send := func(msg string) {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("failed to send message: %v", err)
+		}
+	}
+	read := func() wsMessage {
+		var msg wsMessage
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("failed to read message: %v", err)
+		}
+		return msg
+	}
+	send(`{"type": "connection_init"}`)
+	if msg := read(); msg.Type != wsConnectionAck {
+		t.Fatalf("connection not acknowledged: %+v", msg)
+	}
+	send(`{"id": "1", "type": "start", "payload": {"query": "subscription { newBlocks { number hash } }"}}`)
+	send(`{"id": "2", "type": "start", "payload": {"query": "subscription { newLogs(filter: {addresses: [\"` + testContract.Hex() + `\"]}) { index } }"}}`)
+
+	// Wait for both subscriptions to be installed before importing the block
+	time.Sleep(100 * time.Millisecond)
+	if _, err := ethservice.BlockChain().InsertChain(types.Blocks{block}); err != nil {
+		t.Fatalf("failed to import block: %v", err)
+	}
+	var (
+		blocks int
+		logs   []int
+	)
+	for blocks < 1 || len(logs) < 2 {
+		msg := read()
+		if msg.Type != wsData {
+			t.Fatalf("unexpected message: %+v", msg)
+		}
+		switch msg.ID {
+		case "1":
+			var payload struct {
+				Data struct {
+					NewBlocks struct {
+						Number hexutil.Uint64
+						Hash   common.Hash
+					}
+				}
+			}
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				t.Fatalf("failed to decode block: %v", err)
+			}
+			if payload.Data.NewBlocks.Number != 4 || payload.Data.NewBlocks.Hash != block.Hash() {
+				t.Fatalf("new block mismatch: have %+v", payload.Data.NewBlocks)
+			}
+			blocks++
+		case "2":
+			var payload struct {
+				Data struct{ NewLogs struct{ Index int } }
+			}
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				t.Fatalf("failed to decode log: %v", err)
+			}
+			logs = append(logs, payload.Data.NewLogs.Index)
+		default:
+			t.Fatalf("unexpected operation: %+v", msg)
+		}
+	}
+	if blocks != 1 || fmt.Sprint(logs) != "[0 1]" {
+		t.Fatalf("subscription results mismatch: have %d blocks, logs %v", blocks, logs)
+	}
+	// Stopping a subscription should complete it
+	send(`{"id": "1", "type": "stop"}`)
+	if msg := read(); msg.Type != wsComplete || msg.ID != "1" {
+		t.Fatalf("subscription not completed: %+v", msg)
+	}
+}
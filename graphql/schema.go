@@ -34,6 +34,7 @@ const schema string = `
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Ethereum account at a particular block.
@@ -52,6 +53,31 @@ const schema string = `
         # Storage provides access to the storage of a contract account, indexed
         # by its 32 byte slot identifier.
         storage(slot: Bytes32!): Bytes32!
+        # StorageRange returns up to count entries of the storage of a contract
+        # account, ordered by the keccak256 hashes of their slots and starting
+        # at the given hash. At most 1024 entries are returned at once.
+        storageRange(start: Bytes32, count: Int!): StorageRange!
+    }
+
+    # StorageEntry is a single slot of the storage of a contract account.
+    type StorageEntry {
+        # Hash is the keccak256 hash of the slot identifier, the key of the entry
+        # in the storage trie.
+        hash: Bytes32!
+        # Slot is the slot identifier. This will be null if the node doesn't
+        # know the preimage of the hash.
+        slot: Bytes32
+        # Value is the value stored in the slot.
+        value: Bytes32!
+    }
+
+    # StorageRange is a range of the storage of a contract account.
+    type StorageRange {
+        # Entries is the list of storage slots in the range.
+        entries: [StorageEntry!]!
+        # NextHash is the hash to start the next range at. This will be null if
+        # the range reaches the end of the storage.
+        nextHash: Bytes32
     }
 
     # Log is an Ethereum event log.
@@ -67,6 +93,9 @@ const schema string = `
         data: Bytes!
         # Transaction is the transaction that generated this log entry.
         transaction: Transaction!
+        # Cursor is an opaque identifier of this log, to be passed as the after
+        # argument of logs accessors to retrieve the logs following it.
+        cursor: String!
     }
 
     # Transaction is an Ethereum transaction.
@@ -115,6 +144,69 @@ const schema string = `
         # Logs is a list of log entries emitted by this transaction. If the
         # transaction has not yet been mined, this field will be null.
         logs: [Log!]
+        # Trace is the tree of calls made while executing this transaction, as
+        # reported by the call tracer. If the transaction has not yet been mined,
+        # this field will be null. Tracing requires a full node with the state
+        # of the parent block available, started with --graphql.tracing.
+        trace: CallFrame
+        # Cursor is an opaque identifier of this transaction, to be passed as the
+        # after argument of Block.transactions to retrieve the transactions
+        # following it.
+        cursor: String!
+    }
+
+    # CallFrame is a call made during the execution of a transaction.
+    type CallFrame {
+        # Type is the kind of the call: CALL, CALLCODE, DELEGATECALL, STATICCALL,
+        # CREATE, CREATE2 or SELFDESTRUCT.
+        type: String!
+        # From is the address of the caller.
+        from: Address!
+        # To is the address of the callee, or of the created contract.
+        to: Address
+        # Value is the value, in wei, transferred by the call. This is null for
+        # calls which can't transfer value.
+        value: BigInt
+        # Gas is the amount of gas available to the call.
+        gas: Long!
+        # GasUsed is the amount of gas used by the call.
+        gasUsed: Long!
+        # Input is the data passed to the call, or the init code of a contract
+        # creation.
+        input: Bytes!
+        # Output is the data returned by the call.
+        output: Bytes!
+        # Error is the reason the call failed, or null if it succeeded.
+        error: String
+        # RevertReason is the decoded reason of a reverted transaction, if any.
+        revertReason: String
+        # Calls is the list of calls made by this call.
+        calls: [CallFrame!]!
+    }
+
+    # Receipt is the outcome of a transaction included in a block.
+    type Receipt {
+        # Transaction is the transaction this receipt belongs to.
+        transaction: Transaction!
+        # Status is the return status of the transaction: 1 if it succeeded, or
+        # 0 if it failed. Before Byzantium this field is always 0, see root.
+        status: Long!
+        # Root is the intermediate state root after the transaction executed.
+        # This is only set for blocks before Byzantium.
+        root: Bytes
+        # GasUsed is the amount of gas that was used processing the transaction.
+        gasUsed: Long!
+        # CumulativeGasUsed is the total gas used in the block up to and including
+        # the transaction.
+        cumulativeGasUsed: Long!
+        # CreatedContract is the account that was created by a contract creation
+        # transaction. If the transaction was not a contract creation transaction,
+        # this field will be null.
+        createdContract(block: Long): Account
+        # Logs is a list of log entries emitted by the transaction.
+        logs: [Log!]!
+        # LogsBloom is a bloom filter of the log entries emitted by the transaction.
+        logsBloom: Bytes!
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -192,13 +284,21 @@ const schema string = `
         ommerHash: Bytes32!
         # Transactions is a list of transactions associated with this block. If
         # transactions are unavailable for this block, this field will be null.
-        transactions: [Transaction!]
+        # The list may be paged by requesting the first transactions after the
+        # cursor of the last transaction of the previous page.
+        transactions(first: Int, after: String): [Transaction!]
         # TransactionAt returns the transaction at the specified index. If
         # transactions are unavailable for this block, or if the index is out of
         # bounds, this field will be null.
         transactionAt(index: Int!): Transaction
-        # Logs returns a filtered set of logs from this block.
-        logs(filter: BlockFilterCriteria!): [Log!]!
+        # Receipts is the list of receipts of the transactions in this block,
+        # fetched in a single batch. If the receipts are unavailable for this
+        # block, this field will be null.
+        receipts: [Receipt!]
+        # Logs returns a filtered set of logs from this block. The list may be
+        # paged by requesting the first logs after the cursor of the last log of
+        # the previous page.
+        logs(filter: BlockFilterCriteria!, first: Int, after: String): [Log!]!
         # Account fetches an Ethereum account at the current block's state.
         account(address: Address!): Account!
         # Call executes a local call operation at the current block's state.
@@ -302,8 +402,10 @@ const schema string = `
         pending: Pending!
         # Transaction returns a transaction specified by its hash.
         transaction(hash: Bytes32!): Transaction
-        # Logs returns log entries matching the provided filter.
-        logs(filter: FilterCriteria!): [Log!]!
+        # Logs returns log entries matching the provided filter. The list may be
+        # paged by requesting the first logs after the cursor of the last log of
+        # the previous page.
+        logs(filter: FilterCriteria!, first: Int, after: String): [Log!]!
         # GasPrice returns the node's estimate of a gas price sufficient to
         # ensure a transaction is mined in a timely fashion.
         gasPrice: BigInt!
@@ -317,4 +419,15 @@ const schema string = `
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # Subscriptions are served over websockets on the GraphQL endpoint, using
+    # the graphql-ws protocol.
+    type Subscription {
+        # NewBlocks fires for every block added to the canonical chain.
+        newBlocks: Block!
+        # NewLogs fires for every log matching the filter in a block added to
+        # the canonical chain. Logs of blocks reorganised out of the canonical
+        # chain are not retracted.
+        newLogs(filter: BlockFilterCriteria!): Log!
+    }
 `
@@ -36,18 +36,20 @@ type Service struct {
 	vhosts   []string         // Recognised vhosts
 	timeouts rpc.HTTPTimeouts // Timeout settings for HTTP requests.
 	backend  ethapi.Backend   // The backend that queries will operate onn.
+	tracing  bool             // Whether transactions may be traced by clients
 	handler  http.Handler     // The `http.Handler` used to answer queries.
 	listener net.Listener     // The listening socket.
 }
 
 // New constructs a new GraphQL service instance.
-func New(backend ethapi.Backend, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts) (*Service, error) {
+func New(backend ethapi.Backend, endpoint string, cors, vhosts []string, timeouts rpc.HTTPTimeouts, tracing bool) (*Service, error) {
 	return &Service{
 		endpoint: endpoint,
 		cors:     cors,
 		vhosts:   vhosts,
 		timeouts: timeouts,
 		backend:  backend,
+		tracing:  tracing,
 	}, nil
 }
 
@@ -61,7 +63,7 @@ func (s *Service) APIs() []rpc.API { return nil }
 // layer was also initialized to spawn any goroutines required by the service.
 func (s *Service) Start(server *p2p.Server) error {
 	var err error
-	s.handler, err = newHandler(s.backend)
+	s.handler, err = newHandler(s.backend, s.cors, s.tracing)
 	if err != nil {
 		return err
 	}
@@ -73,16 +75,21 @@ func (s *Service) Start(server *p2p.Server) error {
 	return nil
 }
 
-// newHandler returns a new `http.Handler` that will answer GraphQL queries.
-// It additionally exports an interactive query browser on the / endpoint.
-func newHandler(backend ethapi.Backend) (http.Handler, error) {
+// newHandler returns a new `http.Handler` that will answer GraphQL queries, and
+// subscriptions over websockets from the allowed origins. It additionally
+// exports an interactive query browser on the / endpoint. Transaction traces are
+// only resolved if tracing is enabled.
+func newHandler(backend ethapi.Backend, allowedOrigins []string, tracing bool) (http.Handler, error) {
+	if !tracing {
+		backend = untracedBackend{backend}
+	}
 	q := Resolver{backend}
 
 	s, err := graphql.ParseSchema(schema, &q)
 	if err != nil {
 		return nil, err
 	}
-	h := &relay.Handler{Schema: s}
+	h := newWebsocketHandler(s, allowedOrigins, &relay.Handler{Schema: s})
 
 	mux := http.NewServeMux()
 	mux.Handle("/", GraphiQL{})
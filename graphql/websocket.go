@@ -0,0 +1,236 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	wsReadLimit    = 1024 * 1024      // Maximum size of a message sent by a client
+	wsWriteTimeout = 10 * time.Second // Time allowed to write a message to a client
+)
+
+// Message types of the graphql-ws protocol, used by the Apollo and GraphiQL
+// clients to run subscriptions over websockets.
+const (
+	wsConnectionInit      = "connection_init"
+	wsConnectionAck       = "connection_ack"
+	wsConnectionTerminate = "connection_terminate"
+	wsStart               = "start"
+	wsStop                = "stop"
+	wsData                = "data"
+	wsError               = "error"
+	wsComplete            = "complete"
+)
+
+// wsMessage is a message of the graphql-ws protocol.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsStartPayload is the payload of a message starting an operation.
+type wsStartPayload struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// wsHandler serves GraphQL operations, subscriptions included, to the clients
+// connecting over websockets. Plain HTTP requests are passed on to the next
+// handler.
+type wsHandler struct {
+	schema   *graphql.Schema
+	upgrader websocket.Upgrader
+	next     http.Handler
+}
+
+// newWebsocketHandler creates a handler serving the given schema over websockets
+// to the clients connecting from the allowed origins.
+func newWebsocketHandler(schema *graphql.Schema, allowedOrigins []string, next http.Handler) http.Handler {
+	return &wsHandler{
+		schema: schema,
+		upgrader: websocket.Upgrader{
+			CheckOrigin:  wsOriginValidator(allowedOrigins),
+			Subprotocols: []string{"graphql-ws"},
+		},
+		next: next,
+	}
+}
+
+// wsOriginValidator returns a handshake validator accepting the connections
+// without an origin, from the same host or from one of the allowed origins.
+func wsOriginValidator(allowedOrigins []string) func(*http.Request) bool {
+	origins := make(map[string]struct{})
+	for _, origin := range allowedOrigins {
+		origins[strings.ToLower(origin)] = struct{}{}
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		if _, ok := origins["*"]; ok {
+			return true
+		}
+		if _, ok := origins[strings.ToLower(origin)]; ok {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+func (h *wsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL websocket upgrade failed", "err", err)
+		return
+	}
+	c := &wsConn{
+		conn:   conn,
+		schema: h.schema,
+		ops:    make(map[string]context.CancelFunc),
+	}
+	c.serve()
+}
+
+// wsConn is a websocket connection of a GraphQL client, running any number of
+// concurrent operations.
+type wsConn struct {
+	conn   *websocket.Conn
+	schema *graphql.Schema
+
+	ops    map[string]context.CancelFunc // Cancellers of the running operations
+	opsMu  sync.Mutex
+	opsWg  sync.WaitGroup
+	sendMu sync.Mutex
+}
+
+// serve reads and handles the messages of the client until the connection is
+// closed or terminated, cancelling all the running operations afterwards.
+func (c *wsConn) serve() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		c.opsWg.Wait()
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(wsReadLimit)
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case wsConnectionInit:
+			c.send(wsMessage{Type: wsConnectionAck})
+		case wsStart:
+			c.start(ctx, msg)
+		case wsStop:
+			c.stop(msg.ID)
+		case wsConnectionTerminate:
+			return
+		default:
+			c.sendError(msg.ID, "unknown message type "+msg.Type)
+		}
+	}
+}
+
+// start runs an operation, sending its results to the client as they arrive.
+func (c *wsConn) start(ctx context.Context, msg wsMessage) {
+	var payload wsStartPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		c.sendError(msg.ID, "invalid payload: "+err.Error())
+		return
+	}
+	c.opsMu.Lock()
+	if _, ok := c.ops[msg.ID]; ok {
+		c.opsMu.Unlock()
+		c.sendError(msg.ID, "duplicate operation id")
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	c.ops[msg.ID] = cancel
+	c.opsMu.Unlock()
+
+	responses, err := c.schema.Subscribe(ctx, payload.Query, payload.OperationName, payload.Variables)
+	if err != nil {
+		c.stop(msg.ID)
+		c.sendError(msg.ID, err.Error())
+		return
+	}
+	c.opsWg.Add(1)
+	go func() {
+		defer c.opsWg.Done()
+
+		for response := range responses {
+			blob, err := json.Marshal(response)
+			if err != nil {
+				log.Debug("Failed to encode GraphQL response", "err", err)
+				continue
+			}
+			c.send(wsMessage{ID: msg.ID, Type: wsData, Payload: blob})
+		}
+		c.stop(msg.ID)
+		c.send(wsMessage{ID: msg.ID, Type: wsComplete})
+	}()
+}
+
+// stop cancels a running operation.
+func (c *wsConn) stop(id string) {
+	c.opsMu.Lock()
+	defer c.opsMu.Unlock()
+
+	if cancel, ok := c.ops[id]; ok {
+		cancel()
+		delete(c.ops, id)
+	}
+}
+
+// send writes a message to the client.
+func (c *wsConn) send(msg wsMessage) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		log.Debug("Failed to send GraphQL websocket message", "err", err)
+	}
+}
+
+// sendError reports an error of an operation to the client.
+func (c *wsConn) sendError(id string, message string) {
+	payload, _ := json.Marshal(map[string]string{"message": message})
+	c.send(wsMessage{ID: id, Type: wsError, Payload: payload})
+}
@@ -22,11 +22,15 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/ethereum/go-ethereum/accounts/scwallet"
 	"github.com/ethereum/go-ethereum/common"
@@ -36,6 +40,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -68,6 +73,83 @@ func (s *PublicEthereumAPI) GasPrice(ctx context.Context) (*hexutil.Big, error)
 	return (*hexutil.Big)(price), err
 }
 
+// FeeHistoryResult is the gas usage and the gas prices paid within a range of
+// recent blocks, along with the state of the transaction pool.
+type FeeHistoryResult struct {
+	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
+	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
+	GasUsedRatio []float64        `json:"gasUsedRatio"`
+	Pending      *PendingFees     `json:"pending"`
+}
+
+// PendingFees is the number of transactions in the pool along with the
+// requested percentiles of the gas prices offered by the executable ones.
+type PendingFees struct {
+	Pending hexutil.Uint   `json:"pending"`
+	Queued  hexutil.Uint   `json:"queued"`
+	Reward  []*hexutil.Big `json:"reward,omitempty"`
+}
+
+// FeeHistory returns the gas used ratio and the requested percentiles of the gas
+// prices paid within a range of blocks ending with the given one, weighted by
+// the gas used by the transactions. The percentiles of the gas prices offered by
+// the executable transactions of the pool are reported along.
+func (s *PublicEthereumAPI) FeeHistory(ctx context.Context, blockCount hexutil.Uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*FeeHistoryResult, error) {
+	oldest, reward, gasUsedRatio, err := s.b.FeeHistory(ctx, int(blockCount), lastBlock, rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	pending, queued := s.b.Stats()
+	results := &FeeHistoryResult{
+		OldestBlock:  (*hexutil.Big)(oldest),
+		GasUsedRatio: gasUsedRatio,
+		Pending: &PendingFees{
+			Pending: hexutil.Uint(pending),
+			Queued:  hexutil.Uint(queued),
+		},
+	}
+	if reward != nil {
+		results.Reward = make([][]*hexutil.Big, len(reward))
+		for i, w := range reward {
+			results.Reward[i] = make([]*hexutil.Big, len(w))
+			for j, v := range w {
+				results.Reward[i][j] = (*hexutil.Big)(v)
+			}
+		}
+	}
+	if len(rewardPercentiles) > 0 {
+		var txs types.Transactions
+		executable, _ := s.b.TxPoolContent()
+		for _, list := range executable {
+			txs = append(txs, list...)
+		}
+		for _, price := range pricePercentiles(txs, rewardPercentiles) {
+			results.Pending.Reward = append(results.Pending.Reward, (*hexutil.Big)(price))
+		}
+	}
+	return results, nil
+}
+
+// pricePercentiles returns the requested percentiles of the gas prices offered
+// by the given transactions, unweighted as their gas usage is not yet known.
+func pricePercentiles(txs types.Transactions, percentiles []float64) []*big.Int {
+	prices := make([]*big.Int, len(txs))
+	for i, tx := range txs {
+		prices[i] = tx.GasPrice()
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Cmp(prices[j]) < 0 })
+
+	result := make([]*big.Int, len(percentiles))
+	for i, p := range percentiles {
+		if len(prices) == 0 {
+			result[i] = new(big.Int)
+			continue
+		}
+		result[i] = new(big.Int).Set(prices[int(float64(len(prices)-1)*p/100)])
+	}
+	return result
+}
+
 // ProtocolVersion returns the current Ethereum protocol version this node supports
 func (s *PublicEthereumAPI) ProtocolVersion() hexutil.Uint {
 	return hexutil.Uint(s.b.ProtocolVersion())
@@ -527,6 +609,18 @@ func (s *PublicBlockChainAPI) BlockNumber() hexutil.Uint64 {
 	return hexutil.Uint64(header.Number.Uint64())
 }
 
+// EarliestBlocks returns the number of the earliest block whose data is still
+// available locally for each kind of chain data (headers, hashes, bodies,
+// receipts and difficulties). Older data may have been pruned from the ancient
+// store by its retention policy.
+func (s *PublicBlockChainAPI) EarliestBlocks() map[string]hexutil.Uint64 {
+	earliest := make(map[string]hexutil.Uint64)
+	for kind, tail := range rawdb.ReadAncientTails(s.b.ChainDb()) {
+		earliest[kind] = hexutil.Uint64(tail)
+	}
+	return earliest
+}
+
 // GetBalance returns the amount of wei for the given address in the state of the
 // given block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
 // block numbers are also allowed.
@@ -743,13 +837,61 @@ type CallArgs struct {
 	Data     *hexutil.Bytes  `json:"data"`
 }
 
-// account indicates the overriding fields of account during the execution of
-// a message call.
+// SetDefaultFrom sets the sender of the call to the first account of the first
+// wallet if none is specified.
+func (args *CallArgs) SetDefaultFrom(am *accounts.Manager) {
+	if args.From != nil {
+		return
+	}
+	if wallets := am.Wallets(); len(wallets) > 0 {
+		if accounts := wallets[0].Accounts(); len(accounts) > 0 {
+			args.From = &accounts[0].Address
+		}
+	}
+}
+
+// ToMessage converts the call arguments to a message executable by the EVM,
+// filling in the defaults for missing fields. The gas allowance is capped by
+// the global gas cap if one is set.
+func (args *CallArgs) ToMessage(globalGasCap *big.Int) types.Message {
+	// Set sender address or use zero address if none specified
+	var addr common.Address
+	if args.From != nil {
+		addr = *args.From
+	}
+	// Set default gas & gas price if none were set
+	gas := uint64(math.MaxUint64 / 2)
+	if args.Gas != nil {
+		gas = uint64(*args.Gas)
+	}
+	if globalGasCap != nil && globalGasCap.Uint64() < gas {
+		log.Warn("Caller gas above allowance, capping", "requested", gas, "cap", globalGasCap)
+		gas = globalGasCap.Uint64()
+	}
+	gasPrice := new(big.Int).SetUint64(defaultGasPrice)
+	if args.GasPrice != nil {
+		gasPrice = args.GasPrice.ToInt()
+	}
+
+	value := new(big.Int)
+	if args.Value != nil {
+		value = args.Value.ToInt()
+	}
+
+	var data []byte
+	if args.Data != nil {
+		data = []byte(*args.Data)
+	}
+	return types.NewMessage(addr, args.To, 0, value, gas, gasPrice, data, false)
+}
+
+// OverrideAccount indicates the overriding fields of account during the execution
+// of a message call.
 // Note, state and stateDiff can't be specified at the same time. If state is
 // set, message execution will only use the data in the given state. Otherwise
 // if statDiff is set, all diff will be applied first and then execute the call
 // message.
-type account struct {
+type OverrideAccount struct {
 	Nonce     *hexutil.Uint64              `json:"nonce"`
 	Code      *hexutil.Bytes               `json:"code"`
 	Balance   **hexutil.Big                `json:"balance"`
@@ -757,26 +899,15 @@ type account struct {
 	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
 }
 
-func DoCall(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumber, overrides map[common.Address]account, vmCfg vm.Config, timeout time.Duration, globalGasCap *big.Int) ([]byte, uint64, bool, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+// StateOverride is the collection of overridden accounts.
+type StateOverride map[common.Address]OverrideAccount
 
-	state, header, err := b.StateAndHeaderByNumber(ctx, blockNr)
-	if state == nil || err != nil {
-		return nil, 0, false, err
-	}
-	// Set sender address or use a default if none specified
-	var addr common.Address
-	if args.From == nil {
-		if wallets := b.AccountManager().Wallets(); len(wallets) > 0 {
-			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
-				addr = accounts[0].Address
-			}
-		}
-	} else {
-		addr = *args.From
+// Apply overrides the fields of specified accounts into the given state.
+func (diff *StateOverride) Apply(state *state.StateDB) error {
+	if diff == nil {
+		return nil
 	}
-	// Override the fields of specified contracts before execution.
-	for addr, account := range overrides {
+	for addr, account := range *diff {
 		// Override account nonce.
 		if account.Nonce != nil {
 			state.SetNonce(addr, uint64(*account.Nonce))
@@ -790,7 +921,7 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumb
 			state.SetBalance(addr, (*big.Int)(*account.Balance))
 		}
 		if account.State != nil && account.StateDiff != nil {
-			return nil, 0, false, fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
 		}
 		// Replace entire state if caller requires.
 		if account.State != nil {
@@ -803,32 +934,40 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumb
 			}
 		}
 	}
-	// Set default gas & gas price if none were set
-	gas := uint64(math.MaxUint64 / 2)
-	if args.Gas != nil {
-		gas = uint64(*args.Gas)
-	}
-	if globalGasCap != nil && globalGasCap.Uint64() < gas {
-		log.Warn("Caller gas above allowance, capping", "requested", gas, "cap", globalGasCap)
-		gas = globalGasCap.Uint64()
-	}
-	gasPrice := new(big.Int).SetUint64(defaultGasPrice)
-	if args.GasPrice != nil {
-		gasPrice = args.GasPrice.ToInt()
-	}
+	return nil
+}
 
-	value := new(big.Int)
-	if args.Value != nil {
-		value = args.Value.ToInt()
-	}
+// CallOutput is the outcome of executing a call with DoCall.
+type CallOutput struct {
+	ReturnData []byte // Data returned by the call, or the revert reason if reverted
+	UsedGas    uint64 // Gas used by the call, including refunds
+	Err        error  // Error the EVM execution failed with, e.g. vm.ErrExecutionReverted
+}
 
-	var data []byte
-	if args.Data != nil {
-		data = []byte(*args.Data)
+// Failed reports whether the EVM execution of the call failed.
+func (out *CallOutput) Failed() bool {
+	return out.Err != nil
+}
+
+// DoCall executes a call on top of the state of the given block. An error is
+// only returned if the call could not be executed at all, failures of the EVM
+// execution itself are reported in the output.
+func DoCall(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, vmCfg vm.Config, timeout time.Duration, globalGasCap *big.Int) (*CallOutput, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+
+	state, header, err := b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
 	}
+	// Set sender address or use a default if none specified
+	args.SetDefaultFrom(b.AccountManager())
 
+	// Override the fields of specified contracts before execution.
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
 	// Create new call message
-	msg := types.NewMessage(addr, args.To, 0, value, gas, gasPrice, data, false)
+	msg := args.ToMessage(globalGasCap)
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -845,7 +984,7 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumb
 	// Get a new instance of the EVM.
 	evm, vmError, err := b.GetEVM(ctx, msg, state, header)
 	if err != nil {
-		return nil, 0, false, err
+		return nil, err
 	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -857,15 +996,19 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumb
 	// Setup the gas pool (also for unmetered requests)
 	// and apply the message.
 	gp := new(core.GasPool).AddGas(math.MaxUint64)
-	res, gas, failed, err := core.ApplyMessage(evm, msg, gp)
+	st := core.NewStateTransition(evm, msg, gp)
+	res, gas, _, err := st.TransitionDb()
 	if err := vmError(); err != nil {
-		return nil, 0, false, err
+		return nil, err
 	}
 	// If the timer caused an abort, return an appropriate error message
 	if evm.Cancelled() {
-		return nil, 0, false, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
 	}
-	return res, gas, failed, err
+	if err != nil {
+		return nil, err
+	}
+	return &CallOutput{ReturnData: res, UsedGas: gas, Err: st.VMError()}, nil
 }
 
 // Call executes the given transaction on the state for the given block number.
@@ -874,13 +1017,230 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumb
 //
 // Note, this function doesn't make and changes in the state/blockchain and is
 // useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *map[common.Address]account) (hexutil.Bytes, error) {
-	var accounts map[common.Address]account
-	if overrides != nil {
-		accounts = *overrides
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride) (hexutil.Bytes, error) {
+	out, err := DoCall(ctx, s.b, args, blockNr, overrides, vm.Config{}, 5*time.Second, s.b.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
+	if out.Err == vm.ErrExecutionReverted {
+		return nil, newRevertError(out.ReturnData)
+	}
+	return out.ReturnData, nil
+}
+
+// revertError is an API error carrying the data returned by a reverted call,
+// with its reason decoded into the message if it's ABI encoded.
+type revertError struct {
+	error
+	reason string // Data returned by the reverted call, hex encoded
+}
+
+// newRevertError creates a revertError from the data returned by a reverted
+// call.
+func newRevertError(data []byte) *revertError {
+	err := errors.New("execution reverted")
+	if reason, errUnpack := abi.UnpackRevert(data); errUnpack == nil {
+		err = fmt.Errorf("execution reverted: %v", reason)
 	}
-	result, _, _, err := DoCall(ctx, s.b, args, blockNr, accounts, vm.Config{}, 5*time.Second, s.b.RPCGasCap())
-	return (hexutil.Bytes)(result), err
+	return &revertError{error: err, reason: hexutil.Encode(data)}
+}
+
+// ErrorCode returns the JSON error code for a revertal.
+// See: https://github.com/ethereum/wiki/wiki/JSON-RPC-Error-Codes-Improvement-Proposal
+func (e *revertError) ErrorCode() int {
+	return 3
+}
+
+// ErrorData returns the hex encoded data returned by the reverted call.
+func (e *revertError) ErrorData() interface{} {
+	return e.reason
+}
+
+// BlockOverrides is a set of header fields to override when executing calls on
+// top of a block, allowing calls to be simulated in the context of a future or
+// hypothetical block.
+type BlockOverrides struct {
+	Number     *hexutil.Big    `json:"number"`
+	Difficulty *hexutil.Big    `json:"difficulty"`
+	Time       *hexutil.Uint64 `json:"time"`
+	GasLimit   *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase   *common.Address `json:"coinbase"`
+}
+
+// Apply returns a copy of the header with the specified fields overridden.
+func (diff *BlockOverrides) Apply(header *types.Header) *types.Header {
+	header = types.CopyHeader(header)
+	if diff == nil {
+		return header
+	}
+	if diff.Number != nil {
+		header.Number = new(big.Int).Set(diff.Number.ToInt())
+	}
+	if diff.Difficulty != nil {
+		header.Difficulty = new(big.Int).Set(diff.Difficulty.ToInt())
+	}
+	if diff.Time != nil {
+		header.Time = uint64(*diff.Time)
+	}
+	if diff.GasLimit != nil {
+		header.GasLimit = uint64(*diff.GasLimit)
+	}
+	if diff.Coinbase != nil {
+		header.Coinbase = *diff.Coinbase
+	}
+	return header
+}
+
+// CallResult is the outcome of a single call executed by eth_callMany.
+type CallResult struct {
+	ReturnData   hexutil.Bytes  `json:"returnData"`
+	Logs         []*types.Log   `json:"logs"`
+	GasUsed      hexutil.Uint64 `json:"gasUsed"`
+	Status       hexutil.Uint64 `json:"status"`
+	Error        string         `json:"error,omitempty"`
+	RevertReason string         `json:"revertReason,omitempty"`
+}
+
+// DoCallMany executes an ordered list of calls on top of the state of the given
+// block, each call seeing the state changes made by the previous ones.
+func DoCallMany(ctx context.Context, b Backend, calls []CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, blockOverrides *BlockOverrides, timeout time.Duration, globalGasCap *big.Int) ([]*CallResult, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM calls finished", "calls", len(calls), "runtime", time.Since(start)) }(time.Now())
+
+	state, header, err := b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Override the fields of specified contracts and of the block before execution.
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	base := header
+	header = blockOverrides.Apply(header)
+
+	// Setup context so it may be cancelled when all the calls have completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	// Abort the call being executed once the context is done
+	var (
+		evmLock sync.Mutex
+		evm     *vm.EVM
+	)
+	go func() {
+		<-ctx.Done()
+
+		evmLock.Lock()
+		if evm != nil {
+			evm.Cancel()
+		}
+		evmLock.Unlock()
+	}()
+	var (
+		results = make([]*CallResult, 0, len(calls))
+		logs    = 0 // Number of logs emitted by the previous calls
+		eip161  = b.ChainConfig().IsEIP161F(header.Number)
+	)
+	for i, args := range calls {
+		// Set sender address or use a default if none specified
+		args.SetDefaultFrom(b.AccountManager())
+		msg := args.ToMessage(globalGasCap)
+
+		// The EVM funds the sender without limits, remember the real balance to
+		// only apply the balance change of the call to it afterwards.
+		balance := new(big.Int).Set(state.GetBalance(msg.From()))
+
+		callEVM, vmError, err := b.GetEVM(ctx, msg, state, header)
+		if err != nil {
+			return nil, err
+		}
+		if header.Number.Cmp(base.Number) != 0 {
+			callEVM.GetHash = overriddenGetHash(ctx, b, base)
+		}
+		evmLock.Lock()
+		if evm = callEVM; ctx.Err() != nil {
+			evm.Cancel()
+		}
+		evmLock.Unlock()
+
+		state.Prepare(common.Hash{}, header.Hash(), i)
+
+		gp := new(core.GasPool).AddGas(math.MaxUint64)
+		st := core.NewStateTransition(callEVM, msg, gp)
+		res, gas, failed, err := st.TransitionDb()
+		if err := vmError(); err != nil {
+			return nil, err
+		}
+		if callEVM.Cancelled() {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %v", i, err)
+		}
+		balance.Add(balance, new(big.Int).Sub(state.GetBalance(msg.From()), math.MaxBig256))
+		if balance.Sign() < 0 {
+			balance.SetUint64(0)
+		}
+		state.SetBalance(msg.From(), balance)
+		state.Finalise(eip161)
+
+		emitted := state.GetLogs(common.Hash{})
+		result := &CallResult{
+			ReturnData: res,
+			Logs:       emitted[logs:],
+			GasUsed:    hexutil.Uint64(gas),
+			Status:     hexutil.Uint64(types.ReceiptStatusSuccessful),
+		}
+		if result.Logs == nil {
+			result.Logs = []*types.Log{}
+		}
+		if failed {
+			result.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			result.Error = "execution failed"
+			if st.VMError() == vm.ErrExecutionReverted {
+				result.Error = "execution reverted"
+				if reason, err := abi.UnpackRevert(res); err == nil {
+					result.RevertReason = reason
+				}
+			}
+		}
+		results, logs = append(results, result), len(emitted)
+	}
+	return results, nil
+}
+
+// overriddenGetHash returns the block hash lookup of calls executed with an
+// overridden block number: the hashes of the canonical blocks up to and including
+// the one the calls execute on top of, and empty hashes for blocks beyond it.
+func overriddenGetHash(ctx context.Context, b Backend, base *types.Header) vm.GetHashFunc {
+	return func(n uint64) common.Hash {
+		switch {
+		case n == base.Number.Uint64():
+			return base.Hash()
+		case n > base.Number.Uint64():
+			return common.Hash{}
+		}
+		header, err := b.HeaderByNumber(ctx, rpc.BlockNumber(n))
+		if header == nil || err != nil {
+			return common.Hash{}
+		}
+		return header.Hash()
+	}
+}
+
+// CallMany executes an ordered list of calls on top of the state of the given
+// block, carrying the state changes of each call over to the next one. The
+// state and the header of the block can be overridden before execution.
+//
+// Note, this function doesn't make any changes in the state/blockchain and is
+// useful to simulate bundles of transactions.
+func (s *PublicBlockChainAPI) CallMany(ctx context.Context, calls []CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, blockOverrides *BlockOverrides) ([]*CallResult, error) {
+	return DoCallMany(ctx, s.b, calls, blockNr, overrides, blockOverrides, 5*time.Second, s.b.RPCGasCap())
 }
 
 func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNr rpc.BlockNumber, gasCap *big.Int) (hexutil.Uint64, error) {
@@ -907,19 +1267,22 @@ func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNr rpc.Bl
 	cap = hi
 
 	// Create a helper to check if a gas allowance results in an executable transaction
-	executable := func(gas uint64) bool {
+	executable := func(gas uint64) (bool, []byte, error) {
 		args.Gas = (*hexutil.Uint64)(&gas)
 
-		_, _, failed, err := DoCall(ctx, b, args, rpc.PendingBlockNumber, nil, vm.Config{}, 0, gasCap)
-		if err != nil || failed {
-			return false
+		out, err := DoCall(ctx, b, args, rpc.PendingBlockNumber, nil, vm.Config{}, 0, gasCap)
+		if err != nil {
+			return false, nil, nil
+		}
+		if out.Failed() {
+			return false, out.ReturnData, out.Err
 		}
-		return true
+		return true, nil, nil
 	}
 	// Execute the binary search and hone in on an executable gas limit
 	for lo+1 < hi {
 		mid := (hi + lo) / 2
-		if !executable(mid) {
+		if ok, _, _ := executable(mid); !ok {
 			lo = mid
 		} else {
 			hi = mid
@@ -927,7 +1290,10 @@ func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNr rpc.Bl
 	}
 	// Reject the transaction as invalid if it still fails at the highest allowance
 	if hi == cap {
-		if !executable(hi) {
+		if ok, res, vmerr := executable(hi); !ok {
+			if vmerr == vm.ErrExecutionReverted {
+				return 0, newRevertError(res)
+			}
 			return 0, fmt.Errorf("gas required exceeds allowance (%d) or always failing transaction", cap)
 		}
 	}
@@ -944,10 +1310,11 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 // while replaying a transaction in debug mode as well as transaction
 // execution status, the amount of gas used and the return value
 type ExecutionResult struct {
-	Gas         uint64         `json:"gas"`
-	Failed      bool           `json:"failed"`
-	ReturnValue string         `json:"returnValue"`
-	StructLogs  []StructLogRes `json:"structLogs"`
+	Gas          uint64         `json:"gas"`
+	Failed       bool           `json:"failed"`
+	ReturnValue  string         `json:"returnValue"`
+	RevertReason string         `json:"revertReason,omitempty"`
+	StructLogs   []StructLogRes `json:"structLogs"`
 }
 
 // StructLogRes stores a structured log emitted by the EVM while replaying a
@@ -1291,8 +1658,38 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if len(receipts) <= int(index) {
 		return nil, nil
 	}
-	receipt := receipts[index]
+	return marshalReceipt(receipts[index], blockHash, blockNumber, tx, index), nil
+}
+
+// GetBlockReceipts returns the receipts of all the transactions included in the
+// given block, or nil if the block is not found. The transactions of the pending
+// block have no receipts yet, same as for eth_getTransactionReceipt.
+func (s *PublicTransactionPoolAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	if number, ok := blockNrOrHash.Number(); ok && number == rpc.PendingBlockNumber {
+		return nil, nil
+	}
+	block, err := blockByNumberOrHash(ctx, s.b, blockNrOrHash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	receipts, err := s.b.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	txs := block.Transactions()
+	if len(receipts) != len(txs) {
+		return nil, fmt.Errorf("receipts of block #%d [%x] not available", block.NumberU64(), block.Hash())
+	}
+	fields := make([]map[string]interface{}, len(receipts))
+	for i, receipt := range receipts {
+		fields[i] = marshalReceipt(receipt, block.Hash(), block.NumberU64(), txs[i], uint64(i))
+	}
+	return fields, nil
+}
 
+// marshalReceipt converts a receipt of a transaction included in a block into
+// the RPC representation of eth_getTransactionReceipt.
+func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber uint64, tx *types.Transaction, index uint64) map[string]interface{} {
 	var signer types.Signer = types.FrontierSigner{}
 	if tx.Protected() {
 		signer = types.NewEIP155Signer(tx.ChainId())
@@ -1302,7 +1699,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	fields := map[string]interface{}{
 		"blockHash":         blockHash,
 		"blockNumber":       hexutil.Uint64(blockNumber),
-		"transactionHash":   hash,
+		"transactionHash":   tx.Hash(),
 		"transactionIndex":  hexutil.Uint64(index),
 		"from":              from,
 		"to":                tx.To(),
@@ -1326,7 +1723,33 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	if receipt.ContractAddress != (common.Address{}) {
 		fields["contractAddress"] = receipt.ContractAddress
 	}
-	return fields, nil
+	return fields
+}
+
+// blockByNumberOrHash retrieves a block either by number or by hash, checking
+// that it's canonical if requested.
+func blockByNumberOrHash(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if number, ok := blockNrOrHash.Number(); ok {
+		return b.BlockByNumber(ctx, number)
+	}
+	hash, ok := blockNrOrHash.Hash()
+	if !ok {
+		return nil, errors.New("invalid arguments; neither block nor hash specified")
+	}
+	block, err := b.BlockByHash(ctx, hash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	if blockNrOrHash.RequireCanonical {
+		header, err := b.HeaderByNumber(ctx, rpc.BlockNumber(block.NumberU64()))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil || header.Hash() != hash {
+			return nil, fmt.Errorf("hash %x is not currently canonical", hash)
+		}
+	}
+	return block, nil
 }
 
 // sign is a helper function that signs a transaction with the private key of the given address.
@@ -1697,7 +2120,77 @@ func (api *PublicDebugAPI) SeedHash(ctx context.Context, number uint64) (string,
 	if block == nil {
 		return "", fmt.Errorf("block #%d not found", number)
 	}
-	return fmt.Sprintf("0x%x", ethash.SeedHash(number)), nil
+	return fmt.Sprintf("0x%x", ethash.SeedHash(number, api.b.ChainConfig().ECIP1099Block)), nil
+}
+
+// ChainRulesResult is the set of protocol rules in effect at a block.
+type ChainRulesResult struct {
+	Number      hexutil.Uint64   `json:"number"`
+	ChainID     *hexutil.Big     `json:"chainId"`
+	Rules       map[string]bool  `json:"rules"`       // Rule flags as used by the EVM, e.g. isEIP155
+	Active      []string         `json:"active"`      // Protocol changes activated at or before the block
+	Precompiles []common.Address `json:"precompiles"` // Addresses of the available precompiled contracts
+	NextFork    *NextForkResult  `json:"nextFork"`    // Next scheduled fork, nil if none is known
+}
+
+// NextForkResult describes the next scheduled fork after a block.
+type NextForkResult struct {
+	Block     hexutil.Uint64 `json:"block"`
+	Remaining hexutil.Uint64 `json:"remaining"` // Blocks left until the fork, counted from the current head
+	Changes   []string       `json:"changes"`
+}
+
+// ChainRules returns the protocol rules and precompiled contracts in effect at
+// the given block, along with the next fork scheduled after it. Blocks beyond the
+// current head are accepted, since the rules only depend on the chain config.
+func (api *PublicDebugAPI) ChainRules(ctx context.Context, blockNr rpc.BlockNumber) (*ChainRulesResult, error) {
+	number := uint64(blockNr)
+	if blockNr < 0 {
+		header, err := api.b.HeaderByNumber(ctx, blockNr)
+		if header == nil || err != nil {
+			return nil, err
+		}
+		number = header.Number.Uint64()
+	}
+	config, num := api.b.ChainConfig(), new(big.Int).SetUint64(number)
+	rules := config.Rules(num)
+
+	result := &ChainRulesResult{
+		Number:      hexutil.Uint64(number),
+		ChainID:     (*hexutil.Big)(rules.ChainID),
+		Rules:       make(map[string]bool),
+		Active:      []string{},
+		Precompiles: []common.Address{},
+	}
+	flags := reflect.ValueOf(rules)
+	for i := 0; i < flags.NumField(); i++ {
+		if flag := flags.Field(i); flag.Kind() == reflect.Bool {
+			name := flags.Type().Field(i).Name
+			result.Rules[strings.ToLower(name[:1])+name[1:]] = flag.Bool()
+		}
+	}
+	for _, activation := range config.RuleActivations() {
+		if activation.Block.Cmp(num) <= 0 {
+			result.Active = append(result.Active, activation.Name)
+		}
+	}
+	for addr := range vm.PrecompiledContractsForConfig(config, num) {
+		result.Precompiles = append(result.Precompiles, addr)
+	}
+	sort.Slice(result.Precompiles, func(i, j int) bool {
+		return bytes.Compare(result.Precompiles[i][:], result.Precompiles[j][:]) < 0
+	})
+	if next := config.NextActivations(num); len(next) > 0 {
+		fork := &NextForkResult{Block: hexutil.Uint64(next[0].Block.Uint64())}
+		if head := api.b.CurrentBlock().NumberU64(); fork.Block > hexutil.Uint64(head) {
+			fork.Remaining = fork.Block - hexutil.Uint64(head)
+		}
+		for _, activation := range next {
+			fork.Changes = append(fork.Changes, activation.Name)
+		}
+		result.NextFork = fork
+	}
+	return result, nil
 }
 
 // PrivateDebugAPI is the collection of Ethereum APIs exposed over the private
@@ -0,0 +1,564 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testBackend is a Backend operating on a local chain, implementing only the
+// methods needed to execute calls.
+type testBackend struct {
+	Backend
+	db    ethdb.Database
+	chain *core.BlockChain
+}
+
+// newTestBackend creates a chain of empty blocks and a backend operating on it.
+func newTestBackend(t *testing.T, blocks int) *testBackend {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		gspec = &core.Genesis{Config: params.TestChainConfig}
+	)
+	genesis := gspec.MustCommit(db)
+	chain, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, blocks, nil)
+
+	blockchain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	if _, err := blockchain.InsertChain(chain); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return &testBackend{db: db, chain: blockchain}
+}
+
+func (b *testBackend) ChainDb() ethdb.Database           { return b.db }
+func (b *testBackend) ChainConfig() *params.ChainConfig  { return b.chain.Config() }
+func (b *testBackend) AccountManager() *accounts.Manager { return accounts.NewManager(nil) }
+func (b *testBackend) RPCGasCap() *big.Int               { return nil }
+func (b *testBackend) CurrentBlock() *types.Block        { return b.chain.CurrentBlock() }
+func (b *testBackend) GetTd(hash common.Hash) *big.Int   { return b.chain.GetTdByHash(hash) }
+func (b *testBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return b.chain.GetHeaderByHash(hash), nil
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return b.chain.CurrentHeader(), nil
+	}
+	return b.chain.GetHeaderByNumber(uint64(number)), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header, _ := b.HeaderByNumber(ctx, number)
+	if header == nil {
+		return nil, nil, nil
+	}
+	statedb, err := b.chain.StateAt(header.Root)
+	return statedb, header, err
+}
+
+func (b *testBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header) (*vm.EVM, func() error, error) {
+	state.SetBalance(msg.From(), math.MaxBig256)
+	vmctx := core.NewEVMContext(msg, header, b.chain, nil)
+	return vm.NewEVM(vmctx, state, b.chain.Config(), vm.Config{}), func() error { return nil }, nil
+}
+
+// callManyTestArgs creates the arguments of a free call with the given sender,
+// recipient and value.
+func callManyTestArgs(from, to common.Address, value int64) CallArgs {
+	gas := hexutil.Uint64(1000000)
+	return CallArgs{From: &from, To: &to, Gas: &gas, GasPrice: new(hexutil.Big), Value: (*hexutil.Big)(big.NewInt(value))}
+}
+
+// withCode creates a state override deploying code at an address.
+func withCode(overrides StateOverride, addr common.Address, code string) StateOverride {
+	blob := hexutil.Bytes(common.FromHex(code))
+	account := overrides[addr]
+	account.Code = &blob
+	overrides[addr] = account
+	return overrides
+}
+
+// Tests that the state changes of each call are visible to the subsequent ones,
+// and that logs are reported per call.
+func TestCallManyStateAndLogs(t *testing.T) {
+	b := newTestBackend(t, 1)
+	defer b.chain.Stop()
+
+	var (
+		sender  = common.Address{0x01}
+		counter = common.Address{0xc0}
+		logger  = common.Address{0x10}
+	)
+	overrides := StateOverride{}
+	// Increment and return slot 0: PUSH1 0 SLOAD PUSH1 1 ADD DUP1 PUSH1 0 SSTORE PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	withCode(overrides, counter, "0x6000546001018060005560005260206000f3")
+	// Emit an empty log: PUSH1 0 PUSH1 0 LOG0
+	withCode(overrides, logger, "0x60006000a0")
+
+	calls := []CallArgs{
+		callManyTestArgs(sender, counter, 0),
+		callManyTestArgs(sender, logger, 0),
+		callManyTestArgs(sender, counter, 0),
+		callManyTestArgs(sender, logger, 0),
+	}
+	results, err := DoCallMany(context.Background(), b, calls, rpc.LatestBlockNumber, &overrides, nil, time.Second, nil)
+	if err != nil {
+		t.Fatalf("failed to execute calls: %v", err)
+	}
+	if len(results) != len(calls) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(calls))
+	}
+	for i, want := range []uint64{1, 2} {
+		if have := new(big.Int).SetBytes(results[2*i].ReturnData).Uint64(); have != want {
+			t.Errorf("call %d: counter mismatch: have %d, want %d", 2*i, have, want)
+		}
+		if len(results[2*i].Logs) != 0 {
+			t.Errorf("call %d: log count mismatch: have %d, want 0", 2*i, len(results[2*i].Logs))
+		}
+		if logs := results[2*i+1].Logs; len(logs) != 1 || logs[0].Address != logger {
+			t.Errorf("call %d: logs mismatch: have %v, want one from %x", 2*i+1, logs, logger)
+		}
+	}
+	for i, result := range results {
+		if result.Status != hexutil.Uint64(types.ReceiptStatusSuccessful) || result.Error != "" {
+			t.Errorf("call %d: status mismatch: have %d (%s)", i, result.Status, result.Error)
+		}
+	}
+}
+
+// Tests that the sender's balance only reflects the value transferred by the
+// calls, including value sent back to the sender.
+func TestCallManyBalance(t *testing.T) {
+	b := newTestBackend(t, 1)
+	defer b.chain.Stop()
+
+	var (
+		sender    = common.Address{0x01}
+		recipient = common.Address{0x02}
+		bouncer   = common.Address{0xb0}
+		reader    = common.Address{0xba}
+	)
+	balance := (*hexutil.Big)(big.NewInt(1000))
+	overrides := StateOverride{sender: {Balance: &balance}}
+	// Send the call value back to the caller:
+	// PUSH1 0 PUSH1 0 PUSH1 0 PUSH1 0 CALLVALUE CALLER GAS CALL STOP
+	withCode(overrides, bouncer, "0x600060006000600034335af100")
+	// Return the balance of the sender: PUSH20 sender BALANCE PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+	withCode(overrides, reader, "0x73"+strings.TrimPrefix(sender.Hex(), "0x")+"3160005260206000f3")
+
+	calls := []CallArgs{
+		callManyTestArgs(sender, bouncer, 600),
+		callManyTestArgs(recipient, reader, 0),
+		callManyTestArgs(sender, recipient, 400),
+		callManyTestArgs(recipient, reader, 0),
+	}
+	results, err := DoCallMany(context.Background(), b, calls, rpc.LatestBlockNumber, &overrides, nil, time.Second, nil)
+	if err != nil {
+		t.Fatalf("failed to execute calls: %v", err)
+	}
+	for _, tt := range []struct {
+		call int
+		want uint64
+	}{{1, 1000}, {3, 600}} {
+		if have := new(big.Int).SetBytes(results[tt.call].ReturnData).Uint64(); have != tt.want {
+			t.Errorf("call %d: sender balance mismatch: have %d, want %d", tt.call, have, tt.want)
+		}
+	}
+}
+
+// Tests that the block fields can be overridden, including the number seen by
+// the BLOCKHASH opcode.
+func TestCallManyBlockOverrides(t *testing.T) {
+	b := newTestBackend(t, 3)
+	defer b.chain.Stop()
+
+	var (
+		sender  = common.Address{0x01}
+		number  = common.Address{0xa0}
+		stamp   = common.Address{0xa1}
+		hash2   = common.Address{0xa2}
+		hash3   = common.Address{0xa3}
+		hash5   = common.Address{0xa5}
+		missing = common.Address{0xa6}
+	)
+	overrides := StateOverride{}
+	// Return NUMBER, TIMESTAMP and BLOCKHASH(2), (3) and (5)
+	withCode(overrides, number, "0x4360005260206000f3")
+	withCode(overrides, stamp, "0x4260005260206000f3")
+	withCode(overrides, hash2, "0x60024060005260206000f3")
+	withCode(overrides, hash3, "0x60034060005260206000f3")
+	withCode(overrides, hash5, "0x60054060005260206000f3")
+
+	blockNumber, blockTime := hexutil.Big(*big.NewInt(10)), hexutil.Uint64(1234)
+	blockOverrides := &BlockOverrides{Number: &blockNumber, Time: &blockTime}
+
+	calls := []CallArgs{
+		callManyTestArgs(sender, number, 0),
+		callManyTestArgs(sender, stamp, 0),
+		callManyTestArgs(sender, hash2, 0),
+		callManyTestArgs(sender, hash3, 0),
+		callManyTestArgs(sender, hash5, 0),
+		callManyTestArgs(sender, missing, 0),
+	}
+	results, err := DoCallMany(context.Background(), b, calls, rpc.LatestBlockNumber, &overrides, blockOverrides, time.Second, nil)
+	if err != nil {
+		t.Fatalf("failed to execute calls: %v", err)
+	}
+	for i, want := range []common.Hash{
+		common.BigToHash(big.NewInt(10)),
+		common.BigToHash(big.NewInt(1234)),
+		b.chain.GetHeaderByNumber(2).Hash(),
+		b.chain.GetHeaderByNumber(3).Hash(),
+		{},
+	} {
+		if have := common.BytesToHash(results[i].ReturnData); have != want {
+			t.Errorf("call %d: result mismatch: have %x, want %x", i, have, want)
+		}
+	}
+	if len(results[5].ReturnData) != 0 {
+		t.Errorf("call to account without code returned data: %x", results[5].ReturnData)
+	}
+}
+
+// Tests that calls running over the timeout are aborted.
+func TestCallManyTimeout(t *testing.T) {
+	b := newTestBackend(t, 1)
+	defer b.chain.Stop()
+
+	var (
+		sender = common.Address{0x01}
+		looper = common.Address{0x1f}
+	)
+	// Loop forever: JUMPDEST PUSH1 0 JUMP
+	overrides := withCode(StateOverride{}, looper, "0x5b600056")
+
+	args := callManyTestArgs(sender, looper, 0)
+	gas := hexutil.Uint64(math.MaxUint64 / 2)
+	args.Gas = &gas
+
+	calls := []CallArgs{callManyTestArgs(sender, sender, 0), args}
+	if _, err := DoCallMany(context.Background(), b, calls, rpc.LatestBlockNumber, &overrides, nil, 50*time.Millisecond, nil); err == nil || !strings.Contains(err.Error(), "execution aborted") {
+		t.Errorf("timeout error mismatch: have %v, want execution aborted", err)
+	}
+}
+
+// Tests that eth_call reports reverted calls as errors, even without revert data,
+// but returns the empty result of calls failing otherwise.
+func TestCallRevert(t *testing.T) {
+	b := newTestBackend(t, 1)
+	defer b.chain.Stop()
+	api := NewPublicBlockChainAPI(b)
+
+	var (
+		sender  = common.Address{0x01}
+		bare    = common.Address{0xe0}
+		reason  = common.Address{0xe1}
+		invalid = common.Address{0xe2}
+	)
+	overrides := StateOverride{}
+	// Revert without data: PUSH1 0 PUSH1 0 REVERT
+	withCode(overrides, bare, "0x60006000fd")
+	// Revert with Error("abc"): store the ABI encoded reason and revert with it
+	withCode(overrides, reason, "0x"+
+		"7f08c379a000000000000000000000000000000000000000000000000000000000600052"+ // PUSH32 selector PUSH1 0 MSTORE
+		"602060045260036024527f6162630000000000000000000000000000000000000000000000000000000000604452"+ // offset, length, "abc"
+		"60646000fd") // PUSH1 100 PUSH1 0 REVERT
+	// Hit an invalid opcode
+	withCode(overrides, invalid, "0xfe")
+
+	tests := []struct {
+		to   common.Address
+		err  string
+		data string
+	}{
+		{bare, "execution reverted", "0x"},
+		{reason, "execution reverted: abc", ""},
+		{invalid, "", ""},
+	}
+	for i, tt := range tests {
+		_, err := api.Call(context.Background(), callManyTestArgs(sender, tt.to, 0), rpc.LatestBlockNumber, &overrides)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("test %d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		rerr, ok := err.(*revertError)
+		if !ok {
+			t.Errorf("test %d: error type mismatch: have %T (%v), want revert error", i, err, err)
+			continue
+		}
+		if rerr.Error() != tt.err {
+			t.Errorf("test %d: error mismatch: have %q, want %q", i, rerr.Error(), tt.err)
+		}
+		if tt.data != "" && rerr.ErrorData() != tt.data {
+			t.Errorf("test %d: error data mismatch: have %v, want %s", i, rerr.ErrorData(), tt.data)
+		}
+	}
+}
+
+// feeHistoryBackend is a Backend returning a fixed fee history and pool content.
+type feeHistoryBackend struct {
+	Backend
+	reward       [][]*big.Int
+	gasUsedRatio []float64
+	pool         map[common.Address]types.Transactions
+}
+
+func (b *feeHistoryBackend) FeeHistory(ctx context.Context, blocks int, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []float64, error) {
+	if blocks > len(b.gasUsedRatio) {
+		blocks = len(b.gasUsedRatio)
+	}
+	var reward [][]*big.Int
+	if len(rewardPercentiles) > 0 {
+		reward = b.reward[:blocks]
+	}
+	return big.NewInt(7), reward, b.gasUsedRatio[:blocks], nil
+}
+
+func (b *feeHistoryBackend) Stats() (int, int) { return len(b.pool), 1 }
+
+func (b *feeHistoryBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	return b.pool, nil
+}
+
+// Tests that fee histories are reported along with the percentiles of the gas
+// prices offered by the executable pool transactions.
+func TestFeeHistory(t *testing.T) {
+	var pool = make(map[common.Address]types.Transactions)
+	for i, price := range []int64{30, 10, 20} {
+		pool[common.Address{byte(i)}] = types.Transactions{types.NewTransaction(0, common.Address{}, nil, params.TxGas, big.NewInt(price), nil)}
+	}
+	api := NewPublicEthereumAPI(&feeHistoryBackend{
+		reward:       [][]*big.Int{{big.NewInt(1), big.NewInt(2)}, {big.NewInt(3), big.NewInt(4)}},
+		gasUsedRatio: []float64{0.5, 0.25},
+		pool:         pool,
+	})
+	tests := []struct {
+		blocks      hexutil.Uint64
+		percentiles []float64
+		want        string
+	}{
+		{0, nil, `{"oldestBlock":"0x7","gasUsedRatio":[],"pending":{"pending":"0x3","queued":"0x1"}}`},
+		{2, nil, `{"oldestBlock":"0x7","gasUsedRatio":[0.5,0.25],"pending":{"pending":"0x3","queued":"0x1"}}`},
+		{2, []float64{10, 90}, `{"oldestBlock":"0x7","reward":[["0x1","0x2"],["0x3","0x4"]],"gasUsedRatio":[0.5,0.25],"pending":{"pending":"0x3","queued":"0x1","reward":["0xa","0x14"]}}`},
+	}
+	for i, tt := range tests {
+		result, err := api.FeeHistory(context.Background(), tt.blocks, rpc.LatestBlockNumber, tt.percentiles)
+		if err != nil {
+			t.Errorf("test %d: failed to retrieve fee history: %v", i, err)
+			continue
+		}
+		blob, err := json.Marshal(result)
+		if err != nil {
+			t.Errorf("test %d: failed to encode fee history: %v", i, err)
+			continue
+		}
+		if string(blob) != tt.want {
+			t.Errorf("test %d: fee history mismatch:\nhave %s\nwant %s", i, blob, tt.want)
+		}
+	}
+}
+
+// prunedDatabase is a chain database whose ancient tables were pruned up to the
+// given tails.
+type prunedDatabase struct {
+	ethdb.Database
+	tails map[string]uint64
+}
+
+func (db *prunedDatabase) AncientTail(kind string) (uint64, error) {
+	return db.tails[kind], nil
+}
+
+// Tests that the earliest available block of each kind of chain data is served
+// over RPC.
+func TestEarliestBlocks(t *testing.T) {
+	backend := newTestBackend(t, 1)
+	backend.db = &prunedDatabase{Database: backend.db, tails: map[string]uint64{"bodies": 100, "receipts": 1000}}
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", NewPublicBlockChainAPI(backend)); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	var earliest map[string]hexutil.Uint64
+	if err := client.Call(&earliest, "eth_earliestBlocks"); err != nil {
+		t.Fatalf("failed to retrieve earliest blocks: %v", err)
+	}
+	want := map[string]hexutil.Uint64{"headers": 0, "hashes": 0, "bodies": 100, "receipts": 1000, "diffs": 0}
+	if !reflect.DeepEqual(earliest, want) {
+		t.Errorf("earliest blocks mismatch: have %v, want %v", earliest, want)
+	}
+}
+
+// chainRulesBackend is a Backend with a fixed chain config and head block.
+type chainRulesBackend struct {
+	Backend
+	config *params.ChainConfig
+	head   *types.Header
+}
+
+func (b *chainRulesBackend) ChainConfig() *params.ChainConfig { return b.config }
+func (b *chainRulesBackend) CurrentBlock() *types.Block       { return types.NewBlockWithHeader(b.head) }
+func (b *chainRulesBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	return b.head, nil
+}
+
+// Tests that the rules active at a block are reported along with the next fork,
+// skipping forks which don't activate any rules.
+func TestChainRules(t *testing.T) {
+	api := NewPublicDebugAPI(&chainRulesBackend{
+		config: &params.ChainConfig{
+			ChainID:        big.NewInt(1),
+			HomesteadBlock: big.NewInt(0),
+			DAOForkBlock:   big.NewInt(5), // Opposed, so it never activates anything
+			EIP150Block:    big.NewInt(10),
+			EIP155Block:    big.NewInt(20),
+			EIP158Block:    big.NewInt(20),
+		},
+		head: &types.Header{Number: big.NewInt(2)},
+	})
+	tests := []struct {
+		number  rpc.BlockNumber
+		active  []string
+		rules   map[string]bool
+		next    uint64
+		left    uint64
+		changes []string
+	}{
+		{
+			number:  rpc.LatestBlockNumber,
+			active:  []string{"EIP-2", "EIP-7"},
+			rules:   map[string]bool{"isEIP2F": true, "isEIP150": false},
+			next:    10,
+			left:    8,
+			changes: []string{"EIP-150"},
+		},
+		{
+			number:  10,
+			active:  []string{"EIP-2", "EIP-7", "EIP-150"},
+			rules:   map[string]bool{"isEIP150": true, "isEIP155": false},
+			next:    20,
+			left:    18,
+			changes: []string{"EIP-155", "EIP-160", "EIP-161", "EIP-170"},
+		},
+		{
+			number: 20,
+			active: []string{"EIP-2", "EIP-7", "EIP-150", "EIP-155", "EIP-160", "EIP-161", "EIP-170"},
+			rules:  map[string]bool{"isEIP155": true, "isEIP161F": true},
+		},
+	}
+	for i, tt := range tests {
+		result, err := api.ChainRules(context.Background(), tt.number)
+		if err != nil {
+			t.Errorf("test %d: failed to retrieve chain rules: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(result.Active, tt.active) {
+			t.Errorf("test %d: active changes mismatch: have %v, want %v", i, result.Active, tt.active)
+		}
+		for name, want := range tt.rules {
+			if have, ok := result.Rules[name]; !ok || have != want {
+				t.Errorf("test %d: rule %s mismatch: have %v (found %v), want %v", i, name, have, ok, want)
+			}
+		}
+		switch {
+		case tt.changes == nil && result.NextFork != nil:
+			t.Errorf("test %d: unexpected next fork: %+v", i, result.NextFork)
+		case tt.changes != nil && result.NextFork == nil:
+			t.Errorf("test %d: missing next fork", i)
+		case tt.changes != nil:
+			if uint64(result.NextFork.Block) != tt.next || uint64(result.NextFork.Remaining) != tt.left {
+				t.Errorf("test %d: next fork mismatch: have block %d in %d, want block %d in %d", i, result.NextFork.Block, result.NextFork.Remaining, tt.next, tt.left)
+			}
+			if !reflect.DeepEqual(result.NextFork.Changes, tt.changes) {
+				t.Errorf("test %d: next fork changes mismatch: have %v, want %v", i, result.NextFork.Changes, tt.changes)
+			}
+		}
+	}
+}
+
+// pendingBackend is a testBackend with a pending block, whose transactions have
+// not been executed yet.
+type pendingBackend struct {
+	*testBackend
+	pending *types.Block
+}
+
+func (b *pendingBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	switch number {
+	case rpc.PendingBlockNumber:
+		return b.pending, nil
+	case rpc.LatestBlockNumber:
+		return b.chain.CurrentBlock(), nil
+	}
+	return b.chain.GetBlockByNumber(uint64(number)), nil
+}
+
+func (b *pendingBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return b.chain.GetReceiptsByHash(hash), nil
+}
+
+// Tests that block receipts are served for the blocks of the chain, but not for
+// the pending block.
+func TestGetBlockReceipts(t *testing.T) {
+	backend := newTestBackend(t, 2)
+	defer backend.chain.Stop()
+
+	head := backend.chain.CurrentBlock()
+	tx := types.NewTransaction(0, common.Address{0xaa}, big.NewInt(1), params.TxGas, big.NewInt(1), nil)
+	pending := types.NewBlock(&types.Header{ParentHash: head.Hash(), Number: big.NewInt(3)}, []*types.Transaction{tx}, nil, nil)
+
+	api := NewPublicTransactionPoolAPI(&pendingBackend{testBackend: backend, pending: pending}, new(AddrLocker))
+	for _, number := range []rpc.BlockNumber{0, 2, rpc.LatestBlockNumber} {
+		receipts, err := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(number))
+		if err != nil || receipts == nil {
+			t.Errorf("block %d: failed to retrieve receipts: %v", number, err)
+		}
+	}
+	receipts, err := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+	if err != nil || receipts != nil {
+		t.Errorf("pending receipts mismatch: have %v, %v, want nil", receipts, err)
+	}
+}
@@ -18,8 +18,9 @@ package openrpc
 
 // This file contains a string constant containing the JSON schema data for OpenRPC.
 
-// OpenRPCSchema defines the default full suite of possibly available go-ethereum RPC
-// methods.
+// OpenRPCSchema holds the hand-written descriptions of the go-ethereum RPC methods.
+// They are merged into the OpenRPC document the rpc package generates from the
+// registered APIs, the published result being kept in openrpc.json.
 const OpenRPCSchema = `
 {
     "openrpc": "1.0.0",
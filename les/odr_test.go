@@ -31,9 +31,11 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/light"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type odrTestFn func(ctx context.Context, db ethdb.Database, config *params.ChainConfig, bc *core.BlockChain, lc *light.LightChain, bhash common.Hash) []byte
@@ -88,7 +90,7 @@ func odrAccounts(ctx context.Context, db ethdb.Database, config *params.ChainCon
 	for _, addr := range acc {
 		if bc != nil {
 			header := bc.GetHeaderByHash(bhash)
-			st, err = state.New(header.Root, state.NewDatabase(db))
+			st, err = state.New(header.Root, state.NewDatabase(db), nil)
 		} else {
 			header := lc.GetHeaderByHash(bhash)
 			st = light.NewState(ctx, header, lc.Odr())
@@ -118,7 +120,7 @@ func odrContractCall(ctx context.Context, db ethdb.Database, config *params.Chai
 		data[35] = byte(i)
 		if bc != nil {
 			header := bc.GetHeaderByHash(bhash)
-			statedb, err := state.New(header.Root, state.NewDatabase(db))
+			statedb, err := state.New(header.Root, state.NewDatabase(db), nil)
 
 			if err == nil {
 				from := statedb.GetOrNewStateObject(bankAddr)
@@ -224,3 +226,48 @@ func testOdr(t *testing.T, protocol int, expFail uint64, checkCached bool, fn od
 		test(5)
 	}
 }
+
+// Tests that the receipts of whole blocks are retrieved on demand through the
+// light client API backend, and that none are reported for the pending block.
+func TestOdrBlockReceiptsApiLes2(t *testing.T) {
+	server, client, tearDown := newClientServerEnv(t, 4, 2, nil, true)
+	defer tearDown()
+	client.pm.synchronise(client.rPeer)
+
+	client.peers.lock.Lock()
+	client.rPeer.hasBlock = func(common.Hash, uint64, bool) bool { return true }
+	client.peers.lock.Unlock()
+
+	lc := client.pm.blockchain.(*light.LightChain)
+	backend := &LesApiBackend{eth: &LightEthereum{
+		lesCommons:  lesCommons{chainDb: client.db},
+		odr:         lc.Odr().(*LesOdr),
+		chainConfig: client.pm.chainConfig,
+		blockchain:  lc,
+	}}
+	api := ethapi.NewPublicTransactionPoolAPI(backend, new(ethapi.AddrLocker))
+
+	head := server.pm.blockchain.CurrentHeader().Number.Uint64()
+	for i := uint64(0); i <= head; i++ {
+		block := server.pm.blockchain.(*core.BlockChain).GetBlockByNumber(i)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		receipts, err := api.GetBlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(block.Hash(), false))
+		cancel()
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve receipts: %v", i, err)
+		}
+		if len(receipts) != len(block.Transactions()) {
+			t.Fatalf("block %d: receipt count mismatch: have %d, want %d", i, len(receipts), len(block.Transactions()))
+		}
+		for j, receipt := range receipts {
+			if hash := block.Transactions()[j].Hash(); receipt["transactionHash"] != hash {
+				t.Errorf("block %d, receipt %d: transaction mismatch: have %v, want %x", i, j, receipt["transactionHash"], hash)
+			}
+		}
+	}
+	receipts, err := api.GetBlockReceipts(context.Background(), rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+	if err != nil || receipts != nil {
+		t.Errorf("pending receipts mismatch: have %v, %v, want nil", receipts, err)
+	}
+}
@@ -59,3 +59,35 @@ func FoundationBootnodes() *Enodes {
 	}
 	return nodes
 }
+
+// NetworkGenesis returns the JSON spec of the genesis block of a registered
+// network, or of the network definition file at the given path.
+func NetworkGenesis(network string) (string, error) {
+	n, err := core.ResolveNetwork(network)
+	if err != nil {
+		return "", err
+	}
+	enc, err := json.Marshal(n.Genesis)
+	if err != nil {
+		return "", err
+	}
+	return string(enc), nil
+}
+
+// NetworkBootnodes returns the enode URLs of the discovery v5 bootstrap nodes of
+// a registered network, or of the network definition file at the given path.
+func NetworkBootnodes(network string) (*Enodes, error) {
+	n, err := core.ResolveNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+	nodes := &Enodes{nodes: make([]*discv5.Node, 0, len(n.BootnodesV5))}
+	for _, url := range n.BootnodesV5 {
+		node, err := discv5.ParseNode(url)
+		if err != nil {
+			return nil, err
+		}
+		nodes.nodes = append(nodes.nodes, node)
+	}
+	return nodes, nil
+}
@@ -71,6 +71,11 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the key-value store engine backing the databases, either
+	// "leveldb" or "badger". If empty, the engine of any pre-existing database
+	// is used, defaulting to LevelDB for new ones.
+	DBEngine string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -186,6 +191,11 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	GraphQLVirtualHosts []string `toml:",omitempty"`
 
+	// GraphQLTracing enables resolving the call traces of transactions over GraphQL.
+	// Traces are computed by re-executing the transactions, which is expensive and
+	// thus left to the operator to allow for untrusted clients.
+	GraphQLTracing bool `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
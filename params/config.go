@@ -20,6 +20,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -225,12 +226,21 @@ var (
 		nil,           // EIP1283FBlock
 
 		nil, // PetersburgBlock
+
 		nil, // IstanbulBlock
+		nil, // EIP152FBlock
+		nil, // EIP1108FBlock
+		nil, // EIP1344FBlock
+		nil, // EIP1884FBlock
+		nil, // EIP2028FBlock
+		nil, // EIP2200FBlock
+
 		nil, // EWASMBlock
 
 		nil, // ECIP1010PauseBlock
 		nil, // ECIP1010Length
 		nil, // ECIP1017EraRounds
+		nil, // ECIP1099Block
 		nil, // DisposalBlock
 		nil, // SocialBlock
 		nil, // EthersocialBlock
@@ -239,6 +249,10 @@ var (
 		nil, // Musicoin MCIP3Block UBI
 		nil, // Musicoin MCIP8Block QT
 
+		nil,   // DifficultyBombDelays
+		false, // DifficultyBombDisabled
+		nil,   // BlockRewardSchedule
+
 		new(EthashConfig), // Ethash
 		nil,               // Clique
 		nil,
@@ -287,12 +301,21 @@ var (
 		nil,           // EIP1283FBlock
 
 		nil, // PetersburgBlock
+
 		nil, // IstanbulBlock
+		nil, // EIP152FBlock
+		nil, // EIP1108FBlock
+		nil, // EIP1344FBlock
+		nil, // EIP1884FBlock
+		nil, // EIP2028FBlock
+		nil, // EIP2200FBlock
+
 		nil, // EWASMBlock
 
 		nil, // ECIP1010PauseBlock
 		nil, // ECIP1010Length
 		nil, // ECIP1017EraRounds
+		nil, // ECIP1099Block
 		nil, // DisposalBlock
 		nil, // SocialBlock
 		nil, // EthersocialBlock
@@ -301,6 +324,10 @@ var (
 		nil, // Musicoin MCIP3Block UBI
 		nil, // Musicoin MCIP8Block QT
 
+		nil,   // DifficultyBombDelays
+		false, // DifficultyBombDisabled
+		nil,   // BlockRewardSchedule
+
 		nil, // Ethash
 		&CliqueConfig{
 			Period: 0,
@@ -348,12 +375,21 @@ var (
 		nil,           // EIP1283FBlock
 
 		nil, // PetersburgBlock
+
 		nil, // IstanbulBlock
+		nil, // EIP152FBlock
+		nil, // EIP1108FBlock
+		nil, // EIP1344FBlock
+		nil, // EIP1884FBlock
+		nil, // EIP2028FBlock
+		nil, // EIP2200FBlock
+
 		nil, // EWASMBlock
 
 		nil, // ECIP1010PauseBlock
 		nil, // ECIP1010Length
 		nil, // ECIP1017EraRounds
+		nil, // ECIP1099Block
 		nil, // DisposalBlock
 		nil, // SocialBlock
 		nil, // EthersocialBlock
@@ -362,6 +398,10 @@ var (
 		nil, // Musicoin MCIP3Block UBI
 		nil, // Musicoin MCIP8Block QT
 
+		nil,   // DifficultyBombDelays
+		false, // DifficultyBombDisabled
+		nil,   // BlockRewardSchedule
+
 		new(EthashConfig), // Ethash
 		nil,               // Clique
 		nil,
@@ -429,7 +469,7 @@ type ChainConfig struct {
 	EIP2FBlock *big.Int `json:"eip2FBlock,omitempty"`
 	// DELEGATECALL
 	// https://eips.ethereum.org/EIPS/eip-7
-	EIP7FBlock *big.Int `json:"eip7FBlock,omitempy"`
+	EIP7FBlock *big.Int `json:"eip7FBlock,omitempty"`
 	// Note: EIP 8 was also included in this fork, but was not backwards-incompatible
 
 	// HF: DAO
@@ -516,12 +556,34 @@ type ChainConfig struct {
 
 	PetersburgBlock *big.Int `json:"petersburgBlock,omitempty"` // Petersburg switch block (nil = same as Constantinople)
 
+	// HF: Istanbul
 	IstanbulBlock *big.Int `json:"istanbulBlock,omitempty"` // Istanbul switch block (nil = no fork, 0 = already on istanbul)
-	EWASMBlock    *big.Int `json:"ewasmBlock,omitempty"`    // EWASM switch block (nil = no fork, 0 = already activated)
+	//
+	// Precompiled contract for the Blake2 F compression function
+	// https://eips.ethereum.org/EIPS/eip-152
+	EIP152FBlock *big.Int `json:"eip152FBlock,omitempty"`
+	// Reduction in costs for the alt_bn128 precompiled contracts
+	// https://eips.ethereum.org/EIPS/eip-1108
+	EIP1108FBlock *big.Int `json:"eip1108FBlock,omitempty"`
+	// Opcode CHAINID
+	// https://eips.ethereum.org/EIPS/eip-1344
+	EIP1344FBlock *big.Int `json:"eip1344FBlock,omitempty"`
+	// Repricing of trie-size-dependent opcodes, opcode SELFBALANCE
+	// https://eips.ethereum.org/EIPS/eip-1884
+	EIP1884FBlock *big.Int `json:"eip1884FBlock,omitempty"`
+	// Reduction in cost of transaction calldata
+	// https://eips.ethereum.org/EIPS/eip-2028
+	EIP2028FBlock *big.Int `json:"eip2028FBlock,omitempty"`
+	// Structured definitions for net gas metering
+	// https://eips.ethereum.org/EIPS/eip-2200
+	EIP2200FBlock *big.Int `json:"eip2200FBlock,omitempty"`
+
+	EWASMBlock *big.Int `json:"ewasmBlock,omitempty"` // EWASM switch block (nil = no fork, 0 = already activated)
 
 	ECIP1010PauseBlock *big.Int `json:"ecip1010PauseBlock,omitempty"` // ECIP1010 pause HF block
 	ECIP1010Length     *big.Int `json:"ecip1010Length,omitempty"`     // ECIP1010 length
 	ECIP1017EraRounds  *big.Int `json:"ecip1017EraRounds,omitempty"`  // ECIP1017 era rounds
+	ECIP1099Block      *big.Int `json:"ecip1099Block,omitempty"`      // ECIP1099 ethash epoch length doubling block
 	DisposalBlock      *big.Int `json:"disposalBlock,omitempty"`      // Bomb disposal HF block
 	SocialBlock        *big.Int `json:"socialBlock,omitempty"`        // Ethereum Social Reward block
 	EthersocialBlock   *big.Int `json:"ethersocialBlock,omitempty"`   // Ethersocial Reward block
@@ -530,6 +592,16 @@ type ChainConfig struct {
 	MCIP3Block *big.Int `json:"mcip3Block,omitempty"` // Musicoin 'UBI Fork' block
 	MCIP8Block *big.Int `json:"mcip8Block,omitempty"` // Musicoin 'QT For' block
 
+	// Difficulty bomb policy. From the first scheduled delay on, the delays replace
+	// the bomb rules of EIP649, EIP1234 and ECIP1010.
+	DifficultyBombDelays   BombDelaySchedule `json:"difficultyBombDelays,omitempty"`   // Incremental bomb delays by activation block, added up
+	DifficultyBombDisabled bool              `json:"difficultyBombDisabled,omitempty"` // Whether the difficulty bomb is disabled altogether
+
+	// Block reward policy. From the first entry on, the schedule replaces the block
+	// reward rules of EIP649, EIP1234, ECIP1017 and the Social, Ethersocial and
+	// Musicoin networks.
+	BlockRewardSchedule []BlockReward `json:"blockRewardSchedule,omitempty"`
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
@@ -538,6 +610,71 @@ type ChainConfig struct {
 	TrustedCheckpointOracle *CheckpointOracleConfig `json:"trustedCheckpointOracle"`
 }
 
+// BombDelay is a difficulty bomb delay: from Block on, the bomb is calculated as
+// if the chain was Delay blocks shorter (on top of any earlier delays).
+type BombDelay struct {
+	Block *big.Int `json:"block"`
+	Delay *big.Int `json:"delay"`
+}
+
+// BombDelaySchedule is a list of difficulty bomb delays. Each entry holds only the
+// additional delay introduced at its block, not the total delay in effect.
+type BombDelaySchedule []BombDelay
+
+// UncleRewardRule selects how the miners of uncles, and the miners including them
+// in a block, are rewarded.
+type UncleRewardRule string
+
+const (
+	// UncleRewardStandard rewards uncle miners with (8 - depth) / 8 of the base
+	// reward, and the including miner with 1/32 of it per uncle.
+	UncleRewardStandard UncleRewardRule = "standard"
+
+	// UncleRewardECIP1017 applies the standard rule in the first era, after which
+	// both the uncle and the including miners get 1/32 of the era's reward.
+	UncleRewardECIP1017 UncleRewardRule = "ecip1017"
+
+	// UncleRewardNoInclusion rewards uncle miners as the standard rule does, but
+	// gives the including miner nothing extra.
+	UncleRewardNoInclusion UncleRewardRule = "noInclusion"
+)
+
+// isStandard reports whether the rule is the standard one, which is also the
+// default if none is specified.
+func (rule UncleRewardRule) isStandard() bool {
+	return rule == "" || rule == UncleRewardStandard
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, rejecting unknown rules.
+func (rule *UncleRewardRule) UnmarshalText(input []byte) error {
+	switch r := UncleRewardRule(input); r {
+	case "", UncleRewardStandard, UncleRewardECIP1017, UncleRewardNoInclusion:
+		*rule = r
+		return nil
+	default:
+		return fmt.Errorf("unknown uncle reward rule %q", input)
+	}
+}
+
+// RewardSplit is a fixed amount paid to a beneficiary for every mined block, on
+// top of the miner's reward.
+type RewardSplit struct {
+	Beneficiary common.Address `json:"beneficiary"`
+	Amount      *big.Int       `json:"amount"`
+}
+
+// BlockReward is an entry of a block reward schedule, defining the rewards of
+// every block from Block on, up to the next entry.
+type BlockReward struct {
+	Block     *big.Int        `json:"block"`               // First block the rewards apply to
+	Reward    *big.Int        `json:"reward"`              // Base reward of the block's miner
+	UncleRule UncleRewardRule `json:"uncleRule,omitempty"` // Uncle reward rule (empty = standard)
+	UncleBase *big.Int        `json:"uncleBase,omitempty"` // Base reward the uncle rewards derive from (nil = Reward)
+	EraLength *big.Int        `json:"eraLength,omitempty"` // Length of the disinflation eras, counted from genesis (nil = no eras)
+	EraRate   *big.Rat        `json:"eraRate,omitempty"`   // Reward reduction factor per era (nil = 4/5, as per ECIP1017)
+	Splits    []RewardSplit   `json:"splits,omitempty"`    // Additional per block payments to other beneficiaries
+}
+
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
 type EthashConfig struct{}
 
@@ -711,13 +848,49 @@ func (c *ChainConfig) IsEIP1283F(num *big.Int) bool {
 }
 
 func (c *ChainConfig) IsBombDisposal(num *big.Int) bool {
-	return isForked(c.DisposalBlock, num)
+	return c.DifficultyBombDisabled || isForked(c.DisposalBlock, num)
+}
+
+// BlockRewardAt returns the entry of the block reward schedule in effect at block
+// num, or nil if the schedule is not in effect yet (and the legacy block reward
+// rules apply instead).
+func (c *ChainConfig) BlockRewardAt(num *big.Int) *BlockReward {
+	var reward *BlockReward
+	for i := range c.BlockRewardSchedule {
+		entry := &c.BlockRewardSchedule[i]
+		if isForked(entry.Block, num) && (reward == nil || entry.Block.Cmp(reward.Block) > 0) {
+			reward = entry
+		}
+	}
+	return reward
+}
+
+// BombDelay returns the total difficulty bomb delay scheduled for block num, or
+// nil if no scheduled delay is in effect yet (and the EIP649, EIP1234 and
+// ECIP1010 rules apply instead).
+func (c *ChainConfig) BombDelay(num *big.Int) *big.Int {
+	var total *big.Int
+	for _, delay := range c.DifficultyBombDelays {
+		if isForked(delay.Block, num) {
+			if total == nil {
+				total = new(big.Int)
+			}
+			total.Add(total, delay.Delay)
+		}
+	}
+	return total
 }
 
 func (c *ChainConfig) IsECIP1010(num *big.Int) bool {
 	return isForked(c.ECIP1010PauseBlock, num)
 }
 
+// IsECIP1099 returns whether num is equal to or greater than the ECIP1099 block,
+// from which on the ethash epoch length is doubled.
+func (c *ChainConfig) IsECIP1099(num *big.Int) bool {
+	return isForked(c.ECIP1099Block, num)
+}
+
 // IsPetersburg returns whether num is either
 // - equal to or greater than the PetersburgBlock fork block,
 // - OR is nil, and Constantinople is active
@@ -730,6 +903,36 @@ func (c *ChainConfig) IsIstanbul(num *big.Int) bool {
 	return isForked(c.IstanbulBlock, num)
 }
 
+// IsEIP152F returns whether num is equal to or greater than the Istanbul or EIP152 block.
+func (c *ChainConfig) IsEIP152F(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num) || isForked(c.EIP152FBlock, num)
+}
+
+// IsEIP1108F returns whether num is equal to or greater than the Istanbul or EIP1108 block.
+func (c *ChainConfig) IsEIP1108F(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num) || isForked(c.EIP1108FBlock, num)
+}
+
+// IsEIP1344F returns whether num is equal to or greater than the Istanbul or EIP1344 block.
+func (c *ChainConfig) IsEIP1344F(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num) || isForked(c.EIP1344FBlock, num)
+}
+
+// IsEIP1884F returns whether num is equal to or greater than the Istanbul or EIP1884 block.
+func (c *ChainConfig) IsEIP1884F(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num) || isForked(c.EIP1884FBlock, num)
+}
+
+// IsEIP2028F returns whether num is equal to or greater than the Istanbul or EIP2028 block.
+func (c *ChainConfig) IsEIP2028F(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num) || isForked(c.EIP2028FBlock, num)
+}
+
+// IsEIP2200F returns whether num is equal to or greater than the Istanbul or EIP2200 block.
+func (c *ChainConfig) IsEIP2200F(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num) || isForked(c.EIP2200FBlock, num)
+}
+
 // IsEWASM returns whether num represents a block number after the EWASM fork
 func (c *ChainConfig) IsEWASM(num *big.Int) bool {
 	return isForked(c.EWASMBlock, num)
@@ -783,7 +986,14 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 		{"EIP1052F", c.EIP1052FBlock, newcfg.EIP1052FBlock},
 		{"EIP1234F", c.EIP1234FBlock, newcfg.EIP1234FBlock},
 		{"EIP1283F", c.EIP1283FBlock, newcfg.EIP1283FBlock},
+		{"EIP152F", c.EIP152FBlock, newcfg.EIP152FBlock},
+		{"EIP1108F", c.EIP1108FBlock, newcfg.EIP1108FBlock},
+		{"EIP1344F", c.EIP1344FBlock, newcfg.EIP1344FBlock},
+		{"EIP1884F", c.EIP1884FBlock, newcfg.EIP1884FBlock},
+		{"EIP2028F", c.EIP2028FBlock, newcfg.EIP2028FBlock},
+		{"EIP2200F", c.EIP2200FBlock, newcfg.EIP2200FBlock},
 		{"EWASM", c.EWASMBlock, newcfg.EWASMBlock},
+		{"ECIP1099", c.ECIP1099Block, newcfg.ECIP1099Block},
 	} {
 		if err := func(c1, c2, head *big.Int) *ConfigCompatError {
 			if isForkIncompatible(ch.c1, ch.c2, head) {
@@ -798,6 +1008,15 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if c.IsDAOFork(head) && c.DAOForkSupport != newcfg.DAOForkSupport {
 		return newCompatError("DAO fork support flag", c.DAOForkBlock, newcfg.DAOForkBlock)
 	}
+	if block := bombDelayIncompatible(c, newcfg, head); block != nil {
+		return newCompatError("difficulty bomb delay", block, block)
+	}
+	if block := blockRewardIncompatible(c, newcfg, head); block != nil {
+		return newCompatError("block reward schedule", block, block)
+	}
+	if c.DifficultyBombDisabled != newcfg.DifficultyBombDisabled && isForked(bombStartBlock, head) {
+		return newCompatError("difficulty bomb disabled flag", bombStartBlock, bombStartBlock)
+	}
 	if c.IsEIP155(head) && !configNumEqual(c.ChainID, newcfg.ChainID) {
 		return newCompatError("EIP155 chain ID", c.EIP155Block, newcfg.EIP155Block)
 	}
@@ -863,6 +1082,73 @@ type ConfigCompatError struct {
 	RewindTo uint64
 }
 
+// bombStartBlock is the first block the difficulty bomb has an effect on.
+var bombStartBlock = new(big.Int).Mul(ExpDiffPeriod, big.NewInt(2))
+
+// bombDelayIncompatible returns the first block up to head at which the bomb delays
+// scheduled by two configs differ, or nil if they agree.
+func bombDelayIncompatible(c1, c2 *ChainConfig, head *big.Int) *big.Int {
+	var blocks []*big.Int
+	for _, delay := range append(append(BombDelaySchedule{}, c1.DifficultyBombDelays...), c2.DifficultyBombDelays...) {
+		if isForked(delay.Block, head) {
+			blocks = append(blocks, delay.Block)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Cmp(blocks[j]) < 0 })
+
+	for _, block := range blocks {
+		if !configNumEqual(c1.BombDelay(block), c2.BombDelay(block)) {
+			return block
+		}
+	}
+	return nil
+}
+
+// blockRewardIncompatible returns the first block up to head at which the block
+// reward schedules of two configs differ, or nil if they agree.
+func blockRewardIncompatible(c1, c2 *ChainConfig, head *big.Int) *big.Int {
+	var blocks []*big.Int
+	for _, reward := range append(append([]BlockReward{}, c1.BlockRewardSchedule...), c2.BlockRewardSchedule...) {
+		if isForked(reward.Block, head) {
+			blocks = append(blocks, reward.Block)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].Cmp(blocks[j]) < 0 })
+
+	for _, block := range blocks {
+		if !blockRewardEqual(c1.BlockRewardAt(block), c2.BlockRewardAt(block)) {
+			return block
+		}
+	}
+	return nil
+}
+
+// blockRewardEqual reports whether two block reward schedule entries define the
+// same rewards.
+func blockRewardEqual(r1, r2 *BlockReward) bool {
+	if r1 == nil || r2 == nil {
+		return r1 == r2
+	}
+	if !configNumEqual(r1.Block, r2.Block) || !configNumEqual(r1.Reward, r2.Reward) || !configNumEqual(r1.UncleBase, r2.UncleBase) || !configNumEqual(r1.EraLength, r2.EraLength) {
+		return false
+	}
+	if r1.UncleRule != r2.UncleRule && !(r1.UncleRule.isStandard() && r2.UncleRule.isStandard()) {
+		return false
+	}
+	if (r1.EraRate == nil) != (r2.EraRate == nil) || (r1.EraRate != nil && r1.EraRate.Cmp(r2.EraRate) != 0) {
+		return false
+	}
+	if len(r1.Splits) != len(r2.Splits) {
+		return false
+	}
+	for i := range r1.Splits {
+		if r1.Splits[i].Beneficiary != r2.Splits[i].Beneficiary || !configNumEqual(r1.Splits[i].Amount, r2.Splits[i].Amount) {
+			return false
+		}
+	}
+	return true
+}
+
 func newCompatError(what string, storedblock, newblock *big.Int) *ConfigCompatError {
 	var rew *big.Int
 	switch {
@@ -901,8 +1187,10 @@ type Rules struct {
 	// Constantinople
 	IsEIP145F, IsEIP1014F, IsEIP1052F, IsEIP1283F, IsEIP1234F bool
 	IsPetersburg, IsIstanbul                                  bool
-	IsBombDisposal, IsSocial, IsEthersocial, IsECIP1010       bool
-	IsMCIP0, IsMCIP3, IsMCIP8                                 bool
+	// Istanbul
+	IsEIP152F, IsEIP1108F, IsEIP1344F, IsEIP1884F, IsEIP2028F, IsEIP2200F bool
+	IsBombDisposal, IsSocial, IsEthersocial, IsECIP1010, IsECIP1099       bool
+	IsMCIP0, IsMCIP3, IsMCIP8                                             bool
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -942,10 +1230,18 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 		IsPetersburg: c.IsPetersburg(num),
 		IsIstanbul:   c.IsIstanbul(num),
 
+		IsEIP152F:  c.IsEIP152F(num),
+		IsEIP1108F: c.IsEIP1108F(num),
+		IsEIP1344F: c.IsEIP1344F(num),
+		IsEIP1884F: c.IsEIP1884F(num),
+		IsEIP2028F: c.IsEIP2028F(num),
+		IsEIP2200F: c.IsEIP2200F(num),
+
 		IsBombDisposal: c.IsBombDisposal(num),
 		IsSocial:       c.IsSocial(num),
 		IsEthersocial:  c.IsEthersocial(num),
 		IsECIP1010:     c.IsECIP1010(num),
+		IsECIP1099:     c.IsECIP1099(num),
 
 		IsMCIP0: c.IsMCIP0(num),
 		IsMCIP3: c.IsMCIP3(num),
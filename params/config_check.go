@@ -0,0 +1,279 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// ecip1099EpochLength is the doubled ethash epoch length of ECIP-1099. The
+// transition has to be at a boundary of both the old and the new epochs, so the
+// epoch numbers stay continuous.
+const ecip1099EpochLength = 60000
+
+// Activation is the scheduled activation of an individual protocol change.
+type Activation struct {
+	Fork  string   // Hard fork bundling the change, empty if none
+	Name  string   // Name of the protocol change, e.g. EIP-155
+	Block *big.Int // Block the change activates at (nil = not scheduled)
+}
+
+// Activations returns the activation block of every individual protocol change
+// configurable in the chain config, taking both the hard fork blocks and the
+// per-EIP overrides into account.
+func (c *ChainConfig) Activations() []Activation {
+	activations := []Activation{
+		{"Homestead", "EIP-2", firstForkBlock(c.HomesteadBlock, c.EIP2FBlock)},
+		{"Homestead", "EIP-7", firstForkBlock(c.HomesteadBlock, c.EIP7FBlock)},
+		{"DAO", "DAO fork", c.DAOForkBlock},
+		{"Tangerine Whistle", "EIP-150", c.EIP150Block},
+		{"Spurious Dragon", "EIP-155", c.EIP155Block},
+		{"Spurious Dragon", "EIP-160", firstForkBlock(c.EIP158Block, c.EIP160FBlock)},
+		{"Spurious Dragon", "EIP-161", firstForkBlock(c.EIP158Block, c.EIP161FBlock)},
+		{"Spurious Dragon", "EIP-170", firstForkBlock(c.EIP158Block, c.EIP170FBlock)},
+		{"Byzantium", "EIP-100", firstForkBlock(c.ByzantiumBlock, c.ConstantinopleBlock, c.EIP100FBlock)},
+		{"Byzantium", "EIP-140", firstForkBlock(c.ByzantiumBlock, c.EIP140FBlock)},
+		{"Byzantium", "EIP-198", firstForkBlock(c.ByzantiumBlock, c.EIP198FBlock)},
+		{"Byzantium", "EIP-211", firstForkBlock(c.ByzantiumBlock, c.EIP211FBlock)},
+		{"Byzantium", "EIP-212", firstForkBlock(c.ByzantiumBlock, c.EIP212FBlock)},
+		{"Byzantium", "EIP-213", firstForkBlock(c.ByzantiumBlock, c.EIP213FBlock)},
+		{"Byzantium", "EIP-214", firstForkBlock(c.ByzantiumBlock, c.EIP214FBlock)},
+		{"Byzantium", "EIP-649", firstForkBlock(c.ByzantiumBlock, c.EIP649FBlock)},
+		{"Byzantium", "EIP-658", firstForkBlock(c.ByzantiumBlock, c.EIP658FBlock)},
+		{"Constantinople", "EIP-145", firstForkBlock(c.ConstantinopleBlock, c.EIP145FBlock)},
+		{"Constantinople", "EIP-1014", firstForkBlock(c.ConstantinopleBlock, c.EIP1014FBlock)},
+		{"Constantinople", "EIP-1052", firstForkBlock(c.ConstantinopleBlock, c.EIP1052FBlock)},
+		{"Constantinople", "EIP-1234", firstForkBlock(c.ConstantinopleBlock, c.EIP1234FBlock)},
+		{"Constantinople", "EIP-1283", firstForkBlock(c.ConstantinopleBlock, c.EIP1283FBlock)},
+		{"Petersburg", "EIP-1283 removal", firstForkBlock(c.PetersburgBlock, c.ConstantinopleBlock)},
+		{"Istanbul", "EIP-152", firstForkBlock(c.IstanbulBlock, c.EIP152FBlock)},
+		{"Istanbul", "EIP-1108", firstForkBlock(c.IstanbulBlock, c.EIP1108FBlock)},
+		{"Istanbul", "EIP-1344", firstForkBlock(c.IstanbulBlock, c.EIP1344FBlock)},
+		{"Istanbul", "EIP-1884", firstForkBlock(c.IstanbulBlock, c.EIP1884FBlock)},
+		{"Istanbul", "EIP-2028", firstForkBlock(c.IstanbulBlock, c.EIP2028FBlock)},
+		{"Istanbul", "EIP-2200", firstForkBlock(c.IstanbulBlock, c.EIP2200FBlock)},
+		{"", "EWASM", c.EWASMBlock},
+		{"", "ECIP-1010", c.ECIP1010PauseBlock},
+		{"", "ECIP-1041", c.DisposalBlock},
+		{"", "ECIP-1099", c.ECIP1099Block},
+		{"", "Social", c.SocialBlock},
+		{"", "Ethersocial", c.EthersocialBlock},
+		{"", "MCIP-0", c.MCIP0Block},
+		{"", "MCIP-3", c.MCIP3Block},
+		{"", "MCIP-8", c.MCIP8Block},
+	}
+	// The DAO fork is only a transition if the node supports it
+	if !c.DAOForkSupport {
+		activations[2].Name = "DAO fork (opposed)"
+	}
+	if c.HasECIP1017() {
+		activations = append(activations, Activation{"", "ECIP-1017", new(big.Int)})
+	}
+	for _, delay := range c.DifficultyBombDelays {
+		activations = append(activations, Activation{"", fmt.Sprintf("Bomb delay +%v", delay.Delay), delay.Block})
+	}
+	for _, reward := range c.BlockRewardSchedule {
+		activations = append(activations, Activation{"", fmt.Sprintf("Block reward %v", reward.Reward), reward.Block})
+	}
+	return activations
+}
+
+// Check validates the chain config for internal consistency: the ordering of the
+// hard forks, the dependencies between the individual protocol changes and the
+// completeness of the settings spanning multiple fields.
+//
+// Hard forks scheduled out of order are only reported as warnings, since the
+// protocol changes are individually configurable and some live networks do
+// activate them out of order. Everything else is reported as an error.
+func (c *ChainConfig) Check() (warnings []error, errs []error) {
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	// Hard forks should be scheduled in order. The DAO fork is left out, since
+	// networks opposing it tend to park it at an unreachable block.
+	var last struct {
+		name  string
+		block *big.Int
+	}
+	for _, fork := range []struct {
+		name  string
+		block *big.Int
+	}{
+		{"homesteadBlock", c.HomesteadBlock},
+		{"eip150Block", c.EIP150Block},
+		{"eip155Block", c.EIP155Block},
+		{"eip158Block", c.EIP158Block},
+		{"byzantiumBlock", c.ByzantiumBlock},
+		{"constantinopleBlock", c.ConstantinopleBlock},
+		{"petersburgBlock", c.PetersburgBlock},
+		{"istanbulBlock", c.IstanbulBlock},
+	} {
+		if fork.block == nil {
+			continue
+		}
+		if last.block != nil && fork.block.Cmp(last.block) < 0 {
+			warnings = append(warnings, fmt.Errorf("unusual fork ordering: %s enabled at %v, but %s enabled at %v", last.name, last.block, fork.name, fork.block))
+		}
+		last.name, last.block = fork.name, fork.block
+	}
+	// Individual protocol changes must not precede the ones they build upon
+	requires := func(name string, block *big.Int, dep string, depBlock *big.Int) {
+		if block != nil && (depBlock == nil || depBlock.Cmp(block) > 0) {
+			if depBlock == nil {
+				fail("%s enabled at %v requires %s, which is not enabled", name, block, dep)
+			} else {
+				fail("%s enabled at %v requires %s, which is enabled later at %v", name, block, dep, depBlock)
+			}
+		}
+	}
+	activations := make(map[string]*big.Int)
+	for _, activation := range c.Activations() {
+		activations[activation.Name] = activation.Block
+	}
+	requires("eip158Block", c.EIP158Block, "EIP-155", c.EIP155Block)
+	requires("EIP-1234", activations["EIP-1234"], "EIP-649", activations["EIP-649"])
+	requires("MCIP-3", c.MCIP3Block, "MCIP-0", c.MCIP0Block)
+	requires("MCIP-8", c.MCIP8Block, "MCIP-0", c.MCIP0Block)
+
+	if c.ChainID == nil {
+		for _, name := range []string{"EIP-155", "EIP-1344"} {
+			if block := activations[name]; block != nil {
+				fail("%s enabled at %v requires a chain ID", name, block)
+			}
+		}
+	}
+	// Settings spanning multiple fields must be complete
+	if (c.ECIP1010PauseBlock == nil) != (c.ECIP1010Length == nil) {
+		fail("ECIP-1010 requires both ecip1010PauseBlock and ecip1010Length, have %v and %v", c.ECIP1010PauseBlock, c.ECIP1010Length)
+	}
+	if c.ECIP1010Length != nil && c.ECIP1010Length.Sign() < 0 {
+		fail("negative ECIP-1010 length %v", c.ECIP1010Length)
+	}
+	if c.ECIP1099Block != nil && new(big.Int).Mod(c.ECIP1099Block, big.NewInt(ecip1099EpochLength)).Sign() != 0 {
+		fail("ECIP-1099 enabled at %v, which is not at an epoch boundary (multiple of %d)", c.ECIP1099Block, ecip1099EpochLength)
+	}
+	if c.ECIP1017EraRounds != nil && c.ECIP1017EraRounds.Sign() <= 0 {
+		fail("non-positive ECIP-1017 era rounds %v", c.ECIP1017EraRounds)
+	}
+	var rewards []string
+	for _, rule := range []struct {
+		name  string
+		block *big.Int
+	}{
+		{"socialBlock", c.SocialBlock},
+		{"ethersocialBlock", c.EthersocialBlock},
+		{"mcip0Block", c.MCIP0Block},
+	} {
+		if rule.block != nil {
+			rewards = append(rewards, rule.name)
+		}
+	}
+	if len(rewards) > 1 {
+		fail("conflicting block reward rules %v", rewards)
+	}
+	// Schedules must be well formed
+	delays := make(map[string]bool)
+	for i, delay := range c.DifficultyBombDelays {
+		switch {
+		case delay.Block == nil || delay.Delay == nil:
+			fail("difficulty bomb delay %d: missing block or delay", i)
+		case delay.Block.Sign() < 0 || delay.Delay.Sign() < 0:
+			fail("difficulty bomb delay %d: negative block %v or delay %v", i, delay.Block, delay.Delay)
+		case delays[delay.Block.String()]:
+			fail("difficulty bomb delay %d: duplicate block %v", i, delay.Block)
+		default:
+			delays[delay.Block.String()] = true
+		}
+	}
+	rewarded := make(map[string]bool)
+	for i, reward := range c.BlockRewardSchedule {
+		switch {
+		case reward.Block == nil || reward.Reward == nil:
+			fail("block reward %d: missing block or reward", i)
+			continue
+		case reward.Block.Sign() < 0 || reward.Reward.Sign() < 0:
+			fail("block reward %d: negative block %v or reward %v", i, reward.Block, reward.Reward)
+		case rewarded[reward.Block.String()]:
+			fail("block reward %d: duplicate block %v", i, reward.Block)
+		}
+		rewarded[reward.Block.String()] = true
+
+		if reward.EraLength != nil && reward.EraLength.Sign() <= 0 {
+			fail("block reward %d: non-positive era length %v", i, reward.EraLength)
+		}
+		if reward.EraRate != nil && (reward.EraRate.Sign() <= 0 || reward.EraRate.Cmp(big.NewRat(1, 1)) > 0) {
+			fail("block reward %d: era rate %v out of range (0, 1]", i, reward.EraRate)
+		}
+		for j, split := range reward.Splits {
+			if split.Amount == nil || split.Amount.Sign() < 0 {
+				fail("block reward %d: invalid amount %v of split %d", i, split.Amount, j)
+			}
+		}
+	}
+	// At most one consensus engine may be configured
+	if c.Ethash != nil && c.Clique != nil {
+		fail("both ethash and clique consensus engines configured")
+	}
+	return warnings, errs
+}
+
+// firstForkBlock returns the earliest of the given fork blocks, or nil if none
+// of them are scheduled.
+func firstForkBlock(blocks ...*big.Int) *big.Int {
+	var first *big.Int
+	for _, block := range blocks {
+		if block != nil && (first == nil || block.Cmp(first) < 0) {
+			first = block
+		}
+	}
+	return first
+}
+
+// RuleActivations returns the scheduled protocol changes altering the rules of
+// the chain, ordered by their activation block. An opposed DAO fork is left out,
+// since it doesn't change any rules.
+func (c *ChainConfig) RuleActivations() []Activation {
+	var activations []Activation
+	for _, activation := range c.Activations() {
+		if activation.Block == nil || (activation.Fork == "DAO" && !c.DAOForkSupport) {
+			continue
+		}
+		activations = append(activations, activation)
+	}
+	sort.SliceStable(activations, func(i, j int) bool {
+		return activations[i].Block.Cmp(activations[j].Block) < 0
+	})
+	return activations
+}
+
+// NextActivations returns the protocol changes scheduled at the first block after
+// num that activates any, or nil if nothing is scheduled after num.
+func (c *ChainConfig) NextActivations(num *big.Int) []Activation {
+	var next []Activation
+	for _, activation := range c.RuleActivations() {
+		if activation.Block.Cmp(num) <= 0 {
+			continue
+		}
+		if len(next) > 0 && activation.Block.Cmp(next[0].Block) != 0 {
+			break
+		}
+		next = append(next, activation)
+	}
+	return next
+}
@@ -0,0 +1,210 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests that the bundled chain configs pass the consistency checks, with only the
+// known out of order hard forks being warned about.
+func TestCheckBundledConfigs(t *testing.T) {
+	for name, config := range map[string]*ChainConfig{
+		"mainnet":     MainnetChainConfig,
+		"testnet":     TestnetChainConfig,
+		"rinkeby":     RinkebyChainConfig,
+		"goerli":      GoerliChainConfig,
+		"classic":     ClassicChainConfig,
+		"social":      SocialChainConfig,
+		"ethersocial": EthersocialChainConfig,
+		"musicoin":    MusicoinChainConfig,
+		"mix":         MixChainConfig,
+		"kotti":       KottiChainConfig,
+		"mordor":      MordorChainConfig,
+		"allethash":   AllEthashProtocolChanges,
+		"allclique":   AllCliqueProtocolChanges,
+	} {
+		warnings, errs := config.Check()
+		if len(errs) != 0 {
+			t.Errorf("%s: unexpected config errors: %v", name, errs)
+		}
+		// Ethersocial activated Byzantium before Spurious Dragon
+		if want := name == "ethersocial"; (len(warnings) != 0) != want {
+			t.Errorf("%s: config warnings mismatch: have %v, want %v", name, warnings, want)
+		}
+	}
+}
+
+// Tests that inconsistent chain configs are reported.
+func TestCheckInvalidConfigs(t *testing.T) {
+	tests := []struct {
+		config *ChainConfig
+		errs   int
+	}{
+		// EIP158 without, or before EIP155
+		{&ChainConfig{ChainID: big.NewInt(1), EIP158Block: big.NewInt(10)}, 1},
+		{&ChainConfig{ChainID: big.NewInt(1), EIP155Block: big.NewInt(20), EIP158Block: big.NewInt(10)}, 1},
+		// Individual EIPs preceding their dependencies
+		{&ChainConfig{ChainID: big.NewInt(1), EIP649FBlock: big.NewInt(20), EIP1234FBlock: big.NewInt(10)}, 1},
+		{&ChainConfig{ChainID: big.NewInt(1), MCIP3Block: big.NewInt(10)}, 1},
+		// Istanbul EIPs are independent of each other
+		{&ChainConfig{ChainID: big.NewInt(1), EIP2200FBlock: big.NewInt(10)}, 0},
+		// Replay protection without a chain ID
+		{&ChainConfig{EIP155Block: big.NewInt(10), EIP1344FBlock: big.NewInt(20)}, 2},
+		// ECIP1010 and ECIP1017 half or badly set
+		{&ChainConfig{ChainID: big.NewInt(1), ECIP1010PauseBlock: big.NewInt(10)}, 1},
+		{&ChainConfig{ChainID: big.NewInt(1), ECIP1010Length: big.NewInt(10)}, 1},
+		{&ChainConfig{ChainID: big.NewInt(1), ECIP1017EraRounds: new(big.Int)}, 1},
+		// ECIP1099 off an epoch boundary
+		{&ChainConfig{ChainID: big.NewInt(1), ECIP1099Block: big.NewInt(30000)}, 1},
+		{&ChainConfig{ChainID: big.NewInt(1), ECIP1099Block: big.NewInt(11700000)}, 0},
+		// Conflicting reward rules
+		{&ChainConfig{ChainID: big.NewInt(1), SocialBlock: big.NewInt(0), MCIP0Block: big.NewInt(0)}, 1},
+		// Malformed schedules
+		{&ChainConfig{ChainID: big.NewInt(1), DifficultyBombDelays: BombDelaySchedule{
+			{Block: big.NewInt(10), Delay: big.NewInt(1)},
+			{Block: big.NewInt(10), Delay: big.NewInt(1)},
+			{Block: big.NewInt(20)},
+		}}, 2},
+		{&ChainConfig{ChainID: big.NewInt(1), BlockRewardSchedule: []BlockReward{
+			{Block: big.NewInt(0), Reward: big.NewInt(1), EraLength: new(big.Int), EraRate: big.NewRat(3, 2)},
+			{Block: big.NewInt(0), Reward: big.NewInt(1), Splits: []RewardSplit{{}}},
+		}}, 4},
+		// Multiple consensus engines
+		{&ChainConfig{ChainID: big.NewInt(1), Ethash: new(EthashConfig), Clique: new(CliqueConfig)}, 1},
+	}
+	for i, tt := range tests {
+		if _, errs := tt.config.Check(); len(errs) != tt.errs {
+			t.Errorf("test %d: error count mismatch: have %d, want %d: %v", i, len(errs), tt.errs, errs)
+		}
+	}
+}
+
+// Tests that hard forks scheduled out of order are warned about.
+func TestCheckForkOrdering(t *testing.T) {
+	tests := []struct {
+		config   *ChainConfig
+		warnings int
+	}{
+		{&ChainConfig{HomesteadBlock: big.NewInt(10), EIP150Block: big.NewInt(5)}, 1},
+		{&ChainConfig{ByzantiumBlock: big.NewInt(10), IstanbulBlock: big.NewInt(5)}, 1},
+		{&ChainConfig{HomesteadBlock: big.NewInt(10), ByzantiumBlock: big.NewInt(5), IstanbulBlock: big.NewInt(1)}, 2},
+		{&ChainConfig{HomesteadBlock: big.NewInt(0), DAOForkBlock: big.NewInt(1 << 55), EIP150Block: big.NewInt(10)}, 0},
+	}
+	for i, tt := range tests {
+		if warnings, _ := tt.config.Check(); len(warnings) != tt.warnings {
+			t.Errorf("test %d: warning count mismatch: have %d, want %d: %v", i, len(warnings), tt.warnings, warnings)
+		}
+	}
+}
+
+// Tests that the activation table resolves hard fork blocks and per-EIP overrides.
+func TestActivations(t *testing.T) {
+	config := &ChainConfig{
+		ByzantiumBlock:      big.NewInt(20),
+		EIP140FBlock:        big.NewInt(10),
+		ConstantinopleBlock: big.NewInt(30),
+		EIP1884FBlock:       big.NewInt(40),
+	}
+	want := map[string]*big.Int{
+		"EIP-100":          big.NewInt(20),
+		"EIP-140":          big.NewInt(10),
+		"EIP-1283":         big.NewInt(30),
+		"EIP-1283 removal": big.NewInt(30),
+		"EIP-1884":         big.NewInt(40),
+		"EIP-2200":         nil,
+	}
+	for _, activation := range config.Activations() {
+		block, ok := want[activation.Name]
+		if !ok {
+			continue
+		}
+		if !configNumEqual(activation.Block, block) {
+			t.Errorf("%s: activation mismatch: have %v, want %v", activation.Name, activation.Block, block)
+		}
+		delete(want, activation.Name)
+	}
+	if len(want) != 0 {
+		t.Errorf("missing activations: %v", want)
+	}
+}
+
+// Tests that the next scheduled protocol changes are found.
+func TestNextActivations(t *testing.T) {
+	config := &ChainConfig{
+		HomesteadBlock: big.NewInt(0),
+		DAOForkBlock:   big.NewInt(5), // Opposed, so it never activates anything
+		EIP150Block:    big.NewInt(10),
+		EIP155Block:    big.NewInt(20),
+		EIP158Block:    big.NewInt(20),
+	}
+	tests := []struct {
+		head  int64
+		block int64
+		names []string
+	}{
+		{0, 10, []string{"EIP-150"}},
+		{9, 10, []string{"EIP-150"}},
+		{10, 20, []string{"EIP-155", "EIP-160", "EIP-161", "EIP-170"}},
+		{20, 0, nil},
+	}
+	for i, tt := range tests {
+		next := config.NextActivations(big.NewInt(tt.head))
+		var names []string
+		for _, activation := range next {
+			if activation.Block.Int64() != tt.block {
+				t.Errorf("test %d: %s block mismatch: have %v, want %d", i, activation.Name, activation.Block, tt.block)
+			}
+			names = append(names, activation.Name)
+		}
+		if !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("test %d: activations mismatch: have %v, want %v", i, names, tt.names)
+		}
+	}
+}
+
+// Tests that the JSON tags of the chain config are well formed, catching typos
+// such as misspelled omitempty options, which encoding/json silently ignores.
+func TestChainConfigJSONTags(t *testing.T) {
+	kind := reflect.TypeOf(ChainConfig{})
+	names := make(map[string]string)
+	for i := 0; i < kind.NumField(); i++ {
+		field := kind.Field(i)
+		tag, ok := field.Tag.Lookup("json")
+		if !ok {
+			t.Errorf("%s: missing json tag", field.Name)
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		if parts[0] == "" {
+			t.Errorf("%s: empty json name", field.Name)
+		}
+		if other, ok := names[parts[0]]; ok {
+			t.Errorf("%s: json name %q already used by %s", field.Name, parts[0], other)
+		}
+		names[parts[0]] = field.Name
+
+		for _, option := range parts[1:] {
+			if option != "omitempty" {
+				t.Errorf("%s: unknown json tag option %q", field.Name, option)
+			}
+		}
+	}
+}
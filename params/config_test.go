@@ -17,6 +17,7 @@
 package params
 
 import (
+	"encoding/json"
 	"math/big"
 	"reflect"
 	"testing"
@@ -117,6 +118,68 @@ func TestCheckCompatible(t *testing.T) {
 			head:    25,
 			wantErr: nil,
 		},
+		{
+			stored:  &ChainConfig{IstanbulBlock: big.NewInt(30)},
+			new:     &ChainConfig{EIP152FBlock: big.NewInt(30), EIP1108FBlock: big.NewInt(30), EIP1344FBlock: big.NewInt(30), EIP2028FBlock: big.NewInt(30), EIP2200FBlock: big.NewInt(30)},
+			head:    25,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{EIP1884FBlock: big.NewInt(20), EIP2200FBlock: big.NewInt(20)},
+			new:    &ChainConfig{EIP2200FBlock: big.NewInt(20)},
+			head:   25,
+			wantErr: &ConfigCompatError{
+				What:         "EIP1884F fork block",
+				StoredConfig: big.NewInt(20),
+				NewConfig:    nil,
+				RewindTo:     19,
+			},
+		},
+		{
+			stored:  &ChainConfig{DifficultyBombDelays: BombDelaySchedule{{Block: big.NewInt(10), Delay: big.NewInt(1000)}}},
+			new:     &ChainConfig{DifficultyBombDelays: BombDelaySchedule{{Block: big.NewInt(10), Delay: big.NewInt(1000)}, {Block: big.NewInt(30), Delay: big.NewInt(500)}}},
+			head:    25,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{DifficultyBombDelays: BombDelaySchedule{{Block: big.NewInt(10), Delay: big.NewInt(1000)}}},
+			new:    &ChainConfig{DifficultyBombDelays: BombDelaySchedule{{Block: big.NewInt(10), Delay: big.NewInt(1000)}, {Block: big.NewInt(20), Delay: big.NewInt(500)}}},
+			head:   25,
+			wantErr: &ConfigCompatError{
+				What:         "difficulty bomb delay",
+				StoredConfig: big.NewInt(20),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     19,
+			},
+		},
+		{
+			stored:  &ChainConfig{BlockRewardSchedule: []BlockReward{{Block: big.NewInt(0), Reward: big.NewInt(5)}}},
+			new:     &ChainConfig{BlockRewardSchedule: []BlockReward{{Block: big.NewInt(0), Reward: big.NewInt(5), UncleRule: UncleRewardStandard}, {Block: big.NewInt(30), Reward: big.NewInt(3)}}},
+			head:    25,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{BlockRewardSchedule: []BlockReward{{Block: big.NewInt(0), Reward: big.NewInt(5)}, {Block: big.NewInt(20), Reward: big.NewInt(3)}}},
+			new:    &ChainConfig{BlockRewardSchedule: []BlockReward{{Block: big.NewInt(0), Reward: big.NewInt(5)}, {Block: big.NewInt(20), Reward: big.NewInt(3), EraLength: big.NewInt(10)}}},
+			head:   25,
+			wantErr: &ConfigCompatError{
+				What:         "block reward schedule",
+				StoredConfig: big.NewInt(20),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     19,
+			},
+		},
+		{
+			stored: &ChainConfig{},
+			new:    &ChainConfig{DifficultyBombDisabled: true},
+			head:   250000,
+			wantErr: &ConfigCompatError{
+				What:         "difficulty bomb disabled flag",
+				StoredConfig: big.NewInt(200000),
+				NewConfig:    big.NewInt(200000),
+				RewindTo:     199999,
+			},
+		},
 		{
 			stored: MainnetChainConfig,
 			new: func() *ChainConfig {
@@ -158,3 +221,47 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestIstanbulEIPs(t *testing.T) {
+	full := &ChainConfig{IstanbulBlock: big.NewInt(10)}
+	partial := &ChainConfig{
+		EIP152FBlock:  big.NewInt(10),
+		EIP1108FBlock: big.NewInt(10),
+		EIP1344FBlock: big.NewInt(10),
+		EIP2028FBlock: big.NewInt(10),
+		EIP2200FBlock: big.NewInt(10),
+	}
+	for _, num := range []int64{0, 9} {
+		r := full.Rules(big.NewInt(num))
+		if r.IsEIP152F || r.IsEIP1108F || r.IsEIP1344F || r.IsEIP1884F || r.IsEIP2028F || r.IsEIP2200F {
+			t.Errorf("block %d: Istanbul EIPs active before fork: %+v", num, r)
+		}
+	}
+	r := full.Rules(big.NewInt(10))
+	if !r.IsEIP152F || !r.IsEIP1108F || !r.IsEIP1344F || !r.IsEIP1884F || !r.IsEIP2028F || !r.IsEIP2200F {
+		t.Errorf("Istanbul block does not activate all Istanbul EIPs: %+v", r)
+	}
+	r = partial.Rules(big.NewInt(10))
+	if !r.IsEIP152F || !r.IsEIP1108F || !r.IsEIP1344F || !r.IsEIP2028F || !r.IsEIP2200F {
+		t.Errorf("individually scheduled Istanbul EIPs not active: %+v", r)
+	}
+	if r.IsEIP1884F || r.IsIstanbul {
+		t.Errorf("unscheduled EIP-1884 active: %+v", r)
+	}
+}
+
+func TestBlockRewardScheduleJSON(t *testing.T) {
+	var config ChainConfig
+	blob := `{"blockRewardSchedule": [{"block": 0, "reward": 5000000000000000000, "uncleRule": "ecip1017", "eraLength": 5000000, "eraRate": "4/5"}]}`
+	if err := json.Unmarshal([]byte(blob), &config); err != nil {
+		t.Fatalf("failed to decode chain config: %v", err)
+	}
+	reward := config.BlockRewardAt(big.NewInt(100))
+	if reward == nil || reward.UncleRule != UncleRewardECIP1017 || reward.EraRate.Cmp(big.NewRat(4, 5)) != 0 {
+		t.Errorf("block reward mismatch: %+v", reward)
+	}
+	blob = `{"blockRewardSchedule": [{"block": 0, "reward": 1, "uncleRule": "bogus"}]}`
+	if err := json.Unmarshal([]byte(blob), &config); err == nil {
+		t.Errorf("unknown uncle reward rule accepted")
+	}
+}
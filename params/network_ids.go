@@ -19,10 +19,11 @@ package params
 const (
 	NetworkIDFoundation  = 1
 	NetworkIDClassic     = 1
-	NetworkIDMordor = 7
+	NetworkIDMordor      = 7
 	NetworkIDSocial      = 28
 	NetworkIDEthersocial = 1
 	NetworkIDMix         = 76
+	NetworkIDMusicoin    = 7762959
 	NetworkIDTestnet     = 3
 	NetworkIDRinkeby     = 4
 	NetworkIDGoerli      = 5
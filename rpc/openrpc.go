@@ -16,6 +16,24 @@
 
 package rpc
 
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// openRPCVersion is the version of the OpenRPC specification the generated
+// documents follow, unless the descriptions specify otherwise.
+const openRPCVersion = "1.0.0"
+
 type OpenRPCDiscoverSchemaT struct {
 	OpenRPC    string                   `json:"openrpc"`
 	Info       map[string]interface{}   `json:"info"`
@@ -23,3 +41,368 @@ type OpenRPCDiscoverSchemaT struct {
 	Methods    []map[string]interface{} `json:"methods"`
 	Components map[string]interface{}   `json:"components"`
 }
+
+var (
+	bigIntType        = reflect.TypeOf(big.Int{})
+	hexBigType        = reflect.TypeOf(hexutil.Big{})
+	hexUint64Type     = reflect.TypeOf(hexutil.Uint64(0))
+	hexUintType       = reflect.TypeOf(hexutil.Uint(0))
+	hexBytesType      = reflect.TypeOf(hexutil.Bytes{})
+	addressType       = reflect.TypeOf(common.Address{})
+	hashType          = reflect.TypeOf(common.Hash{})
+	blockNumberType   = reflect.TypeOf(BlockNumber(0))
+	blockNrOrHashType = reflect.TypeOf(BlockNumberOrHash{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// hexSchema returns the JSON schema of a hex encoded string.
+func hexSchema(title, pattern string) map[string]interface{} {
+	return map[string]interface{}{"title": title, "type": "string", "pattern": pattern}
+}
+
+// knownSchemas returns the JSON schemas of the types whose JSON encoding can't
+// be derived from their Go definition.
+func knownSchemas() map[reflect.Type]map[string]interface{} {
+	blockNumber := map[string]interface{}{
+		"title": "blockNumber",
+		"oneOf": []interface{}{
+			map[string]interface{}{"title": "blockNumberTag", "type": "string", "enum": []string{"earliest", "latest", "pending"}},
+			hexSchema("integer", "^0x[a-fA-F0-9]+$"),
+		},
+	}
+	return map[reflect.Type]map[string]interface{}{
+		bigIntType:      {"title": "integer", "type": "integer"},
+		hexBigType:      hexSchema("integer", "^0x[a-fA-F0-9]+$"),
+		hexUint64Type:   hexSchema("integer", "^0x[a-fA-F0-9]+$"),
+		hexUintType:     hexSchema("integer", "^0x[a-fA-F0-9]+$"),
+		hexBytesType:    hexSchema("bytes", "^0x([a-fA-F0-9]{2})*$"),
+		addressType:     hexSchema("address", "^0x[a-fA-F0-9]{40}$"),
+		hashType:        hexSchema("hash", "^0x[a-fA-F0-9]{64}$"),
+		blockNumberType: blockNumber,
+		blockNrOrHashType: {
+			"title": "blockNumberOrHash",
+			"oneOf": []interface{}{
+				blockNumber,
+				hexSchema("hash", "^0x[a-fA-F0-9]{64}$"),
+				map[string]interface{}{
+					"title": "blockNumberOrHashObject",
+					"type":  "object",
+					"properties": map[string]interface{}{
+						"blockNumber":      blockNumber,
+						"blockHash":        hexSchema("hash", "^0x[a-fA-F0-9]{64}$"),
+						"requireCanonical": map[string]interface{}{"type": "boolean"},
+					},
+				},
+			},
+		},
+		rawMessageType: {},
+	}
+}
+
+// openRPCGenerator builds the JSON schemas of the parameters and results of
+// the RPC methods by reflecting over their Go types.
+type openRPCGenerator struct {
+	known   map[reflect.Type]map[string]interface{} // Schemas of types with custom encodings
+	schemas map[string]interface{}                  // Schemas of the named structs encountered
+	names   map[reflect.Type]string                 // Component names of the named structs
+}
+
+// schema returns the JSON schema of the given type. Named struct types are
+// stored as components and referenced, allowing recursive types.
+func (g *openRPCGenerator) schema(typ reflect.Type) map[string]interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if schema, ok := g.known[typ]; ok {
+		return schema
+	}
+	// Types encoding themselves can't be reflected upon, only the text ones are known
+	if typ.Implements(jsonMarshalerType) || reflect.PtrTo(typ).Implements(jsonMarshalerType) {
+		return map[string]interface{}{"title": typ.Name()}
+	}
+	if typ.Implements(textMarshalerType) || reflect.PtrTo(typ).Implements(textMarshalerType) {
+		return map[string]interface{}{"title": typ.Name(), "type": "string"}
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"title": "base64", "type": "string"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(typ.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return g.structSchema(typ)
+		}
+		name, ok := g.names[typ]
+		if !ok {
+			name = componentName(typ, g.schemas)
+			g.names[typ] = name
+			g.schemas[name] = nil // Reserve the name before descending into recursive types
+			g.schemas[name] = g.structSchema(typ)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	// Interfaces, channels and functions may hold anything
+	return map[string]interface{}{}
+}
+
+// structSchema returns the JSON schema of a struct, following the field naming
+// and embedding rules of encoding/json.
+func (g *openRPCGenerator) structSchema(typ reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	g.addFields(typ, properties)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if typ.Name() != "" {
+		schema["title"] = typ.Name()
+	}
+	return schema
+}
+
+// addFields adds the JSON encoded fields of a struct to a set of properties.
+func (g *openRPCGenerator) addFields(typ reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(embedded, properties)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := properties[name]; !ok {
+			properties[name] = g.schema(field.Type)
+		}
+	}
+}
+
+// componentName returns a unique name for the schema of a named type, qualified
+// by the name of its package.
+func componentName(typ reflect.Type, taken map[string]interface{}) string {
+	pkg := typ.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, typ.Name())
+	if pkg != "" {
+		name = pkg + "." + name
+	}
+	unique := name
+	for i := 2; ; i++ {
+		if _, ok := taken[unique]; !ok {
+			return unique
+		}
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+}
+
+// method documents a registered callback.
+func (g *openRPCGenerator) method(name string, cb *callback) map[string]interface{} {
+	params := make([]interface{}, len(cb.argTypes))
+	for i, typ := range cb.argTypes {
+		params[i] = map[string]interface{}{
+			"name":     fmt.Sprintf("arg%d", i),
+			"required": typ.Kind() != reflect.Ptr,
+			"schema":   g.schema(typ),
+		}
+	}
+	result := map[string]interface{}{
+		"name":   "null",
+		"schema": map[string]interface{}{"type": "null"},
+	}
+	fntype := cb.fn.Type()
+	for i := 0; i < fntype.NumOut(); i++ {
+		if i != cb.errPos {
+			result = map[string]interface{}{
+				"name":   name + "Result",
+				"schema": g.schema(fntype.Out(i)),
+			}
+			break
+		}
+	}
+	return map[string]interface{}{
+		"name":   name,
+		"params": params,
+		"result": result,
+	}
+}
+
+// OpenRPCDocument generates the OpenRPC document of the methods registered on
+// the server by reflecting over their signatures. The summaries, descriptions
+// and parameter names are merged in from the raw OpenRPC schema of the server,
+// along with the schemas of the types whose encoding can't be reflected upon.
+func (s *Server) OpenRPCDocument() (*OpenRPCDiscoverSchemaT, error) {
+	descs := new(OpenRPCDiscoverSchemaT)
+	if s.OpenRPCSchemaRaw != "" {
+		if err := json.Unmarshal([]byte(s.OpenRPCSchemaRaw), descs); err != nil {
+			return nil, fmt.Errorf("%v: %v", errOpenRPCDiscoverSchemaInvalid, err)
+		}
+	}
+	gen := &openRPCGenerator{
+		known:   knownSchemas(),
+		schemas: make(map[string]interface{}),
+		names:   make(map[reflect.Type]string),
+	}
+	// Document all the registered methods, sorted by name to keep the names of
+	// the generated components stable
+	s.services.mu.Lock()
+	callbacks := make(map[string]*callback)
+	for module, service := range s.services.services {
+		for name, cb := range service.callbacks {
+			callbacks[module+serviceMethodSeparators[0]+name] = cb
+		}
+	}
+	s.services.mu.Unlock()
+
+	names := make([]string, 0, len(callbacks))
+	for name := range callbacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	methods := make([]map[string]interface{}, len(names))
+	for i, name := range names {
+		methods[i] = gen.method(name, callbacks[name])
+	}
+	// Merge in the hand-written descriptions of the methods
+	written := make(map[string]map[string]interface{})
+	for _, method := range descs.Methods {
+		if name, ok := method["name"].(string); ok {
+			written[name] = method
+		}
+	}
+	for _, method := range methods {
+		if desc, ok := written[method["name"].(string)]; ok {
+			mergeMethod(method, desc, descs.Components)
+		}
+	}
+	// Assemble the document, keeping all the hand-written components
+	doc := &OpenRPCDiscoverSchemaT{
+		OpenRPC:    descs.OpenRPC,
+		Info:       descs.Info,
+		Servers:    make([]map[string]interface{}, 0),
+		Methods:    methods,
+		Components: make(map[string]interface{}),
+	}
+	if doc.OpenRPC == "" {
+		doc.OpenRPC = openRPCVersion
+	}
+	if doc.Info == nil {
+		doc.Info = map[string]interface{}{"title": "Ethereum JSON-RPC", "version": "1.0.0"}
+	}
+	for kind, components := range descs.Components {
+		doc.Components[kind] = components
+	}
+	schemas, _ := doc.Components["schemas"].(map[string]interface{})
+	if schemas == nil {
+		schemas = make(map[string]interface{})
+	}
+	for name, schema := range gen.schemas {
+		schemas[name] = schema
+	}
+	doc.Components["schemas"] = schemas
+	return doc, nil
+}
+
+// mergeMethod merges the hand-written description of a method into the generated
+// one. The generated schemas are kept, unless they are unconstrained.
+func mergeMethod(method, desc map[string]interface{}, components map[string]interface{}) {
+	for _, field := range []string{"summary", "description"} {
+		if v, ok := desc[field]; ok {
+			method[field] = v
+		}
+	}
+	params := method["params"].([]interface{})
+	if descParams, ok := desc["params"].([]interface{}); ok && len(descParams) == len(params) {
+		for i, param := range params {
+			descParam, ok := resolveDescriptor(descParams[i], components)
+			if ok {
+				mergeDescriptor(param.(map[string]interface{}), descParam)
+			}
+		}
+	}
+	if descResult, ok := resolveDescriptor(desc["result"], components); ok {
+		mergeDescriptor(method["result"].(map[string]interface{}), descResult)
+	}
+}
+
+// mergeDescriptor merges a hand-written content descriptor into a generated one.
+func mergeDescriptor(descriptor, desc map[string]interface{}) {
+	for _, field := range []string{"name", "summary", "description"} {
+		if v, ok := desc[field]; ok {
+			descriptor[field] = v
+		}
+	}
+	if schema, ok := desc["schema"]; ok && unconstrained(descriptor["schema"].(map[string]interface{})) {
+		descriptor["schema"] = schema
+	}
+}
+
+// resolveDescriptor returns a content descriptor, following its reference into
+// the components if needed.
+func resolveDescriptor(v interface{}, components map[string]interface{}) (map[string]interface{}, bool) {
+	desc, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	ref, ok := desc["$ref"].(string)
+	if !ok {
+		return desc, true
+	}
+	const prefix = "#/components/contentDescriptors/"
+	if !strings.HasPrefix(ref, prefix) {
+		return nil, false
+	}
+	descriptors, _ := components["contentDescriptors"].(map[string]interface{})
+	desc, ok = descriptors[strings.TrimPrefix(ref, prefix)].(map[string]interface{})
+	return desc, ok
+}
+
+// unconstrained reports whether a generated schema accepts any value, or any
+// object, as the Go type couldn't be reflected upon.
+func unconstrained(schema map[string]interface{}) bool {
+	for _, field := range []string{"$ref", "oneOf", "properties", "items", "pattern", "enum"} {
+		if _, ok := schema[field]; ok {
+			return false
+		}
+	}
+	if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok && !unconstrained(additional) {
+		return false
+	}
+	typ, _ := schema["type"].(string)
+	return typ == "" || typ == "object"
+}
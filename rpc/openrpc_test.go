@@ -0,0 +1,183 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"reflect"
+	"testing"
+)
+
+// findMethod returns the description of a method in an OpenRPC document.
+func findMethod(doc *OpenRPCDiscoverSchemaT, name string) map[string]interface{} {
+	for _, method := range doc.Methods {
+		if method["name"] == name {
+			return method
+		}
+	}
+	return nil
+}
+
+// Tests that the OpenRPC document is generated from the signatures of the
+// registered methods.
+func TestOpenRPCDocumentGeneration(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	doc, err := server.OpenRPCDocument()
+	if err != nil {
+		t.Fatalf("failed to generate document: %v", err)
+	}
+	for i := 1; i < len(doc.Methods); i++ {
+		if doc.Methods[i-1]["name"].(string) >= doc.Methods[i]["name"].(string) {
+			t.Fatalf("methods not sorted: %v before %v", doc.Methods[i-1]["name"], doc.Methods[i]["name"])
+		}
+	}
+	if findMethod(doc, "rpc_discover") == nil || findMethod(doc, "test_echo") == nil {
+		t.Fatalf("registered methods missing from document")
+	}
+	if findMethod(doc, "nftest_someSubscription") != nil {
+		t.Fatalf("subscription documented as method")
+	}
+	echo := findMethod(doc, "test_echo")
+	params := echo["params"].([]interface{})
+	if len(params) != 3 {
+		t.Fatalf("param count mismatch: have %d, want 3", len(params))
+	}
+	wants := []struct {
+		required bool
+		schema   map[string]interface{}
+	}{
+		{true, map[string]interface{}{"type": "string"}},
+		{true, map[string]interface{}{"type": "integer"}},
+		{false, map[string]interface{}{"$ref": "#/components/schemas/rpc.Args"}},
+	}
+	for i, want := range wants {
+		param := params[i].(map[string]interface{})
+		if param["required"] != want.required {
+			t.Errorf("param %d: required mismatch: have %v, want %v", i, param["required"], want.required)
+		}
+		if !reflect.DeepEqual(param["schema"], want.schema) {
+			t.Errorf("param %d: schema mismatch: have %v, want %v", i, param["schema"], want.schema)
+		}
+	}
+	result := echo["result"].(map[string]interface{})
+	if !reflect.DeepEqual(result["schema"], map[string]interface{}{"$ref": "#/components/schemas/rpc.Result"}) {
+		t.Errorf("result schema mismatch: have %v", result["schema"])
+	}
+	// Named structs should be defined as components
+	schemas := doc.Components["schemas"].(map[string]interface{})
+	res, ok := schemas["rpc.Result"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("result component missing")
+	}
+	want := map[string]interface{}{
+		"String": map[string]interface{}{"type": "string"},
+		"Int":    map[string]interface{}{"type": "integer"},
+		"Args":   map[string]interface{}{"$ref": "#/components/schemas/rpc.Args"},
+	}
+	if !reflect.DeepEqual(res["properties"], want) {
+		t.Errorf("result component mismatch: have %v, want %v", res["properties"], want)
+	}
+	// Methods without results should document null, errors should be skipped
+	if result := findMethod(doc, "test_returnError")["result"].(map[string]interface{}); !reflect.DeepEqual(result["schema"], map[string]interface{}{"type": "null"}) {
+		t.Errorf("error only result mismatch: have %v", result["schema"])
+	}
+	if result := findMethod(doc, "test_rets")["result"].(map[string]interface{}); !reflect.DeepEqual(result["schema"], map[string]interface{}{"type": "string"}) {
+		t.Errorf("value and error result mismatch: have %v", result["schema"])
+	}
+}
+
+// Tests that the hand-written descriptions are merged into the generated
+// document, without overriding the reflected schemas.
+func TestOpenRPCDocumentDescriptions(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	err := server.SetOpenRPCSchemaRaw(`{
+		"openrpc": "1.2.4",
+		"info": {"title": "test", "version": "1.0.0"},
+		"methods": [
+			{
+				"name": "test_echo",
+				"summary": "Echoes its arguments.",
+				"params": [
+					{"name": "str", "schema": {"type": "integer"}},
+					{"$ref": "#/components/contentDescriptors/Number"},
+					{"name": "args", "description": "Optional arguments."}
+				],
+				"result": {"name": "echo", "schema": {}}
+			},
+			{
+				"name": "test_callMeBack",
+				"params": [{"name": "method"}],
+				"result": {"name": "response", "schema": {"type": "string"}}
+			},
+			{
+				"name": "test_missing",
+				"params": []
+			}
+		],
+		"components": {
+			"contentDescriptors": {
+				"Number": {"name": "number", "description": "A number.", "schema": {"type": "string"}}
+			}
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("failed to set descriptions: %v", err)
+	}
+	doc, err := server.OpenRPCDocument()
+	if err != nil {
+		t.Fatalf("failed to generate document: %v", err)
+	}
+	if doc.OpenRPC != "1.2.4" || doc.Info["title"] != "test" {
+		t.Errorf("document header mismatch: have %v, %v", doc.OpenRPC, doc.Info)
+	}
+	if findMethod(doc, "test_missing") != nil {
+		t.Errorf("unavailable method documented")
+	}
+	if _, ok := doc.Components["contentDescriptors"]; !ok {
+		t.Errorf("hand-written components dropped")
+	}
+	echo := findMethod(doc, "test_echo")
+	if echo["summary"] != "Echoes its arguments." {
+		t.Errorf("summary mismatch: have %v", echo["summary"])
+	}
+	params := echo["params"].([]interface{})
+	wants := []map[string]interface{}{
+		{"name": "str", "required": true, "schema": map[string]interface{}{"type": "string"}},
+		{"name": "number", "required": true, "description": "A number.", "schema": map[string]interface{}{"type": "integer"}},
+		{"name": "args", "required": false, "description": "Optional arguments.", "schema": map[string]interface{}{"$ref": "#/components/schemas/rpc.Args"}},
+	}
+	for i, want := range wants {
+		if !reflect.DeepEqual(params[i], want) {
+			t.Errorf("param %d mismatch: have %v, want %v", i, params[i], want)
+		}
+	}
+	if name := echo["result"].(map[string]interface{})["name"]; name != "echo" {
+		t.Errorf("result name mismatch: have %v, want echo", name)
+	}
+	// Parameter counts not matching the signature should be ignored, but the
+	// unconstrained result schema should be taken from the descriptions
+	callback := findMethod(doc, "test_callMeBack")
+	if name := callback["params"].([]interface{})[0].(map[string]interface{})["name"]; name != "arg0" {
+		t.Errorf("mismatching param name merged: have %v", name)
+	}
+	if schema := callback["result"].(map[string]interface{})["schema"]; !reflect.DeepEqual(schema, map[string]interface{}{"type": "string"}) {
+		t.Errorf("unconstrained result schema mismatch: have %v", schema)
+	}
+}
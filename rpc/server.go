@@ -32,11 +32,10 @@ const MetadataApi = "rpc"
 
 var (
 	// defaultOpenRPCSchemaRaw can be used to establish a default (package-wide) OpenRPC schema from raw JSON.
-	// Methods will be cross referenced with actual registered method names in order to serve
-	// only server-enabled methods, enabling user and on-the-fly server endpoint availability configuration.
+	// The schema is not served as is, it only provides the descriptions of the methods
+	// documented by the generated OpenRPC document of the server.
 	defaultOpenRPCSchemaRaw string
 
-	errOpenRPCDiscoverUnavailable   = errors.New("openrpc discover data unavailable")
 	errOpenRPCDiscoverSchemaInvalid = errors.New("openrpc discover data invalid")
 )
 
@@ -194,65 +193,10 @@ func (s *RPCService) Modules() map[string]string {
 	return modules
 }
 
-func (s *RPCService) methods() map[string][]string {
-	s.server.services.mu.Lock()
-	defer s.server.services.mu.Unlock()
-
-	methods := make(map[string][]string)
-	for name, ser := range s.server.services.services {
-		for s := range ser.callbacks {
-			_, ok := methods[name]
-			if !ok {
-				methods[name] = []string{s}
-			} else {
-				methods[name] = append(methods[name], s)
-			}
-		}
-	}
-	return methods
-}
-
-// Discover returns a configured schema that is audited for actual server availability.
-// Only methods that the server makes available are included in the 'methods' array of
-// the discover schema. Components are not audited.
-func (s *RPCService) Discover() (schema *OpenRPCDiscoverSchemaT, err error) {
-	if s.server.OpenRPCSchemaRaw == "" {
-		return nil, errOpenRPCDiscoverUnavailable
-	}
-	schema = &OpenRPCDiscoverSchemaT{
-		Servers: make([]map[string]interface{}, 0),
-	}
-	err = json.Unmarshal([]byte(s.server.OpenRPCSchemaRaw), schema)
-	if err != nil {
-		log.Crit("openrpc json umarshal", "error", err)
-	}
-
-	// Audit documented schema methods vs. actual server availability
-	// This removes methods described in the OpenRPC JSON schema document
-	// which are not currently exposed on the server's API.
-	// This is done on the fly (as opposed to at servre init or schema setting)
-	// because it's possible that exposed APIs could be modified in proc.
-	schemaMethodsAvailable := []map[string]interface{}{}
-	serverMethodsAvailable := s.methods()
-
-	for _, m := range schema.Methods {
-		module, path, err := elementizeMethodName(m["name"].(string))
-		if err != nil {
-			return nil, err
-		}
-		paths, ok := serverMethodsAvailable[module]
-		if !ok {
-			continue
-		}
-
-		// the module exists, does the path exist?
-		for _, pa := range paths {
-			if pa == path {
-				schemaMethodsAvailable = append(schemaMethodsAvailable, m)
-				break
-			}
-		}
-	}
-	schema.Methods = schemaMethodsAvailable
-	return
+// Discover returns the OpenRPC document of the methods the server makes available,
+// generated from their signatures and annotated with the descriptions found in
+// the configured raw schema. Since the document is generated on the fly, it
+// reflects the APIs registered at the time of the call.
+func (s *RPCService) Discover() (*OpenRPCDiscoverSchemaT, error) {
+	return s.server.OpenRPCDocument()
 }
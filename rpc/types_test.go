@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 )
 
@@ -64,3 +65,51 @@ func TestBlockNumberJSONUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+func TestBlockNumberOrHashJSONUnmarshal(t *testing.T) {
+	hash := common.HexToHash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+	tests := []struct {
+		input    string
+		mustFail bool
+		expected BlockNumberOrHash
+	}{
+		0:  {`"0x"`, true, BlockNumberOrHash{}},
+		1:  {`"0x0"`, false, BlockNumberOrHashWithNumber(0)},
+		2:  {`"0x12"`, false, BlockNumberOrHashWithNumber(18)},
+		3:  {`"pending"`, false, BlockNumberOrHashWithNumber(PendingBlockNumber)},
+		4:  {`"latest"`, false, BlockNumberOrHashWithNumber(LatestBlockNumber)},
+		5:  {`"earliest"`, false, BlockNumberOrHashWithNumber(EarliestBlockNumber)},
+		6:  {`"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"`, false, BlockNumberOrHashWithHash(hash, false)},
+		7:  {`"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b4zz"`, true, BlockNumberOrHash{}},
+		8:  {`{"blockNumber":"0x1"}`, false, BlockNumberOrHashWithNumber(1)},
+		9:  {`{"blockNumber":"latest"}`, false, BlockNumberOrHashWithNumber(LatestBlockNumber)},
+		10: {`{"blockHash":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"}`, false, BlockNumberOrHashWithHash(hash, false)},
+		11: {`{"blockHash":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421","requireCanonical":true}`, false, BlockNumberOrHashWithHash(hash, true)},
+		12: {`{"blockNumber":"0x1","blockHash":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"}`, true, BlockNumberOrHash{}},
+		13: {`{}`, true, BlockNumberOrHash{}},
+		14: {`someString`, true, BlockNumberOrHash{}},
+	}
+
+	for i, test := range tests {
+		var bnh BlockNumberOrHash
+		err := json.Unmarshal([]byte(test.input), &bnh)
+		if test.mustFail && err == nil {
+			t.Errorf("Test %d should fail", i)
+			continue
+		}
+		if !test.mustFail && err != nil {
+			t.Errorf("Test %d should pass but got err: %v", i, err)
+			continue
+		}
+		if test.mustFail {
+			continue
+		}
+		haveNum, haveNumOk := bnh.Number()
+		wantNum, wantNumOk := test.expected.Number()
+		haveHash, haveHashOk := bnh.Hash()
+		wantHash, wantHashOk := test.expected.Hash()
+		if haveNum != wantNum || haveNumOk != wantNumOk || haveHash != wantHash || haveHashOk != wantHashOk || bnh.RequireCanonical != test.expected.RequireCanonical {
+			t.Errorf("Test %d got unexpected value, want %+v, got %+v", i, test.expected, bnh)
+		}
+	}
+}
@@ -18,6 +18,7 @@ package tests
 
 import (
 	"fmt"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -36,6 +37,7 @@ type TransactionTest struct {
 	EIP158         ttFork
 	Frontier       ttFork
 	Homestead      ttFork
+	Istanbul       ttFork
 }
 
 type ttFork struct {
@@ -45,7 +47,7 @@ type ttFork struct {
 
 func (tt *TransactionTest) Run(config *params.ChainConfig) error {
 
-	validateTx := func(rlpData hexutil.Bytes, signer types.Signer, isHomestead bool) (*common.Address, *common.Hash, error) {
+	validateTx := func(rlpData hexutil.Bytes, signer types.Signer, fork *params.ChainConfig) (*common.Address, *common.Hash, error) {
 		tx := new(types.Transaction)
 		if err := rlp.DecodeBytes(rlpData, tx); err != nil {
 			return nil, nil, err
@@ -55,7 +57,7 @@ func (tt *TransactionTest) Run(config *params.ChainConfig) error {
 			return nil, nil, err
 		}
 		// Intrinsic gas
-		requiredGas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, isHomestead)
+		requiredGas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, fork.IsEIP2F(new(big.Int)), fork.IsEIP2028F(new(big.Int)))
 		if err != nil {
 			return nil, nil, err
 		}
@@ -67,19 +69,19 @@ func (tt *TransactionTest) Run(config *params.ChainConfig) error {
 	}
 
 	for _, testcase := range []struct {
-		name        string
-		signer      types.Signer
-		fork        ttFork
-		isHomestead bool
+		name   string
+		signer types.Signer
+		fork   ttFork
 	}{
-		{"Frontier", types.FrontierSigner{}, tt.Frontier, false},
-		{"Homestead", types.HomesteadSigner{}, tt.Homestead, true},
-		{"EIP150", types.HomesteadSigner{}, tt.EIP150, true},
-		{"EIP158", types.NewEIP155Signer(config.ChainID), tt.EIP158, true},
-		{"Byzantium", types.NewEIP155Signer(config.ChainID), tt.Byzantium, true},
-		{"Constantinople", types.NewEIP155Signer(config.ChainID), tt.Constantinople, true},
+		{"Frontier", types.FrontierSigner{}, tt.Frontier},
+		{"Homestead", types.HomesteadSigner{}, tt.Homestead},
+		{"EIP150", types.HomesteadSigner{}, tt.EIP150},
+		{"EIP158", types.NewEIP155Signer(config.ChainID), tt.EIP158},
+		{"Byzantium", types.NewEIP155Signer(config.ChainID), tt.Byzantium},
+		{"Constantinople", types.NewEIP155Signer(config.ChainID), tt.Constantinople},
+		{"Istanbul", types.NewEIP155Signer(config.ChainID), tt.Istanbul},
 	} {
-		sender, txhash, err := validateTx(tt.RLP, testcase.signer, testcase.isHomestead)
+		sender, txhash, err := validateTx(tt.RLP, testcase.signer, Forks[testcase.name])
 
 		if testcase.fork.Sender == (common.UnprefixedAddress{}) {
 			if err == nil {